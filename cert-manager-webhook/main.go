@@ -0,0 +1,21 @@
+// Command cert-manager-webhook is a cert-manager webhook solver that solves DNS-01 challenges with records served by
+// Ephemerain. It is kept out of the server module so that the server doesn't depend on Kubernetes.
+package main
+
+import (
+	"cert-manager-webhook/solver"
+	"fmt"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/cmd"
+	"os"
+)
+
+func main() {
+	// The API group is chosen when the webhook is deployed, and has to match the groupName of issuers that use it
+	groupName := os.Getenv("GROUP_NAME")
+	if groupName == "" {
+		fmt.Fprintln(os.Stderr, "GROUP_NAME must be set")
+		os.Exit(1)
+	}
+
+	cmd.RunWebhookServer(groupName, solver.New())
+}
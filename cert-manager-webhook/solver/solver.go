@@ -0,0 +1,143 @@
+// Package solver implements cert-manager's webhook Solver interface with the Ephemerain HTTP API.
+package solver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"server/challenge"
+	"server/client"
+	"sync"
+)
+
+// defaultTTL is the TTL of challenge records when the issuer doesn't configure one.
+const defaultTTL = 60
+
+// Config is the solver configuration of an issuer, for example:
+//
+//	webhook:
+//	  groupName: acme.example.com
+//	  solverName: ephemerain
+//	  config:
+//	    endpoint: http://ephemerain.ephemerain.svc/v1
+//	    tokenSecretRef:
+//	      name: ephemerain
+//	      key: token
+type Config struct {
+	// Endpoint is the base URL of the API, including the /v1 prefix.
+	Endpoint string `json:"endpoint"`
+	// TokenSecretRef refers to a secret in the namespace of the issuer holding the API token. It is optional.
+	TokenSecretRef *cmmeta.SecretKeySelector `json:"tokenSecretRef,omitempty"`
+	TTL            uint32                    `json:"ttl,omitempty"`
+}
+
+// loadConfig decodes the configuration of an issuer, filling in defaults.
+func loadConfig(raw *extapi.JSON) (Config, error) {
+	config := Config{TTL: defaultTTL}
+	if raw == nil {
+		return config, errors.New("the solver configuration is missing")
+	}
+	if err := json.Unmarshal(raw.Raw, &config); err != nil {
+		return config, fmt.Errorf("decoding the solver configuration: %w", err)
+	}
+	if config.Endpoint == "" {
+		return config, errors.New("the endpoint is missing from the solver configuration")
+	}
+	return config, nil
+}
+
+var _ webhook.Solver = (*Solver)(nil)
+
+// Solver solves DNS-01 challenges for issuers with the Ephemerain solver configured.
+type Solver struct {
+	kubeClient kubernetes.Interface
+
+	// solvers are kept per endpoint, token and TTL, so that challenges solved with the same configuration share a
+	// challenge.Solver and don't overwrite each other's values
+	mu      sync.Mutex
+	solvers map[string]*challenge.Solver
+}
+
+func New() *Solver {
+	return &Solver{solvers: map[string]*challenge.Solver{}}
+}
+
+// Name is the solverName that issuers refer to the solver by.
+func (s *Solver) Name() string {
+	return "ephemerain"
+}
+
+// Initialize sets up the Kubernetes client used to read API tokens from secrets.
+func (s *Solver) Initialize(kubeClientConfig *rest.Config, stopCh <-chan struct{}) error {
+	kubeClient, err := kubernetes.NewForConfig(kubeClientConfig)
+	if err != nil {
+		return fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+	s.kubeClient = kubeClient
+	return nil
+}
+
+// Present adds the challenge key to the TXT record, keeping any other values it has.
+func (s *Solver) Present(ch *v1alpha1.ChallengeRequest) error {
+	ctx := context.Background()
+	solver, err := s.solverFor(ctx, ch)
+	if err != nil {
+		return err
+	}
+	return solver.Present(ctx, ch.ResolvedFQDN, ch.Key)
+}
+
+// CleanUp removes the challenge key from the TXT record, deleting the record if it was the only value.
+func (s *Solver) CleanUp(ch *v1alpha1.ChallengeRequest) error {
+	ctx := context.Background()
+	solver, err := s.solverFor(ctx, ch)
+	if err != nil {
+		return err
+	}
+	return solver.CleanUp(ctx, ch.ResolvedFQDN, ch.Key)
+}
+
+func (s *Solver) solverFor(ctx context.Context, ch *v1alpha1.ChallengeRequest) (*challenge.Solver, error) {
+	config, err := loadConfig(ch.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	var token string
+	if config.TokenSecretRef != nil {
+		secret, err := s.kubeClient.CoreV1().Secrets(ch.ResourceNamespace).Get(ctx, config.TokenSecretRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("getting API token secret: %w", err)
+		}
+		value, ok := secret.Data[config.TokenSecretRef.Key]
+		if !ok {
+			return nil, fmt.Errorf("API token secret %s/%s has no key %q", ch.ResourceNamespace, config.TokenSecretRef.Name, config.TokenSecretRef.Key)
+		}
+		token = string(value)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := fmt.Sprintf("%s %s %d", config.Endpoint, token, config.TTL)
+	if solver, ok := s.solvers[key]; ok {
+		return solver, nil
+	}
+	var options []client.ClientOption
+	if token != "" {
+		options = append(options, client.WithBearerToken(token))
+	}
+	apiClient, err := client.NewClientWithResponses(config.Endpoint, options...)
+	if err != nil {
+		return nil, err
+	}
+	s.solvers[key] = challenge.NewSolver(apiClient, config.TTL)
+	return s.solvers[key], nil
+}
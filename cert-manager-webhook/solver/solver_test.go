@@ -0,0 +1,92 @@
+package solver
+
+import (
+	"context"
+	"fmt"
+	"github.com/cert-manager/cert-manager/pkg/acme/webhook/apis/acme/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	extapi "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := loadConfig(&extapi.JSON{Raw: []byte(`{"endpoint": "http://ephemerain/v1", "tokenSecretRef": {"name": "ephemerain", "key": "token"}}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://ephemerain/v1", config.Endpoint)
+	assert.Equal(t, "ephemerain", config.TokenSecretRef.Name)
+	assert.Equal(t, "token", config.TokenSecretRef.Key)
+	assert.Equal(t, uint32(defaultTTL), config.TTL)
+
+	_, err = loadConfig(&extapi.JSON{Raw: []byte(`{"ttl": 30}`)})
+	assert.Error(t, err)
+	_, err = loadConfig(nil)
+	assert.Error(t, err)
+}
+
+// TestSolver runs the checks of cert-manager's DNS-01 webhook conformance suite against a running Ephemerain, going
+// through the issuer configuration and token secret the way cert-manager does. TestCertManagerWebhook in the server
+// module starts the server, creates a tenant owning EPHEMERAIN_ZONE and runs this test with the tenant's token.
+func TestSolver(t *testing.T) {
+	endpoint, nameserver, zone := os.Getenv("EPHEMERAIN_ENDPOINT"), os.Getenv("EPHEMERAIN_NAMESERVER"), os.Getenv("EPHEMERAIN_ZONE")
+	if endpoint == "" || nameserver == "" || zone == "" {
+		t.Skip("Run by TestCertManagerWebhook in the server module, which starts a server to run it against")
+	}
+	ctx := context.Background()
+	fqdn := "_acme-challenge." + zone
+
+	solver := New()
+	solver.kubeClient = fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "certs", Name: "ephemerain"},
+		Data:       map[string][]byte{"token": []byte(os.Getenv("EPHEMERAIN_TOKEN")), "other": []byte("not-a-token")},
+	})
+	challenge := func(key string, secretKey string) *v1alpha1.ChallengeRequest {
+		return &v1alpha1.ChallengeRequest{
+			ResourceNamespace: "certs",
+			ResolvedFQDN:      fqdn,
+			Key:               key,
+			Config:            &extapi.JSON{Raw: []byte(fmt.Sprintf(`{"endpoint": %q, "tokenSecretRef": {"name": "ephemerain", "key": %q}}`, endpoint, secretKey))},
+		}
+	}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialer := net.Dialer{}
+			return dialer.DialContext(ctx, network, nameserver)
+		},
+	}
+	lookup := func() []string {
+		txt, err := resolver.LookupTXT(ctx, fqdn)
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return nil
+		}
+		assert.NoError(t, err)
+		return txt
+	}
+
+	// Basic: a presented record can be looked up, and is gone once it is cleaned up
+	assert.NoError(t, solver.Present(challenge("123d==", "token")))
+	assert.Equal(t, []string{"123d=="}, lookup())
+	assert.NoError(t, solver.CleanUp(challenge("123d==", "token")))
+	assert.Empty(t, lookup())
+
+	// Extended: presenting the same key twice is fine, and cleaning up one key keeps the others
+	assert.NoError(t, solver.Present(challenge("123d==", "token")))
+	assert.NoError(t, solver.Present(challenge("123d==", "token")))
+	assert.NoError(t, solver.Present(challenge("456e==", "token")))
+	assert.ElementsMatch(t, []string{"123d==", "456e=="}, lookup())
+	assert.NoError(t, solver.CleanUp(challenge("123d==", "token")))
+	assert.Equal(t, []string{"456e=="}, lookup())
+	assert.NoError(t, solver.CleanUp(challenge("456e==", "token")))
+	assert.NoError(t, solver.CleanUp(challenge("456e==", "token")))
+	assert.Empty(t, lookup())
+
+	// The token comes from the secret, so a key with the wrong token or a key the secret doesn't have fails
+	assert.Error(t, solver.Present(challenge("789f==", "other")))
+	assert.Error(t, solver.Present(challenge("789f==", "missing")))
+	assert.Empty(t, lookup())
+}
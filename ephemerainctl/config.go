@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Environment variables that override the config file.
+const (
+	envConfig     = "EPHEMERAIN_CONFIG"
+	envEndpoint   = "EPHEMERAIN_ENDPOINT"
+	envToken      = "EPHEMERAIN_TOKEN"
+	envNameserver = "EPHEMERAIN_NAMESERVER"
+)
+
+// Config is which server to use and how to authenticate with it. It is read from a JSON config file, then overridden
+// by environment variables and then by flags.
+type Config struct {
+	// Endpoint is the base URL of the API, including the /v1 prefix, e.g. http://ephemerain/v1.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Token is sent as a bearer token with every API request. It is optional.
+	Token string `json:"token,omitempty"`
+	// Nameserver is the host:port of the server's DNS listener, which updates are sent to and answers are waited for
+	// from. It defaults to port 53 of the endpoint's host.
+	Nameserver string `json:"nameserver,omitempty"`
+}
+
+// loadConfig reads the config file at path, or at the path in the environment or the default path if path is empty,
+// and overrides it with the environment. A missing config file is only an error if its path was given.
+func loadConfig(path string, getenv func(string) string) (Config, error) {
+	explicit := true
+	if path == "" {
+		path = getenv(envConfig)
+	}
+	if path == "" {
+		explicit = false
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "ephemerain", "config.json")
+		}
+	}
+
+	var config Config
+	if path != "" {
+		raw, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		case err != nil:
+			return Config{}, fmt.Errorf("reading config file: %w", err)
+		default:
+			if err := json.Unmarshal(raw, &config); err != nil {
+				return Config{}, fmt.Errorf("parsing config file %s: %w", path, err)
+			}
+		}
+	}
+	return config.override(Config{Endpoint: getenv(envEndpoint), Token: getenv(envToken), Nameserver: getenv(envNameserver)}), nil
+}
+
+// override returns c with every field that is set in overrides replaced.
+func (c Config) override(overrides Config) Config {
+	if overrides.Endpoint != "" {
+		c.Endpoint = overrides.Endpoint
+	}
+	if overrides.Token != "" {
+		c.Token = overrides.Token
+	}
+	if overrides.Nameserver != "" {
+		c.Nameserver = overrides.Nameserver
+	}
+	return c
+}
+
+// nameserver returns the host:port DNS messages are sent to.
+func (c Config) nameserver() (string, error) {
+	if c.Nameserver != "" {
+		if _, _, err := net.SplitHostPort(c.Nameserver); err != nil {
+			return net.JoinHostPort(c.Nameserver, "53"), nil
+		}
+		return c.Nameserver, nil
+	}
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil || endpoint.Hostname() == "" {
+		return "", fmt.Errorf("no nameserver is configured, set %s or use -nameserver", envNameserver)
+	}
+	return net.JoinHostPort(endpoint.Hostname(), "53"), nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"endpoint": "http://file/v1", "token": "file-token"}`), 0600))
+
+	env := map[string]string{envConfig: path, envToken: "env-token"}
+	config, err := loadConfig("", func(name string) string { return env[name] })
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Endpoint: "http://file/v1", Token: "env-token"}, config, "the environment overrides the config file")
+	nameserver, err := config.nameserver()
+	assert.NoError(t, err)
+	assert.Equal(t, "file:53", nameserver)
+
+	config = config.override(Config{Nameserver: "127.0.0.1:5353"})
+	nameserver, err = config.nameserver()
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:5353", nameserver)
+
+	// A config file that was asked for has to exist
+	_, err = loadConfig(filepath.Join(t.TempDir(), "missing.json"), func(string) string { return "" })
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+// Command ephemerainctl manages the records of an Ephemerain server from the command line, through its HTTP API and
+// RFC 2136 dynamic updates. It is kept out of the server module so that it can be built and versioned on its own.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"server/client"
+)
+
+const usage = `Usage: ephemerainctl [flags] <command> [command flags] [arguments]
+
+Commands:
+  record get <name> <type>             Print a record
+  record set <name> <type> <value>...  Replace every value of a record
+  record delete <name> <type>          Delete a record
+  zone export <zone>                   Print every record in a zone
+  zone import [file]                   Import the records in a zone file, or standard input
+  update [file]                        Send the nsupdate-style script in file, or standard input, as RFC 2136 updates
+  wait <name> <type> <value>           Wait until the server's DNS answer has the value
+
+Flags:
+`
+
+// usageError is returned for invalid command lines, which are reported along with the usage.
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...interface{}) error {
+	return usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// cli is what every command runs with.
+type cli struct {
+	config Config
+	output outputFormat
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// apiClient returns a client for the configured endpoint.
+func (c *cli) apiClient() (*client.ClientWithResponses, error) {
+	if c.config.Endpoint == "" {
+		return nil, fmt.Errorf("no endpoint is configured, set %s or use -endpoint", envEndpoint)
+	}
+	var options []client.ClientOption
+	if c.config.Token != "" {
+		options = append(options, client.WithBearerToken(c.config.Token))
+	}
+	return client.NewClientWithResponses(c.config.Endpoint, options...)
+}
+
+// commands maps command names, including the group for grouped commands, to what runs them.
+var commands = map[string]func(ctx context.Context, c *cli, args []string) error{
+	"record get":    recordGet,
+	"record set":    recordSet,
+	"record delete": recordDelete,
+	"zone export":   zoneExport,
+	"zone import":   zoneImport,
+	"update":        update,
+	"wait":          wait,
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	flags := flag.NewFlagSet("ephemerainctl", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", "", "Path of the config file. Defaults to $"+envConfig+", or ephemerain/config.json in the user config directory")
+	endpoint := flags.String("endpoint", "", "Base URL of the API, including the /v1 prefix. Overrides $"+envEndpoint)
+	token := flags.String("token", "", "API token. Overrides $"+envToken)
+	nameserver := flags.String("nameserver", "", "host:port of the server's DNS listener. Overrides $"+envNameserver+", and defaults to port 53 of the endpoint's host")
+	output := flags.String("o", string(outputTable), "Output format: table, json or zone")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	config, err := loadConfig(*configPath, os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ephemerainctl:", err)
+		os.Exit(1)
+	}
+	config = config.override(Config{Endpoint: *endpoint, Token: *token, Nameserver: *nameserver})
+
+	c := &cli{config: config, output: outputFormat(*output), stdin: os.Stdin, stdout: os.Stdout}
+	if err := run(ctx, c, flags.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "ephemerainctl:", err)
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			flags.Usage()
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// run runs the command named by the first arguments.
+func run(ctx context.Context, c *cli, args []string) error {
+	if !c.output.valid() {
+		return usagef("unknown output format %q", c.output)
+	}
+	if len(args) == 0 {
+		return usagef("no command given")
+	}
+	if command, ok := commands[args[0]]; ok {
+		return command(ctx, c, args[1:])
+	}
+	if len(args) == 1 {
+		return usagef("unknown command %q", args[0])
+	}
+	if command, ok := commands[args[0]+" "+args[1]]; ok {
+		return command(ctx, c, args[2:])
+	}
+	return usagef("unknown command %q", args[0]+" "+args[1])
+}
+
+// parseFlags parses the flags of a command, which come before its arguments, and checks that it has between minArgs
+// and maxArgs arguments. A negative maxArgs allows any number of them.
+func parseFlags(flags *flag.FlagSet, args []string, minArgs int, maxArgs int) ([]string, error) {
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		return nil, usagef("%s: %s", flags.Name(), err)
+	}
+	args = flags.Args()
+	if len(args) < minArgs || (maxArgs >= 0 && len(args) > maxArgs) {
+		return nil, usagef("%s: wrong number of arguments", flags.Name())
+	}
+	return args, nil
+}
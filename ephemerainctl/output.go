@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/miekg/dns"
+	"io"
+	"net/http"
+	"server/client"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// maxTXTStringLength is the length of the longest character-string a TXT record can hold.
+const maxTXTStringLength = 255
+
+type outputFormat string
+
+const (
+	outputTable outputFormat = "table"
+	outputJSON  outputFormat = "json"
+	outputZone  outputFormat = "zone"
+)
+
+func (f outputFormat) valid() bool {
+	return f == outputTable || f == outputJSON || f == outputZone
+}
+
+func writeJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// writeRecords writes records as a table with a row for every value, or as an RFC 1035 zone file.
+func writeRecords(w io.Writer, format outputFormat, records []client.ZoneExportRecord) error {
+	if format == outputZone {
+		return writeZoneFile(w, records)
+	}
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tTYPE\tTTL\tVALUE")
+	for _, record := range records {
+		for _, value := range record.Values {
+			fmt.Fprintf(table, "%s\t%s\t%d\t%s\n", record.Domain, record.Type, record.Ttl, value)
+		}
+	}
+	return table.Flush()
+}
+
+// writeZoneFile writes records as an RFC 1035 zone file with fully qualified names. Values that aren't valid for
+// their type are written as comments.
+func writeZoneFile(w io.Writer, records []client.ZoneExportRecord) error {
+	for _, record := range records {
+		for _, value := range record.Values {
+			rr, err := resourceRecord(record.Domain, record.Type, record.Ttl, value)
+			if err != nil {
+				if _, err := fmt.Fprintf(w, "; %s %s %s: %s\n", record.Domain, record.Type, value, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintln(w, rr.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resourceRecord returns the record with a value as the API stores it, which is the text for TXT records and the
+// presentation format for every other type.
+func resourceRecord(name string, recordType string, ttl uint32, value string) (dns.RR, error) {
+	if recordType == "TXT" {
+		return &dns.TXT{Hdr: dns.RR_Header{Name: dns.Fqdn(name), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: ttl}, Txt: splitTXT(value)}, nil
+	}
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(name), ttl, recordType, value))
+	if err != nil {
+		return nil, err
+	}
+	if rr == nil {
+		return nil, fmt.Errorf("missing value")
+	}
+	return rr, nil
+}
+
+// splitTXT splits text into as many character-strings as a TXT record needs to hold it.
+func splitTXT(text string) []string {
+	var strs []string
+	for len(text) > maxTXTStringLength {
+		strs = append(strs, text[:maxTXTStringLength])
+		text = text[maxTXTStringLength:]
+	}
+	return append(strs, text)
+}
+
+// writeImportReport writes what importing a zone file did, with a row for every record.
+func writeImportReport(w io.Writer, format outputFormat, report client.ZoneImportReport) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(w, report)
+	case outputZone:
+		return fmt.Errorf("import reports can't be written as a zone file")
+	}
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "RESULT\tLINE\tNAME\tTYPE\tVALUE\tREASON")
+	for _, group := range []struct {
+		result  string
+		records []client.ZoneImportRecord
+	}{
+		{"created", report.Created},
+		{"updated", report.Updated},
+		{"skipped", report.Skipped},
+		{"failed", report.Failed},
+	} {
+		for _, record := range group.records {
+			fmt.Fprintf(table, "%s\t%d\t%s\t%s\t%s\t%s\n", group.result, record.Line, valueOf(record.Domain), valueOf(record.Type), valueOf(record.Value), valueOf(record.Reason))
+		}
+	}
+	if report.Deleted != nil {
+		for _, record := range *report.Deleted {
+			fmt.Fprintf(table, "deleted\t\t%s\t%s\t\t\n", record.Domain, record.Type)
+		}
+	}
+	return table.Flush()
+}
+
+func valueOf(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// unexpectedStatus describes an API response that didn't have the status a command expected. The API only returns
+// statuses for errors, so the most common ones get a hint.
+func unexpectedStatus(response *http.Response) error {
+	switch response.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%s, check that the token is allowed to do this", response.Status)
+	case http.StatusTooManyRequests:
+		if retryAfter, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+			return fmt.Errorf("%s, retry in %d seconds", response.Status, retryAfter)
+		}
+	}
+	return fmt.Errorf("unexpected status %s", strings.TrimSpace(response.Status))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"server/client"
+	"strings"
+	"testing"
+)
+
+func TestWriteRecords(t *testing.T) {
+	records := []client.ZoneExportRecord{
+		{Domain: "www.example.com.", Type: "A", Ttl: 300, Values: []string{"192.0.2.1", "192.0.2.2"}},
+		{Domain: "example.com.", Type: "TXT", Ttl: 60, Values: []string{`say "hi"`, strings.Repeat("x", 300)}},
+		{Domain: "bad.example.com.", Type: "A", Ttl: 60, Values: []string{"not-an-address"}},
+	}
+
+	var table strings.Builder
+	assert.NoError(t, writeRecords(&table, outputTable, records[:1]))
+	assert.Equal(t, "NAME              TYPE  TTL  VALUE\nwww.example.com.  A     300  192.0.2.1\nwww.example.com.  A     300  192.0.2.2\n", table.String())
+
+	var zone strings.Builder
+	assert.NoError(t, writeRecords(&zone, outputZone, records))
+	lines := strings.Split(strings.TrimSuffix(zone.String(), "\n"), "\n")
+	assert.Len(t, lines, 5)
+	assert.Equal(t, "www.example.com.\t300\tIN\tA\t192.0.2.1", lines[0])
+	assert.Equal(t, `example.com.	60	IN	TXT	"say \"hi\""`, lines[2])
+	assert.Equal(t, `example.com.	60	IN	TXT	"`+strings.Repeat("x", 255)+`" "`+strings.Repeat("x", 45)+`"`, lines[3], "long TXT values are split into character-strings")
+	assert.True(t, strings.HasPrefix(lines[4], "; bad.example.com. A not-an-address: "), lines[4])
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/miekg/dns"
+	"net/http"
+	"server/client"
+	"strings"
+)
+
+// defaultTTL is the TTL the server gives records that are set without one.
+const defaultTTL = 60
+
+func viewParam(view string) *client.View {
+	if view == "" {
+		return nil
+	}
+	v := client.View(view)
+	return &v
+}
+
+func recordGet(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("record get", flag.ContinueOnError)
+	view := flags.String("view", "", "Split-horizon view to read the record from")
+	args, err := parseFlags(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	apiClient, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+
+	name, recordType := dns.Fqdn(args[0]), strings.ToUpper(args[1])
+	response, err := apiClient.GetDomainWithResponse(ctx, client.Domain(name), client.RecordType(recordType), &client.GetDomainParams{View: viewParam(*view)})
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() == http.StatusNotFound {
+		return fmt.Errorf("there is no %s record for %s", recordType, name)
+	}
+	if response.JSON200 == nil {
+		return unexpectedStatus(response.HTTPResponse)
+	}
+
+	record := client.ZoneExportRecord{Domain: name, Type: recordType, Ttl: defaultTTL}
+	if response.JSON200.Values != nil {
+		record.Values = *response.JSON200.Values
+	} else if response.JSON200.Value != nil {
+		record.Values = []string{*response.JSON200.Value}
+	}
+	if response.JSON200.Ttl != nil {
+		record.Ttl = *response.JSON200.Ttl
+	}
+	if c.output == outputJSON {
+		return writeJSON(c.stdout, record)
+	}
+	return writeRecords(c.stdout, c.output, []client.ZoneExportRecord{record})
+}
+
+func recordSet(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("record set", flag.ContinueOnError)
+	view := flags.String("view", "", "Split-horizon view to set the record in")
+	ttl := flags.Uint("ttl", defaultTTL, "TTL of the record in seconds")
+	args, err := parseFlags(flags, args, 3, -1)
+	if err != nil {
+		return err
+	}
+	apiClient, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+
+	name, recordType, values, recordTTL := dns.Fqdn(args[0]), strings.ToUpper(args[1]), args[2:], uint32(*ttl)
+	response, err := apiClient.PutDomainWithResponse(ctx, client.Domain(name), client.RecordType(recordType), &client.PutDomainParams{View: viewParam(*view)}, client.PutDomainJSONRequestBody{Values: &values, Ttl: &recordTTL})
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() == http.StatusBadRequest {
+		return fmt.Errorf("%s isn't a valid %s record", strings.Join(values, ", "), recordType)
+	}
+	if response.StatusCode() != http.StatusNoContent {
+		return unexpectedStatus(response.HTTPResponse)
+	}
+	return nil
+}
+
+func recordDelete(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("record delete", flag.ContinueOnError)
+	view := flags.String("view", "", "Split-horizon view to delete the record from")
+	args, err := parseFlags(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	apiClient, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+
+	name, recordType := dns.Fqdn(args[0]), strings.ToUpper(args[1])
+	response, err := apiClient.DeleteDomainWithResponse(ctx, client.Domain(name), client.RecordType(recordType), &client.DeleteDomainParams{View: viewParam(*view)})
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() == http.StatusNotFound {
+		return fmt.Errorf("there is no %s record for %s", recordType, name)
+	}
+	if response.StatusCode() != http.StatusNoContent {
+		return unexpectedStatus(response.HTTPResponse)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/miekg/dns"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tsigKey is a key that updates are signed with.
+type tsigKey struct {
+	name      string
+	algorithm string
+	secret    string
+}
+
+// tsigAlgorithms maps the algorithm names nsupdate accepts to their TSIG algorithm names.
+var tsigAlgorithms = map[string]string{
+	"hmac-md5":    dns.HmacMD5,
+	"hmac-sha1":   dns.HmacSHA1,
+	"hmac-sha224": dns.HmacSHA224,
+	"hmac-sha256": dns.HmacSHA256,
+	"hmac-sha384": dns.HmacSHA384,
+	"hmac-sha512": dns.HmacSHA512,
+}
+
+// parseTSIGKey parses a key name that may be prefixed with its algorithm, like nsupdate's key command does. Keys
+// without an algorithm use hmac-md5, the same as nsupdate.
+func parseTSIGKey(name string, secret string) (*tsigKey, error) {
+	algorithm := "hmac-md5"
+	if i := strings.Index(name, ":"); i >= 0 {
+		algorithm, name = strings.ToLower(name[:i]), name[i+1:]
+	}
+	tsigAlgorithm, ok := tsigAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown TSIG algorithm %s", algorithm)
+	}
+	if name == "" || secret == "" {
+		return nil, fmt.Errorf("TSIG keys need a name and a secret")
+	}
+	return &tsigKey{name: dns.Fqdn(strings.ToLower(name)), algorithm: tsigAlgorithm, secret: secret}, nil
+}
+
+// updateMessage is an update that a send command in a script sends.
+type updateMessage struct {
+	server string
+	key    *tsigKey
+	msg    *dns.Msg
+}
+
+// updateScript is the state of an nsupdate-style script as it is parsed.
+type updateScript struct {
+	server  string
+	zone    string
+	key     *tsigKey
+	ttl     uint32
+	pending *dns.Msg
+	// firstName is the name of the first record in pending, which is the zone of the update if none is set
+	firstName string
+	messages  []updateMessage
+}
+
+// parseUpdateScript parses a script of nsupdate commands into the updates it sends. The server, zone, key, ttl,
+// update add, update delete and send commands are supported, and updates that haven't been sent at the end of the
+// script are sent like nsupdate does. Names are always fully qualified.
+func parseUpdateScript(r io.Reader, server string, key *tsigKey) ([]updateMessage, error) {
+	script := &updateScript{server: server, key: key, ttl: defaultTTL}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, ";") {
+			continue
+		}
+		if err := script.command(text); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	script.send()
+	return script.messages, nil
+}
+
+// nextField splits the first whitespace separated field off of text.
+func nextField(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		return text[:i], strings.TrimSpace(text[i:])
+	}
+	return text, ""
+}
+
+func (s *updateScript) command(text string) error {
+	command, rest := nextField(text)
+	command = strings.ToLower(command)
+	if command == "update" {
+		command, rest = nextField(rest)
+		command = strings.ToLower(command)
+	}
+	switch command {
+	case "server":
+		host, rest := nextField(rest)
+		port, rest := nextField(rest)
+		if host == "" || rest != "" {
+			return fmt.Errorf("server takes a host and an optional port")
+		}
+		if port == "" {
+			port = "53"
+		}
+		s.server = net.JoinHostPort(host, port)
+	case "zone":
+		zone, rest := nextField(rest)
+		if zone == "" || rest != "" {
+			return fmt.Errorf("zone takes a name")
+		}
+		s.zone = dns.Fqdn(zone)
+	case "key":
+		name, rest := nextField(rest)
+		secret, rest := nextField(rest)
+		if rest != "" {
+			return fmt.Errorf("key takes a name and a secret")
+		}
+		key, err := parseTSIGKey(name, secret)
+		if err != nil {
+			return err
+		}
+		s.key = key
+	case "ttl":
+		ttl, err := strconv.ParseUint(rest, 10, 32)
+		if err != nil {
+			return fmt.Errorf("ttl takes a number of seconds")
+		}
+		s.ttl = uint32(ttl)
+	case "add":
+		return s.add(rest)
+	case "del", "delete":
+		return s.delete(rest)
+	case "send":
+		s.send()
+	default:
+		return fmt.Errorf("unsupported command %q", command)
+	}
+	return nil
+}
+
+// recordFields parses the name, TTL, class and type that start a record in an update command, returning the rest of
+// the record as its data. The TTL and class are optional, and the only class is IN.
+func (s *updateScript) recordFields(text string) (name string, ttl uint32, recordType string, data string, err error) {
+	name, text = nextField(text)
+	if name == "" {
+		return "", 0, "", "", fmt.Errorf("missing name")
+	}
+	ttl = s.ttl
+	field, text := nextField(text)
+	if parsed, err := strconv.ParseUint(field, 10, 32); err == nil {
+		ttl = uint32(parsed)
+		field, text = nextField(text)
+	}
+	if strings.EqualFold(field, "IN") {
+		field, text = nextField(text)
+	}
+	if field != "" {
+		if _, ok := dns.StringToType[strings.ToUpper(field)]; !ok {
+			return "", 0, "", "", fmt.Errorf("unknown record type %s", field)
+		}
+	}
+	return dns.Fqdn(name), ttl, strings.ToUpper(field), text, nil
+}
+
+func (s *updateScript) message(name string) *dns.Msg {
+	if s.pending == nil {
+		// Updates take their class from the zone section, so it has to be set before any are added. It is set again
+		// once the zone is known.
+		s.pending = new(dns.Msg)
+		s.pending.SetUpdate(name)
+		s.firstName = name
+	}
+	return s.pending
+}
+
+func (s *updateScript) add(text string) error {
+	name, ttl, recordType, data, err := s.recordFields(text)
+	if err != nil {
+		return err
+	}
+	if recordType == "" || data == "" {
+		return fmt.Errorf("add needs a type and data")
+	}
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, ttl, recordType, data))
+	if err != nil {
+		return err
+	}
+	s.message(name).Insert([]dns.RR{rr})
+	return nil
+}
+
+// delete deletes every record of a name, every record of a name and type, or a single record, depending on how much
+// of the record is given.
+func (s *updateScript) delete(text string) error {
+	name, _, recordType, data, err := s.recordFields(text)
+	if err != nil {
+		return err
+	}
+	switch {
+	case recordType == "":
+		s.message(name).RemoveName([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: name}}})
+	case data == "":
+		s.message(name).RemoveRRset([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: dns.StringToType[recordType]}}})
+	default:
+		rr, err := dns.NewRR(fmt.Sprintf("%s 0 IN %s %s", name, recordType, data))
+		if err != nil {
+			return err
+		}
+		s.message(name).Remove([]dns.RR{rr})
+	}
+	return nil
+}
+
+// send finishes the pending update, if there is one.
+func (s *updateScript) send() {
+	if s.pending == nil {
+		return
+	}
+	// Ephemerain doesn't need the zone to be given, so the update is for the zone of its first record if it isn't
+	zone := s.zone
+	if zone == "" {
+		zone = s.firstName
+	}
+	s.pending.SetUpdate(zone)
+	s.messages = append(s.messages, updateMessage{server: s.server, key: s.key, msg: s.pending})
+	s.pending = nil
+}
+
+// sendUpdate sends an update and checks that it succeeded.
+func sendUpdate(ctx context.Context, update updateMessage) error {
+	if update.server == "" {
+		return fmt.Errorf("no server to send the update to")
+	}
+	dnsClient := &dns.Client{Net: "udp", Timeout: 10 * time.Second}
+	if update.key != nil {
+		dnsClient.TsigSecret = map[string]string{update.key.name: update.key.secret}
+		update.msg.SetTsig(update.key.name, update.key.algorithm, 300, time.Now().Unix())
+	}
+	response, _, err := dnsClient.ExchangeContext(ctx, update.msg, update.server)
+	if err != nil {
+		return fmt.Errorf("sending update to %s: %w", update.server, err)
+	}
+	if response.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("update of %s was refused with %s", update.msg.Question[0].Name, dns.RcodeToString[response.Rcode])
+	}
+	return nil
+}
+
+// update sends the updates in an nsupdate-style script. Updates go to the configured nameserver unless the script
+// has a server command.
+func update(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("update", flag.ContinueOnError)
+	keyFlag := flags.String("y", "", "TSIG key to sign updates with, as [hmac:]name:secret")
+	args, err := parseFlags(flags, args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	var key *tsigKey
+	if *keyFlag != "" {
+		parts := strings.Split(*keyFlag, ":")
+		if len(parts) < 2 {
+			return usagef("update: -y takes [hmac:]name:secret")
+		}
+		name, secret := strings.Join(parts[:len(parts)-1], ":"), parts[len(parts)-1]
+		if key, err = parseTSIGKey(name, secret); err != nil {
+			return err
+		}
+	}
+	// The script can name its own server, so a missing nameserver is only a problem if it doesn't
+	server, _ := c.config.nameserver()
+
+	script := c.stdin
+	if len(args) == 1 && args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		script = file
+	}
+	messages, err := parseUpdateScript(script, server, key)
+	if err != nil {
+		return err
+	}
+	for _, message := range messages {
+		if err := sendUpdate(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
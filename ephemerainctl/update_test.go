@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseUpdateScript(t *testing.T) {
+	script := `
+; Records are added and deleted in one update
+server 127.0.0.1 5353
+zone example.com
+key hmac-sha256:ci-key c2VjcmV0
+update add www.example.com 300 IN A 192.0.2.1
+add _acme-challenge.example.com TXT "two words"
+update delete old.example.com A
+delete gone.example.com
+send
+ttl 120
+update delete www.example.com A 192.0.2.1
+update add www.example.com AAAA 2001:db8::1
+`
+	messages, err := parseUpdateScript(strings.NewReader(script), "ns.example.com:53", nil)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2, "updates that aren't sent at the end of the script are sent anyway")
+
+	first := messages[0]
+	assert.Equal(t, "127.0.0.1:5353", first.server)
+	assert.Equal(t, &tsigKey{name: "ci-key.", algorithm: dns.HmacSHA256, secret: "c2VjcmV0"}, first.key)
+	assert.Equal(t, dns.OpcodeUpdate, first.msg.Opcode)
+	assert.Equal(t, "example.com.", first.msg.Question[0].Name)
+	var updates []string
+	for _, rr := range first.msg.Ns {
+		updates = append(updates, rr.String())
+	}
+	assert.Equal(t, []string{
+		"www.example.com.\t300\tIN\tA\t192.0.2.1",
+		"_acme-challenge.example.com.\t60\tIN\tTXT\t\"two words\"",
+		"old.example.com.\t0\tCLASS255\tA\t",
+		"gone.example.com.\t0\tCLASS255\tANY\t",
+	}, updates)
+
+	second := messages[1]
+	assert.Len(t, second.msg.Ns, 2)
+	assert.Equal(t, uint16(dns.ClassNONE), second.msg.Ns[0].Header().Class, "deleting a single record")
+	assert.Equal(t, uint32(120), second.msg.Ns[1].Header().Ttl)
+
+	// The zone defaults to the name of the first record
+	messages, err = parseUpdateScript(strings.NewReader("add a.example.org A 192.0.2.1\n"), "ns.example.com:53", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "ns.example.com:53", messages[0].server)
+	assert.Equal(t, "a.example.org.", messages[0].msg.Question[0].Name)
+}
+
+func TestParseUpdateScript_Invalid(t *testing.T) {
+	for _, script := range []string{
+		"prereq nxdomain example.com",
+		"add example.com A",
+		"add example.com BOGUS 1",
+		"add example.com A not-an-address",
+		"key hmac-bogus:name c2VjcmV0",
+		"server",
+	} {
+		_, err := parseUpdateScript(strings.NewReader(script), "", nil)
+		assert.Error(t, err, script)
+		assert.Contains(t, err.Error(), "line 1", script)
+	}
+}
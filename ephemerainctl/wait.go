@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/miekg/dns"
+	"strings"
+	"time"
+)
+
+// answerHas reports whether any record in answer is the expected one. TXT records match if they have the same text,
+// however it is split into character-strings, and other records if their data is the same.
+func answerHas(answer []dns.RR, expected dns.RR) bool {
+	for _, rr := range answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			if expectedTXT, ok := expected.(*dns.TXT); ok && strings.EqualFold(txt.Hdr.Name, expectedTXT.Hdr.Name) && strings.Join(txt.Txt, "") == strings.Join(expectedTXT.Txt, "") {
+				return true
+			}
+			continue
+		}
+		if dns.IsDuplicate(rr, expected) {
+			return true
+		}
+	}
+	return false
+}
+
+// wait queries the server's DNS listener until its answer has the record, so scripts can tell when a change has
+// taken effect. Queries don't ask for recursion, so the answer is the server's own.
+func wait(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("wait", flag.ContinueOnError)
+	timeout := flags.Duration("timeout", 2*time.Minute, "How long to wait before giving up")
+	interval := flags.Duration("interval", time.Second, "How long to wait between queries")
+	args, err := parseFlags(flags, args, 3, 3)
+	if err != nil {
+		return err
+	}
+	server, err := c.config.nameserver()
+	if err != nil {
+		return err
+	}
+
+	name, recordType, value := dns.Fqdn(args[0]), strings.ToUpper(args[1]), args[2]
+	expected, err := resourceRecord(name, recordType, 0, value)
+	if err != nil {
+		return fmt.Errorf("%s isn't a valid %s record: %w", value, recordType, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	dnsClient := &dns.Client{Net: "udp", Timeout: 5 * time.Second}
+	query := new(dns.Msg)
+	query.SetQuestion(name, expected.Header().Rrtype)
+	query.RecursionDesired = false
+	for {
+		response, _, err := dnsClient.ExchangeContext(ctx, query, server)
+		if err == nil && answerHas(response.Answer, expected) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("timed out waiting for %s %s to be %s, last query failed: %w", name, recordType, value, err)
+			}
+			return fmt.Errorf("timed out waiting for %s %s to be %s", name, recordType, value)
+		case <-time.After(*interval):
+		}
+	}
+}
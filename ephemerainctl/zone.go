@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/miekg/dns"
+	"os"
+	"server/client"
+)
+
+func zoneExport(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("zone export", flag.ContinueOnError)
+	view := flags.String("view", "", "Split-horizon view to export the records of")
+	args, err := parseFlags(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	apiClient, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+
+	response, err := apiClient.ExportZoneWithResponse(ctx, dns.Fqdn(args[0]), &client.ExportZoneParams{View: viewParam(*view)})
+	if err != nil {
+		return err
+	}
+	if response.JSON200 == nil {
+		return unexpectedStatus(response.HTTPResponse)
+	}
+	if c.output == outputJSON {
+		return writeJSON(c.stdout, response.JSON200)
+	}
+	return writeRecords(c.stdout, c.output, response.JSON200.Records)
+}
+
+// zoneImport imports a zone file with POST /zone, which adds its records without deleting any others.
+func zoneImport(ctx context.Context, c *cli, args []string) error {
+	flags := flag.NewFlagSet("zone import", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "Report what importing the zone file would do without changing any records")
+	args, err := parseFlags(flags, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	apiClient, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+
+	zoneFile := c.stdin
+	if len(args) == 1 && args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		zoneFile = file
+	}
+
+	response, err := apiClient.PostZoneWithBodyWithResponse(ctx, &client.PostZoneParams{DryRun: dryRun}, "text/plain", zoneFile)
+	if err != nil {
+		return err
+	}
+	report := response.JSON201
+	if report == nil {
+		report = response.JSON200
+	}
+	if report == nil && response.JSON400 != nil {
+		// Nothing is imported if any record fails, so the report is written to show which ones did
+		if err := writeImportReport(c.stdout, c.output, *response.JSON400); err != nil {
+			return err
+		}
+		return fmt.Errorf("%d records in the zone file couldn't be imported, so nothing was imported", len(response.JSON400.Failed))
+	}
+	if report == nil {
+		return unexpectedStatus(response.HTTPResponse)
+	}
+	return writeImportReport(c.stdout, c.output, *report)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/hashicorp/go-hclog"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ErrACMEDNSAccountNotFound is returned by GetACMEDNSAccount when there is no account with the username.
+var ErrACMEDNSAccountNotFound = errors.New("acme-dns account not found")
+
+// ACMEDNSAccount is a registration made through the acme-dns compatible API. Its credentials can only set the TXT
+// record at its own subdomain. Only a hash of the password is kept. Domain is the name of the TXT record, which decides
+// the tenant the account is kept with.
+type ACMEDNSAccount struct {
+	Username     string   `json:"username"`
+	PasswordHash string   `json:"passwordHash"`
+	Subdomain    string   `json:"subdomain"`
+	Domain       Domain   `json:"domain"`
+	AllowFrom    []string `json:"allowFrom"`
+}
+
+// acmeDNSRecordTTL is the TTL of challenge records, which are only looked up once by the ACME server and change with
+// every certificate.
+const acmeDNSRecordTTL = 1
+
+// acmeDNSUUIDPattern matches the UUIDs that acme-dns uses as usernames and subdomains. Clients don't rely on the format,
+// but it is checked so that nothing else ends up in a DNS name.
+var acmeDNSUUIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// acmeDNSTXTPattern matches the values of DNS-01 challenge records, which are base64url encoded SHA-256 hashes.
+var acmeDNSTXTPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	raw[6] = raw[6]&0x0f | 0x40
+	raw[8] = raw[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", raw[0:4], raw[4:6], raw[6:8], raw[8:10], raw[10:]), nil
+}
+
+// acmeDNSHandler implements the API of joohoi/acme-dns, so that ACME clients with acme-dns support can solve DNS-01
+// challenges with records served by Ephemerain. Unlike the rest of the API, errors have a JSON body, because that's
+// what acme-dns clients expect.
+type acmeDNSHandler struct {
+	registrar Registrar
+	zone      Domain
+}
+
+func newACMEDNSHandler(registrar Registrar, zone Domain) http.Handler {
+	a := acmeDNSHandler{registrar: registrar, zone: Domain(canonicalName(string(zone)))}
+	r := chi.NewRouter()
+	r.Post("/register", a.register)
+	r.Post("/update", a.update)
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return r
+}
+
+func writeACMEDNSResponse(w http.ResponseWriter, r *http.Request, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		hclog.FromContext(r.Context()).Info("Error writing acme-dns response", "error", err)
+	}
+}
+
+func writeACMEDNSError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeACMEDNSResponse(w, r, status, map[string]string{"error": message})
+}
+
+func (a acmeDNSHandler) fulldomain(subdomain string) Domain {
+	return Domain(subdomain + "." + string(a.zone))
+}
+
+func (a acmeDNSHandler) register(w http.ResponseWriter, r *http.Request) {
+	logger := hclog.FromContext(r.Context())
+
+	// The body is optional, and only says which networks updates are allowed from
+	var body struct {
+		AllowFrom []string `json:"allowfrom"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			logger.Info("Malformed acme-dns registration", "error", err)
+			writeACMEDNSError(w, r, http.StatusBadRequest, "malformed_json_payload")
+			return
+		}
+	}
+	allowFrom := []string{}
+	for _, cidr := range body.AllowFrom {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			logger.Info("Invalid acme-dns allowfrom network", "cidr", cidr, "error", err)
+			writeACMEDNSError(w, r, http.StatusBadRequest, "invalid_allowfrom_cidr")
+			return
+		}
+		allowFrom = append(allowFrom, network.String())
+	}
+
+	username, err := newUUID()
+	if err != nil {
+		logger.Error("Error generating acme-dns username", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	subdomain, err := newUUID()
+	if err != nil {
+		logger.Error("Error generating acme-dns subdomain", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	password, err := newSecretToken("")
+	if err != nil {
+		logger.Error("Error generating acme-dns password", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	account := ACMEDNSAccount{Username: username, PasswordHash: secretTokenHash(password), Subdomain: subdomain, Domain: a.fulldomain(subdomain), AllowFrom: allowFrom}
+	if err := a.registrar.CreateACMEDNSAccount(r.Context(), account); err != nil {
+		logger.Error("Error from registrar when creating acme-dns account", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Registered acme-dns account", "username", username, "fulldomain", a.fulldomain(subdomain))
+	writeACMEDNSResponse(w, r, http.StatusCreated, map[string]interface{}{
+		"username":   username,
+		"password":   password,
+		"fulldomain": strings.TrimSuffix(string(a.fulldomain(subdomain)), "."),
+		"subdomain":  subdomain,
+		"allowfrom":  allowFrom,
+	})
+}
+
+// authenticate returns the account whose credentials are in the request's X-Api-User and X-Api-Key headers, if the
+// request is allowed to use it.
+func (a acmeDNSHandler) authenticate(r *http.Request) (ACMEDNSAccount, bool, error) {
+	username, password := r.Header.Get("X-Api-User"), r.Header.Get("X-Api-Key")
+	if !acmeDNSUUIDPattern.MatchString(username) || password == "" {
+		return ACMEDNSAccount{}, false, nil
+	}
+	account, err := a.registrar.GetACMEDNSAccount(r.Context(), username)
+	if errors.Is(err, ErrACMEDNSAccountNotFound) {
+		return account, false, nil
+	}
+	if err != nil {
+		return account, false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(secretTokenHash(password)), []byte(account.PasswordHash)) != 1 {
+		return account, false, nil
+	}
+	if len(account.AllowFrom) == 0 {
+		return account, true, nil
+	}
+	ip := net.ParseIP(clientIP(r))
+	for _, cidr := range account.AllowFrom {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return account, true, nil
+		}
+	}
+	return account, false, nil
+}
+
+func (a acmeDNSHandler) update(w http.ResponseWriter, r *http.Request) {
+	logger := hclog.FromContext(r.Context())
+
+	account, ok, err := a.authenticate(r)
+	if err != nil {
+		logger.Error("Error from registrar when getting acme-dns account", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		logger.Info("acme-dns update isn't authorized", "username", r.Header.Get("X-Api-User"))
+		writeACMEDNSError(w, r, http.StatusUnauthorized, "forbidden")
+		return
+	}
+
+	var body struct {
+		Subdomain string `json:"subdomain"`
+		TXT       string `json:"txt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logger.Info("Malformed acme-dns update", "error", err)
+		writeACMEDNSError(w, r, http.StatusBadRequest, "malformed_json_payload")
+		return
+	}
+	if !acmeDNSUUIDPattern.MatchString(body.Subdomain) {
+		writeACMEDNSError(w, r, http.StatusBadRequest, "bad_subdomain")
+		return
+	}
+	if !acmeDNSTXTPattern.MatchString(body.TXT) {
+		writeACMEDNSError(w, r, http.StatusBadRequest, "bad_txt")
+		return
+	}
+	if body.Subdomain != account.Subdomain {
+		logger.Info("acme-dns update is for another account's subdomain", "username", account.Username, "subdomain", body.Subdomain)
+		writeACMEDNSError(w, r, http.StatusUnauthorized, "forbidden")
+		return
+	}
+
+	ctx := withAuditActor(r.Context(), "acme-dns:"+account.Username, AuditEntryProtocolAcmeDns)
+	fqdn := a.fulldomain(account.Subdomain)
+	current, err := a.registrar.GetRecord(ctx, fqdn, RecordTypeTXT)
+	if errors.Is(err, ErrRecordNotFound) {
+		current, err = "", nil
+	}
+	// The record is swapped rather than set so that if another update changed it since it was read, which happens when
+	// the challenges for a wildcard and its apex are solved at the same time, the other update's value is kept too
+	for attempt := 0; err == nil; attempt++ {
+		var previous string
+		var existed bool
+		previous, existed, err = a.registrar.SwapRecord(ctx, fqdn, RecordTypeTXT, acmeDNSChallengeValues(body.TXT, current).String())
+		if !existed {
+			previous = ""
+		}
+		if err != nil || previous == current || attempt == maxACMEDNSUpdateAttempts-1 {
+			break
+		}
+		current = previous
+	}
+	if isForbidden(err) {
+		logger.Info("Not allowed to set acme-dns challenge record", "domain", fqdn, "error", err)
+		writeACMEDNSError(w, r, http.StatusUnauthorized, "forbidden")
+		return
+	} else if err != nil {
+		logger.Error("Error from registrar when setting acme-dns challenge record", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Set acme-dns challenge record", "domain", fqdn, "username", account.Username)
+	writeACMEDNSResponse(w, r, http.StatusOK, map[string]string{"txt": body.TXT})
+}
+
+// maxACMEDNSUpdateAttempts is how many times an update retries when the record keeps being changed by other updates.
+const maxACMEDNSUpdateAttempts = 5
+
+// acmeDNSChallengeValues returns the record set with txt and the most recent other value of current, so that two
+// challenges can be solved at once.
+func acmeDNSChallengeValues(txt string, current string) RecordSet {
+	set := RecordSet{TTL: acmeDNSRecordTTL, Values: []string{txt}}
+	if current == "" {
+		return set
+	}
+	for _, value := range parseRecordSet(current).Values {
+		if value != txt {
+			set.Values = append(set.Values, value)
+			break
+		}
+	}
+	return set
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type acmeDNSRegistration struct {
+	Username   string   `json:"username"`
+	Password   string   `json:"password"`
+	Fulldomain string   `json:"fulldomain"`
+	Subdomain  string   `json:"subdomain"`
+	AllowFrom  []string `json:"allowfrom"`
+}
+
+func acmeDNSRequest(t *testing.T, handler http.Handler, path string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	r.RemoteAddr = "192.0.2.10:1234"
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func registerACMEDNS(t *testing.T, handler http.Handler, body string) acmeDNSRegistration {
+	w := acmeDNSRequest(t, handler, "/register", body, nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var registration acmeDNSRegistration
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&registration))
+	return registration
+}
+
+func TestACMEDNS(t *testing.T) {
+	registrar := newMemoryRegistrar()
+	handler := newACMEDNSHandler(registrar, "auth.example.com")
+
+	registration := registerACMEDNS(t, handler, "")
+	assert.Regexp(t, acmeDNSUUIDPattern, registration.Username)
+	assert.Regexp(t, acmeDNSUUIDPattern, registration.Subdomain)
+	assert.Equal(t, registration.Subdomain+".auth.example.com", registration.Fulldomain)
+	assert.Empty(t, registration.AllowFrom)
+
+	update := func(registration acmeDNSRegistration, password string, subdomain string, txt string) *httptest.ResponseRecorder {
+		return acmeDNSRequest(t, handler, "/update", `{"subdomain": "`+subdomain+`", "txt": "`+txt+`"}`, map[string]string{
+			"X-Api-User": registration.Username,
+			"X-Api-Key":  password,
+		})
+	}
+	first, second, third := strings.Repeat("a", 43), strings.Repeat("b", 43), strings.Repeat("c", 43)
+
+	// The two most recent values are kept
+	for _, txt := range []string{first, second, third} {
+		w := update(registration, registration.Password, registration.Subdomain, txt)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"txt": "`+txt+`"}`, w.Body.String())
+	}
+	value, err := registrar.GetRecord(context.Background(), Domain(registration.Fulldomain+"."), RecordTypeTXT)
+	assert.NoError(t, err)
+	assert.Equal(t, RecordSet{TTL: acmeDNSRecordTTL, Values: []string{third, second}}, parseRecordSet(value))
+
+	// Credentials only work for their own subdomain
+	other := registerACMEDNS(t, handler, "")
+	assert.Equal(t, http.StatusUnauthorized, update(registration, "wrong", registration.Subdomain, first).Code)
+	assert.Equal(t, http.StatusUnauthorized, update(other, other.Password, registration.Subdomain, first).Code)
+	assert.Equal(t, http.StatusBadRequest, update(registration, registration.Password, registration.Subdomain, "too short").Code)
+	assert.Equal(t, http.StatusBadRequest, update(registration, registration.Password, "www", first).Code)
+
+	// Updates can be limited to some networks
+	restricted := registerACMEDNS(t, handler, `{"allowfrom": ["198.51.100.0/24"]}`)
+	assert.Equal(t, []string{"198.51.100.0/24"}, restricted.AllowFrom)
+	assert.Equal(t, http.StatusUnauthorized, update(restricted, restricted.Password, restricted.Subdomain, first).Code)
+	allowed := registerACMEDNS(t, handler, `{"allowfrom": ["192.0.2.0/24"]}`)
+	assert.Equal(t, http.StatusOK, update(allowed, allowed.Password, allowed.Subdomain, first).Code)
+	assert.Equal(t, http.StatusBadRequest, acmeDNSRequest(t, handler, "/register", `{"allowfrom": ["nonsense"]}`, nil).Code)
+}
+
+func TestACMEDNSChallengeValues(t *testing.T) {
+	assert.Equal(t, []string{"new"}, acmeDNSChallengeValues("new", "").Values)
+	assert.Equal(t, []string{"new", "old"}, acmeDNSChallengeValues("new", RecordSet{Values: []string{"old", "older"}}.String()).Values)
+	assert.Equal(t, []string{"new", "old"}, acmeDNSChallengeValues("new", RecordSet{Values: []string{"new", "old"}}.String()).Values)
+}
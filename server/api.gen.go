@@ -13,37 +13,448 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	"github.com/go-chi/chi/v5"
 )
 
+// Defines values for AuditEntryAction.
+const (
+	AuditEntryActionDelete AuditEntryAction = "delete"
+
+	AuditEntryActionSet AuditEntryAction = "set"
+)
+
+// Defines values for AuditEntryProtocol.
+const (
+	AuditEntryProtocolAcmeDns AuditEntryProtocol = "acme-dns"
+
+	AuditEntryProtocolExternalDns AuditEntryProtocol = "external-dns"
+
+	AuditEntryProtocolLease AuditEntryProtocol = "lease"
+
+	AuditEntryProtocolRest AuditEntryProtocol = "rest"
+
+	AuditEntryProtocolRfc2136 AuditEntryProtocol = "rfc2136"
+
+	AuditEntryProtocolZoneUpload AuditEntryProtocol = "zone-upload"
+)
+
 // Defines values for RecordType.
 const (
 	RecordTypeA RecordType = "A"
 
+	RecordTypeAAAA RecordType = "AAAA"
+
+	RecordTypeAFSDB RecordType = "AFSDB"
+
+	RecordTypeCAA RecordType = "CAA"
+
 	RecordTypeCNAME RecordType = "CNAME"
 
+	RecordTypeHINFO RecordType = "HINFO"
+
+	RecordTypeLOC RecordType = "LOC"
+
+	RecordTypeMX RecordType = "MX"
+
+	RecordTypeNAPTR RecordType = "NAPTR"
+
+	RecordTypeNS RecordType = "NS"
+
+	RecordTypePTR RecordType = "PTR"
+
+	RecordTypeRP RecordType = "RP"
+
+	RecordTypeSRV RecordType = "SRV"
+
 	RecordTypeTXT RecordType = "TXT"
 )
 
+// Defines values for WebhookEventType.
+const (
+	WebhookEventTypeCreated WebhookEventType = "created"
+
+	WebhookEventTypeDeleted WebhookEventType = "deleted"
+
+	WebhookEventTypeExpired WebhookEventType = "expired"
+
+	WebhookEventTypeUpdated WebhookEventType = "updated"
+)
+
+// A change to a record. Actor is who made the change: token:<token id> for API requests with a bearer token, tsig:<key name> for signed RFC 2136 updates and ip:<address> for everything else. The token id is the same one used in log lines.
+type AuditEntry struct {
+	Action AuditEntryAction `json:"action"`
+	Actor  string           `json:"actor"`
+	Domain string           `json:"domain"`
+	Id     string           `json:"id"`
+
+	// Value the record was set to. Missing for deletes.
+	NewValue *string `json:"newValue,omitempty"`
+
+	// Value the record had before the change, if it existed
+	OldValue *string `json:"oldValue,omitempty"`
+
+	// How the change was made. Records deleted because their lease expired have the lease protocol, TXT records set through the acme-dns compatible API have the acme-dns protocol, and records changed by external-dns through its webhook have the external-dns protocol.
+	Protocol   AuditEntryProtocol `json:"protocol"`
+	RecordType RecordType         `json:"recordType"`
+	Timestamp  time.Time          `json:"timestamp"`
+	View       *string            `json:"view,omitempty"`
+	Zone       string             `json:"zone"`
+}
+
+// AuditEntryAction defines model for AuditEntry.Action.
+type AuditEntryAction string
+
+// How the change was made. Records deleted because their lease expired have the lease protocol, TXT records set through the acme-dns compatible API have the acme-dns protocol, and records changed by external-dns through its webhook have the external-dns protocol.
+type AuditEntryProtocol string
+
+// CreatedTenant defines model for CreatedTenant.
+type CreatedTenant struct {
+	Tenant Tenant `json:"tenant"`
+
+	// API token of the tenant. Requests with `Authorization: Bearer <token>` can only change records of the tenant. It is only ever returned once.
+	Token string `json:"token"`
+}
+
+// Lease defines model for Lease.
+type Lease struct {
+	// Fully qualified name of the subdomain the lease owns, along with every name beneath it
+	Domain  string    `json:"domain"`
+	Expires time.Time `json:"expires"`
+	Id      string    `json:"id"`
+}
+
+// NewTenant defines model for NewTenant.
+type NewTenant struct {
+	// Lower case letters, digits and hyphens
+	Id     string        `json:"id"`
+	Quotas *RecordQuotas `json:"quotas,omitempty"`
+
+	// Names of TSIG keys configured on the server. RFC 2136 updates signed with one of them can only change records of the tenant.
+	TsigKeys *[]string `json:"tsigKeys,omitempty"`
+
+	// Zones the tenant owns. A name belongs to the tenant with the longest zone containing it, so tenants can own zones beneath each other's, but no two tenants can own the same zone.
+	Zones []string `json:"zones"`
+}
+
+// RecordQuotas defines model for RecordQuotas.
+type RecordQuotas struct {
+	// How many records can be created with a single API token. Zero or missing disables the limit.
+	RecordsPerToken *int64 `json:"recordsPerToken,omitempty"`
+
+	// How many records can be created in a single zone. Zero or missing disables the limit.
+	RecordsPerZone *int64 `json:"recordsPerZone,omitempty"`
+}
+
+// RecordReference defines model for RecordReference.
+type RecordReference struct {
+	Domain string     `json:"domain"`
+	Type   RecordType `json:"type"`
+	View   *string    `json:"view,omitempty"`
+}
+
 // RecordType defines model for RecordType.
 type RecordType string
 
-// RecordValue defines model for RecordValue.
+// A record's values. Values other than TXT values are in zone file presentation format, e.g. `10 mx.example.com.` for an MX record.
 type RecordValue struct {
+	// TTL of the record in seconds. Defaults to 60.
+	Ttl *uint32 `json:"ttl,omitempty"`
+
+	// The record's value. When setting a record, either this or values is required.
+	Value *string `json:"value,omitempty"`
+
+	// Every value of the record, for records with more than one
+	Values *[]string `json:"values,omitempty"`
+}
+
+// A past or current value of a record. Deleting a record adds a version with deleted set, whose value is the value the record had when it was deleted.
+type RecordVersion struct {
+	// Whether the record didn't exist before this version. Records that existed before the server kept history have no version that created them.
+	Created   bool      `json:"created"`
+	Deleted   bool      `json:"deleted"`
+	Timestamp time.Time `json:"timestamp"`
+	Value     string    `json:"value"`
+	Version   int64     `json:"version"`
+}
+
+// Subdomain defines model for Subdomain.
+type Subdomain struct {
+	// Fully qualified name of the claimed subdomain
+	Domain  string    `json:"domain"`
+	Expires time.Time `json:"expires"`
+
+	// ID of the lease on the subdomain, for renewing or releasing it
+	LeaseId string `json:"leaseId"`
+
+	// Owner token for the subdomain. Records at or beneath the subdomain can only be changed by requests with `Authorization: Bearer <token>`, and the token can't be used to change records anywhere else. It is only ever returned once.
+	Token string `json:"token"`
+}
+
+// Tenant defines model for Tenant.
+type Tenant struct {
+	Id string `json:"id"`
+
+	// Prefix of the keys the tenant's records are stored under in the backend
+	KeyPrefix string       `json:"keyPrefix"`
+	Quotas    RecordQuotas `json:"quotas"`
+	TsigKeys  []string     `json:"tsigKeys"`
+	Zones     []string     `json:"zones"`
+}
+
+// Webhook defines model for Webhook.
+type Webhook struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Id        string    `json:"id"`
+	Url       string    `json:"url"`
+	Zone      string    `json:"zone"`
+}
+
+// One attempt at delivering an event to a webhook
+type WebhookDelivery struct {
+	// Attempt number, starting at 1. Failed attempts are retried with exponential backoff.
+	Attempt   int     `json:"attempt"`
+	Error     *string `json:"error,omitempty"`
+	EventId   string  `json:"eventId"`
+	EventType string  `json:"eventType"`
+
+	// HTTP status the webhook responded with, if it responded at all
+	StatusCode *int      `json:"statusCode,omitempty"`
+	Success    bool      `json:"success"`
+	Timestamp  time.Time `json:"timestamp"`
+	WebhookId  string    `json:"webhookId"`
+}
+
+// Payload POSTed to webhooks. Value is the new value for created and updated records, and the last value for deleted and expired records.
+type WebhookEvent struct {
+	Domain string `json:"domain"`
+	Id     string `json:"id"`
+
+	// Value an updated record had before the update
+	PreviousValue *string          `json:"previousValue,omitempty"`
+	RecordType    RecordType       `json:"recordType"`
+	Timestamp     time.Time        `json:"timestamp"`
+	Type          WebhookEventType `json:"type"`
+	Value         *string          `json:"value,omitempty"`
+	View          *string          `json:"view,omitempty"`
+	Zone          string           `json:"zone"`
+}
+
+// WebhookEventType defines model for WebhookEvent.Type.
+type WebhookEventType string
+
+// WebhookRegistration defines model for WebhookRegistration.
+type WebhookRegistration struct {
+	// Key for signing payloads. Every delivery has an X-Ephemerain-Signature header of the form sha256=<hex encoded HMAC-SHA256 of the request body>.
+	Secret string `json:"secret"`
+
+	// URL the JSON encoded WebhookEvent is POSTed to
+	Url string `json:"url"`
+
+	// Zone to receive changes for. Changes to any name in or below the zone are delivered.
+	Zone string `json:"zone"`
+}
+
+// How the server sees the client. Compare with the TXT records of whoami.<zone>.
+type Whoami struct {
+	// Contents of the X-Forwarded-For header, if any
+	ForwardedFor *string `json:"forwardedFor,omitempty"`
+
+	// ID the server logged the request with
+	RequestId string `json:"requestId"`
+
+	// Address and port the request came from
+	Source string `json:"source"`
+
+	// Protocol the request was made with
+	Transport string `json:"transport"`
+}
+
+// ZoneExport defines model for ZoneExport.
+type ZoneExport struct {
+	Records []ZoneExportRecord `json:"records"`
+	Zone    string             `json:"zone"`
+}
+
+// ZoneExportRecord defines model for ZoneExportRecord.
+type ZoneExportRecord struct {
+	Domain string `json:"domain"`
+	Ttl    uint32 `json:"ttl"`
+
+	// Record type, which is SOA or NS for the records the server generates at the zone apex
+	Type   string   `json:"type"`
+	Values []string `json:"values"`
+}
+
+// A record in an imported zone file, or a line of the file that couldn't be parsed
+type ZoneImportRecord struct {
+	Domain *string `json:"domain,omitempty"`
+
+	// Line of the zone file the record starts on
+	Line int `json:"line"`
+
+	// Why the record was skipped or failed
+	Reason *string `json:"reason,omitempty"`
+	Ttl    *uint32 `json:"ttl,omitempty"`
+
+	// The record's type. This isn't necessarily a supported RecordType.
+	Type  *string `json:"type,omitempty"`
 	Value *string `json:"value,omitempty"`
 }
 
+// ZoneImportReport defines model for ZoneImportReport.
+type ZoneImportReport struct {
+	// Records that didn't exist before
+	Created []ZoneImportRecord `json:"created"`
+
+	// Records that were deleted because the zone file replacing their zone didn't have them
+	Deleted *[]RecordReference `json:"deleted,omitempty"`
+	DryRun  bool               `json:"dryRun"`
+
+	// Records that couldn't be imported
+	Failed []ZoneImportRecord `json:"failed"`
+
+	// Records that already had the value in the zone file, or that the server generates itself
+	Skipped []ZoneImportRecord `json:"skipped"`
+
+	// Records that replaced a different value
+	Updated []ZoneImportRecord `json:"updated"`
+}
+
+// ZoneRestoreRequest defines model for ZoneRestoreRequest.
+type ZoneRestoreRequest struct {
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ZoneRestoreResult defines model for ZoneRestoreResult.
+type ZoneRestoreResult struct {
+	// Records that were deleted because they didn't exist at the timestamp
+	Deleted []RecordReference `json:"deleted"`
+
+	// Records that were set back to the value they had at the timestamp
+	Restored []RecordReference `json:"restored"`
+
+	// Records that changed since the timestamp, but whose history doesn't go back far enough to know what they were at the timestamp. They are left alone.
+	Unrestorable []RecordReference `json:"unrestorable"`
+}
+
 // Domain defines model for Domain.
 type Domain string
 
+// LeaseId defines model for LeaseId.
+type LeaseId string
+
+// View defines model for View.
+type View string
+
+// WebhookId defines model for WebhookId.
+type WebhookId string
+
+// GetAuditParams defines parameters for GetAudit.
+type GetAuditParams struct {
+	Zone *string `json:"zone,omitempty"`
+
+	// Only list changes made at or after this time
+	Since *time.Time `json:"since,omitempty"`
+
+	// Only list changes made at or before this time
+	Until *time.Time `json:"until,omitempty"`
+
+	// Maximum number of entries to return. Defaults to 100, and can be at most 1000.
+	Limit *int `json:"limit,omitempty"`
+}
+
+// DeleteDomainParams defines parameters for DeleteDomain.
+type DeleteDomainParams struct {
+	// Split-horizon view the record belongs to. Reads fall back to the default view if the record doesn't exist in the view.
+	View *View `json:"view,omitempty"`
+}
+
+// GetDomainParams defines parameters for GetDomain.
+type GetDomainParams struct {
+	// Split-horizon view the record belongs to. Reads fall back to the default view if the record doesn't exist in the view.
+	View *View `json:"view,omitempty"`
+}
+
 // PutDomainJSONBody defines parameters for PutDomain.
 type PutDomainJSONBody RecordValue
 
+// PutDomainParams defines parameters for PutDomain.
+type PutDomainParams struct {
+	// Split-horizon view the record belongs to. Reads fall back to the default view if the record doesn't exist in the view.
+	View *View `json:"view,omitempty"`
+}
+
+// ListRecordVersionsParams defines parameters for ListRecordVersions.
+type ListRecordVersionsParams struct {
+	// Split-horizon view the record belongs to. Reads fall back to the default view if the record doesn't exist in the view.
+	View *View `json:"view,omitempty"`
+}
+
+// RestoreRecordVersionParams defines parameters for RestoreRecordVersion.
+type RestoreRecordVersionParams struct {
+	// Split-horizon view the record belongs to. Reads fall back to the default view if the record doesn't exist in the view.
+	View *View `json:"view,omitempty"`
+}
+
+// CreateTenantJSONBody defines parameters for CreateTenant.
+type CreateTenantJSONBody NewTenant
+
+// WatchRecordsParams defines parameters for WatchRecords.
+type WatchRecordsParams struct {
+	// Only stream changes to records whose name starts with this prefix
+	Prefix *string `json:"prefix,omitempty"`
+}
+
+// ListWebhooksParams defines parameters for ListWebhooks.
+type ListWebhooksParams struct {
+	Zone *string `json:"zone,omitempty"`
+}
+
+// CreateWebhookJSONBody defines parameters for CreateWebhook.
+type CreateWebhookJSONBody WebhookRegistration
+
+// PostZoneParams defines parameters for PostZone.
+type PostZoneParams struct {
+	// Report what importing the zone would do without changing any records
+	DryRun *bool `json:"dryRun,omitempty"`
+}
+
+// ExportZoneParams defines parameters for ExportZone.
+type ExportZoneParams struct {
+	// Split-horizon view the record belongs to. Reads fall back to the default view if the record doesn't exist in the view.
+	View *View `json:"view,omitempty"`
+}
+
+// ReplaceZoneParams defines parameters for ReplaceZone.
+type ReplaceZoneParams struct {
+	// Report what importing the zone would do without changing any records
+	DryRun *bool `json:"dryRun,omitempty"`
+}
+
+// ImportZoneRecordsParams defines parameters for ImportZoneRecords.
+type ImportZoneRecordsParams struct {
+	// Report what importing the zone would do without changing any records
+	DryRun *bool `json:"dryRun,omitempty"`
+}
+
+// RestoreZoneJSONBody defines parameters for RestoreZone.
+type RestoreZoneJSONBody ZoneRestoreRequest
+
 // PutDomainJSONRequestBody defines body for PutDomain for application/json ContentType.
 type PutDomainJSONRequestBody PutDomainJSONBody
 
+// CreateTenantJSONRequestBody defines body for CreateTenant for application/json ContentType.
+type CreateTenantJSONRequestBody CreateTenantJSONBody
+
+// CreateWebhookJSONRequestBody defines body for CreateWebhook for application/json ContentType.
+type CreateWebhookJSONRequestBody CreateWebhookJSONBody
+
+// RestoreZoneJSONRequestBody defines body for RestoreZone for application/json ContentType.
+type RestoreZoneJSONRequestBody RestoreZoneJSONBody
+
 // RequestEditorFn  is the function signature for the RequestEditor callback function
 type RequestEditorFn func(ctx context.Context, req *http.Request) error
 
@@ -117,20 +528,86 @@ func WithRequestEditorFn(fn RequestEditorFn) ClientOption {
 
 // The interface specification for the client above.
 type ClientInterface interface {
+	// GetAudit request
+	GetAudit(ctx context.Context, params *GetAuditParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DeleteDomain request
+	DeleteDomain(ctx context.Context, domain Domain, recordType RecordType, params *DeleteDomainParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
 	// GetDomain request
-	GetDomain(ctx context.Context, domain Domain, recordType RecordType, reqEditors ...RequestEditorFn) (*http.Response, error)
+	GetDomain(ctx context.Context, domain Domain, recordType RecordType, params *GetDomainParams, reqEditors ...RequestEditorFn) (*http.Response, error)
 
 	// PutDomain request with any body
-	PutDomainWithBody(ctx context.Context, domain Domain, recordType RecordType, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+	PutDomainWithBody(ctx context.Context, domain Domain, recordType RecordType, params *PutDomainParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	PutDomain(ctx context.Context, domain Domain, recordType RecordType, params *PutDomainParams, body PutDomainJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ListRecordVersions request
+	ListRecordVersions(ctx context.Context, domain Domain, recordType RecordType, params *ListRecordVersionsParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// RestoreRecordVersion request
+	RestoreRecordVersion(ctx context.Context, domain Domain, recordType RecordType, version int64, params *RestoreRecordVersionParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ReleaseLease request
+	ReleaseLease(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetLease request
+	GetLease(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// RenewLease request
+	RenewLease(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ClaimSubdomain request
+	ClaimSubdomain(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ListTenants request
+	ListTenants(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// CreateTenant request with any body
+	CreateTenantWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
 
-	PutDomain(ctx context.Context, domain Domain, recordType RecordType, body PutDomainJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+	CreateTenant(ctx context.Context, body CreateTenantJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// WatchRecords request
+	WatchRecords(ctx context.Context, params *WatchRecordsParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ListWebhooks request
+	ListWebhooks(ctx context.Context, params *ListWebhooksParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// CreateWebhook request with any body
+	CreateWebhookWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	CreateWebhook(ctx context.Context, body CreateWebhookJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// DeleteWebhook request
+	DeleteWebhook(ctx context.Context, webhookId WebhookId, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ListWebhookDeliveries request
+	ListWebhookDeliveries(ctx context.Context, webhookId WebhookId, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// GetWhoami request
+	GetWhoami(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error)
 
 	// PostZone request with any body
-	PostZoneWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+	PostZoneWithBody(ctx context.Context, params *PostZoneParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ExportZone request
+	ExportZone(ctx context.Context, zone string, params *ExportZoneParams, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ReplaceZone request with any body
+	ReplaceZoneWithBody(ctx context.Context, zone string, params *ReplaceZoneParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// ImportZoneRecords request with any body
+	ImportZoneRecordsWithBody(ctx context.Context, zone string, params *ImportZoneRecordsParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	// RestoreZone request with any body
+	RestoreZoneWithBody(ctx context.Context, zone string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error)
+
+	RestoreZone(ctx context.Context, zone string, body RestoreZoneJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error)
 }
 
-func (c *Client) GetDomain(ctx context.Context, domain Domain, recordType RecordType, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	req, err := NewGetDomainRequest(c.Server, domain, recordType)
+func (c *Client) GetAudit(ctx context.Context, params *GetAuditParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetAuditRequest(c.Server, params)
 	if err != nil {
 		return nil, err
 	}
@@ -141,8 +618,8 @@ func (c *Client) GetDomain(ctx context.Context, domain Domain, recordType Record
 	return c.Client.Do(req)
 }
 
-func (c *Client) PutDomainWithBody(ctx context.Context, domain Domain, recordType RecordType, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	req, err := NewPutDomainRequestWithBody(c.Server, domain, recordType, contentType, body)
+func (c *Client) DeleteDomain(ctx context.Context, domain Domain, recordType RecordType, params *DeleteDomainParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDeleteDomainRequest(c.Server, domain, recordType, params)
 	if err != nil {
 		return nil, err
 	}
@@ -153,8 +630,8 @@ func (c *Client) PutDomainWithBody(ctx context.Context, domain Domain, recordTyp
 	return c.Client.Do(req)
 }
 
-func (c *Client) PutDomain(ctx context.Context, domain Domain, recordType RecordType, body PutDomainJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	req, err := NewPutDomainRequest(c.Server, domain, recordType, body)
+func (c *Client) GetDomain(ctx context.Context, domain Domain, recordType RecordType, params *GetDomainParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetDomainRequest(c.Server, domain, recordType, params)
 	if err != nil {
 		return nil, err
 	}
@@ -165,8 +642,8 @@ func (c *Client) PutDomain(ctx context.Context, domain Domain, recordType Record
 	return c.Client.Do(req)
 }
 
-func (c *Client) PostZoneWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
-	req, err := NewPostZoneRequestWithBody(c.Server, contentType, body)
+func (c *Client) PutDomainWithBody(ctx context.Context, domain Domain, recordType RecordType, params *PutDomainParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPutDomainRequestWithBody(c.Server, domain, recordType, params, contentType, body)
 	if err != nil {
 		return nil, err
 	}
@@ -177,390 +654,3599 @@ func (c *Client) PostZoneWithBody(ctx context.Context, contentType string, body
 	return c.Client.Do(req)
 }
 
-// NewGetDomainRequest generates requests for GetDomain
-func NewGetDomainRequest(server string, domain Domain, recordType RecordType) (*http.Request, error) {
-	var err error
-
-	var pathParam0 string
-
-	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "domain", runtime.ParamLocationPath, domain)
+func (c *Client) PutDomain(ctx context.Context, domain Domain, recordType RecordType, params *PutDomainParams, body PutDomainJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPutDomainRequest(c.Server, domain, recordType, params, body)
 	if err != nil {
 		return nil, err
 	}
-
-	var pathParam1 string
-
-	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "recordType", runtime.ParamLocationPath, recordType)
-	if err != nil {
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
 		return nil, err
 	}
+	return c.Client.Do(req)
+}
 
-	serverURL, err := url.Parse(server)
+func (c *Client) ListRecordVersions(ctx context.Context, domain Domain, recordType RecordType, params *ListRecordVersionsParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewListRecordVersionsRequest(c.Server, domain, recordType, params)
 	if err != nil {
 		return nil, err
 	}
-
-	operationPath := fmt.Sprintf("/domains/%s/record/%s", pathParam0, pathParam1)
-	if operationPath[0] == '/' {
-		operationPath = "." + operationPath
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
 	}
+	return c.Client.Do(req)
+}
 
-	queryURL, err := serverURL.Parse(operationPath)
+func (c *Client) RestoreRecordVersion(ctx context.Context, domain Domain, recordType RecordType, version int64, params *RestoreRecordVersionParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewRestoreRecordVersionRequest(c.Server, domain, recordType, version, params)
 	if err != nil {
 		return nil, err
 	}
-
-	req, err := http.NewRequest("GET", queryURL.String(), nil)
-	if err != nil {
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
 		return nil, err
 	}
-
-	return req, nil
+	return c.Client.Do(req)
 }
 
-// NewPutDomainRequest calls the generic PutDomain builder with application/json body
-func NewPutDomainRequest(server string, domain Domain, recordType RecordType, body PutDomainJSONRequestBody) (*http.Request, error) {
-	var bodyReader io.Reader
-	buf, err := json.Marshal(body)
+func (c *Client) ReleaseLease(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewReleaseLeaseRequest(c.Server, leaseId)
 	if err != nil {
 		return nil, err
 	}
-	bodyReader = bytes.NewReader(buf)
-	return NewPutDomainRequestWithBody(server, domain, recordType, "application/json", bodyReader)
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
 }
 
-// NewPutDomainRequestWithBody generates requests for PutDomain with any type of body
-func NewPutDomainRequestWithBody(server string, domain Domain, recordType RecordType, contentType string, body io.Reader) (*http.Request, error) {
-	var err error
-
-	var pathParam0 string
-
-	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "domain", runtime.ParamLocationPath, domain)
+func (c *Client) GetLease(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetLeaseRequest(c.Server, leaseId)
 	if err != nil {
 		return nil, err
 	}
-
-	var pathParam1 string
-
-	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "recordType", runtime.ParamLocationPath, recordType)
-	if err != nil {
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
 		return nil, err
 	}
+	return c.Client.Do(req)
+}
 
-	serverURL, err := url.Parse(server)
+func (c *Client) RenewLease(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewRenewLeaseRequest(c.Server, leaseId)
 	if err != nil {
 		return nil, err
 	}
-
-	operationPath := fmt.Sprintf("/domains/%s/record/%s", pathParam0, pathParam1)
-	if operationPath[0] == '/' {
-		operationPath = "." + operationPath
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
 	}
+	return c.Client.Do(req)
+}
 
-	queryURL, err := serverURL.Parse(operationPath)
+func (c *Client) ClaimSubdomain(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewClaimSubdomainRequest(c.Server)
 	if err != nil {
 		return nil, err
 	}
-
-	req, err := http.NewRequest("PUT", queryURL.String(), body)
-	if err != nil {
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
 		return nil, err
 	}
-
-	req.Header.Add("Content-Type", contentType)
-
-	return req, nil
+	return c.Client.Do(req)
 }
 
-// NewPostZoneRequestWithBody generates requests for PostZone with any type of body
-func NewPostZoneRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
-	var err error
-
-	serverURL, err := url.Parse(server)
+func (c *Client) ListTenants(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewListTenantsRequest(c.Server)
 	if err != nil {
 		return nil, err
 	}
-
-	operationPath := fmt.Sprintf("/zone")
-	if operationPath[0] == '/' {
-		operationPath = "." + operationPath
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
 	}
+	return c.Client.Do(req)
+}
 
-	queryURL, err := serverURL.Parse(operationPath)
+func (c *Client) CreateTenantWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCreateTenantRequestWithBody(c.Server, contentType, body)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
 
-	req, err := http.NewRequest("POST", queryURL.String(), body)
+func (c *Client) CreateTenant(ctx context.Context, body CreateTenantJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCreateTenantRequest(c.Server, body)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
 
-	req.Header.Add("Content-Type", contentType)
+func (c *Client) WatchRecords(ctx context.Context, params *WatchRecordsParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewWatchRecordsRequest(c.Server, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
 
-	return req, nil
+func (c *Client) ListWebhooks(ctx context.Context, params *ListWebhooksParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewListWebhooksRequest(c.Server, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
 }
 
-func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
-	for _, r := range c.RequestEditors {
-		if err := r(ctx, req); err != nil {
-			return err
-		}
+func (c *Client) CreateWebhookWithBody(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCreateWebhookRequestWithBody(c.Server, contentType, body)
+	if err != nil {
+		return nil, err
 	}
-	for _, r := range additionalEditors {
-		if err := r(ctx, req); err != nil {
-			return err
-		}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
 	}
-	return nil
+	return c.Client.Do(req)
 }
 
-// ClientWithResponses builds on ClientInterface to offer response payloads
-type ClientWithResponses struct {
-	ClientInterface
+func (c *Client) CreateWebhook(ctx context.Context, body CreateWebhookJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewCreateWebhookRequest(c.Server, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
 }
 
-// NewClientWithResponses creates a new ClientWithResponses, which wraps
-// Client with return type handling
-func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
-	client, err := NewClient(server, opts...)
+func (c *Client) DeleteWebhook(ctx context.Context, webhookId WebhookId, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewDeleteWebhookRequest(c.Server, webhookId)
 	if err != nil {
 		return nil, err
 	}
-	return &ClientWithResponses{client}, nil
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
 }
 
-// WithBaseURL overrides the baseURL.
-func WithBaseURL(baseURL string) ClientOption {
-	return func(c *Client) error {
-		newBaseURL, err := url.Parse(baseURL)
-		if err != nil {
-			return err
-		}
-		c.Server = newBaseURL.String()
-		return nil
+func (c *Client) ListWebhookDeliveries(ctx context.Context, webhookId WebhookId, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewListWebhookDeliveriesRequest(c.Server, webhookId)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
 	}
+	return c.Client.Do(req)
 }
 
-// ClientWithResponsesInterface is the interface specification for the client with responses above.
-type ClientWithResponsesInterface interface {
-	// GetDomain request
-	GetDomainWithResponse(ctx context.Context, domain Domain, recordType RecordType, reqEditors ...RequestEditorFn) (*GetDomainResponse, error)
+func (c *Client) GetWhoami(ctx context.Context, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewGetWhoamiRequest(c.Server)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
 
-	// PutDomain request with any body
-	PutDomainWithBodyWithResponse(ctx context.Context, domain Domain, recordType RecordType, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PutDomainResponse, error)
+func (c *Client) PostZoneWithBody(ctx context.Context, params *PostZoneParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewPostZoneRequestWithBody(c.Server, params, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
 
-	PutDomainWithResponse(ctx context.Context, domain Domain, recordType RecordType, body PutDomainJSONRequestBody, reqEditors ...RequestEditorFn) (*PutDomainResponse, error)
+func (c *Client) ExportZone(ctx context.Context, zone string, params *ExportZoneParams, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewExportZoneRequest(c.Server, zone, params)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
 
-	// PostZone request with any body
-	PostZoneWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostZoneResponse, error)
+func (c *Client) ReplaceZoneWithBody(ctx context.Context, zone string, params *ReplaceZoneParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewReplaceZoneRequestWithBody(c.Server, zone, params, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
 }
 
-type GetDomainResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-	JSON200      *RecordValue
+func (c *Client) ImportZoneRecordsWithBody(ctx context.Context, zone string, params *ImportZoneRecordsParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewImportZoneRecordsRequestWithBody(c.Server, zone, params, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
 }
 
-// Status returns HTTPResponse.Status
-func (r GetDomainResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+func (c *Client) RestoreZoneWithBody(ctx context.Context, zone string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewRestoreZoneRequestWithBody(c.Server, zone, contentType, body)
+	if err != nil {
+		return nil, err
 	}
-	return http.StatusText(0)
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+func (c *Client) RestoreZone(ctx context.Context, zone string, body RestoreZoneJSONRequestBody, reqEditors ...RequestEditorFn) (*http.Response, error) {
+	req, err := NewRestoreZoneRequest(c.Server, zone, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.applyEditors(ctx, req, reqEditors); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(req)
+}
+
+// NewGetAuditRequest generates requests for GetAudit
+func NewGetAuditRequest(server string, params *GetAuditParams) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/audit")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.Zone != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "zone", runtime.ParamLocationQuery, *params.Zone); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	if params.Since != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "since", runtime.ParamLocationQuery, *params.Since); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	if params.Until != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "until", runtime.ParamLocationQuery, *params.Until); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	if params.Limit != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "limit", runtime.ParamLocationQuery, *params.Limit); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewDeleteDomainRequest generates requests for DeleteDomain
+func NewDeleteDomainRequest(server string, domain Domain, recordType RecordType, params *DeleteDomainParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "domain", runtime.ParamLocationPath, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "recordType", runtime.ParamLocationPath, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/domains/%s/record/%s", pathParam0, pathParam1)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.View != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "view", runtime.ParamLocationQuery, *params.View); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("DELETE", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
 }
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r GetDomainResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+// NewGetDomainRequest generates requests for GetDomain
+func NewGetDomainRequest(server string, domain Domain, recordType RecordType, params *GetDomainParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "domain", runtime.ParamLocationPath, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "recordType", runtime.ParamLocationPath, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/domains/%s/record/%s", pathParam0, pathParam1)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.View != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "view", runtime.ParamLocationQuery, *params.View); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewPutDomainRequest calls the generic PutDomain builder with application/json body
+func NewPutDomainRequest(server string, domain Domain, recordType RecordType, params *PutDomainParams, body PutDomainJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewPutDomainRequestWithBody(server, domain, recordType, params, "application/json", bodyReader)
+}
+
+// NewPutDomainRequestWithBody generates requests for PutDomain with any type of body
+func NewPutDomainRequestWithBody(server string, domain Domain, recordType RecordType, params *PutDomainParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "domain", runtime.ParamLocationPath, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "recordType", runtime.ParamLocationPath, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/domains/%s/record/%s", pathParam0, pathParam1)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.View != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "view", runtime.ParamLocationQuery, *params.View); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("PUT", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewListRecordVersionsRequest generates requests for ListRecordVersions
+func NewListRecordVersionsRequest(server string, domain Domain, recordType RecordType, params *ListRecordVersionsParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "domain", runtime.ParamLocationPath, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "recordType", runtime.ParamLocationPath, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/domains/%s/record/%s/versions", pathParam0, pathParam1)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.View != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "view", runtime.ParamLocationQuery, *params.View); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewRestoreRecordVersionRequest generates requests for RestoreRecordVersion
+func NewRestoreRecordVersionRequest(server string, domain Domain, recordType RecordType, version int64, params *RestoreRecordVersionParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "domain", runtime.ParamLocationPath, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam1 string
+
+	pathParam1, err = runtime.StyleParamWithLocation("simple", false, "recordType", runtime.ParamLocationPath, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	var pathParam2 string
+
+	pathParam2, err = runtime.StyleParamWithLocation("simple", false, "version", runtime.ParamLocationPath, version)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/domains/%s/record/%s/versions/%s/restore", pathParam0, pathParam1, pathParam2)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.View != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "view", runtime.ParamLocationQuery, *params.View); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("POST", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewReleaseLeaseRequest generates requests for ReleaseLease
+func NewReleaseLeaseRequest(server string, leaseId LeaseId) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "leaseId", runtime.ParamLocationPath, leaseId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/leases/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("DELETE", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetLeaseRequest generates requests for GetLease
+func NewGetLeaseRequest(server string, leaseId LeaseId) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "leaseId", runtime.ParamLocationPath, leaseId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/leases/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewRenewLeaseRequest generates requests for RenewLease
+func NewRenewLeaseRequest(server string, leaseId LeaseId) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "leaseId", runtime.ParamLocationPath, leaseId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/leases/%s/renew", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewClaimSubdomainRequest generates requests for ClaimSubdomain
+func NewClaimSubdomainRequest(server string) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/subdomains")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewListTenantsRequest generates requests for ListTenants
+func NewListTenantsRequest(server string) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/tenants")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewCreateTenantRequest calls the generic CreateTenant builder with application/json body
+func NewCreateTenantRequest(server string, body CreateTenantJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewCreateTenantRequestWithBody(server, "application/json", bodyReader)
+}
+
+// NewCreateTenantRequestWithBody generates requests for CreateTenant with any type of body
+func NewCreateTenantRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/tenants")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewWatchRecordsRequest generates requests for WatchRecords
+func NewWatchRecordsRequest(server string, params *WatchRecordsParams) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/watch")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.Prefix != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "prefix", runtime.ParamLocationQuery, *params.Prefix); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewListWebhooksRequest generates requests for ListWebhooks
+func NewListWebhooksRequest(server string, params *ListWebhooksParams) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/webhooks")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.Zone != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "zone", runtime.ParamLocationQuery, *params.Zone); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewCreateWebhookRequest calls the generic CreateWebhook builder with application/json body
+func NewCreateWebhookRequest(server string, body CreateWebhookJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewCreateWebhookRequestWithBody(server, "application/json", bodyReader)
+}
+
+// NewCreateWebhookRequestWithBody generates requests for CreateWebhook with any type of body
+func NewCreateWebhookRequestWithBody(server string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/webhooks")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewDeleteWebhookRequest generates requests for DeleteWebhook
+func NewDeleteWebhookRequest(server string, webhookId WebhookId) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "webhookId", runtime.ParamLocationPath, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/webhooks/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("DELETE", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewListWebhookDeliveriesRequest generates requests for ListWebhookDeliveries
+func NewListWebhookDeliveriesRequest(server string, webhookId WebhookId) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "webhookId", runtime.ParamLocationPath, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/webhooks/%s/deliveries", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewGetWhoamiRequest generates requests for GetWhoami
+func NewGetWhoamiRequest(server string) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/whoami")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewPostZoneRequestWithBody generates requests for PostZone with any type of body
+func NewPostZoneRequestWithBody(server string, params *PostZoneParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/zone")
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.DryRun != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "dryRun", runtime.ParamLocationQuery, *params.DryRun); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewExportZoneRequest generates requests for ExportZone
+func NewExportZoneRequest(server string, zone string, params *ExportZoneParams) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "zone", runtime.ParamLocationPath, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/zones/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.View != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "view", runtime.ParamLocationQuery, *params.View); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("GET", queryURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// NewReplaceZoneRequestWithBody generates requests for ReplaceZone with any type of body
+func NewReplaceZoneRequestWithBody(server string, zone string, params *ReplaceZoneParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "zone", runtime.ParamLocationPath, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/zones/%s", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.DryRun != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "dryRun", runtime.ParamLocationQuery, *params.DryRun); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("PUT", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewImportZoneRecordsRequestWithBody generates requests for ImportZoneRecords with any type of body
+func NewImportZoneRecordsRequestWithBody(server string, zone string, params *ImportZoneRecordsParams, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "zone", runtime.ParamLocationPath, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/zones/%s/records:import", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	queryValues := queryURL.Query()
+
+	if params.DryRun != nil {
+
+		if queryFrag, err := runtime.StyleParamWithLocation("form", true, "dryRun", runtime.ParamLocationQuery, *params.DryRun); err != nil {
+			return nil, err
+		} else if parsed, err := url.ParseQuery(queryFrag); err != nil {
+			return nil, err
+		} else {
+			for k, v := range parsed {
+				for _, v2 := range v {
+					queryValues.Add(k, v2)
+				}
+			}
+		}
+
+	}
+
+	queryURL.RawQuery = queryValues.Encode()
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+// NewRestoreZoneRequest calls the generic RestoreZone builder with application/json body
+func NewRestoreZoneRequest(server string, zone string, body RestoreZoneJSONRequestBody) (*http.Request, error) {
+	var bodyReader io.Reader
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	bodyReader = bytes.NewReader(buf)
+	return NewRestoreZoneRequestWithBody(server, zone, "application/json", bodyReader)
+}
+
+// NewRestoreZoneRequestWithBody generates requests for RestoreZone with any type of body
+func NewRestoreZoneRequestWithBody(server string, zone string, contentType string, body io.Reader) (*http.Request, error) {
+	var err error
+
+	var pathParam0 string
+
+	pathParam0, err = runtime.StyleParamWithLocation("simple", false, "zone", runtime.ParamLocationPath, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+
+	operationPath := fmt.Sprintf("/zones/%s/restore", pathParam0)
+	if operationPath[0] == '/' {
+		operationPath = "." + operationPath
+	}
+
+	queryURL, err := serverURL.Parse(operationPath)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", queryURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Content-Type", contentType)
+
+	return req, nil
+}
+
+func (c *Client) applyEditors(ctx context.Context, req *http.Request, additionalEditors []RequestEditorFn) error {
+	for _, r := range c.RequestEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	for _, r := range additionalEditors {
+		if err := r(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClientWithResponses builds on ClientInterface to offer response payloads
+type ClientWithResponses struct {
+	ClientInterface
+}
+
+// NewClientWithResponses creates a new ClientWithResponses, which wraps
+// Client with return type handling
+func NewClientWithResponses(server string, opts ...ClientOption) (*ClientWithResponses, error) {
+	client, err := NewClient(server, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientWithResponses{client}, nil
+}
+
+// WithBaseURL overrides the baseURL.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) error {
+		newBaseURL, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+		c.Server = newBaseURL.String()
+		return nil
+	}
+}
+
+// ClientWithResponsesInterface is the interface specification for the client with responses above.
+type ClientWithResponsesInterface interface {
+	// GetAudit request
+	GetAuditWithResponse(ctx context.Context, params *GetAuditParams, reqEditors ...RequestEditorFn) (*GetAuditResponse, error)
+
+	// DeleteDomain request
+	DeleteDomainWithResponse(ctx context.Context, domain Domain, recordType RecordType, params *DeleteDomainParams, reqEditors ...RequestEditorFn) (*DeleteDomainResponse, error)
+
+	// GetDomain request
+	GetDomainWithResponse(ctx context.Context, domain Domain, recordType RecordType, params *GetDomainParams, reqEditors ...RequestEditorFn) (*GetDomainResponse, error)
+
+	// PutDomain request with any body
+	PutDomainWithBodyWithResponse(ctx context.Context, domain Domain, recordType RecordType, params *PutDomainParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PutDomainResponse, error)
+
+	PutDomainWithResponse(ctx context.Context, domain Domain, recordType RecordType, params *PutDomainParams, body PutDomainJSONRequestBody, reqEditors ...RequestEditorFn) (*PutDomainResponse, error)
+
+	// ListRecordVersions request
+	ListRecordVersionsWithResponse(ctx context.Context, domain Domain, recordType RecordType, params *ListRecordVersionsParams, reqEditors ...RequestEditorFn) (*ListRecordVersionsResponse, error)
+
+	// RestoreRecordVersion request
+	RestoreRecordVersionWithResponse(ctx context.Context, domain Domain, recordType RecordType, version int64, params *RestoreRecordVersionParams, reqEditors ...RequestEditorFn) (*RestoreRecordVersionResponse, error)
+
+	// ReleaseLease request
+	ReleaseLeaseWithResponse(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*ReleaseLeaseResponse, error)
+
+	// GetLease request
+	GetLeaseWithResponse(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*GetLeaseResponse, error)
+
+	// RenewLease request
+	RenewLeaseWithResponse(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*RenewLeaseResponse, error)
+
+	// ClaimSubdomain request
+	ClaimSubdomainWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*ClaimSubdomainResponse, error)
+
+	// ListTenants request
+	ListTenantsWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*ListTenantsResponse, error)
+
+	// CreateTenant request with any body
+	CreateTenantWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CreateTenantResponse, error)
+
+	CreateTenantWithResponse(ctx context.Context, body CreateTenantJSONRequestBody, reqEditors ...RequestEditorFn) (*CreateTenantResponse, error)
+
+	// WatchRecords request
+	WatchRecordsWithResponse(ctx context.Context, params *WatchRecordsParams, reqEditors ...RequestEditorFn) (*WatchRecordsResponse, error)
+
+	// ListWebhooks request
+	ListWebhooksWithResponse(ctx context.Context, params *ListWebhooksParams, reqEditors ...RequestEditorFn) (*ListWebhooksResponse, error)
+
+	// CreateWebhook request with any body
+	CreateWebhookWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CreateWebhookResponse, error)
+
+	CreateWebhookWithResponse(ctx context.Context, body CreateWebhookJSONRequestBody, reqEditors ...RequestEditorFn) (*CreateWebhookResponse, error)
+
+	// DeleteWebhook request
+	DeleteWebhookWithResponse(ctx context.Context, webhookId WebhookId, reqEditors ...RequestEditorFn) (*DeleteWebhookResponse, error)
+
+	// ListWebhookDeliveries request
+	ListWebhookDeliveriesWithResponse(ctx context.Context, webhookId WebhookId, reqEditors ...RequestEditorFn) (*ListWebhookDeliveriesResponse, error)
+
+	// GetWhoami request
+	GetWhoamiWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetWhoamiResponse, error)
+
+	// PostZone request with any body
+	PostZoneWithBodyWithResponse(ctx context.Context, params *PostZoneParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostZoneResponse, error)
+
+	// ExportZone request
+	ExportZoneWithResponse(ctx context.Context, zone string, params *ExportZoneParams, reqEditors ...RequestEditorFn) (*ExportZoneResponse, error)
+
+	// ReplaceZone request with any body
+	ReplaceZoneWithBodyWithResponse(ctx context.Context, zone string, params *ReplaceZoneParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*ReplaceZoneResponse, error)
+
+	// ImportZoneRecords request with any body
+	ImportZoneRecordsWithBodyWithResponse(ctx context.Context, zone string, params *ImportZoneRecordsParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*ImportZoneRecordsResponse, error)
+
+	// RestoreZone request with any body
+	RestoreZoneWithBodyWithResponse(ctx context.Context, zone string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*RestoreZoneResponse, error)
+
+	RestoreZoneWithResponse(ctx context.Context, zone string, body RestoreZoneJSONRequestBody, reqEditors ...RequestEditorFn) (*RestoreZoneResponse, error)
+}
+
+type GetAuditResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *[]AuditEntry
+}
+
+// Status returns HTTPResponse.Status
+func (r GetAuditResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetAuditResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DeleteDomainResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DeleteDomainResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DeleteDomainResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetDomainResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *RecordValue
+}
+
+// Status returns HTTPResponse.Status
+func (r GetDomainResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetDomainResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PutDomainResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r PutDomainResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PutDomainResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ListRecordVersionsResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *[]RecordVersion
+}
+
+// Status returns HTTPResponse.Status
+func (r ListRecordVersionsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ListRecordVersionsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type RestoreRecordVersionResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r RestoreRecordVersionResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r RestoreRecordVersionResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ReleaseLeaseResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r ReleaseLeaseResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ReleaseLeaseResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetLeaseResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *Lease
+}
+
+// Status returns HTTPResponse.Status
+func (r GetLeaseResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetLeaseResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type RenewLeaseResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *Lease
+}
+
+// Status returns HTTPResponse.Status
+func (r RenewLeaseResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r RenewLeaseResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ClaimSubdomainResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON201      *Subdomain
+}
+
+// Status returns HTTPResponse.Status
+func (r ClaimSubdomainResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ClaimSubdomainResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ListTenantsResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *[]Tenant
+}
+
+// Status returns HTTPResponse.Status
+func (r ListTenantsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ListTenantsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type CreateTenantResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON201      *CreatedTenant
+}
+
+// Status returns HTTPResponse.Status
+func (r CreateTenantResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r CreateTenantResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type WatchRecordsResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r WatchRecordsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r WatchRecordsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ListWebhooksResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *[]Webhook
+}
+
+// Status returns HTTPResponse.Status
+func (r ListWebhooksResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ListWebhooksResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type CreateWebhookResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON201      *Webhook
+}
+
+// Status returns HTTPResponse.Status
+func (r CreateWebhookResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r CreateWebhookResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type DeleteWebhookResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+}
+
+// Status returns HTTPResponse.Status
+func (r DeleteWebhookResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r DeleteWebhookResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ListWebhookDeliveriesResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *[]WebhookDelivery
+}
+
+// Status returns HTTPResponse.Status
+func (r ListWebhookDeliveriesResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ListWebhookDeliveriesResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type GetWhoamiResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *Whoami
+}
+
+// Status returns HTTPResponse.Status
+func (r GetWhoamiResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r GetWhoamiResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type PostZoneResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ZoneImportReport
+	JSON201      *ZoneImportReport
+	JSON400      *ZoneImportReport
+}
+
+// Status returns HTTPResponse.Status
+func (r PostZoneResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r PostZoneResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ExportZoneResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ZoneExport
+}
+
+// Status returns HTTPResponse.Status
+func (r ExportZoneResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ExportZoneResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ReplaceZoneResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ZoneImportReport
+	JSON400      *ZoneImportReport
+}
+
+// Status returns HTTPResponse.Status
+func (r ReplaceZoneResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ReplaceZoneResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type ImportZoneRecordsResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ZoneImportReport
+	JSON201      *ZoneImportReport
+	JSON400      *ZoneImportReport
+}
+
+// Status returns HTTPResponse.Status
+func (r ImportZoneRecordsResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r ImportZoneRecordsResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+type RestoreZoneResponse struct {
+	Body         []byte
+	HTTPResponse *http.Response
+	JSON200      *ZoneRestoreResult
+}
+
+// Status returns HTTPResponse.Status
+func (r RestoreZoneResponse) Status() string {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.Status
+	}
+	return http.StatusText(0)
+}
+
+// StatusCode returns HTTPResponse.StatusCode
+func (r RestoreZoneResponse) StatusCode() int {
+	if r.HTTPResponse != nil {
+		return r.HTTPResponse.StatusCode
+	}
+	return 0
+}
+
+// GetAuditWithResponse request returning *GetAuditResponse
+func (c *ClientWithResponses) GetAuditWithResponse(ctx context.Context, params *GetAuditParams, reqEditors ...RequestEditorFn) (*GetAuditResponse, error) {
+	rsp, err := c.GetAudit(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetAuditResponse(rsp)
+}
+
+// DeleteDomainWithResponse request returning *DeleteDomainResponse
+func (c *ClientWithResponses) DeleteDomainWithResponse(ctx context.Context, domain Domain, recordType RecordType, params *DeleteDomainParams, reqEditors ...RequestEditorFn) (*DeleteDomainResponse, error) {
+	rsp, err := c.DeleteDomain(ctx, domain, recordType, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDeleteDomainResponse(rsp)
+}
+
+// GetDomainWithResponse request returning *GetDomainResponse
+func (c *ClientWithResponses) GetDomainWithResponse(ctx context.Context, domain Domain, recordType RecordType, params *GetDomainParams, reqEditors ...RequestEditorFn) (*GetDomainResponse, error) {
+	rsp, err := c.GetDomain(ctx, domain, recordType, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetDomainResponse(rsp)
+}
+
+// PutDomainWithBodyWithResponse request with arbitrary body returning *PutDomainResponse
+func (c *ClientWithResponses) PutDomainWithBodyWithResponse(ctx context.Context, domain Domain, recordType RecordType, params *PutDomainParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PutDomainResponse, error) {
+	rsp, err := c.PutDomainWithBody(ctx, domain, recordType, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePutDomainResponse(rsp)
+}
+
+func (c *ClientWithResponses) PutDomainWithResponse(ctx context.Context, domain Domain, recordType RecordType, params *PutDomainParams, body PutDomainJSONRequestBody, reqEditors ...RequestEditorFn) (*PutDomainResponse, error) {
+	rsp, err := c.PutDomain(ctx, domain, recordType, params, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePutDomainResponse(rsp)
+}
+
+// ListRecordVersionsWithResponse request returning *ListRecordVersionsResponse
+func (c *ClientWithResponses) ListRecordVersionsWithResponse(ctx context.Context, domain Domain, recordType RecordType, params *ListRecordVersionsParams, reqEditors ...RequestEditorFn) (*ListRecordVersionsResponse, error) {
+	rsp, err := c.ListRecordVersions(ctx, domain, recordType, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseListRecordVersionsResponse(rsp)
+}
+
+// RestoreRecordVersionWithResponse request returning *RestoreRecordVersionResponse
+func (c *ClientWithResponses) RestoreRecordVersionWithResponse(ctx context.Context, domain Domain, recordType RecordType, version int64, params *RestoreRecordVersionParams, reqEditors ...RequestEditorFn) (*RestoreRecordVersionResponse, error) {
+	rsp, err := c.RestoreRecordVersion(ctx, domain, recordType, version, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRestoreRecordVersionResponse(rsp)
+}
+
+// ReleaseLeaseWithResponse request returning *ReleaseLeaseResponse
+func (c *ClientWithResponses) ReleaseLeaseWithResponse(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*ReleaseLeaseResponse, error) {
+	rsp, err := c.ReleaseLease(ctx, leaseId, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseReleaseLeaseResponse(rsp)
+}
+
+// GetLeaseWithResponse request returning *GetLeaseResponse
+func (c *ClientWithResponses) GetLeaseWithResponse(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*GetLeaseResponse, error) {
+	rsp, err := c.GetLease(ctx, leaseId, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetLeaseResponse(rsp)
+}
+
+// RenewLeaseWithResponse request returning *RenewLeaseResponse
+func (c *ClientWithResponses) RenewLeaseWithResponse(ctx context.Context, leaseId LeaseId, reqEditors ...RequestEditorFn) (*RenewLeaseResponse, error) {
+	rsp, err := c.RenewLease(ctx, leaseId, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRenewLeaseResponse(rsp)
+}
+
+// ClaimSubdomainWithResponse request returning *ClaimSubdomainResponse
+func (c *ClientWithResponses) ClaimSubdomainWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*ClaimSubdomainResponse, error) {
+	rsp, err := c.ClaimSubdomain(ctx, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseClaimSubdomainResponse(rsp)
+}
+
+// ListTenantsWithResponse request returning *ListTenantsResponse
+func (c *ClientWithResponses) ListTenantsWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*ListTenantsResponse, error) {
+	rsp, err := c.ListTenants(ctx, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseListTenantsResponse(rsp)
+}
+
+// CreateTenantWithBodyWithResponse request with arbitrary body returning *CreateTenantResponse
+func (c *ClientWithResponses) CreateTenantWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CreateTenantResponse, error) {
+	rsp, err := c.CreateTenantWithBody(ctx, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCreateTenantResponse(rsp)
+}
+
+func (c *ClientWithResponses) CreateTenantWithResponse(ctx context.Context, body CreateTenantJSONRequestBody, reqEditors ...RequestEditorFn) (*CreateTenantResponse, error) {
+	rsp, err := c.CreateTenant(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCreateTenantResponse(rsp)
+}
+
+// WatchRecordsWithResponse request returning *WatchRecordsResponse
+func (c *ClientWithResponses) WatchRecordsWithResponse(ctx context.Context, params *WatchRecordsParams, reqEditors ...RequestEditorFn) (*WatchRecordsResponse, error) {
+	rsp, err := c.WatchRecords(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWatchRecordsResponse(rsp)
+}
+
+// ListWebhooksWithResponse request returning *ListWebhooksResponse
+func (c *ClientWithResponses) ListWebhooksWithResponse(ctx context.Context, params *ListWebhooksParams, reqEditors ...RequestEditorFn) (*ListWebhooksResponse, error) {
+	rsp, err := c.ListWebhooks(ctx, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseListWebhooksResponse(rsp)
+}
+
+// CreateWebhookWithBodyWithResponse request with arbitrary body returning *CreateWebhookResponse
+func (c *ClientWithResponses) CreateWebhookWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*CreateWebhookResponse, error) {
+	rsp, err := c.CreateWebhookWithBody(ctx, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCreateWebhookResponse(rsp)
+}
+
+func (c *ClientWithResponses) CreateWebhookWithResponse(ctx context.Context, body CreateWebhookJSONRequestBody, reqEditors ...RequestEditorFn) (*CreateWebhookResponse, error) {
+	rsp, err := c.CreateWebhook(ctx, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCreateWebhookResponse(rsp)
+}
+
+// DeleteWebhookWithResponse request returning *DeleteWebhookResponse
+func (c *ClientWithResponses) DeleteWebhookWithResponse(ctx context.Context, webhookId WebhookId, reqEditors ...RequestEditorFn) (*DeleteWebhookResponse, error) {
+	rsp, err := c.DeleteWebhook(ctx, webhookId, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDeleteWebhookResponse(rsp)
+}
+
+// ListWebhookDeliveriesWithResponse request returning *ListWebhookDeliveriesResponse
+func (c *ClientWithResponses) ListWebhookDeliveriesWithResponse(ctx context.Context, webhookId WebhookId, reqEditors ...RequestEditorFn) (*ListWebhookDeliveriesResponse, error) {
+	rsp, err := c.ListWebhookDeliveries(ctx, webhookId, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseListWebhookDeliveriesResponse(rsp)
+}
+
+// GetWhoamiWithResponse request returning *GetWhoamiResponse
+func (c *ClientWithResponses) GetWhoamiWithResponse(ctx context.Context, reqEditors ...RequestEditorFn) (*GetWhoamiResponse, error) {
+	rsp, err := c.GetWhoami(ctx, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGetWhoamiResponse(rsp)
+}
+
+// PostZoneWithBodyWithResponse request with arbitrary body returning *PostZoneResponse
+func (c *ClientWithResponses) PostZoneWithBodyWithResponse(ctx context.Context, params *PostZoneParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostZoneResponse, error) {
+	rsp, err := c.PostZoneWithBody(ctx, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePostZoneResponse(rsp)
+}
+
+// ExportZoneWithResponse request returning *ExportZoneResponse
+func (c *ClientWithResponses) ExportZoneWithResponse(ctx context.Context, zone string, params *ExportZoneParams, reqEditors ...RequestEditorFn) (*ExportZoneResponse, error) {
+	rsp, err := c.ExportZone(ctx, zone, params, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseExportZoneResponse(rsp)
+}
+
+// ReplaceZoneWithBodyWithResponse request with arbitrary body returning *ReplaceZoneResponse
+func (c *ClientWithResponses) ReplaceZoneWithBodyWithResponse(ctx context.Context, zone string, params *ReplaceZoneParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*ReplaceZoneResponse, error) {
+	rsp, err := c.ReplaceZoneWithBody(ctx, zone, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseReplaceZoneResponse(rsp)
+}
+
+// ImportZoneRecordsWithBodyWithResponse request with arbitrary body returning *ImportZoneRecordsResponse
+func (c *ClientWithResponses) ImportZoneRecordsWithBodyWithResponse(ctx context.Context, zone string, params *ImportZoneRecordsParams, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*ImportZoneRecordsResponse, error) {
+	rsp, err := c.ImportZoneRecordsWithBody(ctx, zone, params, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseImportZoneRecordsResponse(rsp)
+}
+
+// RestoreZoneWithBodyWithResponse request with arbitrary body returning *RestoreZoneResponse
+func (c *ClientWithResponses) RestoreZoneWithBodyWithResponse(ctx context.Context, zone string, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*RestoreZoneResponse, error) {
+	rsp, err := c.RestoreZoneWithBody(ctx, zone, contentType, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRestoreZoneResponse(rsp)
+}
+
+func (c *ClientWithResponses) RestoreZoneWithResponse(ctx context.Context, zone string, body RestoreZoneJSONRequestBody, reqEditors ...RequestEditorFn) (*RestoreZoneResponse, error) {
+	rsp, err := c.RestoreZone(ctx, zone, body, reqEditors...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRestoreZoneResponse(rsp)
+}
+
+// ParseGetAuditResponse parses an HTTP response from a GetAuditWithResponse call
+func ParseGetAuditResponse(rsp *http.Response) (*GetAuditResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetAuditResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest []AuditEntry
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseDeleteDomainResponse parses an HTTP response from a DeleteDomainWithResponse call
+func ParseDeleteDomainResponse(rsp *http.Response) (*DeleteDomainResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DeleteDomainResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseGetDomainResponse parses an HTTP response from a GetDomainWithResponse call
+func ParseGetDomainResponse(rsp *http.Response) (*GetDomainResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetDomainResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest RecordValue
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParsePutDomainResponse parses an HTTP response from a PutDomainWithResponse call
+func ParsePutDomainResponse(rsp *http.Response) (*PutDomainResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PutDomainResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseListRecordVersionsResponse parses an HTTP response from a ListRecordVersionsWithResponse call
+func ParseListRecordVersionsResponse(rsp *http.Response) (*ListRecordVersionsResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListRecordVersionsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest []RecordVersion
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseRestoreRecordVersionResponse parses an HTTP response from a RestoreRecordVersionWithResponse call
+func ParseRestoreRecordVersionResponse(rsp *http.Response) (*RestoreRecordVersionResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &RestoreRecordVersionResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseReleaseLeaseResponse parses an HTTP response from a ReleaseLeaseWithResponse call
+func ParseReleaseLeaseResponse(rsp *http.Response) (*ReleaseLeaseResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ReleaseLeaseResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseGetLeaseResponse parses an HTTP response from a GetLeaseWithResponse call
+func ParseGetLeaseResponse(rsp *http.Response) (*GetLeaseResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetLeaseResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest Lease
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseRenewLeaseResponse parses an HTTP response from a RenewLeaseWithResponse call
+func ParseRenewLeaseResponse(rsp *http.Response) (*RenewLeaseResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &RenewLeaseResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest Lease
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseClaimSubdomainResponse parses an HTTP response from a ClaimSubdomainWithResponse call
+func ParseClaimSubdomainResponse(rsp *http.Response) (*ClaimSubdomainResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ClaimSubdomainResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 201:
+		var dest Subdomain
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON201 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseListTenantsResponse parses an HTTP response from a ListTenantsWithResponse call
+func ParseListTenantsResponse(rsp *http.Response) (*ListTenantsResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListTenantsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest []Tenant
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseCreateTenantResponse parses an HTTP response from a CreateTenantWithResponse call
+func ParseCreateTenantResponse(rsp *http.Response) (*CreateTenantResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CreateTenantResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 201:
+		var dest CreatedTenant
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON201 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseWatchRecordsResponse parses an HTTP response from a WatchRecordsWithResponse call
+func ParseWatchRecordsResponse(rsp *http.Response) (*WatchRecordsResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &WatchRecordsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseListWebhooksResponse parses an HTTP response from a ListWebhooksWithResponse call
+func ParseListWebhooksResponse(rsp *http.Response) (*ListWebhooksResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListWebhooksResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest []Webhook
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseCreateWebhookResponse parses an HTTP response from a CreateWebhookWithResponse call
+func ParseCreateWebhookResponse(rsp *http.Response) (*CreateWebhookResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CreateWebhookResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 201:
+		var dest Webhook
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON201 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseDeleteWebhookResponse parses an HTTP response from a DeleteWebhookWithResponse call
+func ParseDeleteWebhookResponse(rsp *http.Response) (*DeleteWebhookResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DeleteWebhookResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	return response, nil
+}
+
+// ParseListWebhookDeliveriesResponse parses an HTTP response from a ListWebhookDeliveriesWithResponse call
+func ParseListWebhookDeliveriesResponse(rsp *http.Response) (*ListWebhookDeliveriesResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ListWebhookDeliveriesResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest []WebhookDelivery
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseGetWhoamiResponse parses an HTTP response from a GetWhoamiWithResponse call
+func ParseGetWhoamiResponse(rsp *http.Response) (*GetWhoamiResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GetWhoamiResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest Whoami
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParsePostZoneResponse parses an HTTP response from a PostZoneWithResponse call
+func ParsePostZoneResponse(rsp *http.Response) (*PostZoneResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PostZoneResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest ZoneImportReport
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 201:
+		var dest ZoneImportReport
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON201 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest ZoneImportReport
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseExportZoneResponse parses an HTTP response from a ExportZoneWithResponse call
+func ParseExportZoneResponse(rsp *http.Response) (*ExportZoneResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ExportZoneResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest ZoneExport
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case rsp.StatusCode == 200:
+		// Content-type (text/dns) unsupported
+
+	}
+
+	return response, nil
+}
+
+// ParseReplaceZoneResponse parses an HTTP response from a ReplaceZoneWithResponse call
+func ParseReplaceZoneResponse(rsp *http.Response) (*ReplaceZoneResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ReplaceZoneResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest ZoneImportReport
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest ZoneImportReport
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseImportZoneRecordsResponse parses an HTTP response from a ImportZoneRecordsWithResponse call
+func ParseImportZoneRecordsResponse(rsp *http.Response) (*ImportZoneRecordsResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ImportZoneRecordsResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest ZoneImportReport
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 201:
+		var dest ZoneImportReport
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON201 = &dest
+
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 400:
+		var dest ZoneImportReport
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON400 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ParseRestoreZoneResponse parses an HTTP response from a RestoreZoneWithResponse call
+func ParseRestoreZoneResponse(rsp *http.Response) (*RestoreZoneResponse, error) {
+	bodyBytes, err := ioutil.ReadAll(rsp.Body)
+	defer func() { _ = rsp.Body.Close() }()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &RestoreZoneResponse{
+		Body:         bodyBytes,
+		HTTPResponse: rsp,
+	}
+
+	switch {
+	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
+		var dest ZoneRestoreResult
+		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
+			return nil, err
+		}
+		response.JSON200 = &dest
+
+	}
+
+	return response, nil
+}
+
+// ServerInterface represents all server handlers.
+type ServerInterface interface {
+
+	// (GET /audit)
+	GetAudit(w http.ResponseWriter, r *http.Request, params GetAuditParams)
+
+	// (DELETE /domains/{domain}/record/{recordType})
+	DeleteDomain(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType, params DeleteDomainParams)
+
+	// (GET /domains/{domain}/record/{recordType})
+	GetDomain(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType, params GetDomainParams)
+
+	// (PUT /domains/{domain}/record/{recordType})
+	PutDomain(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType, params PutDomainParams)
+
+	// (GET /domains/{domain}/record/{recordType}/versions)
+	ListRecordVersions(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType, params ListRecordVersionsParams)
+
+	// (POST /domains/{domain}/record/{recordType}/versions/{version}/restore)
+	RestoreRecordVersion(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType, version int64, params RestoreRecordVersionParams)
+
+	// (DELETE /leases/{leaseId})
+	ReleaseLease(w http.ResponseWriter, r *http.Request, leaseId LeaseId)
+
+	// (GET /leases/{leaseId})
+	GetLease(w http.ResponseWriter, r *http.Request, leaseId LeaseId)
+
+	// (POST /leases/{leaseId}/renew)
+	RenewLease(w http.ResponseWriter, r *http.Request, leaseId LeaseId)
+
+	// (POST /subdomains)
+	ClaimSubdomain(w http.ResponseWriter, r *http.Request)
+
+	// (GET /tenants)
+	ListTenants(w http.ResponseWriter, r *http.Request)
+
+	// (POST /tenants)
+	CreateTenant(w http.ResponseWriter, r *http.Request)
+
+	// (GET /watch)
+	WatchRecords(w http.ResponseWriter, r *http.Request, params WatchRecordsParams)
+
+	// (GET /webhooks)
+	ListWebhooks(w http.ResponseWriter, r *http.Request, params ListWebhooksParams)
+
+	// (POST /webhooks)
+	CreateWebhook(w http.ResponseWriter, r *http.Request)
+
+	// (DELETE /webhooks/{webhookId})
+	DeleteWebhook(w http.ResponseWriter, r *http.Request, webhookId WebhookId)
+
+	// (GET /webhooks/{webhookId}/deliveries)
+	ListWebhookDeliveries(w http.ResponseWriter, r *http.Request, webhookId WebhookId)
+
+	// (GET /whoami)
+	GetWhoami(w http.ResponseWriter, r *http.Request)
+
+	// (POST /zone)
+	PostZone(w http.ResponseWriter, r *http.Request, params PostZoneParams)
+
+	// (GET /zones/{zone})
+	ExportZone(w http.ResponseWriter, r *http.Request, zone string, params ExportZoneParams)
+
+	// (PUT /zones/{zone})
+	ReplaceZone(w http.ResponseWriter, r *http.Request, zone string, params ReplaceZoneParams)
+
+	// (POST /zones/{zone}/records:import)
+	ImportZoneRecords(w http.ResponseWriter, r *http.Request, zone string, params ImportZoneRecordsParams)
+
+	// (POST /zones/{zone}/restore)
+	RestoreZone(w http.ResponseWriter, r *http.Request, zone string)
+}
+
+// ServerInterfaceWrapper converts contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler            ServerInterface
+	HandlerMiddlewares []MiddlewareFunc
+	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
+
+// GetAudit operation middleware
+func (siw *ServerInterfaceWrapper) GetAudit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAuditParams
+
+	// ------------- Optional query parameter "zone" -------------
+	if paramValue := r.URL.Query().Get("zone"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "zone", r.URL.Query(), &params.Zone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "zone", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "since" -------------
+	if paramValue := r.URL.Query().Get("since"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "since", r.URL.Query(), &params.Since)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "since", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "until" -------------
+	if paramValue := r.URL.Query().Get("until"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "until", r.URL.Query(), &params.Until)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "until", Err: err})
+		return
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+	if paramValue := r.URL.Query().Get("limit"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", r.URL.Query(), &params.Limit)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "limit", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetAudit(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// DeleteDomain operation middleware
+func (siw *ServerInterfaceWrapper) DeleteDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "domain" -------------
+	var domain Domain
+
+	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "domain", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "recordType" -------------
+	var recordType RecordType
+
+	err = runtime.BindStyledParameter("simple", false, "recordType", chi.URLParam(r, "recordType"), &recordType)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recordType", Err: err})
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params DeleteDomainParams
+
+	// ------------- Optional query parameter "view" -------------
+	if paramValue := r.URL.Query().Get("view"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "view", r.URL.Query(), &params.View)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "view", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteDomain(w, r, domain, recordType, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetDomain operation middleware
+func (siw *ServerInterfaceWrapper) GetDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "domain" -------------
+	var domain Domain
+
+	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "domain", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "recordType" -------------
+	var recordType RecordType
+
+	err = runtime.BindStyledParameter("simple", false, "recordType", chi.URLParam(r, "recordType"), &recordType)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recordType", Err: err})
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetDomainParams
+
+	// ------------- Optional query parameter "view" -------------
+	if paramValue := r.URL.Query().Get("view"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "view", r.URL.Query(), &params.View)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "view", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetDomain(w, r, domain, recordType, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// PutDomain operation middleware
+func (siw *ServerInterfaceWrapper) PutDomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "domain" -------------
+	var domain Domain
+
+	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "domain", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "recordType" -------------
+	var recordType RecordType
+
+	err = runtime.BindStyledParameter("simple", false, "recordType", chi.URLParam(r, "recordType"), &recordType)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recordType", Err: err})
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PutDomainParams
+
+	// ------------- Optional query parameter "view" -------------
+	if paramValue := r.URL.Query().Get("view"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "view", r.URL.Query(), &params.View)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "view", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PutDomain(w, r, domain, recordType, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ListRecordVersions operation middleware
+func (siw *ServerInterfaceWrapper) ListRecordVersions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "domain" -------------
+	var domain Domain
+
+	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "domain", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "recordType" -------------
+	var recordType RecordType
+
+	err = runtime.BindStyledParameter("simple", false, "recordType", chi.URLParam(r, "recordType"), &recordType)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recordType", Err: err})
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListRecordVersionsParams
+
+	// ------------- Optional query parameter "view" -------------
+	if paramValue := r.URL.Query().Get("view"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "view", r.URL.Query(), &params.View)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "view", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListRecordVersions(w, r, domain, recordType, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// RestoreRecordVersion operation middleware
+func (siw *ServerInterfaceWrapper) RestoreRecordVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "domain" -------------
+	var domain Domain
+
+	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "domain", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "recordType" -------------
+	var recordType RecordType
+
+	err = runtime.BindStyledParameter("simple", false, "recordType", chi.URLParam(r, "recordType"), &recordType)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recordType", Err: err})
+		return
+	}
+
+	// ------------- Path parameter "version" -------------
+	var version int64
+
+	err = runtime.BindStyledParameter("simple", false, "version", chi.URLParam(r, "version"), &version)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "version", Err: err})
+		return
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params RestoreRecordVersionParams
+
+	// ------------- Optional query parameter "view" -------------
+	if paramValue := r.URL.Query().Get("view"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "view", r.URL.Query(), &params.View)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "view", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RestoreRecordVersion(w, r, domain, recordType, version, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ReleaseLease operation middleware
+func (siw *ServerInterfaceWrapper) ReleaseLease(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "leaseId" -------------
+	var leaseId LeaseId
+
+	err = runtime.BindStyledParameter("simple", false, "leaseId", chi.URLParam(r, "leaseId"), &leaseId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "leaseId", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ReleaseLease(w, r, leaseId)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// GetLease operation middleware
+func (siw *ServerInterfaceWrapper) GetLease(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "leaseId" -------------
+	var leaseId LeaseId
+
+	err = runtime.BindStyledParameter("simple", false, "leaseId", chi.URLParam(r, "leaseId"), &leaseId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "leaseId", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetLease(w, r, leaseId)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// RenewLease operation middleware
+func (siw *ServerInterfaceWrapper) RenewLease(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "leaseId" -------------
+	var leaseId LeaseId
+
+	err = runtime.BindStyledParameter("simple", false, "leaseId", chi.URLParam(r, "leaseId"), &leaseId)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "leaseId", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RenewLease(w, r, leaseId)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ClaimSubdomain operation middleware
+func (siw *ServerInterfaceWrapper) ClaimSubdomain(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ClaimSubdomain(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ListTenants operation middleware
+func (siw *ServerInterfaceWrapper) ListTenants(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListTenants(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// CreateTenant operation middleware
+func (siw *ServerInterfaceWrapper) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateTenant(w, r)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// WatchRecords operation middleware
+func (siw *ServerInterfaceWrapper) WatchRecords(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params WatchRecordsParams
+
+	// ------------- Optional query parameter "prefix" -------------
+	if paramValue := r.URL.Query().Get("prefix"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "prefix", r.URL.Query(), &params.Prefix)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "prefix", Err: err})
+		return
+	}
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.WatchRecords(w, r, params)
+	}
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
+}
+
+// ListWebhooks operation middleware
+func (siw *ServerInterfaceWrapper) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ListWebhooksParams
+
+	// ------------- Optional query parameter "zone" -------------
+	if paramValue := r.URL.Query().Get("zone"); paramValue != "" {
+
 	}
-	return 0
-}
 
-type PutDomainResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
-}
+	err = runtime.BindQueryParameter("form", true, false, "zone", r.URL.Query(), &params.Zone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "zone", Err: err})
+		return
+	}
 
-// Status returns HTTPResponse.Status
-func (r PutDomainResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListWebhooks(w, r, params)
 	}
-	return http.StatusText(0)
-}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r PutDomainResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
 	}
-	return 0
-}
 
-type PostZoneResponse struct {
-	Body         []byte
-	HTTPResponse *http.Response
+	handler(w, r.WithContext(ctx))
 }
 
-// Status returns HTTPResponse.Status
-func (r PostZoneResponse) Status() string {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.Status
+// CreateWebhook operation middleware
+func (siw *ServerInterfaceWrapper) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.CreateWebhook(w, r)
 	}
-	return http.StatusText(0)
-}
 
-// StatusCode returns HTTPResponse.StatusCode
-func (r PostZoneResponse) StatusCode() int {
-	if r.HTTPResponse != nil {
-		return r.HTTPResponse.StatusCode
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
 	}
-	return 0
+
+	handler(w, r.WithContext(ctx))
 }
 
-// GetDomainWithResponse request returning *GetDomainResponse
-func (c *ClientWithResponses) GetDomainWithResponse(ctx context.Context, domain Domain, recordType RecordType, reqEditors ...RequestEditorFn) (*GetDomainResponse, error) {
-	rsp, err := c.GetDomain(ctx, domain, recordType, reqEditors...)
+// DeleteWebhook operation middleware
+func (siw *ServerInterfaceWrapper) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "webhookId" -------------
+	var webhookId WebhookId
+
+	err = runtime.BindStyledParameter("simple", false, "webhookId", chi.URLParam(r, "webhookId"), &webhookId)
 	if err != nil {
-		return nil, err
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "webhookId", Err: err})
+		return
 	}
-	return ParseGetDomainResponse(rsp)
-}
 
-// PutDomainWithBodyWithResponse request with arbitrary body returning *PutDomainResponse
-func (c *ClientWithResponses) PutDomainWithBodyWithResponse(ctx context.Context, domain Domain, recordType RecordType, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PutDomainResponse, error) {
-	rsp, err := c.PutDomainWithBody(ctx, domain, recordType, contentType, body, reqEditors...)
-	if err != nil {
-		return nil, err
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.DeleteWebhook(w, r, webhookId)
 	}
-	return ParsePutDomainResponse(rsp)
-}
 
-func (c *ClientWithResponses) PutDomainWithResponse(ctx context.Context, domain Domain, recordType RecordType, body PutDomainJSONRequestBody, reqEditors ...RequestEditorFn) (*PutDomainResponse, error) {
-	rsp, err := c.PutDomain(ctx, domain, recordType, body, reqEditors...)
-	if err != nil {
-		return nil, err
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
 	}
-	return ParsePutDomainResponse(rsp)
+
+	handler(w, r.WithContext(ctx))
 }
 
-// PostZoneWithBodyWithResponse request with arbitrary body returning *PostZoneResponse
-func (c *ClientWithResponses) PostZoneWithBodyWithResponse(ctx context.Context, contentType string, body io.Reader, reqEditors ...RequestEditorFn) (*PostZoneResponse, error) {
-	rsp, err := c.PostZoneWithBody(ctx, contentType, body, reqEditors...)
+// ListWebhookDeliveries operation middleware
+func (siw *ServerInterfaceWrapper) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "webhookId" -------------
+	var webhookId WebhookId
+
+	err = runtime.BindStyledParameter("simple", false, "webhookId", chi.URLParam(r, "webhookId"), &webhookId)
 	if err != nil {
-		return nil, err
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "webhookId", Err: err})
+		return
 	}
-	return ParsePostZoneResponse(rsp)
-}
 
-// ParseGetDomainResponse parses an HTTP response from a GetDomainWithResponse call
-func ParseGetDomainResponse(rsp *http.Response) (*GetDomainResponse, error) {
-	bodyBytes, err := ioutil.ReadAll(rsp.Body)
-	defer func() { _ = rsp.Body.Close() }()
-	if err != nil {
-		return nil, err
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ListWebhookDeliveries(w, r, webhookId)
 	}
 
-	response := &GetDomainResponse{
-		Body:         bodyBytes,
-		HTTPResponse: rsp,
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
 	}
 
-	switch {
-	case strings.Contains(rsp.Header.Get("Content-Type"), "json") && rsp.StatusCode == 200:
-		var dest RecordValue
-		if err := json.Unmarshal(bodyBytes, &dest); err != nil {
-			return nil, err
-		}
-		response.JSON200 = &dest
+	handler(w, r.WithContext(ctx))
+}
+
+// GetWhoami operation middleware
+func (siw *ServerInterfaceWrapper) GetWhoami(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.GetWhoami(w, r)
 	}
 
-	return response, nil
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
 }
 
-// ParsePutDomainResponse parses an HTTP response from a PutDomainWithResponse call
-func ParsePutDomainResponse(rsp *http.Response) (*PutDomainResponse, error) {
-	bodyBytes, err := ioutil.ReadAll(rsp.Body)
-	defer func() { _ = rsp.Body.Close() }()
+// PostZone operation middleware
+func (siw *ServerInterfaceWrapper) PostZone(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostZoneParams
+
+	// ------------- Optional query parameter "dryRun" -------------
+	if paramValue := r.URL.Query().Get("dryRun"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "dryRun", r.URL.Query(), &params.DryRun)
 	if err != nil {
-		return nil, err
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "dryRun", Err: err})
+		return
 	}
 
-	response := &PutDomainResponse{
-		Body:         bodyBytes,
-		HTTPResponse: rsp,
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.PostZone(w, r, params)
 	}
 
-	return response, nil
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler(w, r.WithContext(ctx))
 }
 
-// ParsePostZoneResponse parses an HTTP response from a PostZoneWithResponse call
-func ParsePostZoneResponse(rsp *http.Response) (*PostZoneResponse, error) {
-	bodyBytes, err := ioutil.ReadAll(rsp.Body)
-	defer func() { _ = rsp.Body.Close() }()
+// ExportZone operation middleware
+func (siw *ServerInterfaceWrapper) ExportZone(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var err error
+
+	// ------------- Path parameter "zone" -------------
+	var zone string
+
+	err = runtime.BindStyledParameter("simple", false, "zone", chi.URLParam(r, "zone"), &zone)
 	if err != nil {
-		return nil, err
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "zone", Err: err})
+		return
 	}
 
-	response := &PostZoneResponse{
-		Body:         bodyBytes,
-		HTTPResponse: rsp,
-	}
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ExportZoneParams
 
-	return response, nil
-}
+	// ------------- Optional query parameter "view" -------------
+	if paramValue := r.URL.Query().Get("view"); paramValue != "" {
 
-// ServerInterface represents all server handlers.
-type ServerInterface interface {
+	}
 
-	// (GET /domains/{domain}/record/{recordType})
-	GetDomain(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType)
+	err = runtime.BindQueryParameter("form", true, false, "view", r.URL.Query(), &params.View)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "view", Err: err})
+		return
+	}
 
-	// (PUT /domains/{domain}/record/{recordType})
-	PutDomain(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType)
+	var handler = func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ExportZone(w, r, zone, params)
+	}
 
-	// (POST /zone)
-	PostZone(w http.ResponseWriter, r *http.Request)
-}
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
 
-// ServerInterfaceWrapper converts contexts to parameters.
-type ServerInterfaceWrapper struct {
-	Handler            ServerInterface
-	HandlerMiddlewares []MiddlewareFunc
-	ErrorHandlerFunc   func(w http.ResponseWriter, r *http.Request, err error)
+	handler(w, r.WithContext(ctx))
 }
 
-type MiddlewareFunc func(http.HandlerFunc) http.HandlerFunc
-
-// GetDomain operation middleware
-func (siw *ServerInterfaceWrapper) GetDomain(w http.ResponseWriter, r *http.Request) {
+// ReplaceZone operation middleware
+func (siw *ServerInterfaceWrapper) ReplaceZone(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
 
-	// ------------- Path parameter "domain" -------------
-	var domain Domain
+	// ------------- Path parameter "zone" -------------
+	var zone string
 
-	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	err = runtime.BindStyledParameter("simple", false, "zone", chi.URLParam(r, "zone"), &zone)
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "domain", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "zone", Err: err})
 		return
 	}
 
-	// ------------- Path parameter "recordType" -------------
-	var recordType RecordType
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ReplaceZoneParams
 
-	err = runtime.BindStyledParameter("simple", false, "recordType", chi.URLParam(r, "recordType"), &recordType)
+	// ------------- Optional query parameter "dryRun" -------------
+	if paramValue := r.URL.Query().Get("dryRun"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "dryRun", r.URL.Query(), &params.DryRun)
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recordType", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "dryRun", Err: err})
 		return
 	}
 
 	var handler = func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.GetDomain(w, r, domain, recordType)
+		siw.Handler.ReplaceZone(w, r, zone, params)
 	}
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -570,32 +4256,37 @@ func (siw *ServerInterfaceWrapper) GetDomain(w http.ResponseWriter, r *http.Requ
 	handler(w, r.WithContext(ctx))
 }
 
-// PutDomain operation middleware
-func (siw *ServerInterfaceWrapper) PutDomain(w http.ResponseWriter, r *http.Request) {
+// ImportZoneRecords operation middleware
+func (siw *ServerInterfaceWrapper) ImportZoneRecords(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	var err error
 
-	// ------------- Path parameter "domain" -------------
-	var domain Domain
+	// ------------- Path parameter "zone" -------------
+	var zone string
 
-	err = runtime.BindStyledParameter("simple", false, "domain", chi.URLParam(r, "domain"), &domain)
+	err = runtime.BindStyledParameter("simple", false, "zone", chi.URLParam(r, "zone"), &zone)
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "domain", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "zone", Err: err})
 		return
 	}
 
-	// ------------- Path parameter "recordType" -------------
-	var recordType RecordType
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ImportZoneRecordsParams
 
-	err = runtime.BindStyledParameter("simple", false, "recordType", chi.URLParam(r, "recordType"), &recordType)
+	// ------------- Optional query parameter "dryRun" -------------
+	if paramValue := r.URL.Query().Get("dryRun"); paramValue != "" {
+
+	}
+
+	err = runtime.BindQueryParameter("form", true, false, "dryRun", r.URL.Query(), &params.DryRun)
 	if err != nil {
-		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "recordType", Err: err})
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "dryRun", Err: err})
 		return
 	}
 
 	var handler = func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.PutDomain(w, r, domain, recordType)
+		siw.Handler.ImportZoneRecords(w, r, zone, params)
 	}
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -605,12 +4296,23 @@ func (siw *ServerInterfaceWrapper) PutDomain(w http.ResponseWriter, r *http.Requ
 	handler(w, r.WithContext(ctx))
 }
 
-// PostZone operation middleware
-func (siw *ServerInterfaceWrapper) PostZone(w http.ResponseWriter, r *http.Request) {
+// RestoreZone operation middleware
+func (siw *ServerInterfaceWrapper) RestoreZone(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	var err error
+
+	// ------------- Path parameter "zone" -------------
+	var zone string
+
+	err = runtime.BindStyledParameter("simple", false, "zone", chi.URLParam(r, "zone"), &zone)
+	if err != nil {
+		siw.ErrorHandlerFunc(w, r, &InvalidParamFormatError{ParamName: "zone", Err: err})
+		return
+	}
+
 	var handler = func(w http.ResponseWriter, r *http.Request) {
-		siw.Handler.PostZone(w, r)
+		siw.Handler.RestoreZone(w, r, zone)
 	}
 
 	for _, middleware := range siw.HandlerMiddlewares {
@@ -733,15 +4435,75 @@ func HandlerWithOptions(si ServerInterface, options ChiServerOptions) http.Handl
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/audit", wrapper.GetAudit)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/domains/{domain}/record/{recordType}", wrapper.DeleteDomain)
+	})
 	r.Group(func(r chi.Router) {
 		r.Get(options.BaseURL+"/domains/{domain}/record/{recordType}", wrapper.GetDomain)
 	})
 	r.Group(func(r chi.Router) {
 		r.Put(options.BaseURL+"/domains/{domain}/record/{recordType}", wrapper.PutDomain)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/domains/{domain}/record/{recordType}/versions", wrapper.ListRecordVersions)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/domains/{domain}/record/{recordType}/versions/{version}/restore", wrapper.RestoreRecordVersion)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/leases/{leaseId}", wrapper.ReleaseLease)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/leases/{leaseId}", wrapper.GetLease)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/leases/{leaseId}/renew", wrapper.RenewLease)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/subdomains", wrapper.ClaimSubdomain)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/tenants", wrapper.ListTenants)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/tenants", wrapper.CreateTenant)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/watch", wrapper.WatchRecords)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/webhooks", wrapper.ListWebhooks)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/webhooks", wrapper.CreateWebhook)
+	})
+	r.Group(func(r chi.Router) {
+		r.Delete(options.BaseURL+"/webhooks/{webhookId}", wrapper.DeleteWebhook)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/webhooks/{webhookId}/deliveries", wrapper.ListWebhookDeliveries)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/whoami", wrapper.GetWhoami)
+	})
 	r.Group(func(r chi.Router) {
 		r.Post(options.BaseURL+"/zone", wrapper.PostZone)
 	})
+	r.Group(func(r chi.Router) {
+		r.Get(options.BaseURL+"/zones/{zone}", wrapper.ExportZone)
+	})
+	r.Group(func(r chi.Router) {
+		r.Put(options.BaseURL+"/zones/{zone}", wrapper.ReplaceZone)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/zones/{zone}/records:import", wrapper.ImportZoneRecords)
+	})
+	r.Group(func(r chi.Router) {
+		r.Post(options.BaseURL+"/zones/{zone}/restore", wrapper.RestoreZone)
+	})
 
 	return r
 }
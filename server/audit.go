@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"time"
+)
+
+// AuditQuery filters the audit log. Zero values don't filter.
+type AuditQuery struct {
+	Zone  Domain
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// auditActor is who is making changes, and through which protocol.
+type auditActor struct {
+	actor    string
+	protocol AuditEntryProtocol
+}
+
+type auditActorContextKey struct{}
+
+// withAuditActor returns a context that attributes record changes to actor in the audit log.
+func withAuditActor(ctx context.Context, actor string, protocol AuditEntryProtocol) context.Context {
+	return context.WithValue(ctx, auditActorContextKey{}, auditActor{actor: actor, protocol: protocol})
+}
+
+func auditActorFromContext(ctx context.Context) auditActor {
+	actor, _ := ctx.Value(auditActorContextKey{}).(auditActor)
+	return actor
+}
+
+// httpAuditActor identifies the caller of an API request by their token, or by their address if they didn't send one.
+func httpAuditActor(r *http.Request) string {
+	if token := apiToken(r); token != "" {
+		return "token:" + tokenID(token)
+	}
+	return "ip:" + clientIP(r)
+}
+
+// auditActorMiddleware attributes changes made by API requests to the caller.
+func auditActorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(withAuditActor(r.Context(), httpAuditActor(r), AuditEntryProtocolRest)))
+	})
+}
+
+// AuditingRegistrar adds an entry to the audit log for every record written through it.
+type AuditingRegistrar struct {
+	Registrar
+
+	zones ZoneSet
+	now   func() time.Time
+}
+
+func NewAuditingRegistrar(next Registrar, zones ZoneSet) *AuditingRegistrar {
+	return &AuditingRegistrar{Registrar: next, zones: zones, now: time.Now}
+}
+
+func (a *AuditingRegistrar) SetRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) error {
+	_, _, err := a.SwapRecord(ctx, fqdn, recordType, value)
+	return err
+}
+
+func (a *AuditingRegistrar) SwapRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) (string, bool, error) {
+	previous, existed, err := a.Registrar.SwapRecord(ctx, fqdn, recordType, value)
+	if err != nil {
+		return previous, existed, err
+	}
+
+	entry := a.newEntry(ctx, fqdn, recordType, AuditEntryActionSet)
+	entry.NewValue = &value
+	if existed {
+		entry.OldValue = &previous
+	}
+	a.append(ctx, entry)
+	return previous, existed, nil
+}
+
+func (a *AuditingRegistrar) DeleteRecord(ctx context.Context, fqdn Domain, recordType RecordType, currentValue string) error {
+	if err := a.Registrar.DeleteRecord(ctx, fqdn, recordType, currentValue); err != nil {
+		return err
+	}
+
+	entry := a.newEntry(ctx, fqdn, recordType, AuditEntryActionDelete)
+	entry.OldValue = &currentValue
+	a.append(ctx, entry)
+	return nil
+}
+
+func (a *AuditingRegistrar) WriteRecords(ctx context.Context, writes []RecordWrite) ([]RecordWriteResult, error) {
+	results, err := a.Registrar.WriteRecords(ctx, writes)
+	if err != nil {
+		return results, err
+	}
+
+	for i, write := range writes {
+		value := write.Value
+		var entry AuditEntry
+		if write.Delete {
+			entry = a.newEntry(ctx, write.Domain, write.Type, AuditEntryActionDelete)
+			entry.OldValue = &value
+		} else {
+			entry = a.newEntry(ctx, write.Domain, write.Type, AuditEntryActionSet)
+			entry.NewValue = &value
+			if results[i].Existed {
+				entry.OldValue = &results[i].Previous
+			}
+		}
+		a.append(ctx, entry)
+	}
+	return results, nil
+}
+
+func (a *AuditingRegistrar) newEntry(ctx context.Context, fqdn Domain, recordType RecordType, action AuditEntryAction) AuditEntry {
+	actor := auditActorFromContext(ctx)
+	entry := AuditEntry{
+		Timestamp:  a.now().UTC(),
+		Actor:      actor.actor,
+		Protocol:   actor.protocol,
+		Action:     action,
+		Zone:       string(a.zones.ZoneOf(fqdn)),
+		Domain:     canonicalName(string(fqdn)),
+		RecordType: recordType,
+	}
+	if view := viewFromContext(ctx); view != "" {
+		entry.View = &view
+	}
+	return entry
+}
+
+// append adds entry to the audit log. The change has already been made by then, so failing to log it is logged rather
+// than returned to the caller.
+func (a *AuditingRegistrar) append(ctx context.Context, entry AuditEntry) {
+	if entry.Actor == "" {
+		hclog.FromContext(ctx).Warn("Record changed without an audit actor", "domain", entry.Domain, "type", entry.RecordType)
+	}
+	if err := a.Registrar.AppendAuditEntry(ctx, entry); err != nil {
+		hclog.FromContext(ctx).Error("Error adding audit log entry", "entry", entry, "error", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAuditingRegistrar(t *testing.T) {
+	ctx := withAuditActor(context.Background(), "token:0123456789abcdef", AuditEntryProtocolRest)
+	backend := newMemoryRegistrar()
+	registrar := NewAuditingRegistrar(backend, ZoneSet{"example.com."})
+
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, registrar.SetRecord(withView(ctx, "internal"), "www.example.com.", RecordTypeA, "2.2.2.2"))
+	update := withAuditActor(ctx, "ip:192.0.2.1", AuditEntryProtocolRfc2136)
+	assert.NoError(t, registrar.SetRecord(update, "www.example.com.", RecordTypeA, "3.3.3.3"))
+	assert.NoError(t, registrar.DeleteRecord(update, "www.example.com.", RecordTypeA, "3.3.3.3"))
+
+	// Failed changes aren't logged
+	assert.Error(t, registrar.DeleteRecord(update, "www.example.com.", RecordTypeA, "3.3.3.3"))
+
+	entries, err := backend.ListAuditEntries(ctx, AuditQuery{Zone: "example.com."})
+	assert.NoError(t, err)
+	if !assert.Len(t, entries, 4) {
+		return
+	}
+	for _, entry := range entries {
+		assert.Equal(t, "www.example.com.", entry.Domain)
+		assert.Equal(t, "example.com.", entry.Zone)
+	}
+
+	deleted, updated, internal, created := entries[0], entries[1], entries[2], entries[3]
+	assert.Equal(t, "token:0123456789abcdef", created.Actor)
+	assert.Equal(t, AuditEntryProtocolRest, created.Protocol)
+	assert.Equal(t, AuditEntryActionSet, created.Action)
+	assert.Nil(t, created.OldValue)
+	assert.Equal(t, "1.1.1.1", *created.NewValue)
+
+	assert.Equal(t, "internal", *internal.View)
+	assert.Nil(t, internal.OldValue)
+
+	assert.Equal(t, "ip:192.0.2.1", updated.Actor)
+	assert.Equal(t, AuditEntryProtocolRfc2136, updated.Protocol)
+	assert.Equal(t, "1.1.1.1", *updated.OldValue)
+	assert.Equal(t, "3.3.3.3", *updated.NewValue)
+
+	assert.Equal(t, AuditEntryActionDelete, deleted.Action)
+	assert.Equal(t, "3.3.3.3", *deleted.OldValue)
+	assert.Nil(t, deleted.NewValue)
+}
@@ -0,0 +1,49 @@
+// Package challenge sets the TXT records of DNS-01 challenges through the Ephemerain HTTP API. It is shared by the
+// lego provider and the cert-manager webhook solver, which only differ in how they are configured.
+package challenge
+
+import (
+	"context"
+	"server/client"
+)
+
+// Solver adds and removes challenge values from TXT records. The challenges for a wildcard and its apex share a TXT
+// record, so values are added to and removed from the record rather than replacing it.
+type Solver struct {
+	records *RecordSets
+	ttl     uint32
+}
+
+func NewSolver(apiClient *client.ClientWithResponses, ttl uint32) *Solver {
+	return &Solver{records: NewRecordSets(apiClient, ttl), ttl: ttl}
+}
+
+// Present adds value to the TXT record at fqdn, keeping any other values it has.
+func (s *Solver) Present(ctx context.Context, fqdn, value string) error {
+	return s.records.Update(ctx, fqdn, client.RecordTypeTXT, func(set *RecordSet) bool {
+		if set.Contains(value) {
+			return false
+		}
+		set.Values = append(set.Values, value)
+		set.TTL = s.ttl
+		return true
+	})
+}
+
+// CleanUp removes value from the TXT record at fqdn, deleting the record if it was the only value.
+func (s *Solver) CleanUp(ctx context.Context, fqdn, value string) error {
+	return s.records.Update(ctx, fqdn, client.RecordTypeTXT, func(set *RecordSet) bool {
+		remaining := []string{}
+		for _, existing := range set.Values {
+			if existing != value {
+				remaining = append(remaining, existing)
+			}
+		}
+		if len(remaining) == len(set.Values) {
+			return false
+		}
+		set.Values = remaining
+		set.TTL = s.ttl
+		return true
+	})
+}
@@ -0,0 +1,108 @@
+package challenge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"server/client"
+	"sync"
+)
+
+// RecordSet is every value of a record along with its TTL, as the API stores it.
+type RecordSet struct {
+	Values []string
+	TTL    uint32
+	// Existed is whether the record existed before it was changed
+	Existed bool
+}
+
+// Contains returns whether value is one of the values of the record set.
+func (s RecordSet) Contains(value string) bool {
+	for _, existing := range s.Values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordSets changes records through the API by reading them and writing them back, since the API replaces every value
+// of a record at once.
+type RecordSets struct {
+	client     *client.ClientWithResponses
+	defaultTTL uint32
+
+	// mu serializes changes, since records are read and then written back
+	mu sync.Mutex
+}
+
+// NewRecordSets returns RecordSets that give records that don't exist yet defaultTTL.
+func NewRecordSets(apiClient *client.ClientWithResponses, defaultTTL uint32) *RecordSets {
+	return &RecordSets{client: apiClient, defaultTTL: defaultTTL}
+}
+
+// Update calls change with the record set of fqdn and recordType, which is empty if the record doesn't exist, and
+// writes it back if change returns true. The record is deleted if no values are left.
+func (r *RecordSets) Update(ctx context.Context, fqdn string, recordType client.RecordType, change func(*RecordSet) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	set, err := r.get(ctx, fqdn, recordType)
+	if err != nil {
+		return err
+	}
+	if !change(&set) {
+		return nil
+	}
+	return r.put(ctx, fqdn, recordType, set)
+}
+
+func (r *RecordSets) get(ctx context.Context, fqdn string, recordType client.RecordType) (RecordSet, error) {
+	response, err := r.client.GetDomainWithResponse(ctx, client.Domain(fqdn), recordType, &client.GetDomainParams{})
+	if err != nil {
+		return RecordSet{}, fmt.Errorf("getting %s %s: %w", fqdn, recordType, err)
+	}
+	switch {
+	case response.StatusCode() == http.StatusNotFound:
+		return RecordSet{TTL: r.defaultTTL}, nil
+	case response.JSON200 == nil:
+		return RecordSet{}, fmt.Errorf("getting %s %s: unexpected status %s", fqdn, recordType, response.Status())
+	}
+	set := RecordSet{TTL: r.defaultTTL, Existed: true}
+	if response.JSON200.Values != nil {
+		set.Values = *response.JSON200.Values
+	} else if response.JSON200.Value != nil {
+		set.Values = []string{*response.JSON200.Value}
+	}
+	if response.JSON200.Ttl != nil {
+		set.TTL = *response.JSON200.Ttl
+	}
+	return set, nil
+}
+
+// put stores set, or deletes the record if set doesn't have any values.
+func (r *RecordSets) put(ctx context.Context, fqdn string, recordType client.RecordType, set RecordSet) error {
+	if len(set.Values) == 0 {
+		if !set.Existed {
+			return nil
+		}
+		response, err := r.client.DeleteDomainWithResponse(ctx, client.Domain(fqdn), recordType, &client.DeleteDomainParams{})
+		if err != nil {
+			return fmt.Errorf("deleting %s %s: %w", fqdn, recordType, err)
+		}
+		// The record having already been deleted is as good as deleting it
+		if response.StatusCode() != http.StatusNoContent && response.StatusCode() != http.StatusNotFound {
+			return fmt.Errorf("deleting %s %s: unexpected status %s", fqdn, recordType, response.Status())
+		}
+		return nil
+	}
+
+	response, err := r.client.PutDomainWithResponse(ctx, client.Domain(fqdn), recordType, &client.PutDomainParams{}, client.PutDomainJSONRequestBody{Values: &set.Values, Ttl: &set.TTL})
+	if err != nil {
+		return fmt.Errorf("setting %s %s: %w", fqdn, recordType, err)
+	}
+	if response.StatusCode()/100 != 2 {
+		return fmt.Errorf("setting %s %s: unexpected status %s", fqdn, recordType, response.Status())
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// BearerToken sends token as a bearer token with a single request, replacing the token of the client if it has one.
+func BearerToken(token string) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// WithBearerToken sends token as a bearer token with every request.
+func WithBearerToken(token string) ClientOption {
+	return WithRequestEditorFn(BearerToken(token))
+}
@@ -0,0 +1,5 @@
+// Package client is a client for the Ephemerain HTTP API. The server's own copy of the generated code is in package
+// main, which other packages can't import.
+package client
+
+//go:generate oapi-codegen -generate types,client -o client.gen.go -package client ../../api.yaml
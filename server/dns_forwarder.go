@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/miekg/dns"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ForwardingConfig configures relaying queries for names outside of the authoritative zones to upstream resolvers.
+// Forwarding is off unless both Upstreams and AllowedNetworks are set, so the server can't accidentally become an
+// open resolver.
+type ForwardingConfig struct {
+	// Upstreams are the resolvers to forward to, as host:port. They are tried in order.
+	Upstreams []string
+	// AllowedNetworks are the client networks that may use forwarding. Everyone else gets REFUSED.
+	AllowedNetworks []*net.IPNet
+	// CacheSize is the maximum number of upstream responses to keep in memory.
+	CacheSize int
+	Timeout   time.Duration
+}
+
+// ParseForwardingConfig builds a ForwardingConfig from the comma separated FORWARD_UPSTREAMS and
+// FORWARD_ALLOWED_NETWORKS environment variables.
+func ParseForwardingConfig(upstreams string, allowedNetworks string) (ForwardingConfig, error) {
+	config := ForwardingConfig{CacheSize: 1000, Timeout: 2 * time.Second}
+	for _, upstream := range strings.Split(upstreams, ",") {
+		if upstream = strings.TrimSpace(upstream); upstream == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(upstream); err != nil {
+			upstream = net.JoinHostPort(upstream, "53")
+		}
+		config.Upstreams = append(config.Upstreams, upstream)
+	}
+	networks, err := ParseNetworks(allowedNetworks)
+	if err != nil {
+		return ForwardingConfig{}, fmt.Errorf("invalid forwarding networks: %w", err)
+	}
+	config.AllowedNetworks = networks
+	return config, nil
+}
+
+func (c ForwardingConfig) enabled() bool {
+	return len(c.Upstreams) > 0 && len(c.AllowedNetworks) > 0
+}
+
+func (c ForwardingConfig) allowed(ip net.IP) bool {
+	return networksContain(c.AllowedNetworks, ip)
+}
+
+type forwardCacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+}
+
+type forwardCacheEntry struct {
+	response *dns.Msg
+	expires  time.Time
+}
+
+// forwarder relays queries to upstream resolvers, caching the responses for as long as their TTLs allow.
+type forwarder struct {
+	config ForwardingConfig
+	// exchange sends m to upstream over network, which is either udp or tcp
+	exchange func(ctx context.Context, m *dns.Msg, upstream string, network string) (*dns.Msg, error)
+	now      func() time.Time
+
+	mu    sync.Mutex
+	cache map[forwardCacheKey]forwardCacheEntry
+}
+
+func newForwarder(config ForwardingConfig) *forwarder {
+	clients := map[string]*dns.Client{
+		"udp": {Timeout: config.Timeout},
+		"tcp": {Net: "tcp", Timeout: config.Timeout},
+	}
+	return &forwarder{
+		config: config,
+		exchange: func(ctx context.Context, m *dns.Msg, upstream string, network string) (*dns.Msg, error) {
+			response, _, err := clients[network].ExchangeContext(ctx, m, upstream)
+			return response, err
+		},
+		now:   time.Now,
+		cache: map[forwardCacheKey]forwardCacheEntry{},
+	}
+}
+
+// responseTTL is how long a response may be cached, which is the lowest TTL in it. Negative responses are cached for
+// the SOA minimum TTL, as described by RFC 2308.
+func responseTTL(m *dns.Msg) time.Duration {
+	var ttl uint32
+	first := true
+	for _, section := range [][]dns.RR{m.Answer, m.Ns} {
+		for _, rr := range section {
+			candidate := rr.Header().Ttl
+			if soa, ok := rr.(*dns.SOA); ok && soa.Minttl < candidate {
+				candidate = soa.Minttl
+			}
+			if first || candidate < ttl {
+				ttl, first = candidate, false
+			}
+		}
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+func (f *forwarder) lookupCache(key forwardCacheKey, now time.Time) *dns.Msg {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, ok := f.cache[key]
+	if !ok {
+		return nil
+	}
+	if now.After(entry.expires) {
+		delete(f.cache, key)
+		return nil
+	}
+
+	// Age the TTLs so downstream caches don't hold on to the response for longer than upstream said they could
+	response := entry.response.Copy()
+	remaining := uint32(entry.expires.Sub(now) / time.Second)
+	for _, section := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype != dns.TypeOPT && rr.Header().Ttl > remaining {
+				rr.Header().Ttl = remaining
+			}
+		}
+	}
+	return response
+}
+
+func (f *forwarder) storeCache(key forwardCacheKey, response *dns.Msg, now time.Time) {
+	// Truncated responses are incomplete, so they are only passed on to the client that asked for them
+	ttl := responseTTL(response)
+	if ttl <= 0 || f.config.CacheSize <= 0 || response.Truncated || (response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError) {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.cache) >= f.config.CacheSize {
+		// Make room by dropping expired entries, or an arbitrary one if nothing has expired yet
+		for k, entry := range f.cache {
+			if now.After(entry.expires) {
+				delete(f.cache, k)
+			}
+		}
+		for k := range f.cache {
+			if len(f.cache) < f.config.CacheSize {
+				break
+			}
+			delete(f.cache, k)
+		}
+	}
+	f.cache[key] = forwardCacheEntry{response: response.Copy(), expires: now.Add(ttl)}
+}
+
+// Forward answers r using the cache or the upstream resolvers.
+func (f *forwarder) Forward(ctx context.Context, r *dns.Msg) (*dns.Msg, error) {
+	question := r.Question[0]
+	key := forwardCacheKey{name: strings.ToLower(question.Name), qtype: question.Qtype, qclass: question.Qclass}
+	now := f.now()
+
+	response := f.lookupCache(key, now)
+	if response == nil {
+		query := new(dns.Msg)
+		query.SetQuestion(question.Name, question.Qtype)
+		query.Question[0].Qclass = question.Qclass
+		query.RecursionDesired = true
+		query.SetEdns0(dns.DefaultMsgSize, false)
+
+		var err error
+		for _, upstream := range f.config.Upstreams {
+			response, err = f.exchange(ctx, query, upstream, "udp")
+			if err == nil && response.Truncated {
+				// Like a stub resolver, ask again over TCP for the answer that didn't fit in a UDP response
+				response, err = f.exchange(ctx, query, upstream, "tcp")
+			}
+			if err == nil {
+				break
+			}
+			hclog.FromContext(ctx).Warn("Error forwarding query", "upstream", upstream, "error", err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		f.storeCache(key, response, now)
+	}
+
+	reply := new(dns.Msg)
+	reply.SetReply(r)
+	reply.Rcode = response.Rcode
+	reply.Truncated = response.Truncated
+	reply.RecursionAvailable = true
+	reply.Answer = response.Answer
+	reply.Ns = response.Ns
+	for _, rr := range response.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			reply.Extra = append(reply.Extra, rr)
+		}
+	}
+	return reply, nil
+}
+
+// Handler wraps the authoritative handler so that queries for names outside of zones are forwarded upstream for
+// allowed clients, and refused for everyone else. Responses to allowed clients have the RA bit set.
+func (f *forwarder) Handler(zones ZoneSet, authoritative dns.Handler) dns.Handler {
+	return dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		allowed := f.config.allowed(remoteIP(w.RemoteAddr()))
+		if r.Opcode != dns.OpcodeQuery || len(r.Question) != 1 {
+			authoritative.ServeDNS(w, r)
+			return
+		}
+		if _, inZone := zones.Find(Domain(r.Question[0].Name)); inZone {
+			if allowed {
+				w = &recursionAvailableResponseWriter{ResponseWriter: w}
+			}
+			authoritative.ServeDNS(w, r)
+			return
+		}
+
+		ctx := hclog.WithContext(context.Background(), hclog.L(), "request_id", r.Id)
+		logger := hclog.FromContext(ctx)
+
+		var response *dns.Msg
+		if !allowed {
+			logger.Info("Refusing to forward query from client outside of the allowed networks", "client", w.RemoteAddr())
+			response = new(dns.Msg)
+			response.SetRcode(r, dns.RcodeRefused)
+		} else {
+			var err error
+			if response, err = f.Forward(ctx, r); err != nil {
+				logger.Error("Error forwarding query", "error", err)
+				response = new(dns.Msg)
+				response.SetRcode(r, dns.RcodeServerFailure)
+				response.RecursionAvailable = true
+			} else if w.RemoteAddr().Network() == "udp" {
+				// Answers fetched over TCP may not fit in the client's UDP buffer, so the client has to retry over TCP
+				size := dns.MinMsgSize
+				if opt := r.IsEdns0(); opt != nil {
+					size = int(opt.UDPSize())
+				}
+				response.Truncate(size)
+			}
+		}
+		writeResponse(logger, w, response)
+	})
+}
+
+type recursionAvailableResponseWriter struct {
+	dns.ResponseWriter
+}
+
+func (w *recursionAvailableResponseWriter) WriteMsg(m *dns.Msg) error {
+	m.RecursionAvailable = true
+	return w.ResponseWriter.WriteMsg(m)
+}
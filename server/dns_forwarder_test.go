@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestForwarder(t *testing.T) {
+	config, err := ParseForwardingConfig("192.0.2.53, 192.0.2.54:5353", "10.0.0.0/8")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.53:53", "192.0.2.54:5353"}, config.Upstreams)
+
+	f := newForwarder(config)
+	now := time.Unix(1000, 0)
+	f.now = func() time.Time { return now }
+	var exchanges []string
+	f.exchange = func(ctx context.Context, m *dns.Msg, upstream string, network string) (*dns.Msg, error) {
+		exchanges = append(exchanges, upstream)
+		if upstream == "192.0.2.53:53" {
+			return nil, &net.OpError{Op: "read", Err: context.DeadlineExceeded}
+		}
+		response := new(dns.Msg)
+		response.SetReply(m)
+		response.Answer = append(response.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   net.ParseIP("93.184.216.34"),
+		})
+		return response, nil
+	}
+
+	handler := f.Handler(ZoneSet{"ephemeral.test."}, dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+		_ = w.WriteMsg(m)
+	}))
+	query := func(name string, client string) *dns.Msg {
+		w := &recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP(client), Port: 5353}}
+		r := new(dns.Msg)
+		r.SetQuestion(name, dns.TypeA)
+		handler.ServeDNS(w, r)
+		assert.Len(t, w.messages, 1)
+		return w.messages[0]
+	}
+
+	response := query("example.com.", "10.1.2.3")
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.True(t, response.RecursionAvailable)
+	assert.False(t, response.Authoritative)
+	assert.Equal(t, "93.184.216.34", response.Answer[0].(*dns.A).A.String())
+	assert.Equal(t, []string{"192.0.2.53:53", "192.0.2.54:5353"}, exchanges, "the failing upstream should be skipped")
+
+	// The second lookup is answered from the cache, with the TTL aged accordingly
+	now = now.Add(100 * time.Second)
+	response = query("Example.com.", "10.1.2.3")
+	assert.Len(t, exchanges, 2)
+	assert.Equal(t, uint32(200), response.Answer[0].Header().Ttl)
+
+	// Clients outside of the allowed networks can't use the server as a resolver
+	response = query("example.com.", "203.0.113.1")
+	assert.Equal(t, dns.RcodeRefused, response.Rcode)
+	assert.Empty(t, response.Answer)
+
+	// Names inside the authoritative zones are answered authoritatively, with RA only for allowed clients
+	response = query("app.ephemeral.test.", "10.1.2.3")
+	assert.True(t, response.Authoritative)
+	assert.True(t, response.RecursionAvailable)
+	response = query("app.ephemeral.test.", "203.0.113.1")
+	assert.True(t, response.Authoritative)
+	assert.False(t, response.RecursionAvailable)
+}
+
+func TestForwarder_RetriesTruncatedResponsesOverTCP(t *testing.T) {
+	config, err := ParseForwardingConfig("192.0.2.53", "10.0.0.0/8")
+	assert.NoError(t, err)
+	f := newForwarder(config)
+	var exchanges []string
+	f.exchange = func(ctx context.Context, m *dns.Msg, upstream string, network string) (*dns.Msg, error) {
+		exchanges = append(exchanges, network)
+		response := new(dns.Msg)
+		response.SetReply(m)
+		if network == "udp" || m.Question[0].Name == "truncated.example.com." {
+			response.Truncated = true
+			return response, nil
+		}
+		for i := 1; i <= 50; i++ {
+			response.Answer = append(response.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+				A:   net.IPv4(192, 0, 2, byte(i)),
+			})
+		}
+		return response, nil
+	}
+
+	handler := f.Handler(ZoneSet{"ephemeral.test."}, dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {}))
+	query := func(name string, udpSize uint16) *dns.Msg {
+		w := &recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 5353}}
+		r := new(dns.Msg)
+		r.SetQuestion(name, dns.TypeA)
+		if udpSize > 0 {
+			r.SetEdns0(udpSize, false)
+		}
+		handler.ServeDNS(w, r)
+		assert.Len(t, w.messages, 1)
+		return w.messages[0]
+	}
+
+	response := query("large.example.com.", 4096)
+	assert.False(t, response.Truncated)
+	assert.Len(t, response.Answer, 50)
+	assert.Equal(t, []string{"udp", "tcp"}, exchanges)
+
+	// Clients that can't take the whole answer over UDP are told to retry over TCP
+	response = query("large.example.com.", 0)
+	assert.True(t, response.Truncated)
+	assert.Less(t, len(response.Answer), 50)
+	assert.Len(t, exchanges, 2, "the complete answer should be cached")
+
+	// Truncated answers are passed on, but never cached
+	exchanges = nil
+	assert.True(t, query("truncated.example.com.", 4096).Truncated)
+	assert.True(t, query("truncated.example.com.", 4096).Truncated)
+	assert.Equal(t, []string{"udp", "tcp", "udp", "tcp"}, exchanges)
+}
+
+func TestForwardingConfig_DisabledByDefault(t *testing.T) {
+	config, err := ParseForwardingConfig("", "")
+	assert.NoError(t, err)
+	assert.False(t, config.enabled())
+
+	config, err = ParseForwardingConfig("192.0.2.53", "")
+	assert.NoError(t, err)
+	assert.False(t, config.enabled(), "forwarding without an ACL would make an open resolver")
+}
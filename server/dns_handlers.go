@@ -3,14 +3,34 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/hashicorp/go-hclog"
 	"github.com/miekg/dns"
 	"net"
-	"regexp"
-	"strings"
 )
 
-func handleIPQuery(registrar Registrar) func(w dns.ResponseWriter, r *dns.Msg) {
+func soaRecord(dom Domain) *dns.SOA {
+	// TODO: What are these supposed to be?
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: string(dom), Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 60},
+		Ns:      "ns-822.awsdns-38.net.",
+		Mbox:    "awsdns-hostmaster.amazon.com.",
+		Serial:  1,
+		Refresh: 7200,
+		Retry:   900,
+		Expire:  1209600,
+		Minttl:  86400,
+	}
+}
+
+func nsRecord(dom Domain) *dns.NS {
+	return &dns.NS{
+		Hdr: dns.RR_Header{Name: string(dom), Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 60},
+		Ns:  "ns1.bam0.com.",
+	}
+}
+
+func handleIPQuery(registrar Registrar, synthesizers *SynthesizerRegistry, views ViewConfig, clientSubnetResolvers []*net.IPNet) func(w dns.ResponseWriter, r *dns.Msg) {
 	return func(w dns.ResponseWriter, r *dns.Msg) {
 		ctx := hclog.WithContext(context.Background(), hclog.L(), "request_id", r.Id)
 		logger := hclog.FromContext(ctx)
@@ -20,41 +40,37 @@ func handleIPQuery(registrar Registrar) func(w dns.ResponseWriter, r *dns.Msg) {
 		if r.Opcode == dns.OpcodeUpdate {
 			logger.Info("Performing update")
 
-			var err error = nil
-			for _, ns := range r.Ns {
-				fqdn := Domain(ns.Header().Name)
-				switch ns.Header().Rrtype {
-				case dns.TypeA:
-					ip := ns.(*dns.A).A.String()
-					err = registrar.SetRecord(ctx, fqdn, RecordTypeA, ip)
-				case dns.TypeCNAME:
-					target := ns.(*dns.CNAME).Target
-					err = registrar.SetRecord(ctx, fqdn, RecordTypeCNAME, target)
-				case dns.TypeTXT:
-					// TODO: Support multiple values
-					txt := ns.(*dns.TXT).Txt
-					if len(txt) == 0 {
-						err = errors.New("Missing txt value")
-					} else {
-						values := txt[0]
-						switch ns.Header().Class {
-						case dns.ClassINET:
-							err = registrar.SetRecord(ctx, fqdn, RecordTypeTXT, values)
-						case dns.ClassNONE:
-							err = registrar.DeleteRecord(ctx, fqdn, RecordTypeTXT, values)
-						}
-					}
+			actor, err := updateAuditActor(ctx, registrar, w, r)
+			if err != nil {
+				logger.Info("Refusing update", "error", err)
+				m := new(dns.Msg)
+				m.SetRcode(r, dns.RcodeNotAuth)
+				writeResponse(logger, w, m)
+				return
+			}
+			ctx = withTSIGKey(withAuditActor(ctx, actor, AuditEntryProtocolRfc2136), verifiedTSIGKey(w, r))
 
-				}
+			// Updates are applied atomically, so if any record can't be changed nothing in the update is
+			writes, err := planUpdate(ctx, registrar, r.Ns)
+			if err == nil && len(writes) > 0 {
+				_, err = registrar.WriteRecords(ctx, writes)
+			}
+			if err != nil {
+				logger.Info("Error applying update", "error", err)
 			}
 
 			// TODO: What is the return message supposed to say?
 			m := new(dns.Msg)
-			if err != nil {
+			m.SetReply(r)
+			if isForbidden(err) {
+				m.Rcode = dns.RcodeRefused
+			} else if errors.Is(err, errUnsupportedUpdate) {
+				m.Rcode = dns.RcodeNotImplemented
+			} else if err != nil {
 				m.Rcode = dns.RcodeServerFailure
 			}
-			m.SetReply(r)
 			m.Compress = false
+			signUpdateResponse(w, r, m)
 			logger.Info("Sending response message", "message", m.String())
 			if err := w.WriteMsg(m); err != nil {
 				logger.Error("Error sending response message", "error", err)
@@ -70,109 +86,205 @@ func handleIPQuery(registrar Registrar) func(w dns.ResponseWriter, r *dns.Msg) {
 		m.RecursionAvailable = false
 
 		dom := Domain(r.Question[0].Name)
+		ip, subnet := queryClientIP(w, r, clientSubnetResolvers)
+		setClientSubnetScope(m, r, subnet, views)
+		if view := views.Match(ip); view != "" {
+			logger = logger.With("view", view)
+			ctx = withView(hclog.WithContext(ctx, logger), view)
+		}
 
-		switch r.Question[0].Qtype {
-		default:
-			m.Rcode = dns.RcodeNameError
-		case dns.TypeNS:
-			// TODO: Should this, like, recurse or something? Letsencrypt always checks for NS records on random
-			// subdomains
-			m.Rcode = dns.RcodeSuccess
-			rr := &dns.NS{
-				Hdr: dns.RR_Header{Name: string(dom), Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 60},
-				Ns:  "ns1.bam0.com.",
+		// NS and SOA queries are answered the same way for every name, so they aren't passed to synthesizers
+		if qtype := r.Question[0].Qtype; qtype != dns.TypeNS && qtype != dns.TypeSOA {
+			zone, answers, synthesized := synthesizers.Synthesize(ctx, SynthesisRequest{
+				Name:       dom,
+				Qtype:      qtype,
+				Query:      r,
+				RemoteAddr: w.RemoteAddr(),
+				Transport:  w.RemoteAddr().Network(),
+			})
+			if recordType := RecordType(dns.TypeToString[qtype]); synthesized && len(answers) == 0 && isStoredRecordType(recordType) {
+				// The synthesizer owns the name but has nothing of this type, so records stored for it, like
+				// _acme-challenge TXT records beneath an IP echo name, are still answered
+				if stored, err := lookupAnswers(ctx, registrar, dom, recordType); err == nil {
+					answers = stored
+				}
 			}
-			m.Answer = append(m.Answer, rr)
-		case dns.TypeSOA:
-			m.Rcode = dns.RcodeSuccess
-			// TODO: What are these supposed to be?
-			rr := &dns.SOA{
-				Hdr:     dns.RR_Header{Name: string(dom), Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 60},
-				Ns:      "ns-822.awsdns-38.net.",
-				Mbox:    "awsdns-hostmaster.amazon.com.",
-				Serial:  1,
-				Refresh: 7200,
-				Retry:   900,
-				Expire:  1209600,
-				Minttl:  86400,
+			if synthesized {
+				m.Rcode = dns.RcodeSuccess
+				m.Answer = answers
+				if len(answers) == 0 {
+					m.Ns = append(m.Ns, soaRecord(zone))
+				}
+				writeResponse(logger, w, m)
+				return
 			}
-			m.Answer = append(m.Answer, rr)
-		case dns.TypeCNAME:
-			value, err := registrar.GetRecord(ctx, dom, "CNAME")
+		}
+
+		switch qtype := r.Question[0].Qtype; qtype {
+		default:
+			recordType := RecordType(dns.TypeToString[qtype])
+			if !isStoredRecordType(recordType) {
+				m.Rcode = dns.RcodeNameError
+				break
+			}
+			answers, err := lookupAnswers(ctx, registrar, dom, recordType)
 			if err != nil {
-				logger.Error("Error getting CNAME record", "fqdn", dom, "error", err)
+				logger.Error("Error getting record", "fqdn", dom, "type", recordType, "error", err)
 				m.Rcode = dns.RcodeNameError
 			} else {
 				m.Rcode = dns.RcodeSuccess
-				rr := &dns.CNAME{
-					Hdr:    dns.RR_Header{Name: string(dom), Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 60},
-					Target: value,
-				}
-				m.Answer = append(m.Answer, rr)
+				m.Answer = append(m.Answer, answers...)
 			}
+		case dns.TypeNS:
+			// TODO: Should this, like, recurse or something? Letsencrypt always checks for NS records on random
+			// subdomains
+			m.Rcode = dns.RcodeSuccess
+			m.Answer = append(m.Answer, nsRecord(dom))
+		case dns.TypeSOA:
+			m.Rcode = dns.RcodeSuccess
+			m.Answer = append(m.Answer, soaRecord(dom))
 		case dns.TypeTXT:
-			value, err := registrar.GetRecord(ctx, dom, "TXT")
+			answers, err := lookupAnswers(ctx, registrar, dom, RecordTypeTXT)
 			if err != nil {
 				logger.Error("Error getting TXT record", "fqdn", dom, "error", err)
 
 				// TODO: This seems really weird. Is it correct to fallback to CNAME if TXT isn't present?
 				// registry.terraform.io seems to do it and AWS ACM validation queries TXT records even though
 				// it says to create CNAME records, so maybe???
-				// TODO: Cleanup duplication
-				value, err := registrar.GetRecord(ctx, dom, "CNAME")
+				answers, err = lookupAnswers(ctx, registrar, dom, RecordTypeCNAME)
 				if err != nil {
 					logger.Error("Error getting CNAME record", "fqdn", dom, "error", err)
 					m.Rcode = dns.RcodeNameError
-				} else {
-					m.Rcode = dns.RcodeSuccess
-					rr := &dns.CNAME{
-						Hdr:    dns.RR_Header{Name: string(dom), Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 60},
-						Target: value,
-					}
-					m.Answer = append(m.Answer, rr)
-				}
-			} else {
-				m.Rcode = dns.RcodeSuccess
-				rr := &dns.TXT{
-					Hdr: dns.RR_Header{Name: string(dom), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
-					Txt: []string{value},
+					break
 				}
-				m.Answer = append(m.Answer, rr)
 			}
-		case dns.TypeA:
-			ipv4QueryRegex := regexp.MustCompile(`(?P<ipv4>(?:\d+\D){3}\d+)\.ip\.[^.]+\.[^.]+\.`)
-			submatch := ipv4QueryRegex.FindStringSubmatch(string(dom))
-			if len(submatch) == 2 {
-				requestedIPv4 := submatch[1]
+			m.Rcode = dns.RcodeSuccess
+			m.Answer = append(m.Answer, answers...)
+		}
+
+		writeResponse(logger, w, m)
+	}
+}
 
-				normalizedIPv4 := strings.Join(regexp.MustCompile(`\D`).Split(requestedIPv4, 4), ".")
+// errUnsupportedUpdate is returned for the records of an RFC 2136 update that can't be stored.
+var errUnsupportedUpdate = errors.New("unsupported update")
 
-				m.Rcode = dns.RcodeSuccess
-				rr := &dns.A{
-					Hdr: dns.RR_Header{Name: string(dom), Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
-					A:   net.ParseIP(normalizedIPv4),
-				}
-				m.Answer = append(m.Answer, rr)
-			} else {
-				value, err := registrar.GetRecord(ctx, dom, "A")
+// updatedRecord is a record changed by an RFC 2136 update, along with what it was before the update.
+type updatedRecord struct {
+	fqdn       Domain
+	recordType RecordType
+	current    string
+	existed    bool
+	set        RecordSet
+}
+
+// planUpdate returns the writes that make the changes in the update section of an RFC 2136 update, so that they can be
+// made by a single WriteRecords call. Each change is made to the record as the changes before it in the update left
+// it, and records that end up the way they were aren't written.
+func planUpdate(ctx context.Context, registrar Registrar, rrs []dns.RR) ([]RecordWrite, error) {
+	var records []*updatedRecord
+	index := map[string]*updatedRecord{}
+	record := func(fqdn Domain, recordType RecordType) (*updatedRecord, error) {
+		key := string(fqdn) + " " + string(recordType)
+		if record, ok := index[key]; ok {
+			return record, nil
+		}
+		record := &updatedRecord{fqdn: fqdn, recordType: recordType, set: RecordSet{TTL: defaultRecordTTL}}
+		current, err := registrar.GetRecord(ctx, fqdn, recordType)
+		if err == nil {
+			record.current, record.existed, record.set = current, true, parseRecordSet(current)
+		} else if !errors.Is(err, ErrRecordNotFound) {
+			return nil, err
+		}
+		index[key] = record
+		records = append(records, record)
+		return record, nil
+	}
+
+	for _, rr := range rrs {
+		header := rr.Header()
+		fqdn := Domain(canonicalName(header.Name))
+		recordType := RecordType(dns.TypeToString[header.Rrtype])
+
+		// RFC 2136 section 2.5 says what the class and type of each record in the update section mean
+		if header.Class == dns.ClassANY && header.Rrtype == dns.TypeANY {
+			for _, recordType := range storedRecordTypes {
+				record, err := record(fqdn, recordType)
 				if err != nil {
-					logger.Error("Error getting A record", "fqdn", dom, "error", err)
-					m.Rcode = dns.RcodeNameError
-				} else {
-					m.Rcode = dns.RcodeSuccess
-					rr := &dns.A{
-						Hdr: dns.RR_Header{Name: string(dom), Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
-						A:   net.ParseIP(value),
-					}
-					m.Answer = append(m.Answer, rr)
+					return nil, err
 				}
+				record.set.Values = nil
 			}
+			continue
 		}
-
-		logger.Info("Writing response", "message", m.String())
-		err := w.WriteMsg(m)
+		if !isStoredRecordType(recordType) {
+			return nil, fmt.Errorf("%w: %s records can't be stored", errUnsupportedUpdate, recordType)
+		}
+		record, err := record(fqdn, recordType)
 		if err != nil {
-			logger.Error("Failed to write DNS response", "error", err.Error())
+			return nil, err
+		}
+		value := recordValue(rr)
+		switch header.Class {
+		case dns.ClassANY:
+			record.set.Values = nil
+		case dns.ClassNONE:
+			// Deleting a value the record doesn't have does nothing, like every other RFC 2136 delete
+			record.set.Values = removeString(record.set.Values, value)
+		case dns.ClassINET:
+			if value == "" {
+				return nil, fmt.Errorf("%w: %s record without a value", errUnsupportedUpdate, recordType)
+			}
+			// A name with a CNAME record can't have any other value, so adding one replaces it
+			if recordType == RecordTypeCNAME {
+				record.set.Values = nil
+			}
+			if !containsString(record.set.Values, value) {
+				record.set.Values = append(record.set.Values, value)
+			}
+			// Every value of a record has the same TTL, so the TTL of the last value added is used for all of them
+			record.set.TTL = header.Ttl
+		default:
+			return nil, fmt.Errorf("%w: class %s", errUnsupportedUpdate, dns.ClassToString[header.Class])
 		}
 	}
+
+	var writes []RecordWrite
+	for _, record := range records {
+		switch {
+		case len(record.set.Values) == 0 && record.existed:
+			writes = append(writes, RecordWrite{Domain: record.fqdn, Type: record.recordType, Value: record.current, Delete: true})
+		case len(record.set.Values) > 0 && (!record.existed || record.set.String() != record.current):
+			writes = append(writes, RecordWrite{Domain: record.fqdn, Type: record.recordType, Value: record.set.String()})
+		}
+	}
+	return writes, nil
+}
+
+// removeString returns values without value.
+func removeString(values []string, value string) []string {
+	var remaining []string
+	for _, v := range values {
+		if v != value {
+			remaining = append(remaining, v)
+		}
+	}
+	return remaining
+}
+
+// lookupAnswers returns the answers to a query for a record stored in the registrar.
+func lookupAnswers(ctx context.Context, registrar Registrar, dom Domain, recordType RecordType) ([]dns.RR, error) {
+	value, err := registrar.GetRecord(ctx, dom, recordType)
+	if err != nil {
+		return nil, err
+	}
+	return parseRecordSet(value).resourceRecords(dom, recordType)
+}
+
+func writeResponse(logger hclog.Logger, w dns.ResponseWriter, m *dns.Msg) {
+	logger.Info("Writing response", "message", m.String())
+	err := w.WriteMsg(m)
+	if err != nil {
+		logger.Error("Failed to write DNS response", "error", err.Error())
+	}
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestUpdate_Classes(t *testing.T) {
+	ctx := context.Background()
+	registrar := newMemoryRegistrar()
+	handler := handleIPQuery(registrar, NewSynthesizerRegistry(), nil, nil)
+	update := func(build func(m *dns.Msg)) int {
+		m := new(dns.Msg)
+		m.SetUpdate("example.com.")
+		build(m)
+		w := &recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP("192.0.2.7"), Port: 5353}}
+		handler(w, m)
+		if !assert.Len(t, w.messages, 1) {
+			return -1
+		}
+		return w.messages[0].Rcode
+	}
+	rr := func(text string) dns.RR {
+		rr, err := dns.NewRR(text)
+		assert.NoError(t, err)
+		return rr
+	}
+	record := func(fqdn Domain, recordType RecordType) string {
+		value, err := registrar.GetRecord(ctx, fqdn, recordType)
+		if err != nil {
+			return ""
+		}
+		return value
+	}
+
+	// Adding records adds them to the record set, with the TTL they're added with
+	assert.Equal(t, dns.RcodeSuccess, update(func(m *dns.Msg) {
+		m.Insert([]dns.RR{
+			rr("www.example.com. 300 IN A 192.0.2.1"),
+			rr("www.example.com. 300 IN A 192.0.2.2"),
+			rr("www.example.com. 300 IN A 192.0.2.1"),
+			rr("www.example.com. 60 IN MX 10 mail.example.com."),
+			rr("alias.example.com. 60 IN CNAME a.example.com."),
+			rr("alias.example.com. 60 IN CNAME b.example.com."),
+		})
+	}))
+	assert.Equal(t, "$TTL 300\n192.0.2.1\n192.0.2.2", record("www.example.com.", RecordTypeA))
+	assert.Equal(t, "10 mail.example.com.", record("www.example.com.", RecordTypeMX))
+	assert.Equal(t, "b.example.com.", record("alias.example.com.", RecordTypeCNAME), "a name only has one CNAME")
+
+	// Class NONE deletes a single value, class ANY deletes the record of a type and type ANY deletes every record
+	assert.Equal(t, dns.RcodeSuccess, update(func(m *dns.Msg) {
+		m.Remove([]dns.RR{rr("www.example.com. 0 IN A 192.0.2.1"), rr("www.example.com. 0 IN A 192.0.2.9")})
+	}))
+	assert.Equal(t, "$TTL 300\n192.0.2.2", record("www.example.com.", RecordTypeA))
+	assert.Equal(t, dns.RcodeSuccess, update(func(m *dns.Msg) {
+		m.RemoveRRset([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: "www.example.com.", Rrtype: dns.TypeA}}})
+	}))
+	assert.Empty(t, record("www.example.com.", RecordTypeA))
+	assert.Equal(t, "10 mail.example.com.", record("www.example.com.", RecordTypeMX))
+	assert.Equal(t, dns.RcodeSuccess, update(func(m *dns.Msg) {
+		m.RemoveName([]dns.RR{&dns.ANY{Hdr: dns.RR_Header{Name: "www.example.com."}}})
+	}))
+	assert.Empty(t, record("www.example.com.", RecordTypeMX))
+
+	// Changes apply in order, so a record set can be replaced by deleting it before adding to it
+	assert.NoError(t, registrar.SetRecord(ctx, "_acme-challenge.example.com.", RecordTypeTXT, "old"))
+	assert.Equal(t, dns.RcodeSuccess, update(func(m *dns.Msg) {
+		txt := []dns.RR{rr(`_acme-challenge.example.com. 120 IN TXT "new"`)}
+		m.RemoveRRset(txt)
+		m.Insert(txt)
+	}))
+	assert.Equal(t, "$TTL 120\nnew", record("_acme-challenge.example.com.", RecordTypeTXT))
+
+	// Records that can't be stored fail the whole update
+	assert.Equal(t, dns.RcodeNotImplemented, update(func(m *dns.Msg) {
+		m.Insert([]dns.RR{rr("new.example.com. 60 IN A 192.0.2.3"), rr("new.example.com. 60 IN SSHFP 1 1 0123456789abcdef")})
+	}))
+	assert.Empty(t, record("new.example.com.", RecordTypeA))
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"expvar"
+	"github.com/miekg/dns"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsRateLimitMetrics counts responses that were suppressed by response rate limiting. It is exposed to the admin over
+// the HTTP API at /debug/vars.
+var dnsRateLimitMetrics = expvar.NewMap("dns_rrl")
+
+type responseCategory string
+
+const (
+	responseCategoryAnswer   responseCategory = "answer"
+	responseCategoryNXDomain responseCategory = "nxdomain"
+	responseCategoryError    responseCategory = "error"
+)
+
+// ResponseRateLimitConfig configures BIND-style response rate limiting (RRL) for the DNS listener. Responses are
+// grouped by the netblock of the client and the category of the response, and each group gets its own token bucket.
+type ResponseRateLimitConfig struct {
+	// ResponsesPerSecond limits successful responses, including NODATA. Zero disables rate limiting entirely.
+	ResponsesPerSecond float64
+	// NXDomainsPerSecond limits NXDOMAIN responses. Defaults to ResponsesPerSecond if zero.
+	NXDomainsPerSecond float64
+	// ErrorsPerSecond limits all other error responses. Defaults to ResponsesPerSecond if zero.
+	ErrorsPerSecond float64
+	// Window is how many seconds worth of responses a client can burst before being limited.
+	Window time.Duration
+	// Slip sends every Nth rate limited response as an empty truncated reply instead of dropping it, which makes
+	// legitimate clients retry over TCP. Zero drops every limited response, one truncates every limited response.
+	Slip int
+	// IPv4PrefixLength and IPv6PrefixLength control how client addresses are grouped into netblocks.
+	IPv4PrefixLength int
+	IPv6PrefixLength int
+}
+
+// DefaultResponseRateLimitConfig returns limits similar to the values BIND recommends for authoritative servers.
+func DefaultResponseRateLimitConfig() ResponseRateLimitConfig {
+	return ResponseRateLimitConfig{
+		ResponsesPerSecond: 10,
+		NXDomainsPerSecond: 10,
+		ErrorsPerSecond:    10,
+		Window:             15 * time.Second,
+		Slip:               2,
+		IPv4PrefixLength:   24,
+		IPv6PrefixLength:   56,
+	}
+}
+
+func (c ResponseRateLimitConfig) enabled() bool {
+	return c.ResponsesPerSecond > 0
+}
+
+func (c ResponseRateLimitConfig) rate(category responseCategory) float64 {
+	rate := c.ResponsesPerSecond
+	switch category {
+	case responseCategoryNXDomain:
+		if c.NXDomainsPerSecond > 0 {
+			rate = c.NXDomainsPerSecond
+		}
+	case responseCategoryError:
+		if c.ErrorsPerSecond > 0 {
+			rate = c.ErrorsPerSecond
+		}
+	}
+	return rate
+}
+
+type rateLimitKey struct {
+	netblock string
+	category responseCategory
+}
+
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+	// limited counts consecutive limited responses so that every Slip-th one can be truncated instead of dropped
+	limited int
+}
+
+type responseRateLimiter struct {
+	config ResponseRateLimitConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[rateLimitKey]*tokenBucket
+	lastSweep time.Time
+}
+
+func newResponseRateLimiter(config ResponseRateLimitConfig) *responseRateLimiter {
+	if config.Window <= 0 {
+		config.Window = time.Second
+	}
+	if config.IPv4PrefixLength <= 0 {
+		config.IPv4PrefixLength = 32
+	}
+	if config.IPv6PrefixLength <= 0 {
+		config.IPv6PrefixLength = 128
+	}
+	return &responseRateLimiter{
+		config:  config,
+		now:     time.Now,
+		buckets: map[rateLimitKey]*tokenBucket{},
+	}
+}
+
+func (l *responseRateLimiter) netblock(addr net.Addr) string {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return addr.String()
+		}
+		ip = net.ParseIP(host)
+	}
+
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return ipv4.Mask(net.CIDRMask(l.config.IPv4PrefixLength, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(l.config.IPv6PrefixLength, 128)).String()
+}
+
+type rateLimitAction int
+
+const (
+	rateLimitAllow rateLimitAction = iota
+	rateLimitDrop
+	rateLimitSlip
+)
+
+func (l *responseRateLimiter) take(addr net.Addr, category responseCategory) rateLimitAction {
+	rate := l.config.rate(category)
+	burst := rate * l.config.Window.Seconds()
+	key := rateLimitKey{netblock: l.netblock(addr), category: category}
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, lastRefill: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.lastRefill).Seconds() * rate
+	if bucket.tokens > burst {
+		bucket.tokens = burst
+	}
+	bucket.lastRefill = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		bucket.limited = 0
+		return rateLimitAllow
+	}
+
+	bucket.limited++
+	if l.config.Slip > 0 && bucket.limited%l.config.Slip == 0 {
+		return rateLimitSlip
+	}
+	return rateLimitDrop
+}
+
+// sweep forgets buckets that would have refilled completely, so that spoofed source addresses can't grow the bucket
+// map without bound. Must be called with l.mu held.
+func (l *responseRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.config.Window {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.lastRefill) >= l.config.Window {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func categorizeResponse(m *dns.Msg) responseCategory {
+	switch m.Rcode {
+	case dns.RcodeSuccess:
+		return responseCategoryAnswer
+	case dns.RcodeNameError:
+		return responseCategoryNXDomain
+	default:
+		return responseCategoryError
+	}
+}
+
+// Handler wraps a DNS handler so that its UDP responses are rate limited. TCP responses are never limited, since the
+// TCP handshake already proves the client isn't spoofing its source address.
+func (l *responseRateLimiter) Handler(next dns.Handler) dns.Handler {
+	return dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		if _, isTCP := w.RemoteAddr().(*net.TCPAddr); isTCP {
+			next.ServeDNS(w, r)
+			return
+		}
+		next.ServeDNS(&rateLimitedResponseWriter{ResponseWriter: w, limiter: l}, r)
+	})
+}
+
+type rateLimitedResponseWriter struct {
+	dns.ResponseWriter
+	limiter *responseRateLimiter
+}
+
+func (w *rateLimitedResponseWriter) WriteMsg(m *dns.Msg) error {
+	category := categorizeResponse(m)
+	switch w.limiter.take(w.RemoteAddr(), category) {
+	case rateLimitDrop:
+		dnsRateLimitMetrics.Add("dropped_"+string(category), 1)
+		return nil
+	case rateLimitSlip:
+		dnsRateLimitMetrics.Add("slipped_"+string(category), 1)
+		truncated := new(dns.Msg)
+		truncated.MsgHdr = m.MsgHdr
+		truncated.Question = m.Question
+		truncated.Truncated = true
+		return w.ResponseWriter.WriteMsg(truncated)
+	default:
+		return w.ResponseWriter.WriteMsg(m)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func rateLimitTestConfig() ResponseRateLimitConfig {
+	return ResponseRateLimitConfig{
+		ResponsesPerSecond: 2,
+		NXDomainsPerSecond: 1,
+		Window:             time.Second,
+		Slip:               2,
+		IPv4PrefixLength:   24,
+		IPv6PrefixLength:   56,
+	}
+}
+
+func TestResponseRateLimiter_LimitsPerNetblockAndCategory(t *testing.T) {
+	limiter := newResponseRateLimiter(rateLimitTestConfig())
+	now := time.Unix(1000, 0)
+	limiter.now = func() time.Time { return now }
+
+	client := &net.UDPAddr{IP: net.ParseIP("192.0.2.10")}
+	neighbour := &net.UDPAddr{IP: net.ParseIP("192.0.2.200")}
+	otherNetblock := &net.UDPAddr{IP: net.ParseIP("198.51.100.1")}
+
+	assert.Equal(t, rateLimitAllow, limiter.take(client, responseCategoryAnswer))
+	assert.Equal(t, rateLimitAllow, limiter.take(neighbour, responseCategoryAnswer))
+	// The /24 has used up its burst, so subsequent answers alternate between dropped and slipped
+	assert.Equal(t, rateLimitDrop, limiter.take(client, responseCategoryAnswer))
+	assert.Equal(t, rateLimitSlip, limiter.take(client, responseCategoryAnswer))
+	assert.Equal(t, rateLimitDrop, limiter.take(neighbour, responseCategoryAnswer))
+
+	// Other categories and other netblocks have their own buckets
+	assert.Equal(t, rateLimitAllow, limiter.take(client, responseCategoryNXDomain))
+	assert.Equal(t, rateLimitDrop, limiter.take(client, responseCategoryNXDomain))
+	assert.Equal(t, rateLimitAllow, limiter.take(otherNetblock, responseCategoryAnswer))
+
+	// Tokens refill over time
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, rateLimitAllow, limiter.take(client, responseCategoryAnswer))
+	assert.Equal(t, rateLimitDrop, limiter.take(client, responseCategoryAnswer))
+}
+
+func TestResponseRateLimiter_GroupsIPv6ByPrefix(t *testing.T) {
+	limiter := newResponseRateLimiter(rateLimitTestConfig())
+
+	assert.Equal(t, limiter.netblock(&net.UDPAddr{IP: net.ParseIP("2001:db8:0:1::1")}), limiter.netblock(&net.UDPAddr{IP: net.ParseIP("2001:db8:0:ff::2")}))
+	assert.NotEqual(t, limiter.netblock(&net.UDPAddr{IP: net.ParseIP("2001:db8:0:1::1")}), limiter.netblock(&net.UDPAddr{IP: net.ParseIP("2001:db8:1::1")}))
+}
+
+func TestResponseRateLimiter_SlipsTruncatedResponses(t *testing.T) {
+	limiter := newResponseRateLimiter(rateLimitTestConfig())
+	limiter.now = func() time.Time { return time.Unix(1000, 0) }
+
+	handler := limiter.Handler(dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("10.0.0.1"),
+		})
+		_ = w.WriteMsg(m)
+	}))
+
+	w := &recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP("192.0.2.10")}}
+	query := new(dns.Msg)
+	query.SetQuestion("example.com.", dns.TypeA)
+	for i := 0; i < 4; i++ {
+		handler.ServeDNS(w, query)
+	}
+
+	// Two answers fit in the burst, the third is dropped, and the fourth is slipped
+	assert.Len(t, w.messages, 3)
+	assert.Len(t, w.messages[0].Answer, 1)
+	assert.Len(t, w.messages[1].Answer, 1)
+	assert.True(t, w.messages[2].Truncated)
+	assert.Empty(t, w.messages[2].Answer)
+	assert.Equal(t, query.Question, w.messages[2].Question)
+}
+
+func TestResponseRateLimiter_IgnoresTCP(t *testing.T) {
+	limiter := newResponseRateLimiter(rateLimitTestConfig())
+	handler := limiter.Handler(dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		_ = w.WriteMsg(m)
+	}))
+
+	w := &recordingResponseWriter{remoteAddr: &net.TCPAddr{IP: net.ParseIP("192.0.2.10")}}
+	query := new(dns.Msg)
+	query.SetQuestion("example.com.", dns.TypeA)
+	for i := 0; i < 10; i++ {
+		handler.ServeDNS(w, query)
+	}
+
+	assert.Len(t, w.messages, 10)
+}
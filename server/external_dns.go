@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"strings"
+)
+
+// externalDNSMediaType is the media type of every request and response of the external-dns webhook provider protocol.
+// external-dns asks for it in the Accept header of its first request, and won't use a provider that answers with
+// anything else.
+const externalDNSMediaType = "application/external.dns.webhook+json;version=1"
+
+// externalDNSEndpoint is a record as external-dns sees it. Names don't have a trailing dot, and a RecordTTL of zero
+// means the TTL isn't configured.
+type externalDNSEndpoint struct {
+	DNSName    string   `json:"dnsName"`
+	Targets    []string `json:"targets"`
+	RecordType string   `json:"recordType"`
+	RecordTTL  int64    `json:"recordTTL,omitempty"`
+}
+
+// externalDNSChanges is a set of changes that external-dns asks the provider to make. UpdateOld has the endpoints in
+// UpdateNew as they were when external-dns last listed them.
+type externalDNSChanges struct {
+	Create    []externalDNSEndpoint `json:"create,omitempty"`
+	UpdateOld []externalDNSEndpoint `json:"updateOld,omitempty"`
+	UpdateNew []externalDNSEndpoint `json:"updateNew,omitempty"`
+	Delete    []externalDNSEndpoint `json:"delete,omitempty"`
+}
+
+// externalDNSDomainFilter tells external-dns which names the provider manages.
+type externalDNSDomainFilter struct {
+	Include []string `json:"include,omitempty"`
+}
+
+// externalDNSHandler implements the external-dns webhook provider protocol, so that external-dns can manage records
+// without a sidecar. Records are only managed in the default view, since external-dns has no notion of views, and
+// changes go through the same tenant checks and record quotas as the rest of the API.
+type externalDNSHandler struct {
+	api DomainAPIImpl
+}
+
+func newExternalDNSHandler(api DomainAPIImpl) http.Handler {
+	e := externalDNSHandler{api: api}
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if accept := r.Header.Get("Accept"); accept != "" && !strings.Contains(accept, "application/external.dns.webhook+json") && !strings.Contains(accept, "*/*") {
+				hclog.FromContext(r.Context()).Info("external-dns webhook request doesn't accept the webhook media type", "accept", accept)
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withAuditActor(r.Context(), httpAuditActor(r), AuditEntryProtocolExternalDns)))
+		})
+	})
+	r.Get("/", e.negotiate)
+	r.Get("/records", e.records)
+	r.Post("/records", e.applyChanges)
+	r.Post("/adjustendpoints", e.adjustEndpoints)
+	return r
+}
+
+func writeExternalDNSResponse(w http.ResponseWriter, r *http.Request, body interface{}) {
+	w.Header().Set("Content-Type", externalDNSMediaType)
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		hclog.FromContext(r.Context()).Info("Error writing external-dns webhook response", "error", err)
+	}
+}
+
+// zones returns the zones whose records are managed, which is every name if no zones are configured.
+func (e externalDNSHandler) zones() ZoneSet {
+	if len(e.api.zones) == 0 {
+		return ZoneSet{"."}
+	}
+	return e.api.zones
+}
+
+func (e externalDNSHandler) negotiate(w http.ResponseWriter, r *http.Request) {
+	filter := externalDNSDomainFilter{}
+	for _, zone := range e.api.zones {
+		filter.Include = append(filter.Include, strings.TrimSuffix(string(zone), "."))
+	}
+	writeExternalDNSResponse(w, r, filter)
+}
+
+func (e externalDNSHandler) records(w http.ResponseWriter, r *http.Request) {
+	logger := hclog.FromContext(r.Context())
+
+	// Zones can contain each other, so records are only listed once
+	seen := map[string]bool{}
+	endpoints := []externalDNSEndpoint{}
+	for _, zone := range e.zones() {
+		records, err := e.api.registrar.ListRecords(r.Context(), zone)
+		if err != nil {
+			logger.Error("Error from registrar when listing records for external-dns", "zone", zone, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for _, record := range records {
+			key := canonicalName(string(record.Domain)) + " " + string(record.Type)
+			if record.View != "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			set := parseRecordSet(record.Value)
+			endpoints = append(endpoints, externalDNSEndpoint{
+				DNSName:    strings.TrimSuffix(canonicalName(string(record.Domain)), "."),
+				Targets:    set.Values,
+				RecordType: string(record.Type),
+				RecordTTL:  int64(set.TTL),
+			})
+		}
+	}
+	writeExternalDNSResponse(w, r, endpoints)
+}
+
+// adjustEndpoints fills in the TTL of endpoints that don't configure one, so that external-dns doesn't keep trying to
+// update records whose listed TTL is the default.
+func (e externalDNSHandler) adjustEndpoints(w http.ResponseWriter, r *http.Request) {
+	var endpoints []externalDNSEndpoint
+	if err := json.NewDecoder(r.Body).Decode(&endpoints); err != nil {
+		hclog.FromContext(r.Context()).Info("Malformed external-dns endpoints", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for i := range endpoints {
+		endpoints[i].DNSName = strings.TrimSuffix(strings.ToLower(endpoints[i].DNSName), ".")
+		if endpoints[i].RecordTTL <= 0 {
+			endpoints[i].RecordTTL = int64(defaultRecordTTL)
+		}
+	}
+	writeExternalDNSResponse(w, r, endpoints)
+}
+
+// externalDNSWrite returns the record write that sets endpoint, or false if it isn't a record that can be stored.
+func externalDNSWrite(endpoint externalDNSEndpoint) (RecordWrite, bool) {
+	recordType := RecordType(endpoint.RecordType)
+	if endpoint.DNSName == "" || !isStoredRecordType(recordType) || len(endpoint.Targets) == 0 {
+		return RecordWrite{}, false
+	}
+	set := RecordSet{TTL: defaultRecordTTL}
+	if endpoint.RecordTTL > 0 && endpoint.RecordTTL <= int64(^uint32(0)) {
+		set.TTL = uint32(endpoint.RecordTTL)
+	}
+	for _, target := range endpoint.Targets {
+		if strings.Contains(target, "\n") {
+			return RecordWrite{}, false
+		}
+		// external-dns quotes the TXT records it uses to track which records it owns, but TXT values are stored as
+		// their text
+		if recordType == RecordTypeTXT && len(target) >= 2 && strings.HasPrefix(target, `"`) && strings.HasSuffix(target, `"`) {
+			target = target[1 : len(target)-1]
+		}
+		set.Values = append(set.Values, target)
+	}
+	return RecordWrite{Domain: Domain(canonicalName(endpoint.DNSName)), Type: recordType, Value: set.String()}, true
+}
+
+// applyChanges makes every change at once, so that a record and the TXT record external-dns uses to track its owner
+// are always changed together.
+func (e externalDNSHandler) applyChanges(w http.ResponseWriter, r *http.Request) {
+	logger := hclog.FromContext(r.Context())
+
+	var changes externalDNSChanges
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		logger.Info("Malformed external-dns changes", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// A record that is both deleted and set by the same changes ends up being set
+	var writes []RecordWrite
+	indexes := map[string]int{}
+	add := func(write RecordWrite) {
+		key := string(write.Domain) + " " + string(write.Type)
+		if i, ok := indexes[key]; ok {
+			writes[i] = write
+			return
+		}
+		indexes[key] = len(writes)
+		writes = append(writes, write)
+	}
+	// Only the names that negotiate and records say are managed can be changed
+	zones := e.zones()
+	outsideZones := func(fqdn Domain) bool {
+		if _, ok := zones.Find(fqdn); ok {
+			return false
+		}
+		logger.Info("external-dns endpoint is outside of the managed zones", "domain", fqdn)
+		w.WriteHeader(http.StatusBadRequest)
+		return true
+	}
+	for _, endpoint := range changes.Delete {
+		fqdn, recordType := Domain(canonicalName(endpoint.DNSName)), RecordType(endpoint.RecordType)
+		if outsideZones(fqdn) {
+			return
+		}
+		if !isStoredRecordType(recordType) {
+			continue
+		}
+		current, err := e.api.registrar.GetRecord(r.Context(), fqdn, recordType)
+		if errors.Is(err, ErrRecordNotFound) {
+			continue
+		} else if err != nil {
+			logger.Error("Error from registrar when getting record to delete for external-dns", "domain", fqdn, "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		add(RecordWrite{Domain: fqdn, Type: recordType, Value: current, Delete: true})
+	}
+	for _, endpoint := range append(changes.Create, changes.UpdateNew...) {
+		write, ok := externalDNSWrite(endpoint)
+		if !ok {
+			logger.Info("Invalid external-dns endpoint", "domain", endpoint.DNSName, "type", endpoint.RecordType)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if outsideZones(write.Domain) {
+			return
+		}
+		add(write)
+	}
+	if len(writes) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var claims []RecordReference
+	for _, write := range writes {
+		if err := e.api.checkTenant(r, write.Domain); isForbidden(err) {
+			logger.Info("Not allowed to change record for external-dns", "domain", write.Domain, "error", err)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		} else if err != nil {
+			logger.Error("Error looking up the tenant of the record", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !write.Delete {
+			claims = append(claims, RecordReference{Domain: string(write.Domain), Type: write.Type})
+		}
+	}
+	// Every record that is set is claimed at once, so that the changes can't go past the quota between two claims
+	if len(claims) > 0 {
+		if err := e.api.claimRecordQuota(r, claims); errors.Is(err, ErrQuotaExceeded) || isForbidden(err) {
+			logger.Info("Record quota exceeded", "records", len(claims), "error", err)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		} else if err != nil {
+			logger.Error("Error from registrar when checking record quota", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	logger.Info("Applying external-dns changes", "create", len(changes.Create), "update", len(changes.UpdateNew), "delete", len(changes.Delete))
+	_, err := e.api.registrar.WriteRecords(r.Context(), writes)
+	switch {
+	case isForbidden(err):
+		logger.Info("Not allowed to apply external-dns changes", "error", err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case errors.Is(err, ErrRecordChanged):
+		logger.Info("Record changed while applying external-dns changes", "error", err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	case err != nil:
+		logger.Error("Error from registrar when applying external-dns changes", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
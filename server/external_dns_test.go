@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// externalDNSClient makes requests the way the webhook provider of external-dns does.
+type externalDNSClient struct {
+	t      *testing.T
+	server *httptest.Server
+}
+
+func (c externalDNSClient) do(method string, path string, body interface{}, response interface{}) *http.Response {
+	var payload strings.Builder
+	if body != nil {
+		assert.NoError(c.t, json.NewEncoder(&payload).Encode(body))
+	}
+	r, err := http.NewRequest(method, c.server.URL+path, strings.NewReader(payload.String()))
+	assert.NoError(c.t, err)
+	r.Header.Set("Accept", externalDNSMediaType)
+	if body != nil {
+		r.Header.Set("Content-Type", externalDNSMediaType)
+	}
+	w, err := c.server.Client().Do(r)
+	assert.NoError(c.t, err)
+	defer w.Body.Close()
+	if response != nil {
+		assert.Equal(c.t, externalDNSMediaType, w.Header.Get("Content-Type"))
+		assert.NoError(c.t, json.NewDecoder(w.Body).Decode(response))
+	}
+	return w
+}
+
+func TestExternalDNS(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryRegistrar()
+	registrar := NewAuditingRegistrar(backend, ZoneSet{"example.com."})
+	server := httptest.NewServer(newExternalDNSHandler(DomainAPIImpl{registrar: registrar, zones: ZoneSet{"example.com."}}))
+	defer server.Close()
+	client := externalDNSClient{t: t, server: server}
+
+	// Negotiation
+	var filter map[string][]string
+	assert.Equal(t, http.StatusOK, client.do(http.MethodGet, "/", nil, &filter).StatusCode)
+	assert.Equal(t, map[string][]string{"include": {"example.com"}}, filter)
+
+	var endpoints []externalDNSEndpoint
+	assert.Equal(t, http.StatusOK, client.do(http.MethodGet, "/records", nil, &endpoints).StatusCode)
+	assert.Empty(t, endpoints)
+
+	// Endpoints without a TTL get the default one
+	assert.Equal(t, http.StatusOK, client.do(http.MethodPost, "/adjustendpoints", []externalDNSEndpoint{
+		{DNSName: "App.example.com.", Targets: []string{"192.0.2.1"}, RecordType: "A"},
+	}, &endpoints).StatusCode)
+	assert.Equal(t, []externalDNSEndpoint{{DNSName: "app.example.com", Targets: []string{"192.0.2.1"}, RecordType: "A", RecordTTL: 60}}, endpoints)
+
+	// Records are created along with the TXT records external-dns tracks their owner with
+	app := externalDNSEndpoint{DNSName: "app.example.com", Targets: []string{"192.0.2.1", "192.0.2.2"}, RecordType: "A", RecordTTL: 300}
+	owner := externalDNSEndpoint{DNSName: "a-app.example.com", Targets: []string{`"heritage=external-dns,external-dns/owner=default"`}, RecordType: "TXT", RecordTTL: 60}
+	assert.Equal(t, http.StatusNoContent, client.do(http.MethodPost, "/records", externalDNSChanges{Create: []externalDNSEndpoint{app, owner}}, nil).StatusCode)
+	value, err := registrar.GetRecord(ctx, "app.example.com.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, RecordSet{TTL: 300, Values: []string{"192.0.2.1", "192.0.2.2"}}, parseRecordSet(value))
+	value, err = registrar.GetRecord(ctx, "a-app.example.com.", RecordTypeTXT)
+	assert.NoError(t, err)
+	assert.Equal(t, "heritage=external-dns,external-dns/owner=default", value)
+
+	assert.Equal(t, http.StatusOK, client.do(http.MethodGet, "/records", nil, &endpoints).StatusCode)
+	owner.Targets = []string{"heritage=external-dns,external-dns/owner=default"}
+	assert.ElementsMatch(t, []externalDNSEndpoint{app, owner}, endpoints)
+
+	// Updates replace every target
+	updated := externalDNSEndpoint{DNSName: "app.example.com", Targets: []string{"192.0.2.3"}, RecordType: "A", RecordTTL: 300}
+	assert.Equal(t, http.StatusNoContent, client.do(http.MethodPost, "/records", externalDNSChanges{UpdateOld: []externalDNSEndpoint{app}, UpdateNew: []externalDNSEndpoint{updated}}, nil).StatusCode)
+	value, err = registrar.GetRecord(ctx, "app.example.com.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, RecordSet{TTL: 300, Values: []string{"192.0.2.3"}}, parseRecordSet(value))
+
+	assert.Equal(t, http.StatusNoContent, client.do(http.MethodPost, "/records", externalDNSChanges{Delete: []externalDNSEndpoint{updated, owner}}, nil).StatusCode)
+	_, err = registrar.GetRecord(ctx, "app.example.com.", RecordTypeA)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+	_, err = registrar.GetRecord(ctx, "a-app.example.com.", RecordTypeTXT)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	entries, err := backend.ListAuditEntries(ctx, AuditQuery{Zone: "example.com."})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 5)
+	for _, entry := range entries {
+		assert.Equal(t, AuditEntryProtocolExternalDns, entry.Protocol)
+	}
+
+	// Invalid changes aren't applied
+	invalid := externalDNSEndpoint{DNSName: "bad.example.com", Targets: []string{"x"}, RecordType: "SOA"}
+	assert.Equal(t, http.StatusBadRequest, client.do(http.MethodPost, "/records", externalDNSChanges{Create: []externalDNSEndpoint{app, invalid}}, nil).StatusCode)
+	_, err = registrar.GetRecord(ctx, "app.example.com.", RecordTypeA)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	// Neither are changes to names outside of the managed zones
+	outside := externalDNSEndpoint{DNSName: "app.example.org", Targets: []string{"192.0.2.1"}, RecordType: "A"}
+	assert.Equal(t, http.StatusBadRequest, client.do(http.MethodPost, "/records", externalDNSChanges{Create: []externalDNSEndpoint{app, outside}}, nil).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, client.do(http.MethodPost, "/records", externalDNSChanges{Delete: []externalDNSEndpoint{outside}}, nil).StatusCode)
+	_, err = registrar.GetRecord(ctx, "app.example.com.", RecordTypeA)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestExternalDNS_403_IfQuotaExceeded(t *testing.T) {
+	config := EphemerainConfig{Zones: ZoneSet{"quota.com."}, RecordQuotas: RecordQuotaConfig{RecordsPerZone: 3}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		applyChanges := func(changes externalDNSChanges) int {
+			body, err := json.Marshal(changes)
+			assert.NoError(t, err)
+			response, err := http.Post(strings.TrimSuffix(apiClient.Server, "v1/")+"external-dns/records", externalDNSMediaType, bytes.NewReader(body))
+			assert.NoError(t, err)
+			defer response.Body.Close()
+			return response.StatusCode
+		}
+		endpoints := func(names ...string) []externalDNSEndpoint {
+			var endpoints []externalDNSEndpoint
+			for _, name := range names {
+				endpoints = append(endpoints, externalDNSEndpoint{DNSName: name + ".quota.com", Targets: []string{"192.0.2.1"}, RecordType: "A"})
+			}
+			return endpoints
+		}
+
+		// Every record that is set is claimed at once, so changes with more records than the quota set nothing and
+		// claim nothing either
+		assert.Equal(t, http.StatusForbidden, applyChanges(externalDNSChanges{Create: endpoints("d", "e", "f", "g")}))
+		_, err := resolver.LookupHost(ctx, "d.quota.com")
+		assert.Error(t, err)
+
+		assert.Equal(t, http.StatusNoContent, applyChanges(externalDNSChanges{Create: endpoints("a", "b", "c")}))
+		assert.Equal(t, http.StatusForbidden, applyChanges(externalDNSChanges{Create: endpoints("d")}))
+		hosts, err := resolver.LookupHost(ctx, "c.quota.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"192.0.2.1"}, hosts)
+	})
+}
+
+func TestExternalDNS_406IfNotAcceptable(t *testing.T) {
+	handler := newExternalDNSHandler(DomainAPIImpl{registrar: newMemoryRegistrar()})
+	r := httptest.NewRequest(http.MethodGet, "/records", nil)
+	r.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrVersionNotFound is returned when restoring a version that isn't in the history of a record.
+var ErrVersionNotFound = errors.New("version not found")
+
+// ErrHistoryTooOld is returned when restoring a zone to a time further back than the history that is kept.
+var ErrHistoryTooOld = errors.New("history isn't kept that long")
+
+// restoreVersion makes the record match version, which is the current version if versions is empty. Restoring a
+// deleted version deletes the record. It reports whether anything had to be changed.
+func restoreVersion(ctx context.Context, registrar Registrar, fqdn Domain, recordType RecordType, versions []RecordVersion, version RecordVersion) (bool, error) {
+	current := versions[0]
+	if version.Deleted {
+		if current.Deleted {
+			return false, nil
+		}
+		return true, registrar.DeleteRecord(ctx, fqdn, recordType, current.Value)
+	}
+	if !current.Deleted && current.Value == version.Value {
+		return false, nil
+	}
+	return true, registrar.SetRecord(ctx, fqdn, recordType, version.Value)
+}
+
+// restoreRecordVersion sets the record in the view from ctx back to the numbered version.
+func restoreRecordVersion(ctx context.Context, registrar Registrar, fqdn Domain, recordType RecordType, number int64) error {
+	versions, err := registrar.RecordHistory(ctx, fqdn, recordType)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		if version.Version == number {
+			_, err := restoreVersion(ctx, registrar, fqdn, recordType, versions, version)
+			return err
+		}
+	}
+	return ErrVersionNotFound
+}
+
+// versionAt returns the version a record had at t, given its versions newest first. If the record didn't exist at t,
+// the returned version is deleted. The second return value is false if the history doesn't go back far enough to tell.
+func versionAt(versions []RecordVersion, t time.Time) (RecordVersion, bool) {
+	for _, version := range versions {
+		if !version.Timestamp.After(t) {
+			return version, true
+		}
+	}
+	// Every version is newer than t. The record only didn't exist at t if the oldest one created it, rather than
+	// being left from versions that have been trimmed or from before history was kept.
+	if len(versions) > 0 && versions[len(versions)-1].Created {
+		return RecordVersion{Deleted: true}, true
+	}
+	return RecordVersion{}, false
+}
+
+// restoreZone sets every record in zone that changed after t back to what it was at t.
+func restoreZone(ctx context.Context, registrar Registrar, zone Domain, t time.Time) (ZoneRestoreResult, error) {
+	result := ZoneRestoreResult{Restored: []RecordReference{}, Deleted: []RecordReference{}, Unrestorable: []RecordReference{}}
+	if t.Before(time.Now().Add(-recordHistoryRetention)) {
+		return result, ErrHistoryTooOld
+	}
+
+	changed, err := registrar.ListChangedRecords(ctx, t)
+	if err != nil {
+		return result, err
+	}
+	for _, record := range changed {
+		fqdn := Domain(record.Domain)
+		if !isSubdomain(fqdn, zone) {
+			continue
+		}
+		recordCtx := ctx
+		if record.View != nil {
+			recordCtx = withView(ctx, *record.View)
+		}
+
+		versions, err := registrar.RecordHistory(recordCtx, fqdn, record.Type)
+		if err != nil {
+			return result, err
+		}
+		if len(versions) == 0 {
+			continue
+		}
+		version, ok := versionAt(versions, t)
+		if !ok {
+			result.Unrestorable = append(result.Unrestorable, record)
+			continue
+		}
+		changed, err := restoreVersion(recordCtx, registrar, fqdn, record.Type, versions, version)
+		if err != nil {
+			return result, err
+		}
+		if changed && version.Deleted {
+			result.Deleted = append(result.Deleted, record)
+		} else if changed {
+			result.Restored = append(result.Restored, record)
+		}
+	}
+	return result, nil
+}
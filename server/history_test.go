@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRestoreRecordVersion(t *testing.T) {
+	ctx := context.Background()
+	registrar := newMemoryRegistrar()
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.com.", RecordTypeA, "2.2.2.2"))
+	assert.NoError(t, registrar.DeleteRecord(ctx, "www.example.com.", RecordTypeA, "2.2.2.2"))
+
+	assert.NoError(t, restoreRecordVersion(ctx, registrar, "www.example.com.", RecordTypeA, 1))
+	value, err := registrar.GetRecord(ctx, "www.example.com.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", value)
+
+	// Restoring a deleted version deletes the record
+	assert.NoError(t, restoreRecordVersion(ctx, registrar, "www.example.com.", RecordTypeA, 3))
+	_, err = registrar.GetRecord(ctx, "www.example.com.", RecordTypeA)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	assert.ErrorIs(t, restoreRecordVersion(ctx, registrar, "www.example.com.", RecordTypeA, 42), ErrVersionNotFound)
+
+	versions, err := registrar.RecordHistory(ctx, "www.example.com.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Len(t, versions, 5)
+	assert.Equal(t, int64(5), versions[0].Version)
+	assert.True(t, versions[0].Deleted)
+	assert.True(t, versions[4].Created)
+	assert.False(t, versions[3].Created)
+}
+
+func TestRestoreZone(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Add(-time.Hour)
+	registrar := newMemoryRegistrar()
+	registrar.now = func() time.Time { return now }
+	tick := func() {
+		now = now.Add(time.Minute)
+	}
+
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, registrar.SetRecord(ctx, "gone.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, registrar.SetRecord(withView(ctx, "internal"), "www.example.com.", RecordTypeA, "10.1.1.1"))
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.net.", RecordTypeA, "1.1.1.1"))
+	for i := 0; i < maxRecordVersions; i++ {
+		assert.NoError(t, registrar.SetRecord(ctx, "busy.example.com.", RecordTypeTXT, strconv.Itoa(i)))
+	}
+	tick()
+	good := now
+	tick()
+
+	// Records from before history was kept have no version that created them
+	registrar.records[recordKey(ctx, "legacy.example.com.", RecordTypeA, "")] = "1.1.1.1"
+	assert.NoError(t, registrar.SetRecord(ctx, "legacy.example.com.", RecordTypeA, "6.6.6.6"))
+
+	// A bad upload changes, adds and removes records
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.com.", RecordTypeA, "6.6.6.6"))
+	assert.NoError(t, registrar.SetRecord(ctx, "new.example.com.", RecordTypeA, "6.6.6.6"))
+	assert.NoError(t, registrar.DeleteRecord(ctx, "gone.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, registrar.SetRecord(withView(ctx, "internal"), "www.example.com.", RecordTypeA, "10.6.6.6"))
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.net.", RecordTypeA, "6.6.6.6"))
+	for i := 0; i < maxRecordVersions; i++ {
+		assert.NoError(t, registrar.SetRecord(ctx, "busy.example.com.", RecordTypeTXT, "bad"+strconv.Itoa(i)))
+	}
+	tick()
+
+	result, err := restoreZone(ctx, registrar, "example.com.", good)
+	assert.NoError(t, err)
+	internal := "internal"
+	assert.ElementsMatch(t, []RecordReference{
+		{Domain: "www.example.com.", Type: RecordTypeA},
+		{Domain: "gone.example.com.", Type: RecordTypeA},
+		{Domain: "www.example.com.", Type: RecordTypeA, View: &internal},
+	}, result.Restored)
+	assert.Equal(t, []RecordReference{{Domain: "new.example.com.", Type: RecordTypeA}}, result.Deleted)
+	assert.ElementsMatch(t, []RecordReference{
+		{Domain: "busy.example.com.", Type: RecordTypeTXT},
+		{Domain: "legacy.example.com.", Type: RecordTypeA},
+	}, result.Unrestorable)
+
+	for fqdn, expected := range map[Domain]string{"www.example.com.": "1.1.1.1", "gone.example.com.": "1.1.1.1", "www.example.net.": "6.6.6.6"} {
+		value, err := registrar.GetRecord(ctx, fqdn, RecordTypeA)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value, fqdn)
+	}
+	value, err := registrar.GetRecord(withView(ctx, "internal"), "www.example.com.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.1.1", value)
+	_, err = registrar.GetRecord(ctx, "new.example.com.", RecordTypeA)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	// Restoring again doesn't change anything
+	tick()
+	result, err = restoreZone(ctx, registrar, "example.com.", good)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Restored)
+	assert.Empty(t, result.Deleted)
+	value, err = registrar.GetRecord(ctx, "legacy.example.com.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "6.6.6.6", value)
+
+	_, err = restoreZone(ctx, registrar, "example.com.", time.Now().Add(-2*recordHistoryRetention))
+	assert.ErrorIs(t, err, ErrHistoryTooOld)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+func (d DomainAPIImpl) GetAudit(w http.ResponseWriter, r *http.Request, params GetAuditParams) {
+	logger := hclog.FromContext(r.Context())
+
+	query := AuditQuery{Limit: defaultAuditLimit}
+	if params.Zone != nil {
+		query.Zone = Domain(canonicalName(*params.Zone))
+	}
+	if params.Since != nil {
+		query.Since = *params.Since
+	}
+	if params.Until != nil {
+		query.Until = *params.Until
+	}
+	if params.Limit != nil {
+		query.Limit = *params.Limit
+	}
+	if query.Limit < 1 || query.Limit > maxAuditLimit || (!query.Since.IsZero() && !query.Until.IsZero() && query.Until.Before(query.Since)) {
+		logger.Info("Invalid audit log query", "query", query)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	entries, err := d.registrar.ListAuditEntries(r.Context(), query)
+	if err != nil {
+		logger.Error("Error from registrar when listing audit log", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		logger.Info("Error writing audit log response", "error", err)
+	}
+}
@@ -2,32 +2,67 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/hashicorp/go-hclog"
-	"github.com/wpalmer/gozone"
+	"net"
 	"net/http"
+	"strings"
 )
 
 type DomainAPIImpl struct {
-	registrar Registrar
+	registrar  Registrar
+	zones      ZoneSet
+	quotas     RecordQuotaConfig
+	views      ViewConfig
+	subdomains SubdomainConfig
+	tenants    *TenantDirectory
+	// tsigSecrets are the configured TSIG keys, which tenants can be given
+	tsigSecrets map[string]string
+	adminToken  string
 }
 
-func (d DomainAPIImpl) GetDomain(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType) {
+func (d DomainAPIImpl) GetDomain(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType, params GetDomainParams) {
 	logger := hclog.FromContext(r.Context())
-	record, err := d.registrar.GetRecord(r.Context(), domain, recordType)
+
+	view := d.views.Match(net.ParseIP(clientIP(r)))
+	if params.View != nil {
+		view = string(*params.View)
+	}
+	if !d.views.Exists(view) {
+		logger.Info("Unknown view", "view", view)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	record, err := d.registrar.GetRecord(withView(r.Context(), view), domain, recordType)
 	if err != nil {
 		logger.Info("Error getting record from registrar", "error", err)
 		w.WriteHeader(http.StatusNotFound)
 	} else {
+		set := parseRecordSet(record)
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(&RecordValue{Value: &record}); err != nil {
+		if err := json.NewEncoder(w).Encode(&RecordValue{Value: &set.Values[0], Values: &set.Values, Ttl: &set.TTL}); err != nil {
 			logger.Info("Error getting record from registrar", "error", err)
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 	}
 }
 
-func (d DomainAPIImpl) PutDomain(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType) {
+func (d DomainAPIImpl) PutDomain(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType, params PutDomainParams) {
 	logger := hclog.FromContext(r.Context())
+
+	var view string
+	if params.View != nil {
+		view = string(*params.View)
+	}
+	if !d.views.Exists(view) {
+		logger.Info("Unknown view", "view", view)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r = r.WithContext(withView(r.Context(), view))
+
 	var body PutDomainJSONRequestBody
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		logger.Error("Malformed request", "error", err)
@@ -36,13 +71,61 @@ func (d DomainAPIImpl) PutDomain(w http.ResponseWriter, r *http.Request, domain
 		return
 	}
 
+	set := RecordSet{TTL: defaultRecordTTL}
+	if body.Values != nil {
+		set.Values = *body.Values
+	} else if body.Value != nil {
+		set.Values = []string{*body.Value}
+	}
+	if body.Ttl != nil {
+		set.TTL = *body.Ttl
+	}
+	if len(set.Values) == 0 {
+		logger.Info("Record has no values")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, value := range set.Values {
+		if strings.Contains(value, "\n") {
+			logger.Info("Record value contains a newline")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
 	// TODO: DNS server library might have handy ways to validate different record values
 	// TODO: Validate the record types. FQDN for CNAME, IP for A, etc
 	// TODO: Validate lengths
 
-	logger.Info("Setting record", "domain", domain, "type", recordType, "value", *body.Value)
-	err := d.registrar.SetRecord(r.Context(), domain, recordType, *body.Value)
-	if err != nil {
+	if err := d.checkTenant(r, domain); err != nil {
+		if isForbidden(err) {
+			logger.Info("Not allowed to set record", "domain", domain, "error", err)
+			w.WriteHeader(http.StatusForbidden)
+		} else {
+			logger.Error("Error looking up the tenant of the record", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := d.claimRecordQuota(r, []RecordReference{{Domain: string(domain), Type: recordType}}); err != nil {
+		if errors.Is(err, ErrQuotaExceeded) {
+			logger.Info("Record quota exceeded", "domain", domain, "type", recordType, "error", err)
+			w.WriteHeader(http.StatusForbidden)
+		} else {
+			logger.Error("Error from registrar when checking record quota", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	logger.Info("Setting record", "domain", domain, "type", recordType, "view", view, "values", set.Values, "ttl", set.TTL)
+	err := d.registrar.SetRecord(r.Context(), domain, recordType, set.String())
+	if isForbidden(err) {
+		logger.Info("Not allowed to set record", "domain", domain, "error", err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	} else if err != nil {
 		logger.Error("Error from registrar when setting record", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -51,33 +134,76 @@ func (d DomainAPIImpl) PutDomain(w http.ResponseWriter, r *http.Request, domain
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// TODO: Maybe this should be scoped to a domain?
-func (d DomainAPIImpl) PostZone(w http.ResponseWriter, r *http.Request) {
+func (d DomainAPIImpl) DeleteDomain(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType, params DeleteDomainParams) {
 	logger := hclog.FromContext(r.Context())
-	var record gozone.Record
-	scanner := gozone.NewScanner(r.Body)
-	defer r.Body.Close()
-	for {
-		err := scanner.Next(&record)
-		if err == nil {
-			logger.Info("Parsed zone record", "record", record)
-
-			if record.Type == gozone.RecordType_A {
-				err := d.registrar.SetRecord(r.Context(), Domain(record.DomainName), RecordTypeA, record.Data[0])
-				if err != nil {
-					logger.Warn("Error setting record", "error", err)
-				}
-			}
-
-		} else if err.Error() == "EOF" {
-			logger.Info("Finishing processing uploaded zone")
-			w.WriteHeader(http.StatusNoContent)
-			return
+
+	var view string
+	if params.View != nil {
+		view = string(*params.View)
+	}
+	if !d.views.Exists(view) {
+		logger.Info("Unknown view", "view", view)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	ctx := withView(r.Context(), view)
+
+	if err := d.checkTenant(r, domain); err != nil {
+		if isForbidden(err) {
+			logger.Info("Not allowed to delete record", "domain", domain, "error", err)
+			w.WriteHeader(http.StatusForbidden)
 		} else {
-			logger.Info("Attempted to post invalid zone", "error", err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
+			logger.Error("Error looking up the tenant of the record", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
 		}
+		return
+	}
+
+	// GetRecord falls back to the default view, which would delete the default record when the view doesn't have one
+	value, err := d.registrar.GetViewRecord(ctx, domain, recordType)
+	if errors.Is(err, ErrRecordNotFound) {
+		logger.Info("Record to delete doesn't exist", "domain", domain, "type", recordType, "view", view)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		logger.Error("Error from registrar when getting record", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Deleting record", "domain", domain, "type", recordType, "view", view)
+	_, err = d.registrar.WriteRecords(ctx, []RecordWrite{{Domain: domain, Type: recordType, Value: value, Delete: true}})
+	if isForbidden(err) {
+		logger.Info("Not allowed to delete record", "domain", domain, "error", err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	} else if errors.Is(err, ErrRecordChanged) {
+		logger.Info("Record changed while it was being deleted", "domain", domain, "error", err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	} else if err != nil {
+		logger.Error("Error from registrar when deleting record", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (d DomainAPIImpl) GetWhoami(w http.ResponseWriter, r *http.Request) {
+	logger := hclog.FromContext(r.Context())
+	whoami := Whoami{
+		Source:    r.RemoteAddr,
+		Transport: r.Proto,
+		RequestId: requestIDFromContext(r.Context()),
+	}
+	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+		whoami.ForwardedFor = &forwardedFor
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(&whoami); err != nil {
+		logger.Info("Error writing whoami response", "error", err)
 	}
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+)
+
+func (d DomainAPIImpl) ListRecordVersions(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType, params ListRecordVersionsParams) {
+	logger := hclog.FromContext(r.Context())
+
+	var view string
+	if params.View != nil {
+		view = string(*params.View)
+	}
+	if !d.views.Exists(view) {
+		logger.Info("Unknown view", "view", view)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	versions, err := d.registrar.RecordHistory(withView(r.Context(), view), domain, recordType)
+	if err != nil {
+		logger.Error("Error from registrar when getting record history", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(versions); err != nil {
+		logger.Info("Error writing record history response", "error", err)
+	}
+}
+
+func (d DomainAPIImpl) RestoreRecordVersion(w http.ResponseWriter, r *http.Request, domain Domain, recordType RecordType, version int64, params RestoreRecordVersionParams) {
+	logger := hclog.FromContext(r.Context())
+
+	var view string
+	if params.View != nil {
+		view = string(*params.View)
+	}
+	if !d.views.Exists(view) {
+		logger.Info("Unknown view", "view", view)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("Restoring record version", "domain", domain, "type", recordType, "view", view, "version", version)
+	err := restoreRecordVersion(withView(r.Context(), view), d.registrar, domain, recordType, version)
+	if errors.Is(err, ErrVersionNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if isForbidden(err) {
+		logger.Info("Not allowed to restore record", "domain", domain, "error", err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	} else if err != nil {
+		logger.Error("Error restoring record version", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (d DomainAPIImpl) RestoreZone(w http.ResponseWriter, r *http.Request, zone string) {
+	logger := hclog.FromContext(r.Context())
+
+	var body RestoreZoneJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logger.Info("Malformed zone restore request", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("Restoring zone", "zone", zone, "timestamp", body.Timestamp)
+	result, err := restoreZone(r.Context(), d.registrar, Domain(canonicalName(zone)), body.Timestamp)
+	if errors.Is(err, ErrHistoryTooOld) {
+		logger.Info("Zone restore timestamp is too old", "timestamp", body.Timestamp)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	} else if isForbidden(err) {
+		logger.Info("Not allowed to restore zone", "zone", zone, "error", err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	} else if err != nil {
+		logger.Error("Error restoring zone", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Restored zone", "zone", zone, "restored", len(result.Restored), "deleted", len(result.Deleted), "unrestorable", len(result.Unrestorable))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(&result); err != nil {
+		logger.Info("Error writing zone restore response", "error", err)
+	}
+}
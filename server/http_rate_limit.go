@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPRateLimitConfig limits how many API requests a single client can make. Counters are kept in the registrar, so
+// the limits apply across all replicas sharing it.
+type HTTPRateLimitConfig struct {
+	// RequestsPerIP is how many requests a client IP can make per Window. Zero disables the limit.
+	RequestsPerIP int64
+	// RequestsPerToken is how many requests can be made with a single API token per Window. Zero disables the limit.
+	RequestsPerToken int64
+	Window           time.Duration
+}
+
+// RecordQuotaConfig limits how many records can be created through the API.
+type RecordQuotaConfig struct {
+	// RecordsPerToken is how many records can be created with a single tenant or subdomain owner token. Other bearer
+	// tokens are only held to RecordsPerZone, since clients can make up a new one for every request. Zero disables the
+	// limit.
+	RecordsPerToken int64
+	// RecordsPerZone is how many records can be created in a single zone. Zero disables the limit.
+	RecordsPerZone int64
+}
+
+// apiToken returns the bearer token the request was made with, or an empty string if there isn't one.
+func apiToken(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) > len("Bearer ") && strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(authorization[len("Bearer "):])
+	}
+	return ""
+}
+
+// tokenID identifies a token in log lines and backend keys without revealing the token itself.
+func tokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+type clientIPContextKey struct{}
+
+// clientIP returns the address of the client that made the request, which is the address the request came from unless
+// it came through a trusted proxy.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey{}).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// clientIPMiddleware finds the address of the client for requests that came through trusted proxies, such as a load
+// balancer, so that rate limits, views and the audit log apply to the client rather than the proxy. Proxies append the
+// address they received a request from to X-Forwarded-For, so the client is the last address that isn't a trusted
+// proxy. Anything before that could have been sent by the client itself, so it isn't trusted.
+func clientIPMiddleware(trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteHost(r)
+			if networksContain(trustedProxies, net.ParseIP(ip)) {
+				forwardedFor := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+				for i := len(forwardedFor) - 1; i >= 0; i-- {
+					forwarded := net.ParseIP(strings.TrimSpace(forwardedFor[i]))
+					if forwarded == nil {
+						break
+					}
+					ip = forwarded.String()
+					if !networksContain(trustedProxies, forwarded) {
+						break
+					}
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPContextKey{}, ip)))
+		})
+	}
+}
+
+func rateLimitMiddleware(registrar Registrar, config HTTPRateLimitConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := hclog.FromContext(r.Context())
+
+			type limit struct {
+				key   string
+				limit int64
+			}
+			var limits []limit
+			if config.RequestsPerIP > 0 {
+				limits = append(limits, limit{key: "ip:" + clientIP(r), limit: config.RequestsPerIP})
+			}
+			if token := apiToken(r); token != "" && config.RequestsPerToken > 0 {
+				limits = append(limits, limit{key: "token:" + tokenID(token), limit: config.RequestsPerToken})
+			}
+
+			for _, l := range limits {
+				retryAfter, err := registrar.ConsumeRateLimit(r.Context(), l.key, l.limit, config.Window)
+				if err != nil {
+					// Failing open keeps the API usable if the rate limit counters are unavailable
+					logger.Error("Error checking rate limit", "key", l.key, "error", err)
+					continue
+				}
+				if retryAfter > 0 {
+					logger.Info("Rate limit exceeded", "key", l.key)
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// claimRecordQuota checks that creating the records doesn't exceed the quota of the requester's token or of the zones
+// the records are in. Requests from a tenant are held to the tenant's own quotas instead, which are counted separately
+// from everyone else's. The records are claimed together, so they have to be claimed before any of them are written.
+func (d DomainAPIImpl) claimRecordQuota(r *http.Request, records []RecordReference) error {
+	tenant, err := d.tenantOf(r)
+	if err != nil {
+		return err
+	}
+	quotas, scope, zoneOf := d.quotas, "", d.zones.ZoneOf
+	if tenant != nil {
+		quotas, scope, zoneOf = tenant.recordQuotas(), tenantKeyPrefix(tenant.Id), tenant.zoneOf
+	}
+
+	if token := apiToken(r); d.issuedToken(token, tenant) && quotas.RecordsPerToken > 0 {
+		if err := d.registrar.ClaimRecordQuota(r.Context(), scope+"token:"+tokenID(token), records, quotas.RecordsPerToken); err != nil {
+			return fmt.Errorf("token quota: %w", err)
+		}
+	}
+	if quotas.RecordsPerZone > 0 {
+		var zones []Domain
+		byZone := map[Domain][]RecordReference{}
+		for _, record := range records {
+			zone := zoneOf(Domain(record.Domain))
+			if _, ok := byZone[zone]; !ok {
+				zones = append(zones, zone)
+			}
+			byZone[zone] = append(byZone[zone], record)
+		}
+		for _, zone := range zones {
+			if err := d.registrar.ClaimRecordQuota(r.Context(), scope+"zone:"+string(zone), byZone[zone], quotas.RecordsPerZone); err != nil {
+				return fmt.Errorf("zone %s quota: %w", zone, err)
+			}
+		}
+	}
+	return nil
+}
+
+// issuedToken reports whether token was handed out by the server, either to tenant or as the owner token of a
+// subdomain. Owner tokens are checked by SubdomainRegistrar before anything is written with them.
+func (d DomainAPIImpl) issuedToken(token string, tenant *RegisteredTenant) bool {
+	if token == "" {
+		return false
+	}
+	return tenant != nil || (d.subdomains.Zone != "" && strings.HasPrefix(token, subdomainTokenPrefix))
+}
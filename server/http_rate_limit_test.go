@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPMiddleware(t *testing.T) {
+	trustedProxies, err := ParseNetworks("10.0.0.0/8, fd00::/8")
+	assert.NoError(t, err)
+	_, err = ParseNetworks("10.0.0.0")
+	assert.Error(t, err)
+
+	var seen string
+	handler := clientIPMiddleware(trustedProxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = clientIP(r)
+	}))
+	request := func(remoteAddr string, forwardedFor ...string) string {
+		r := httptest.NewRequest(http.MethodGet, "/v1/whoami", nil)
+		r.RemoteAddr = remoteAddr
+		for _, value := range forwardedFor {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		return seen
+	}
+
+	assert.Equal(t, "192.0.2.1", request("192.0.2.1:1234"))
+	// Untrusted clients can't choose their address
+	assert.Equal(t, "192.0.2.1", request("192.0.2.1:1234", "198.51.100.7"))
+	// Requests through the load balancer belong to the client it forwarded them for
+	assert.Equal(t, "198.51.100.7", request("10.0.0.2:1234", "198.51.100.7"))
+	assert.Equal(t, "198.51.100.7", request("[fd00::2]:1234", "203.0.113.9, 198.51.100.7, 10.0.0.3"))
+	assert.Equal(t, "198.51.100.7", request("10.0.0.2:1234", "203.0.113.9", "198.51.100.7"))
+	// Garbage in the header stops the walk at the last address that could be trusted
+	assert.Equal(t, "10.0.0.3", request("10.0.0.2:1234", "198.51.100.7, unknown, 10.0.0.3"))
+	assert.Equal(t, "10.0.0.2", request("10.0.0.2:1234"))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"time"
+)
+
+func (d DomainAPIImpl) ClaimSubdomain(w http.ResponseWriter, r *http.Request) {
+	logger := hclog.FromContext(r.Context())
+
+	if d.subdomains.Zone == "" {
+		logger.Info("Ephemeral subdomains aren't enabled")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	lease, token, err := claimSubdomain(r.Context(), d.registrar, d.subdomains.Zone, time.Now().Add(d.subdomains.Lifetime))
+	if errors.Is(err, errNoUnusedSubdomain) {
+		logger.Warn("Couldn't find an unused subdomain", "zone", d.subdomains.Zone)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		logger.Error("Error from registrar when claiming subdomain", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Claimed subdomain", "domain", lease.Domain, "token", tokenID(token), "expires", lease.Expires)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&Subdomain{LeaseId: lease.Id, Domain: string(lease.Domain), Token: token, Expires: lease.Expires.UTC()}); err != nil {
+		logger.Info("Error writing subdomain response", "error", err)
+	}
+}
+
+// ownedLease returns the lease with the given ID if the request has its owner token, writing an error response
+// otherwise.
+func (d DomainAPIImpl) ownedLease(w http.ResponseWriter, r *http.Request, id LeaseId) (SubdomainLease, bool) {
+	logger := hclog.FromContext(r.Context())
+	lease, err := d.registrar.GetLease(r.Context(), string(id))
+	if errors.Is(err, ErrLeaseNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return lease, false
+	} else if err != nil {
+		logger.Error("Error from registrar when getting lease", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return lease, false
+	}
+	if lease.TokenHash != secretTokenHash(apiToken(r)) {
+		logger.Info("Request doesn't have the owner token of the lease", "lease", id)
+		w.WriteHeader(http.StatusForbidden)
+		return lease, false
+	}
+	return lease, true
+}
+
+func (d DomainAPIImpl) GetLease(w http.ResponseWriter, r *http.Request, leaseId LeaseId) {
+	logger := hclog.FromContext(r.Context())
+	lease, ok := d.ownedLease(w, r, leaseId)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(&Lease{Id: lease.Id, Domain: string(lease.Domain), Expires: lease.Expires.UTC()}); err != nil {
+		logger.Info("Error writing lease response", "error", err)
+	}
+}
+
+func (d DomainAPIImpl) RenewLease(w http.ResponseWriter, r *http.Request, leaseId LeaseId) {
+	logger := hclog.FromContext(r.Context())
+	if _, ok := d.ownedLease(w, r, leaseId); !ok {
+		return
+	}
+
+	now := time.Now()
+	lease, err := d.registrar.RenewLease(r.Context(), string(leaseId), now.Add(d.subdomains.Lifetime), now)
+	if errors.Is(err, ErrLeaseNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if errors.Is(err, ErrLeaseExpired) {
+		logger.Info("Lease has already expired", "lease", leaseId)
+		w.WriteHeader(http.StatusGone)
+		return
+	} else if err != nil {
+		logger.Error("Error from registrar when renewing lease", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Renewed lease", "lease", leaseId, "domain", lease.Domain, "expires", lease.Expires)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(&Lease{Id: lease.Id, Domain: string(lease.Domain), Expires: lease.Expires.UTC()}); err != nil {
+		logger.Info("Error writing lease response", "error", err)
+	}
+}
+
+func (d DomainAPIImpl) ReleaseLease(w http.ResponseWriter, r *http.Request, leaseId LeaseId) {
+	logger := hclog.FromContext(r.Context())
+	lease, ok := d.ownedLease(w, r, leaseId)
+	if !ok {
+		return
+	}
+
+	err := releaseLease(r.Context(), d.registrar, lease)
+	if errors.Is(err, ErrRecordChanged) {
+		logger.Info("Records of lease changed while being deleted", "lease", leaseId, "error", err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	} else if err != nil {
+		logger.Error("Error releasing lease", "lease", leaseId, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Released lease", "lease", leaseId, "domain", lease.Domain)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"github.com/miekg/dns"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// authorizeAdmin checks that the request was made with the admin token. The tenant APIs and metrics don't exist at all
+// unless an admin token is configured.
+func (d DomainAPIImpl) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	logger := hclog.FromContext(r.Context())
+	if d.adminToken == "" {
+		logger.Info("Admin APIs are disabled because no admin token is configured")
+		w.WriteHeader(http.StatusNotFound)
+		return false
+	}
+	if !d.isAdmin(r) {
+		logger.Info("Request isn't authorized with the admin token")
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// isAdmin reports whether the request was made with the admin token.
+func (d DomainAPIImpl) isAdmin(r *http.Request) bool {
+	return d.adminToken != "" && subtle.ConstantTimeCompare([]byte(apiToken(r)), []byte(d.adminToken)) == 1
+}
+
+func (d DomainAPIImpl) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	logger := hclog.FromContext(r.Context())
+	if !d.authorizeAdmin(w, r) {
+		return
+	}
+
+	var body CreateTenantJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logger.Info("Malformed tenant", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !tenantIDPattern.MatchString(body.Id) {
+		logger.Info("Invalid tenant ID", "tenant", body.Id)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if len(body.Zones) == 0 {
+		logger.Info("Tenant doesn't have any zones", "tenant", body.Id)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	tenant := RegisteredTenant{Tenant: Tenant{Id: body.Id, Zones: []string{}, TsigKeys: []string{}, KeyPrefix: tenantKeyPrefix(body.Id)}}
+	for _, raw := range body.Zones {
+		zone := canonicalName(raw)
+		if _, ok := d.zones.Find(Domain(zone)); (len(d.zones) > 0 && !ok) || zone == "." {
+			logger.Info("Tenant zone isn't served by this server", "tenant", body.Id, "zone", zone)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !containsString(tenant.Zones, zone) {
+			tenant.Zones = append(tenant.Zones, zone)
+		}
+	}
+	if body.TsigKeys != nil {
+		for _, raw := range *body.TsigKeys {
+			key := dns.Fqdn(strings.ToLower(raw))
+			if _, ok := d.tsigSecrets[key]; !ok {
+				logger.Info("Tenant TSIG key isn't configured", "tenant", body.Id, "key", key)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if !containsString(tenant.TsigKeys, key) {
+				tenant.TsigKeys = append(tenant.TsigKeys, key)
+			}
+		}
+	}
+	if body.Quotas != nil {
+		if (body.Quotas.RecordsPerToken != nil && *body.Quotas.RecordsPerToken < 0) || (body.Quotas.RecordsPerZone != nil && *body.Quotas.RecordsPerZone < 0) {
+			logger.Info("Tenant quotas can't be negative", "tenant", body.Id)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		tenant.Quotas = *body.Quotas
+	}
+
+	token, err := newSecretToken(tenantTokenPrefix)
+	if err != nil {
+		logger.Error("Error generating tenant token", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	tenant.TokenHashes = []string{secretTokenHash(token)}
+
+	err = d.registrar.CreateTenant(r.Context(), tenant)
+	if errors.Is(err, ErrTenantConflict) {
+		logger.Info("Tenant conflicts with an existing tenant", "tenant", body.Id, "error", err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	} else if err != nil {
+		logger.Error("Error from registrar when creating tenant", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if d.tenants != nil {
+		d.tenants.invalidate()
+	}
+
+	logger.Info("Created tenant", "tenant", tenant.Id, "zones", tenant.Zones, "tsigKeys", tenant.TsigKeys)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&CreatedTenant{Tenant: tenant.Tenant, Token: token}); err != nil {
+		logger.Info("Error writing tenant response", "error", err)
+	}
+}
+
+func (d DomainAPIImpl) ListTenants(w http.ResponseWriter, r *http.Request) {
+	logger := hclog.FromContext(r.Context())
+	if !d.authorizeAdmin(w, r) {
+		return
+	}
+
+	registered, err := d.registrar.ListTenants(r.Context())
+	if err != nil {
+		logger.Error("Error from registrar when listing tenants", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	tenants := make([]Tenant, 0, len(registered))
+	for _, tenant := range registered {
+		tenants = append(tenants, tenant.Tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].Id < tenants[j].Id })
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(&tenants); err != nil {
+		logger.Info("Error writing tenants response", "error", err)
+	}
+}
+
+// tenantOf returns the tenant the request's API token belongs to, or nil if it doesn't belong to one.
+func (d DomainAPIImpl) tenantOf(r *http.Request) (*RegisteredTenant, error) {
+	if d.tenants == nil {
+		return nil, nil
+	}
+	return d.tenants.forToken(r.Context(), apiToken(r))
+}
+
+// checkTenant fails with ErrTenantForbidden if the request's API token can't change records of fqdn, which is checked
+// before anything is done on behalf of the request. TenantRegistrar checks every change again.
+func (d DomainAPIImpl) checkTenant(r *http.Request, fqdn Domain) error {
+	if d.tenants == nil {
+		return nil
+	}
+	requester, err := d.tenantOf(r)
+	if err != nil {
+		return err
+	}
+	owner, err := d.tenants.owner(r.Context(), fqdn)
+	if err != nil {
+		return err
+	}
+	if namespaceOf(requester) != namespaceOf(owner) {
+		return ErrTenantForbidden
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RecordChangeResync is the action record changes without a domain are sent with by the watch API.
+const RecordChangeResync RecordChangeAction = "resync"
+
+// watchKeepaliveInterval is how often an idle event stream gets a comment, so proxies don't time it out.
+const watchKeepaliveInterval = 30 * time.Second
+
+func watchEvent(change RecordChange) RecordChange {
+	if change.Domain == "" {
+		return RecordChange{Action: RecordChangeResync}
+	}
+	// Namespaces are backend keys, which aren't part of the API
+	change.Namespace = ""
+	return change
+}
+
+func (d DomainAPIImpl) WatchRecords(w http.ResponseWriter, r *http.Request, params WatchRecordsParams) {
+	if err := d.checkWatcher(r); isForbidden(err) {
+		hclog.FromContext(r.Context()).Info("Not allowed to watch records", "error", err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	} else if err != nil {
+		hclog.FromContext(r.Context()).Error("Error looking up tenant", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var prefix string
+	if params.Prefix != nil {
+		prefix = *params.Prefix
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		d.watchWebSocket(w, r, prefix)
+	} else {
+		d.watchEventStream(w, r, prefix)
+	}
+}
+
+// checkWatcher fails with ErrTenantForbidden if there are tenants and the request's API token doesn't belong to one.
+// Watches only see changes to the records of the requester's tenant, which TenantRegistrar enforces as well.
+func (d DomainAPIImpl) checkWatcher(r *http.Request) error {
+	if d.tenants == nil {
+		return nil
+	}
+	tenant, err := d.tenantOf(r)
+	if err != nil || tenant != nil {
+		return err
+	}
+	tenants, err := d.tenants.list(r.Context())
+	if err != nil {
+		return err
+	}
+	if len(tenants) > 0 {
+		return ErrTenantForbidden
+	}
+	return nil
+}
+
+func (d DomainAPIImpl) watchEventStream(w http.ResponseWriter, r *http.Request, prefix string) {
+	logger := hclog.FromContext(r.Context())
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.Error("Response writer doesn't support streaming")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	changes := d.registrar.Watch(r.Context(), prefix)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(watchKeepaliveInterval)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			event := watchEvent(change)
+			data, _ := json.Marshal(event)
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Action, data); err != nil {
+				logger.Info("Error writing record change", "error", err)
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func (d DomainAPIImpl) watchWebSocket(w http.ResponseWriter, r *http.Request, prefix string) {
+	logger := hclog.FromContext(r.Context())
+
+	// Clients authenticate with bearer tokens rather than cookies, so there's no need for the default origin check
+	server := websocket.Server{Handler: func(conn *websocket.Conn) {
+		ctx := conn.Request().Context()
+		changes := d.registrar.Watch(ctx, prefix)
+
+		// Clients aren't expected to send anything, but reading is how a closed connection is noticed
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			var discard []byte
+			for websocket.Message.Receive(conn, &discard) == nil {
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-closed:
+				return
+			case change, ok := <-changes:
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(conn, watchEvent(change)); err != nil {
+					logger.Info("Error writing record change", "error", err)
+					return
+				}
+			}
+		}
+	}}
+	server.ServeHTTP(w, r)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"github.com/teris-io/shortid"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// authorizeWebhooks checks that the request was made with a tenant token or the admin token. The server makes requests
+// to the URLs of webhooks and logs how they went, so anonymous clients can't register webhooks or see their deliveries.
+func (d DomainAPIImpl) authorizeWebhooks(w http.ResponseWriter, r *http.Request) bool {
+	logger := hclog.FromContext(r.Context())
+	if d.isAdmin(r) {
+		return true
+	}
+	tenant, err := d.tenantOf(r)
+	if err != nil {
+		logger.Error("Error looking up the tenant of the request", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if tenant == nil {
+		logger.Info("Webhooks need a tenant token or the admin token")
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (d DomainAPIImpl) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := hclog.FromContext(r.Context())
+	if !d.authorizeWebhooks(w, r) {
+		return
+	}
+
+	var body CreateWebhookJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		logger.Info("Malformed webhook registration", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	target, err := url.Parse(body.Url)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		logger.Info("Invalid webhook URL", "url", body.Url, "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if body.Zone == "" || body.Secret == "" {
+		logger.Info("Webhook registration is missing zone or secret")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	zone := Domain(canonicalName(body.Zone))
+	if _, ok := d.zones.Find(zone); len(d.zones) > 0 && !ok {
+		logger.Info("Webhook zone isn't served by this server", "zone", zone)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := d.checkTenant(r, zone); err != nil {
+		if isForbidden(err) {
+			logger.Info("Not allowed to register webhook", "zone", zone, "error", err)
+			w.WriteHeader(http.StatusForbidden)
+		} else {
+			logger.Error("Error looking up the tenant of the zone", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	id, err := shortid.Generate()
+	if err != nil {
+		logger.Error("Error generating webhook ID", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	webhook := RegisteredWebhook{
+		Webhook: Webhook{Id: id, Zone: string(zone), Url: body.Url, CreatedAt: time.Now().UTC()},
+		Secret:  body.Secret,
+	}
+	if err := d.registrar.CreateWebhook(r.Context(), webhook); isForbidden(err) {
+		logger.Info("Not allowed to register webhook", "zone", zone, "error", err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	} else if err != nil {
+		logger.Error("Error from registrar when creating webhook", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Registered webhook", "webhook", id, "zone", zone, "url", body.Url)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&webhook.Webhook); err != nil {
+		logger.Info("Error writing webhook response", "error", err)
+	}
+}
+
+func (d DomainAPIImpl) ListWebhooks(w http.ResponseWriter, r *http.Request, params ListWebhooksParams) {
+	logger := hclog.FromContext(r.Context())
+	if !d.authorizeWebhooks(w, r) {
+		return
+	}
+
+	registered, err := d.registrar.ListWebhooks(r.Context())
+	if err != nil {
+		logger.Error("Error from registrar when listing webhooks", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	webhooks := []Webhook{}
+	for _, webhook := range registered {
+		if params.Zone == nil || canonicalName(*params.Zone) == webhook.Zone {
+			webhooks = append(webhooks, webhook.Webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(webhooks); err != nil {
+		logger.Info("Error writing webhooks response", "error", err)
+	}
+}
+
+func (d DomainAPIImpl) DeleteWebhook(w http.ResponseWriter, r *http.Request, webhookId WebhookId) {
+	logger := hclog.FromContext(r.Context())
+	if !d.authorizeWebhooks(w, r) {
+		return
+	}
+
+	err := d.registrar.DeleteWebhook(r.Context(), string(webhookId))
+	if errors.Is(err, ErrWebhookNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		logger.Error("Error from registrar when deleting webhook", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Deleted webhook", "webhook", webhookId)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (d DomainAPIImpl) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request, webhookId WebhookId) {
+	logger := hclog.FromContext(r.Context())
+	if !d.authorizeWebhooks(w, r) {
+		return
+	}
+
+	deliveries, err := d.registrar.ListWebhookDeliveries(r.Context(), string(webhookId))
+	if errors.Is(err, ErrWebhookNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		logger.Error("Error from registrar when listing webhook deliveries", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(deliveries); err != nil {
+		logger.Info("Error writing webhook deliveries response", "error", err)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"net/http"
+	"strings"
+)
+
+// ExportZone returns the records in a zone as a zone file if the client accepts one, and as JSON otherwise.
+func (d DomainAPIImpl) ExportZone(w http.ResponseWriter, r *http.Request, zone string, params ExportZoneParams) {
+	fqdn := Domain(canonicalName(zone))
+	logger := hclog.FromContext(r.Context()).With("zone", fqdn)
+
+	var view string
+	if params.View != nil {
+		view = string(*params.View)
+	}
+	if !d.views.Exists(view) {
+		logger.Info("Unknown view", "view", view)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if _, ok := d.zones.Find(fqdn); len(d.zones) > 0 && !ok {
+		logger.Info("Zone isn't served by this server")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	records, err := exportZone(withView(r.Context(), view), d.registrar, fqdn)
+	if err != nil {
+		logger.Error("Error from registrar when exporting zone", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), zoneFileContentType) {
+		w.Header().Set("Content-Type", zoneFileContentType)
+		w.WriteHeader(http.StatusOK)
+		if err := writeZoneFile(w, fqdn, records); err != nil {
+			logger.Info("Error writing zone file", "error", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(&ZoneExport{Zone: string(fqdn), Records: records}); err != nil {
+		logger.Info("Error writing zone export", "error", err)
+	}
+}
+
+// PostZone imports a zone file without restricting which names it has records for. ReplaceZone and
+// ImportZoneRecords import zone files scoped to a single zone.
+func (d DomainAPIImpl) PostZone(w http.ResponseWriter, r *http.Request, params PostZoneParams) {
+	d.importZoneFile(w, r, "", false, params.DryRun != nil && *params.DryRun, http.StatusCreated)
+}
+
+func (d DomainAPIImpl) ReplaceZone(w http.ResponseWriter, r *http.Request, zone string, params ReplaceZoneParams) {
+	d.importZoneFile(w, r, Domain(canonicalName(zone)), true, params.DryRun != nil && *params.DryRun, http.StatusOK)
+}
+
+func (d DomainAPIImpl) ImportZoneRecords(w http.ResponseWriter, r *http.Request, zone string, params ImportZoneRecordsParams) {
+	d.importZoneFile(w, r, Domain(canonicalName(zone)), false, params.DryRun != nil && *params.DryRun, http.StatusCreated)
+}
+
+// importZoneFile imports the zone file in the request body. If zone isn't empty, every record in the file has to be at
+// or beneath it, and if replace is true the records beneath it that aren't in the file are deleted.
+func (d DomainAPIImpl) importZoneFile(w http.ResponseWriter, r *http.Request, zone Domain, replace bool, dryRun bool, status int) {
+	logger := hclog.FromContext(r.Context()).With("zone", zone, "replace", replace, "dry_run", dryRun)
+	r = r.WithContext(withAuditActor(r.Context(), httpAuditActor(r), AuditEntryProtocolZoneUpload))
+	defer r.Body.Close()
+
+	zones := d.zones
+	if zone != "" {
+		if _, ok := d.zones.Find(zone); len(d.zones) > 0 && !ok {
+			logger.Info("Zone isn't served by this server")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		zones = ZoneSet{zone}
+	}
+
+	report := newZoneImportReport(dryRun)
+	records, err := parseZoneFile(r.Body)
+	if err != nil {
+		logger.Info("Error reading uploaded zone", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	failed := zoneFileErrors(records)
+	if zone != "" {
+		failed = append(failed, zoneScopeErrors(records, zone)...)
+	}
+	if len(failed) > 0 {
+		logger.Info("Attempted to post invalid zone", "errors", len(failed))
+		report.Failed = failed
+		writeZoneImportReport(logger, w, http.StatusBadRequest, report)
+		return
+	}
+
+	sets := planZoneImport(records, zones, &report)
+	var replaced Domain
+	if replace {
+		replaced = zone
+	}
+	err = importZone(r.Context(), d.registrar, sets, replaced, &report, func(records []RecordReference) error {
+		if err := d.claimRecordQuota(r, records); errors.Is(err, ErrQuotaExceeded) || isForbidden(err) {
+			return err
+		} else if err != nil {
+			logger.Warn("Error checking record quota", "error", err)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrQuotaExceeded):
+		logger.Info("Record quota exceeded while processing uploaded zone", "error", err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case isForbidden(err):
+		logger.Info("Uploaded zone changes records in a claimed subdomain", "error", err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	case errors.Is(err, ErrRecordChanged):
+		logger.Info("Record changed while processing uploaded zone", "error", err)
+		w.WriteHeader(http.StatusConflict)
+		return
+	case err != nil:
+		logger.Error("Error from registrar when importing zone", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Finished processing uploaded zone", "created", len(report.Created), "updated", len(report.Updated), "skipped", len(report.Skipped), "failed", len(report.Failed))
+	if dryRun {
+		status = http.StatusOK
+	}
+	writeZoneImportReport(logger, w, status, report)
+}
+
+func writeZoneImportReport(logger hclog.Logger, w http.ResponseWriter, status int, report ZoneImportReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(&report); err != nil {
+		logger.Info("Error writing zone import report", "error", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"sort"
+	"time"
+)
+
+type leaseExpiryContextKey struct{}
+
+// withLeaseExpiry marks records deleted with ctx as having been deleted because their lease expired.
+func withLeaseExpiry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, leaseExpiryContextKey{}, true)
+}
+
+func leaseExpiryFromContext(ctx context.Context) bool {
+	expired, _ := ctx.Value(leaseExpiryContextKey{}).(bool)
+	return expired
+}
+
+// releaseLease deletes every record at or beneath the subdomain a lease owns, then the lease itself. The records in each
+// view are deleted together, and only if they haven't changed since they were listed, so ErrRecordChanged is returned
+// if something else is changing them at the same time.
+func releaseLease(ctx context.Context, registrar Registrar, lease SubdomainLease) error {
+	records, err := registrar.ListRecords(ctx, lease.Domain)
+	if err != nil {
+		return err
+	}
+	writes := map[string][]RecordWrite{}
+	for _, record := range records {
+		writes[record.View] = append(writes[record.View], RecordWrite{Domain: record.Domain, Type: record.Type, Value: record.Value, Delete: true})
+	}
+	views := make([]string, 0, len(writes))
+	for view := range writes {
+		views = append(views, view)
+	}
+	sort.Strings(views)
+	for _, view := range views {
+		if _, err := registrar.WriteRecords(withView(ctx, view), writes[view]); err != nil {
+			return err
+		}
+	}
+	return registrar.DeleteLease(ctx, lease)
+}
+
+// reapExpiredLeases releases every lease that has expired. Every replica does this, which is safe because the records
+// of an expired lease can't be changed except to be deleted: a replica that loses the race to delete them gets
+// ErrRecordChanged and leaves the lease for its next round, by which time it's gone.
+func reapExpiredLeases(ctx context.Context, registrar Registrar, now time.Time) {
+	logger := hclog.FromContext(ctx)
+	leases, err := registrar.ListExpiredLeases(ctx, now)
+	if err != nil {
+		logger.Error("Error listing expired leases", "error", err)
+		return
+	}
+	for _, lease := range leases {
+		leaseCtx := withLeaseExpiry(withAuditActor(ctx, "lease:"+lease.Id, AuditEntryProtocolLease))
+		err := releaseLease(leaseCtx, registrar, lease)
+		switch {
+		case errors.Is(err, ErrRecordChanged):
+			logger.Info("Records of expired lease changed while being deleted", "lease", lease.Id, "domain", lease.Domain, "error", err)
+		case err != nil:
+			logger.Error("Error releasing expired lease", "lease", lease.Id, "domain", lease.Domain, "error", err)
+		default:
+			logger.Info("Released expired lease", "lease", lease.Id, "domain", lease.Domain)
+		}
+	}
+}
+
+// runLeaseReaper reaps expired leases every interval until ctx is cancelled. registrar shouldn't be a
+// SubdomainRegistrar, since the reaper doesn't have the owner tokens of the leases.
+func runLeaseReaper(ctx context.Context, registrar Registrar, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			reapExpiredLeases(ctx, registrar, now)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestReapExpiredLeases(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryRegistrar()
+	registrar := NewAuditingRegistrar(backend, ZoneSet{"eph.example.com."})
+	now := time.Now()
+
+	expired := SubdomainLease{Id: "expired", Domain: "old.eph.example.com.", Expires: now.Add(-time.Minute)}
+	live := SubdomainLease{Id: "live", Domain: "new.eph.example.com.", Expires: now.Add(time.Minute)}
+	assert.NoError(t, registrar.ClaimSubdomain(ctx, expired))
+	assert.NoError(t, registrar.ClaimSubdomain(ctx, live))
+	assert.NoError(t, backend.SetRecord(ctx, "old.eph.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, backend.SetRecord(ctx, "www.old.eph.example.com.", RecordTypeTXT, "hello"))
+	assert.NoError(t, backend.SetRecord(withView(ctx, "internal"), "www.old.eph.example.com.", RecordTypeA, "10.0.0.1"))
+	assert.NoError(t, backend.SetRecord(ctx, "www.new.eph.example.com.", RecordTypeA, "2.2.2.2"))
+
+	reapExpiredLeases(ctx, registrar, now)
+
+	records, err := backend.ListRecords(ctx, "old.eph.example.com.")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+	_, err = backend.GetLease(ctx, "expired")
+	assert.ErrorIs(t, err, ErrLeaseNotFound)
+	assert.NoError(t, backend.ClaimSubdomain(ctx, expired), "the subdomain can be claimed again")
+
+	records, err = backend.ListRecords(ctx, "new.eph.example.com.")
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	_, err = backend.GetLease(ctx, "live")
+	assert.NoError(t, err)
+
+	entries, err := backend.ListAuditEntries(ctx, AuditQuery{Zone: "eph.example.com."})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 3) {
+		for _, entry := range entries {
+			assert.Equal(t, "lease:expired", entry.Actor)
+			assert.Equal(t, AuditEntryProtocolLease, entry.Protocol)
+			assert.Equal(t, AuditEntryActionDelete, entry.Action)
+		}
+	}
+}
+
+func TestRenewLease(t *testing.T) {
+	ctx := context.Background()
+	registrar := newMemoryRegistrar()
+	now := time.Now()
+	assert.NoError(t, registrar.ClaimSubdomain(ctx, SubdomainLease{Id: "lease", Domain: "abc.eph.example.com.", Expires: now.Add(time.Minute)}))
+
+	lease, err := registrar.RenewLease(ctx, "lease", now.Add(time.Hour), now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), lease.Expires)
+	expired, err := registrar.ListExpiredLeases(ctx, now.Add(30*time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+
+	_, err = registrar.RenewLease(ctx, "lease", now.Add(3*time.Hour), now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, ErrLeaseExpired)
+	_, err = registrar.RenewLease(ctx, "missing", now.Add(time.Hour), now)
+	assert.ErrorIs(t, err, ErrLeaseNotFound)
+}
+
+func TestReapExpiredLeases_SendsExpiredEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	receiver := newWebhookReceiver(t, "s3cret", 0)
+	defer receiver.Close()
+
+	backend := newMemoryRegistrar()
+	assert.NoError(t, backend.CreateWebhook(ctx, RegisteredWebhook{
+		Webhook: Webhook{Id: "hook", Zone: "eph.example.com.", Url: receiver.URL},
+		Secret:  "s3cret",
+	}))
+	registrar := NewWebhookRegistrar(ctx, backend, ZoneSet{"eph.example.com."}, WebhookConfig{AllowedNetworks: loopback})
+	assert.NoError(t, backend.ClaimSubdomain(ctx, SubdomainLease{Id: "lease", Domain: "abc.eph.example.com.", Expires: time.Now().Add(-time.Minute)}))
+	assert.NoError(t, backend.SetRecord(ctx, "www.abc.eph.example.com.", RecordTypeA, "1.1.1.1"))
+
+	reapExpiredLeases(ctx, registrar, time.Now())
+
+	assert.Eventually(t, func() bool { return len(receiver.received()) == 1 }, time.Second, time.Millisecond)
+	event := receiver.received()[0]
+	assert.Equal(t, WebhookEventTypeExpired, event.Type)
+	assert.Equal(t, "www.abc.eph.example.com.", event.Domain)
+	assert.Equal(t, "1.1.1.1", *event.Value)
+}
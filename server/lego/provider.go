@@ -0,0 +1,118 @@
+// Package lego implements a lego DNS provider that solves DNS-01 challenges through the Ephemerain HTTP API, for
+// networks where RFC 2136 updates can't be used or need authentication.
+package lego
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	legochallenge "github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/platform/config/env"
+	"net/http"
+	"server/challenge"
+	"server/client"
+	"time"
+)
+
+// Environment variable names.
+const (
+	envNamespace = "EPHEMERAIN_"
+
+	// EnvEndpoint is the base URL of the API, including the /v1 prefix, e.g. http://ephemerain/v1.
+	EnvEndpoint = envNamespace + "ENDPOINT"
+	// EnvToken is sent as a bearer token with every request. It is optional.
+	EnvToken = envNamespace + "TOKEN"
+
+	EnvTTL                = envNamespace + "TTL"
+	EnvPropagationTimeout = envNamespace + "PROPAGATION_TIMEOUT"
+	EnvPollingInterval    = envNamespace + "POLLING_INTERVAL"
+	EnvHTTPTimeout        = envNamespace + "HTTP_TIMEOUT"
+)
+
+// Config is used to configure the creation of the DNSProvider.
+type Config struct {
+	Endpoint           string
+	Token              string
+	TTL                int
+	PropagationTimeout time.Duration
+	PollingInterval    time.Duration
+	HTTPClient         *http.Client
+}
+
+// NewDefaultConfig returns a default configuration for the DNSProvider.
+func NewDefaultConfig() *Config {
+	return &Config{
+		TTL:                env.GetOrDefaultInt(EnvTTL, 60),
+		PropagationTimeout: env.GetOrDefaultSecond(EnvPropagationTimeout, dns01.DefaultPropagationTimeout),
+		PollingInterval:    env.GetOrDefaultSecond(EnvPollingInterval, dns01.DefaultPollingInterval),
+		HTTPClient:         &http.Client{Timeout: env.GetOrDefaultSecond(EnvHTTPTimeout, 30*time.Second)},
+	}
+}
+
+var _ legochallenge.ProviderTimeout = (*DNSProvider)(nil)
+
+// DNSProvider implements lego's challenge.Provider and challenge.ProviderTimeout interfaces.
+type DNSProvider struct {
+	config *Config
+	solver *challenge.Solver
+}
+
+// NewDNSProvider returns a DNSProvider configured from the environment.
+func NewDNSProvider() (*DNSProvider, error) {
+	values, err := env.Get(EnvEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("ephemerain: %w", err)
+	}
+
+	config := NewDefaultConfig()
+	config.Endpoint = values[EnvEndpoint]
+	config.Token = env.GetOrFile(EnvToken)
+	return NewDNSProviderConfig(config)
+}
+
+// NewDNSProviderConfig returns a DNSProvider with the given configuration.
+func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
+	if config == nil {
+		return nil, errors.New("ephemerain: the configuration of the DNS provider is nil")
+	}
+	if config.Endpoint == "" {
+		return nil, errors.New("ephemerain: the endpoint is missing")
+	}
+
+	options := []client.ClientOption{}
+	if config.HTTPClient != nil {
+		options = append(options, client.WithHTTPClient(config.HTTPClient))
+	}
+	if config.Token != "" {
+		options = append(options, client.WithBearerToken(config.Token))
+	}
+	apiClient, err := client.NewClientWithResponses(config.Endpoint, options...)
+	if err != nil {
+		return nil, fmt.Errorf("ephemerain: %w", err)
+	}
+	return &DNSProvider{config: config, solver: challenge.NewSolver(apiClient, uint32(config.TTL))}, nil
+}
+
+// Timeout returns the timeout and interval to use when checking for DNS propagation.
+func (d *DNSProvider) Timeout() (timeout, interval time.Duration) {
+	return d.config.PropagationTimeout, d.config.PollingInterval
+}
+
+// Present adds the challenge value to the TXT record, keeping any other values it has.
+func (d *DNSProvider) Present(domain, token, keyAuth string) error {
+	fqdn, value := dns01.GetRecord(domain, keyAuth)
+	if err := d.solver.Present(context.Background(), fqdn, value); err != nil {
+		return fmt.Errorf("ephemerain: %w", err)
+	}
+	return nil
+}
+
+// CleanUp removes the challenge value from the TXT record, deleting the record if it was the only value.
+func (d *DNSProvider) CleanUp(domain, token, keyAuth string) error {
+	fqdn, value := dns01.GetRecord(domain, keyAuth)
+	if err := d.solver.CleanUp(context.Background(), fqdn, value); err != nil {
+		return fmt.Errorf("ephemerain: %w", err)
+	}
+	return nil
+}
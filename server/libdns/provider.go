@@ -0,0 +1,223 @@
+// Package libdns implements the libdns interfaces with the Ephemerain HTTP API, so that Caddy and certmagic can solve
+// DNS-01 challenges in Ephemerain zones.
+package libdns
+
+import (
+	"context"
+	"fmt"
+	"github.com/libdns/libdns"
+	"server/challenge"
+	"server/client"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTTL is the TTL of records that are created without one, which is the same as the server's default.
+const defaultTTL = 60 * time.Second
+
+var (
+	_ libdns.RecordGetter   = (*Provider)(nil)
+	_ libdns.RecordAppender = (*Provider)(nil)
+	_ libdns.RecordSetter   = (*Provider)(nil)
+	_ libdns.RecordDeleter  = (*Provider)(nil)
+)
+
+// Provider manages the records of Ephemerain zones. The API stores every record of a name and type as a single record
+// set, so records with the same name and type share a TTL.
+type Provider struct {
+	// Endpoint is the base URL of the API, including the /v1 prefix, e.g. http://ephemerain/v1.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Token is sent as a bearer token with every request. It is optional.
+	Token string `json:"token,omitempty"`
+
+	once      sync.Once
+	client    *client.ClientWithResponses
+	records   *challenge.RecordSets
+	clientErr error
+}
+
+func (p *Provider) getClient() (*client.ClientWithResponses, error) {
+	p.once.Do(func() {
+		var options []client.ClientOption
+		if p.Token != "" {
+			options = append(options, client.WithBearerToken(p.Token))
+		}
+		p.client, p.clientErr = client.NewClientWithResponses(p.Endpoint, options...)
+		if p.clientErr == nil {
+			p.records = challenge.NewRecordSets(p.client, uint32(defaultTTL/time.Second))
+		}
+	})
+	return p.client, p.clientErr
+}
+
+func (p *Provider) getRecordSets() (*challenge.RecordSets, error) {
+	if _, err := p.getClient(); err != nil {
+		return nil, err
+	}
+	return p.records, nil
+}
+
+// GetRecords returns every record in the zone, including the SOA and NS records the server generates at its apex.
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	apiClient, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+	response, err := apiClient.ExportZoneWithResponse(ctx, zone, &client.ExportZoneParams{})
+	if err != nil {
+		return nil, fmt.Errorf("exporting zone %s: %w", zone, err)
+	}
+	if response.JSON200 == nil {
+		return nil, fmt.Errorf("exporting zone %s: unexpected status %s", zone, response.Status())
+	}
+
+	var records []libdns.Record
+	for _, set := range response.JSON200.Records {
+		for _, value := range set.Values {
+			records = append(records, fromValue(libdns.RelativeName(set.Domain, canonicalZone(zone)), set.Type, value, set.Ttl))
+		}
+	}
+	return records, nil
+}
+
+// AppendRecords adds the records to the record sets of their name and type, keeping the values and TTL those already
+// have.
+func (p *Provider) AppendRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	return p.update(ctx, zone, recs, func(set *challenge.RecordSet, recs []libdns.Record) []libdns.Record {
+		for _, rec := range recs {
+			if value := toValue(rec); !set.Contains(value) {
+				set.Values = append(set.Values, value)
+			}
+		}
+		if !set.Existed {
+			set.TTL = ttlOf(recs)
+		}
+		return recs
+	})
+}
+
+// SetRecords replaces the record sets of the records' names and types with the records.
+func (p *Provider) SetRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	return p.update(ctx, zone, recs, func(set *challenge.RecordSet, recs []libdns.Record) []libdns.Record {
+		set.Values = nil
+		for _, rec := range recs {
+			if value := toValue(rec); !set.Contains(value) {
+				set.Values = append(set.Values, value)
+			}
+		}
+		set.TTL = ttlOf(recs)
+		return recs
+	})
+}
+
+// DeleteRecords removes the records from the record sets of their names and types. A record without a value removes
+// every value of its name and type. Only the records that matched a value are returned.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	return p.update(ctx, zone, recs, func(set *challenge.RecordSet, recs []libdns.Record) []libdns.Record {
+		var deleted []libdns.Record
+		for _, rec := range recs {
+			for _, value := range set.Values {
+				if rec.Value == "" || toValue(rec) == value {
+					deleted = append(deleted, rec)
+					break
+				}
+			}
+		}
+		remaining := []string{}
+		for _, value := range set.Values {
+			matched := false
+			for _, rec := range deleted {
+				matched = matched || rec.Value == "" || toValue(rec) == value
+			}
+			if !matched {
+				remaining = append(remaining, value)
+			}
+		}
+		set.Values = remaining
+		return deleted
+	})
+}
+
+// update changes the record sets of the records' names and types with change, which is called once per record set
+// with the records of that set and returns the records it changed. The record set is deleted if no values are left.
+// It returns every record that was changed.
+func (p *Provider) update(ctx context.Context, zone string, recs []libdns.Record, change func(*challenge.RecordSet, []libdns.Record) []libdns.Record) ([]libdns.Record, error) {
+	records, err := p.getRecordSets()
+	if err != nil {
+		return nil, err
+	}
+
+	// Records are grouped by name and type, keeping the order they were first seen in
+	type setKey struct {
+		fqdn       string
+		recordType client.RecordType
+	}
+	var keys []setKey
+	groups := map[setKey][]libdns.Record{}
+	for _, rec := range recs {
+		key := setKey{fqdn: libdns.AbsoluteName(rec.Name, canonicalZone(zone)), recordType: client.RecordType(rec.Type)}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], rec)
+	}
+
+	var changed []libdns.Record
+	for _, key := range keys {
+		var changedInSet []libdns.Record
+		err := records.Update(ctx, key.fqdn, key.recordType, func(set *challenge.RecordSet) bool {
+			changedInSet = change(set, groups[key])
+			return len(changedInSet) > 0
+		})
+		if err != nil {
+			return changed, err
+		}
+		changed = append(changed, changedInSet...)
+	}
+	return changed, nil
+}
+
+// canonicalZone returns zone with a trailing dot, which libdns callers don't always include.
+func canonicalZone(zone string) string {
+	if !strings.HasSuffix(zone, ".") {
+		return zone + "."
+	}
+	return zone
+}
+
+// ttlOf returns the TTL in seconds of the first record that has one, or the default TTL.
+func ttlOf(recs []libdns.Record) uint32 {
+	for _, rec := range recs {
+		if rec.TTL > 0 {
+			return uint32(rec.TTL / time.Second)
+		}
+	}
+	return uint32(defaultTTL / time.Second)
+}
+
+// toValue returns the value the API stores rec with. libdns keeps the priority of MX and SRV records apart from the
+// rest of the value, but the API stores them in presentation format.
+func toValue(rec libdns.Record) string {
+	if rec.Type == "MX" || rec.Type == "SRV" {
+		return fmt.Sprintf("%d %s", rec.Priority, rec.Value)
+	}
+	return rec.Value
+}
+
+// fromValue is the inverse of toValue.
+func fromValue(name string, recordType string, value string, ttl uint32) libdns.Record {
+	rec := libdns.Record{Type: recordType, Name: name, Value: value, TTL: time.Duration(ttl) * time.Second}
+	if recordType == "MX" || recordType == "SRV" {
+		if fields := strings.SplitN(value, " ", 2); len(fields) == 2 {
+			if priority, err := strconv.Atoi(fields[0]); err == nil {
+				rec.Priority, rec.Value = priority, fields[1]
+			}
+		}
+	}
+	if rec.Name == "" {
+		rec.Name = "@"
+	}
+	return rec
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/hex"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ipv4AddressRegex matches four numbers separated by dots, dashes or any other non-digit at the end of the name,
+	// such as 10.20.30.40, 10-20-30-40 or myapp-10-20-30-40.
+	ipv4AddressRegex = regexp.MustCompile(`(?:^|\D)(\d{1,3})\D(\d{1,3})\D(\d{1,3})\D(\d{1,3})$`)
+	// hexIPv4AddressRegex matches a hex encoded IPv4 address at the end of the name, such as 0a141e28 or
+	// myapp-0a141e28.
+	hexIPv4AddressRegex = regexp.MustCompile(`(?:^|[.-])([0-9a-fA-F]{8})$`)
+)
+
+// parseMagicIP extracts the IP address encoded in a nip.io/sslip.io style name in zone, such as
+// 10.20.30.40.ip.example.com. (dotted IPv4), myapp-10-20-30-40.ip.example.com. (dashed IPv4 with a label prefix),
+// 0a141e28.ip.example.com. (hex IPv4) or 2001-db8--1.ip.example.com. (dashed IPv6, with -- standing in for ::).
+func parseMagicIP(dom Domain, zone Domain) (net.IP, bool) {
+	suffix := ".ip." + canonicalName(string(zone))
+	name := canonicalName(string(dom))
+	if !strings.HasSuffix(name, suffix) || len(name) == len(suffix) {
+		return nil, false
+	}
+	address := strings.TrimSuffix(name, suffix)
+
+	labels := strings.Split(address, ".")
+	lastLabel := labels[len(labels)-1]
+
+	// A label that is entirely an IPv6 address takes precedence, since 1-2-3-4-5-6-7-8 would otherwise be read as the
+	// IPv4 address 5.6.7.8
+	if ip := parseDashedIPv6(lastLabel); ip != nil {
+		return ip, true
+	}
+
+	if submatch := ipv4AddressRegex.FindStringSubmatch(address); submatch != nil {
+		if ip := net.ParseIP(strings.Join(submatch[1:], ".")); ip != nil {
+			return ip.To4(), true
+		}
+	}
+
+	if submatch := hexIPv4AddressRegex.FindStringSubmatch(address); submatch != nil {
+		decoded, err := hex.DecodeString(submatch[1])
+		if err == nil {
+			return net.IP(decoded), true
+		}
+	}
+
+	// Finally, allow IPv6 addresses with a prefix, like myapp-2001-db8--1, by stripping one dash separated component
+	// at a time
+	for idx := strings.Index(lastLabel, "-"); idx != -1; idx = strings.Index(lastLabel, "-") {
+		lastLabel = lastLabel[idx+1:]
+		if ip := parseDashedIPv6(lastLabel); ip != nil {
+			return ip, true
+		}
+	}
+
+	return nil, false
+}
+
+func parseDashedIPv6(label string) net.IP {
+	if strings.Count(label, "-") < 2 {
+		return nil
+	}
+	ip := net.ParseIP(strings.ReplaceAll(label, "-", ":"))
+	if ip == nil || ip.To4() != nil {
+		return nil
+	}
+	return ip
+}
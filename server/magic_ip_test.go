@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestParseMagicIP(t *testing.T) {
+	for name, expected := range map[Domain]string{
+		"10.20.30.40.ip.example.com.":              "10.20.30.40",
+		"10-20-30-40.ip.example.com.":              "10.20.30.40",
+		"myapp-10-0-0-1.ip.example.com.":           "10.0.0.1",
+		"myapp.10.0.0.1.ip.example.com.":           "10.0.0.1",
+		"app2.10.0.0.1.ip.example.com.":            "10.0.0.1",
+		"0a141e28.ip.example.com.":                 "10.20.30.40",
+		"myapp-0A141E28.ip.Example.com.":           "10.20.30.40",
+		"2001-db8--1.ip.example.com.":              "2001:db8::1",
+		"--1.ip.example.com.":                      "::1",
+		"myapp-2001-db8--1.ip.example.com.":        "2001:db8::1",
+		"www.2001-db8-0-0-0-0-0-1.ip.example.com.": "2001:db8::1",
+		"1-2-3-4-5-6-7-8.ip.example.com.":          "1:2:3:4:5:6:7:8",
+	} {
+		ip, ok := parseMagicIP(name, "example.com.")
+		assert.True(t, ok, "%s should be a magic IP name", name)
+		assert.Equal(t, net.ParseIP(expected).String(), ip.String(), "wrong IP for %s", name)
+	}
+
+	for _, name := range []Domain{
+		"ip.example.com.",
+		"myapp.ip.example.com.",
+		"10.20.30.ip.example.com.",
+		"300.20.30.40.ip.example.com.",
+		"10.20.30.40.example.com.",
+		"10.20.30.40.ip.sub.example.com.",
+		"10.20.30.40.ip.notexample.com.",
+	} {
+		_, ok := parseMagicIP(name, "example.com.")
+		assert.False(t, ok, "%s should not be a magic IP name", name)
+	}
+}
+
+func TestMagicIPQuery_NODATAForWrongAddressFamily(t *testing.T) {
+	handler := handleIPQuery(newMemoryRegistrar(), NewSynthesizerRegistry(), nil, nil)
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		w := &recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP("127.0.0.1")}}
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+		handler(w, r)
+		assert.Len(t, w.messages, 1)
+		return w.messages[0]
+	}
+
+	response := query("2001-db8--1.ip.example.com.", dns.TypeAAAA)
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.Equal(t, "2001:db8::1", response.Answer[0].(*dns.AAAA).AAAA.String())
+
+	response = query("2001-db8--1.ip.example.com.", dns.TypeA)
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.Empty(t, response.Answer)
+	assert.Equal(t, "example.com.", response.Ns[0].(*dns.SOA).Hdr.Name)
+
+	response = query("10-0-0-1.ip.example.com.", dns.TypeAAAA)
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.Empty(t, response.Answer)
+	assert.Len(t, response.Ns, 1)
+}
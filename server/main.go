@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"flag"
 	"fmt"
 	"github.com/go-chi/chi/v5"
@@ -13,12 +15,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 )
 
-func serveDNS(ctx context.Context, packetConn net.PacketConn) error {
+// serveDNS serves DNS over UDP on packetConn, and over TCP on listener if it isn't nil. Clients retry truncated UDP
+// answers over TCP, including the truncated replies response rate limiting slips.
+func serveDNS(ctx context.Context, packetConn net.PacketConn, listener net.Listener, rateLimit ResponseRateLimitConfig, tsigSecrets map[string]string) error {
 	logger := hclog.FromContext(ctx)
 
 	// Same as the default accept function, but allows update messages
@@ -45,26 +50,54 @@ func serveDNS(ctx context.Context, packetConn net.PacketConn) error {
 		return dns.MsgAccept
 	}
 
-	server := &dns.Server{PacketConn: packetConn, TsigSecret: nil, ReusePort: false, MsgAcceptFunc: acceptFunc}
-	go func() {
-		<-ctx.Done()
-		logger.Info("Shutting down DNS server")
-		if err := server.ShutdownContext(ctx); err != nil && err != context.Canceled {
-			logger.Error("Error shutting down DNS server", "error", err)
-		}
-	}()
+	var handler dns.Handler = dns.DefaultServeMux
+	if rateLimit.enabled() {
+		handler = newResponseRateLimiter(rateLimit).Handler(handler)
+	}
+
+	// The server only verifies TSIG signatures if it has a non-nil map of secrets. Otherwise every signature is
+	// reported as valid.
+	if tsigSecrets == nil {
+		tsigSecrets = map[string]string{}
+	}
+	servers := []*dns.Server{{PacketConn: packetConn, Handler: handler, TsigSecret: tsigSecrets, ReusePort: false, MsgAcceptFunc: acceptFunc}}
+	if listener != nil {
+		servers = append(servers, &dns.Server{Listener: listener, Handler: handler, TsigSecret: tsigSecrets, ReusePort: false, MsgAcceptFunc: acceptFunc})
+	}
+
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		server := server
+		go func() {
+			<-ctx.Done()
+			logger.Info("Shutting down DNS server")
+			if err := server.ShutdownContext(ctx); err != nil && err != context.Canceled {
+				logger.Error("Error shutting down DNS server", "error", err)
+			}
+		}()
+		go func() {
+			errs <- server.ActivateAndServe()
+		}()
+	}
+	return <-errs
+}
 
-	return server.ActivateAndServe()
+type requestIDContextKey struct{}
+
+// requestIDFromContext returns the ID the request logging middleware assigned to the current HTTP request.
+func requestIDFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestId
 }
 
-func serveAPI(ctx context.Context, registrar Registrar, listener net.Listener) error {
+func serveAPI(ctx context.Context, registrar Registrar, tenants *TenantDirectory, config EphemerainConfig) error {
 	r := chi.NewRouter()
 
-	// TODO: Ratelimiting
 	r.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			requestId, _ := shortid.Generate()
 			logger := hclog.FromContext(r.Context()).With("request_id", requestId)
+			ctx := context.WithValue(hclog.WithContext(r.Context(), logger), requestIDContextKey{}, requestId)
 
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
@@ -73,14 +106,38 @@ func serveAPI(ctx context.Context, registrar Registrar, listener net.Listener) e
 				logger.Info("HTTP Request", "requestMethod", r.Method, "requestUrl", r.URL.String(), "status", ww.Status(), "latency", time.Since(t1).Seconds(), "protocol", r.Proto)
 			}()
 
-			next.ServeHTTP(ww, r.WithContext(hclog.WithContext(r.Context(), logger)))
+			next.ServeHTTP(ww, r.WithContext(ctx))
 		})
 	})
+	r.Use(clientIPMiddleware(config.TrustedProxies))
+	r.Use(rateLimitMiddleware(registrar, config.HTTPRateLimit))
+	r.Use(auditActorMiddleware)
+	r.Use(apiTokenMiddleware)
 
-	api := DomainAPIImpl{registrar: registrar}
+	api := DomainAPIImpl{
+		registrar:   registrar,
+		zones:       config.Zones,
+		quotas:      config.RecordQuotas,
+		views:       config.Views,
+		subdomains:  config.Subdomains,
+		tenants:     tenants,
+		tsigSecrets: config.TSIGSecrets,
+		adminToken:  config.AdminToken,
+	}
 	r.Mount("/v1", Handler(&api))
+	if config.ACMEDNSZone != "" {
+		r.Mount("/acme-dns", newACMEDNSHandler(registrar, config.ACMEDNSZone))
+	}
+	r.Mount("/external-dns", newExternalDNSHandler(api))
+	// The metrics describe the process and its clients, so only the admin can read them
+	r.Handle("/debug/vars", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if api.authorizeAdmin(w, r) {
+			expvar.Handler().ServeHTTP(w, r)
+		}
+	}))
 
-	server := http.Server{Handler: r}
+	// Requests inherit ctx so that long-lived watch streams end when the server is shut down
+	server := http.Server{Handler: r, BaseContext: func(net.Listener) context.Context { return ctx }}
 
 	go func() {
 		<-ctx.Done()
@@ -91,14 +148,54 @@ func serveAPI(ctx context.Context, registrar Registrar, listener net.Listener) e
 		}
 	}()
 
-	return server.Serve(listener)
+	return server.Serve(config.HTTPListener)
 }
 
 type EphemerainConfig struct {
 	JSONLogs     bool
 	RedisAddress string
 	DNSListener  net.PacketConn
-	HTTPListener net.Listener
+	// DNSTCPListener serves DNS over TCP, which clients fall back to when UDP answers are truncated. It is optional.
+	DNSTCPListener net.Listener
+	HTTPListener   net.Listener
+	// DNSRateLimit configures response rate limiting for the DNS listener. The zero value disables it.
+	DNSRateLimit ResponseRateLimitConfig
+	// HTTPRateLimit configures per client request limits for the HTTP API. The zero value disables them.
+	HTTPRateLimit HTTPRateLimitConfig
+	// TrustedProxies are the networks of proxies in front of the HTTP API, such as a load balancer. Requests from them
+	// are attributed to the client in their X-Forwarded-For header.
+	TrustedProxies []*net.IPNet
+	// RecordQuotas limits how many records can be created through the HTTP API. The zero value disables them.
+	RecordQuotas RecordQuotaConfig
+	// Zones is the list of zones the server is authoritative for. It may be empty, in which case every name is
+	// considered to be in the zone made up of its last two labels.
+	Zones ZoneSet
+	// Synthesizers configures which synthetic answers are enabled in which zone. If it is empty, the IP echo and whoami
+	// synthesizers are enabled for every zone.
+	Synthesizers SynthesizerConfig
+	// Views configures split-horizon views. If it is empty, every client sees the default view.
+	Views ViewConfig
+	// ClientSubnetResolvers are the networks of recursive resolvers whose EDNS Client Subnet options are trusted to
+	// pick the view of a query. Queries from anywhere else are matched by their own address.
+	ClientSubnetResolvers []*net.IPNet
+	// Forwarding configures relaying queries outside of Zones to upstream resolvers. The zero value disables it.
+	Forwarding ForwardingConfig
+	// RecordCacheSize is the number of names to keep in the in-process record cache. Zero disables the cache.
+	RecordCacheSize int
+	// Webhooks configures how record changes are delivered to registered webhooks.
+	Webhooks WebhookConfig
+	// TSIGSecrets maps TSIG key names to base64 encoded secrets. Updates signed with one of these keys are attributed
+	// to the key in the audit log, and updates signed with any other key are refused.
+	TSIGSecrets map[string]string
+	// Subdomains configures the ephemeral subdomains that can be claimed through the API. The zero value disables
+	// claiming them.
+	Subdomains SubdomainConfig
+	// ACMEDNSZone is the zone that the acme-dns compatible API creates challenge records in. The API is disabled if it
+	// is empty.
+	ACMEDNSZone Domain
+	// AdminToken is the API token that can create and list tenants and read the metrics at /debug/vars. The tenant
+	// APIs and metrics are disabled if it is empty.
+	AdminToken string
 }
 
 func runServer(ctx context.Context, config EphemerainConfig) {
@@ -111,18 +208,49 @@ func runServer(ctx context.Context, config EphemerainConfig) {
 
 	hclog.L().Info(fmt.Sprintf("Using redis address %s", config.RedisAddress))
 
-	registrar := NewRedisRegistrar(config.RedisAddress)
+	var registrar Registrar = NewRedisRegistrar(config.RedisAddress)
+	if config.RecordCacheSize > 0 {
+		registrar = NewCachingRegistrar(ctx, registrar, config.RecordCacheSize)
+	}
+	tenants := NewTenantDirectory(ctx, registrar)
+	registrar = NewAuditingRegistrar(registrar, config.Zones)
+	registrar = NewWebhookRegistrar(ctx, registrar, config.Zones, config.Webhooks)
+	registrar = NewTenantRegistrar(registrar, tenants)
+	if config.Subdomains.Zone != "" {
+		reapInterval := config.Subdomains.ReapInterval
+		if reapInterval == 0 {
+			reapInterval = time.Minute
+		}
+		go runLeaseReaper(ctx, registrar, reapInterval)
+		registrar = NewSubdomainRegistrar(registrar, config.Subdomains.Zone)
+	}
+
+	synthesizers, err := NewSynthesizerRegistryFromConfig(config.Synthesizers, config.Zones)
+	if err != nil {
+		hclog.L().Error("Invalid synthesizer configuration", "error", err)
+		panic(err)
+	}
 
-	dns.HandleFunc(".", handleIPQuery(registrar))
+	var handler dns.Handler = dns.HandlerFunc(handleIPQuery(registrar, synthesizers, config.Views, config.ClientSubnetResolvers))
+	if config.Forwarding.enabled() {
+		if len(config.Zones) == 0 {
+			err := errors.New("forwarding requires the authoritative zones to be configured")
+			hclog.L().Error("Invalid forwarding configuration", "error", err)
+			panic(err)
+		}
+		hclog.L().Info("Forwarding queries outside of the authoritative zones", "upstreams", config.Forwarding.Upstreams)
+		handler = newForwarder(config.Forwarding).Handler(config.Zones, handler)
+	}
+	dns.Handle(".", handler)
 	go func() {
-		err := serveDNS(ctx, config.DNSListener)
+		err := serveDNS(ctx, config.DNSListener, config.DNSTCPListener, config.DNSRateLimit, config.TSIGSecrets)
 		if err != nil {
 			hclog.L().Error("Error starting DNS server", "error", err)
 			panic(err)
 		}
 	}()
 	go func() {
-		err := serveAPI(ctx, registrar, config.HTTPListener)
+		err := serveAPI(ctx, registrar, tenants, config)
 		if err != nil && err != http.ErrServerClosed {
 			hclog.L().Error("Error starting API server", "error", err)
 			panic(err)
@@ -130,11 +258,130 @@ func runServer(ctx context.Context, config EphemerainConfig) {
 	}()
 }
 
+func lookupEnvFloat(name string, defaultValue float64) float64 {
+	raw, set := os.LookupEnv(name)
+	if !set {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		hclog.L().Error("Invalid value for environment variable", "name", name, "value", raw, "error", err)
+		panic(err)
+	}
+	return value
+}
+
+func lookupEnvInt(name string, defaultValue int) int {
+	raw, set := os.LookupEnv(name)
+	if !set {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		hclog.L().Error("Invalid value for environment variable", "name", name, "value", raw, "error", err)
+		panic(err)
+	}
+	return value
+}
+
+// ParseNetworks parses a comma separated list of networks in CIDR notation, such as the TRUSTED_PROXIES environment
+// variable.
+func ParseNetworks(raw string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(raw, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network: %w", err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func networksContain(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func main() {
 	redisAddress, redisAddressSet := os.LookupEnv("REDIS_ADDRESS")
 	if !redisAddressSet {
 		redisAddress = "localhost:6379"
 	}
+
+	dnsRateLimit := DefaultResponseRateLimitConfig()
+	dnsRateLimit.ResponsesPerSecond = lookupEnvFloat("DNS_RRL_RESPONSES_PER_SECOND", dnsRateLimit.ResponsesPerSecond)
+	dnsRateLimit.NXDomainsPerSecond = lookupEnvFloat("DNS_RRL_NXDOMAINS_PER_SECOND", dnsRateLimit.NXDomainsPerSecond)
+	dnsRateLimit.ErrorsPerSecond = lookupEnvFloat("DNS_RRL_ERRORS_PER_SECOND", dnsRateLimit.ErrorsPerSecond)
+	dnsRateLimit.Slip = lookupEnvInt("DNS_RRL_SLIP", dnsRateLimit.Slip)
+
+	httpRateLimit := HTTPRateLimitConfig{
+		RequestsPerIP:    int64(lookupEnvInt("HTTP_RATE_LIMIT_PER_IP", 600)),
+		RequestsPerToken: int64(lookupEnvInt("HTTP_RATE_LIMIT_PER_TOKEN", 1200)),
+		Window:           time.Duration(lookupEnvInt("HTTP_RATE_LIMIT_WINDOW_SECONDS", 60)) * time.Second,
+	}
+	recordQuotas := RecordQuotaConfig{
+		RecordsPerToken: int64(lookupEnvInt("RECORD_QUOTA_PER_TOKEN", 0)),
+		RecordsPerZone:  int64(lookupEnvInt("RECORD_QUOTA_PER_ZONE", 0)),
+	}
+
+	synthesizers, err := ParseSynthesizerConfig(os.Getenv("SYNTHESIZERS"))
+	if err != nil {
+		hclog.L().Error("Error parsing SYNTHESIZERS", "error", err)
+		panic(err)
+	}
+
+	tsigSecrets, err := ParseTSIGSecrets(os.Getenv("TSIG_SECRETS"))
+	if err != nil {
+		hclog.L().Error("Error parsing TSIG_SECRETS", "error", err)
+		panic(err)
+	}
+
+	trustedProxies, err := ParseNetworks(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		hclog.L().Error("Error parsing TRUSTED_PROXIES", "error", err)
+		panic(err)
+	}
+
+	views, err := ParseViewConfig(os.Getenv("VIEWS"))
+	if err != nil {
+		hclog.L().Error("Error parsing VIEWS", "error", err)
+		panic(err)
+	}
+	clientSubnetResolvers, err := ParseNetworks(os.Getenv("CLIENT_SUBNET_RESOLVERS"))
+	if err != nil {
+		hclog.L().Error("Error parsing CLIENT_SUBNET_RESOLVERS", "error", err)
+		panic(err)
+	}
+	webhookNetworks, err := ParseNetworks(os.Getenv("WEBHOOK_ALLOWED_NETWORKS"))
+	if err != nil {
+		hclog.L().Error("Error parsing WEBHOOK_ALLOWED_NETWORKS", "error", err)
+		panic(err)
+	}
+
+	forwarding, err := ParseForwardingConfig(os.Getenv("FORWARD_UPSTREAMS"), os.Getenv("FORWARD_ALLOWED_NETWORKS"))
+	if err != nil {
+		hclog.L().Error("Error parsing forwarding configuration", "error", err)
+		panic(err)
+	}
+
+	var subdomainZone Domain
+	if raw := os.Getenv("SUBDOMAIN_ZONE"); raw != "" {
+		subdomainZone = Domain(canonicalName(raw))
+	}
+
+	var acmeDNSZone Domain
+	if raw := os.Getenv("ACME_DNS_ZONE"); raw != "" {
+		acmeDNSZone = Domain(canonicalName(raw))
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	dnsListener, err := net.ListenPacket("udp", "[::]:53")
@@ -143,6 +390,12 @@ func main() {
 		panic(err)
 	}
 
+	dnsTCPListener, err := net.Listen("tcp", "[::]:53")
+	if err != nil {
+		hclog.L().Error("Error starting DNS TCP listener", "error", err)
+		panic(err)
+	}
+
 	httpListener, err := net.Listen("tcp", ":80")
 	if err != nil {
 		hclog.L().Error("Error starting HTTP listener", "error", err)
@@ -150,10 +403,35 @@ func main() {
 	}
 
 	runServer(ctx, EphemerainConfig{
-		JSONLogs:     strings.ToLower(os.Getenv("LOG_FORMAT")) == "json",
-		RedisAddress: redisAddress,
-		DNSListener:  dnsListener,
-		HTTPListener: httpListener,
+		JSONLogs:              strings.ToLower(os.Getenv("LOG_FORMAT")) == "json",
+		RedisAddress:          redisAddress,
+		DNSListener:           dnsListener,
+		DNSTCPListener:        dnsTCPListener,
+		HTTPListener:          httpListener,
+		DNSRateLimit:          dnsRateLimit,
+		HTTPRateLimit:         httpRateLimit,
+		TrustedProxies:        trustedProxies,
+		RecordQuotas:          recordQuotas,
+		Zones:                 ParseZoneSet(os.Getenv("ZONES")),
+		Synthesizers:          synthesizers,
+		Views:                 views,
+		ClientSubnetResolvers: clientSubnetResolvers,
+		Forwarding:            forwarding,
+		RecordCacheSize:       lookupEnvInt("RECORD_CACHE_SIZE", 10000),
+		TSIGSecrets:           tsigSecrets,
+		AdminToken:            os.Getenv("ADMIN_TOKEN"),
+		ACMEDNSZone:           acmeDNSZone,
+		Webhooks: WebhookConfig{
+			MaxAttempts:     lookupEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+			InitialBackoff:  time.Duration(lookupEnvInt("WEBHOOK_INITIAL_BACKOFF_SECONDS", 1)) * time.Second,
+			Timeout:         time.Duration(lookupEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+			AllowedNetworks: webhookNetworks,
+		},
+		Subdomains: SubdomainConfig{
+			Zone:         subdomainZone,
+			Lifetime:     time.Duration(lookupEnvInt("SUBDOMAIN_LIFETIME_SECONDS", 3600)) * time.Second,
+			ReapInterval: time.Duration(lookupEnvInt("SUBDOMAIN_REAP_INTERVAL_SECONDS", 60)) * time.Second,
+		},
 	})
 
 	sig := make(chan os.Signal, 1)
@@ -1,24 +1,36 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	_ "embed"
 	"encoding/json"
+	"fmt"
 	"github.com/go-acme/lego/v4/challenge/dns01"
 	"github.com/go-acme/lego/v4/providers/dns/rfc2136"
 	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/hc-install/product"
 	"github.com/hashicorp/hc-install/releases"
 	"github.com/hashicorp/terraform-exec/tfexec"
+	upstreamlibdns "github.com/libdns/libdns"
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"path"
+	"server/challenge"
+	"server/client"
+	"server/lego"
+	"server/libdns"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestSetARecord(t *testing.T) {
@@ -26,7 +38,7 @@ func TestSetARecord(t *testing.T) {
 		expectedHost := []string{"1.2.3.4"}
 		domain := "testingsub.testingdomain.com."
 
-		response, err := apiClient.PutDomain(ctx, Domain(domain), RecordTypeA, PutDomainJSONRequestBody{Value: &expectedHost[0]})
+		response, err := apiClient.PutDomain(ctx, Domain(domain), RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &expectedHost[0]})
 		assert.NoError(t, err, "Error setting domain")
 		assert.Equal(t, http.StatusNoContent, response.StatusCode, "Error setting domain")
 
@@ -56,9 +68,43 @@ func TestIPSubdomain(t *testing.T) {
 	})
 }
 
+func TestIPv6Subdomain(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		domain := "myapp-2001-db8--1.ip.testingdomain.com."
+
+		host, err := resolver.LookupHost(ctx, domain)
+		assert.NoError(t, err, "Error looking up host")
+		assert.Equal(t, []string{"2001:db8::1"}, host, "Incorrect response")
+	})
+}
+
+func TestWhoami(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		host, err := resolver.LookupHost(ctx, "whoami.testingdomain.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"127.0.0.1"}, host)
+
+		txt, err := resolver.LookupTXT(ctx, "whoami.testingdomain.com.")
+		assert.NoError(t, err)
+		assert.Contains(t, txt, "transport=udp")
+
+		response, err := apiClient.GetWhoami(ctx)
+		assert.NoError(t, err)
+		defer response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var whoami Whoami
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&whoami))
+		sourceHost, _, err := net.SplitHostPort(whoami.Source)
+		assert.NoError(t, err)
+		assert.True(t, net.ParseIP(sourceHost).IsLoopback(), "unexpected source %s", whoami.Source)
+		assert.Equal(t, "HTTP/1.1", whoami.Transport)
+		assert.NotEmpty(t, whoami.RequestId)
+	})
+}
+
 func TestAPI_PutDomain_400_IfBadRequest(t *testing.T) {
 	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
-		domain, err := apiClient.PutDomainWithBody(ctx, "foo.com.", RecordTypeA, "application/json", strings.NewReader("not valid json"))
+		domain, err := apiClient.PutDomainWithBody(ctx, "foo.com.", RecordTypeA, &PutDomainParams{}, "application/json", strings.NewReader("not valid json"))
 		assert.NoError(t, err, "Error getting domain")
 		assert.Equal(t, http.StatusBadRequest, domain.StatusCode)
 	})
@@ -66,7 +112,7 @@ func TestAPI_PutDomain_400_IfBadRequest(t *testing.T) {
 
 func TestAPI_GetDomain_404_IfNotFound(t *testing.T) {
 	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
-		domain, err := apiClient.GetDomain(ctx, "foo.com.", RecordTypeA)
+		domain, err := apiClient.GetDomain(ctx, "foo.com.", RecordTypeA, &GetDomainParams{})
 		assert.NoError(t, err, "Error getting domain")
 		assert.Equal(t, http.StatusNotFound, domain.StatusCode)
 	})
@@ -75,11 +121,11 @@ func TestAPI_GetDomain_404_IfNotFound(t *testing.T) {
 func TestAPI_GetDomain_200_IfFound(t *testing.T) {
 	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
 		records := "2.4.6.8"
-		putResponse, err := apiClient.PutDomain(ctx, "foo.com.", RecordTypeA, PutDomainJSONRequestBody{Value: &records})
+		putResponse, err := apiClient.PutDomain(ctx, "foo.com.", RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &records})
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusNoContent, putResponse.StatusCode)
 
-		domain, err := apiClient.GetDomain(ctx, "foo.com.", RecordTypeA)
+		domain, err := apiClient.GetDomain(ctx, "foo.com.", RecordTypeA, &GetDomainParams{})
 		assert.NoError(t, err, "Error getting domain")
 		defer domain.Body.Close()
 		assert.Equal(t, http.StatusOK, domain.StatusCode)
@@ -92,6 +138,14 @@ func TestAPI_GetDomain_200_IfFound(t *testing.T) {
 	})
 }
 
+func TestAPI_DeleteDomain_404_IfNotFound(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		domain, err := apiClient.DeleteDomain(ctx, "foo.com.", RecordTypeA, &DeleteDomainParams{})
+		assert.NoError(t, err, "Error deleting domain")
+		assert.Equal(t, http.StatusNotFound, domain.StatusCode)
+	})
+}
+
 func TestDNS_ReturnsHardcodedNS(t *testing.T) {
 	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
 		ns, err := resolver.LookupNS(ctx, "bam0.com")
@@ -132,6 +186,212 @@ func TestLegoRFC2136(t *testing.T) {
 	})
 }
 
+func TestRFC2136_TXTValues(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		values := []string{"first", "second"}
+		response, err := apiClient.PutDomain(ctx, "txt.rfc2136.com.", RecordTypeTXT, &PutDomainParams{}, PutDomainJSONRequestBody{Values: &values})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+		// Deleting one value keeps the others
+		update := new(dns.Msg)
+		update.SetUpdate("rfc2136.com.")
+		update.Remove([]dns.RR{&dns.TXT{Hdr: dns.RR_Header{Name: "txt.rfc2136.com.", Rrtype: dns.TypeTXT}, Txt: []string{"first"}}})
+		reply, _, err := new(dns.Client).Exchange(update, nameserver)
+		assert.NoError(t, err)
+		assert.Equal(t, dns.RcodeSuccess, reply.Rcode)
+		txt, err := resolver.LookupTXT(ctx, "txt.rfc2136.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"second"}, txt)
+
+		// Values are stored as their text, without the escapes of their presentation format
+		update = new(dns.Msg)
+		update.SetUpdate("rfc2136.com.")
+		escaped, err := dns.NewRR(`quoted.rfc2136.com. 60 IN TXT "say \"hi\""`)
+		assert.NoError(t, err)
+		update.Insert([]dns.RR{escaped})
+		reply, _, err = new(dns.Client).Exchange(update, nameserver)
+		assert.NoError(t, err)
+		assert.Equal(t, dns.RcodeSuccess, reply.Rcode)
+		txt, err = resolver.LookupTXT(ctx, "quoted.rfc2136.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`say "hi"`}, txt)
+		get, err := apiClient.GetDomain(ctx, "quoted.rfc2136.com.", RecordTypeTXT, &GetDomainParams{})
+		assert.NoError(t, err)
+		defer get.Body.Close()
+		var record RecordValue
+		assert.NoError(t, json.NewDecoder(get.Body).Decode(&record))
+		if assert.NotNil(t, record.Value) {
+			assert.Equal(t, `say "hi"`, *record.Value)
+		}
+
+		// Deleting the last value deletes the record
+		update = new(dns.Msg)
+		update.SetUpdate("rfc2136.com.")
+		update.Remove([]dns.RR{&dns.TXT{Hdr: dns.RR_Header{Name: "txt.rfc2136.com.", Rrtype: dns.TypeTXT}, Txt: []string{"second"}}})
+		reply, _, err = new(dns.Client).Exchange(update, nameserver)
+		assert.NoError(t, err)
+		assert.Equal(t, dns.RcodeSuccess, reply.Rcode)
+		_, err = resolver.LookupTXT(ctx, "txt.rfc2136.com")
+		assert.Error(t, err)
+	})
+}
+
+func TestLegoREST(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		domain := "rest.testing.com"
+		keyAuth := "some-key-auth"
+		// The challenges for a wildcard and its apex are presented for the same domain, so share a TXT record
+		wildcardKeyAuth := "wildcard-key-auth"
+		token := "some-token"
+
+		dnsProvider, err := lego.NewDNSProviderConfig(&lego.Config{Endpoint: apiClient.Server, TTL: 60})
+		assert.NoError(t, err)
+		err = dnsProvider.Present(domain, token, keyAuth)
+		assert.NoError(t, err)
+		err = dnsProvider.Present(domain, token, wildcardKeyAuth)
+		assert.NoError(t, err)
+
+		record, value := dns01.GetRecord(domain, keyAuth)
+		_, wildcardValue := dns01.GetRecord(domain, wildcardKeyAuth)
+
+		txt, err := resolver.LookupTXT(ctx, record)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{value, wildcardValue}, txt)
+
+		err = dnsProvider.CleanUp(domain, token, keyAuth)
+		assert.NoError(t, err)
+
+		txt, err = resolver.LookupTXT(ctx, record)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{wildcardValue}, txt)
+
+		err = dnsProvider.CleanUp(domain, token, wildcardKeyAuth)
+		assert.NoError(t, err)
+
+		txt, err = resolver.LookupTXT(ctx, record)
+		assert.Error(t, err, "Domain name should have been deleted")
+		assert.True(t, err.(*net.DNSError).IsNotFound)
+		assert.Empty(t, txt)
+	})
+}
+
+// TestChallengeSolverConformance runs the checks of cert-manager's DNS-01 webhook conformance suite against the
+// solver shared by the lego provider and the cert-manager webhook.
+func TestChallengeSolverConformance(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		restClient, err := client.NewClientWithResponses(apiClient.Server)
+		assert.NoError(t, err)
+		solver := challenge.NewSolver(restClient, 60)
+		fqdn := "_acme-challenge.cert-manager.testing.com."
+
+		// Basic: a presented record can be looked up, and is gone once it is cleaned up
+		assert.NoError(t, solver.Present(ctx, fqdn, "123d=="))
+		txt, err := resolver.LookupTXT(ctx, fqdn)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"123d=="}, txt)
+		assert.NoError(t, solver.CleanUp(ctx, fqdn, "123d=="))
+		_, err = resolver.LookupTXT(ctx, fqdn)
+		assert.True(t, err.(*net.DNSError).IsNotFound)
+
+		// Extended: presenting the same key twice is fine, and cleaning up one key keeps the others
+		assert.NoError(t, solver.Present(ctx, fqdn, "123d=="))
+		assert.NoError(t, solver.Present(ctx, fqdn, "123d=="))
+		assert.NoError(t, solver.Present(ctx, fqdn, "456e=="))
+		txt, err = resolver.LookupTXT(ctx, fqdn)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"123d==", "456e=="}, txt)
+		assert.NoError(t, solver.CleanUp(ctx, fqdn, "123d=="))
+		txt, err = resolver.LookupTXT(ctx, fqdn)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"456e=="}, txt)
+
+		// Cleaning up a key that is already gone succeeds
+		assert.NoError(t, solver.CleanUp(ctx, fqdn, "456e=="))
+		assert.NoError(t, solver.CleanUp(ctx, fqdn, "456e=="))
+		_, err = resolver.LookupTXT(ctx, fqdn)
+		assert.True(t, err.(*net.DNSError).IsNotFound)
+	})
+}
+
+// TestCertManagerWebhook runs the tests of the cert-manager webhook's Solver against the server. They read the token of
+// a tenant from a secret, so presenting challenges only works if the token is looked up and sent.
+func TestCertManagerWebhook(t *testing.T) {
+	config := EphemerainConfig{AdminToken: "admin"}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		response, err := apiClient.CreateTenant(ctx, CreateTenantJSONRequestBody{Id: "certs", Zones: []string{"certs.testing.com"}}, withBearerToken("admin"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		var tenant CreatedTenant
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&tenant))
+
+		test := exec.CommandContext(ctx, "go", "test", "-count=1", "-v", "-run", "^TestSolver$", "./solver")
+		test.Dir = "../cert-manager-webhook"
+		test.Env = append(os.Environ(),
+			"EPHEMERAIN_ENDPOINT="+apiClient.Server,
+			"EPHEMERAIN_NAMESERVER="+nameserver,
+			"EPHEMERAIN_ZONE=certs.testing.com.",
+			"EPHEMERAIN_TOKEN="+tenant.Token,
+		)
+		output, err := test.CombinedOutput()
+		assert.NoError(t, err, string(output))
+		assert.Contains(t, string(output), "--- PASS: TestSolver", "the solver tests shouldn't be skipped")
+	})
+}
+
+func TestLibdnsProvider(t *testing.T) {
+	config := EphemerainConfig{Zones: ZoneSet{"libdns.testing.com."}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		zone := "libdns.testing.com."
+		provider := &libdns.Provider{Endpoint: apiClient.Server}
+
+		// Appending keeps the values that are already there, like certmagic does for a wildcard and its apex
+		_, err := provider.AppendRecords(ctx, zone, []upstreamlibdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "one"}})
+		assert.NoError(t, err)
+		_, err = provider.AppendRecords(ctx, zone, []upstreamlibdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "two"}})
+		assert.NoError(t, err)
+		txt, err := resolver.LookupTXT(ctx, "_acme-challenge.libdns.testing.com.")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"one", "two"}, txt)
+
+		_, err = provider.SetRecords(ctx, zone, []upstreamlibdns.Record{{Type: "A", Name: "www", Value: "192.0.2.1", TTL: 5 * time.Minute}})
+		assert.NoError(t, err)
+		_, err = provider.SetRecords(ctx, zone, []upstreamlibdns.Record{{Type: "A", Name: "www", Value: "192.0.2.2", TTL: 5 * time.Minute}})
+		assert.NoError(t, err)
+		host, err := resolver.LookupHost(ctx, "www.libdns.testing.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"192.0.2.2"}, host)
+
+		_, err = provider.AppendRecords(ctx, zone, []upstreamlibdns.Record{{Type: "MX", Name: "@", Value: "mail.libdns.testing.com.", Priority: 10}})
+		assert.NoError(t, err)
+
+		records, err := provider.GetRecords(ctx, zone)
+		assert.NoError(t, err)
+		assert.Subset(t, records, []upstreamlibdns.Record{
+			{Type: "TXT", Name: "_acme-challenge", Value: "one", TTL: time.Minute},
+			{Type: "TXT", Name: "_acme-challenge", Value: "two", TTL: time.Minute},
+			{Type: "A", Name: "www", Value: "192.0.2.2", TTL: 5 * time.Minute},
+			{Type: "MX", Name: "@", Value: "mail.libdns.testing.com.", Priority: 10, TTL: time.Minute},
+		})
+
+		// Deleting removes only the given values, and the record once none are left
+		deleted, err := provider.DeleteRecords(ctx, zone, []upstreamlibdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "one"}, {Type: "TXT", Name: "_acme-challenge", Value: "three"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []upstreamlibdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "one"}}, deleted)
+		txt, err = resolver.LookupTXT(ctx, "_acme-challenge.libdns.testing.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"two"}, txt)
+		deleted, err = provider.DeleteRecords(ctx, zone, []upstreamlibdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "one"}})
+		assert.NoError(t, err)
+		assert.Empty(t, deleted)
+
+		_, err = provider.DeleteRecords(ctx, zone, []upstreamlibdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "two"}})
+		assert.NoError(t, err)
+		_, err = resolver.LookupTXT(ctx, "_acme-challenge.libdns.testing.com.")
+		assert.True(t, err.(*net.DNSError).IsNotFound)
+	})
+}
+
 //go:embed test_data/tfc2135.tf
 var tfc2135Config []byte
 
@@ -164,3 +424,865 @@ func TestTerraformRFC2135(t *testing.T) {
 		assert.Equal(t, []string{"1.2.3.4"}, addrs)
 	})
 }
+
+//go:embed test_data/ephemerain.tf
+var ephemerainTerraformConfig []byte
+
+func TestTerraformProvider(t *testing.T) {
+	config := EphemerainConfig{Subdomains: SubdomainConfig{Zone: "eph.example.com.", Lifetime: time.Hour}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		installer := releases.ExactVersion{Product: product.Terraform, Version: version.Must(version.NewVersion("1.1.6"))}
+		terraformExecPath, err := installer.Install(ctx)
+		assert.NoError(t, err)
+
+		tempDir, err := ioutil.TempDir("", "ephemerain-test")
+		defer os.RemoveAll(tempDir)
+		assert.NoError(t, err)
+
+		// The provider is built from source and used through a development override, so it doesn't need to be published
+		pluginDir := path.Join(tempDir, "plugins")
+		build := exec.CommandContext(ctx, "go", "build", "-o", path.Join(pluginDir, "terraform-provider-ephemerain"), ".")
+		build.Dir = "../terraform-provider-ephemerain"
+		output, err := build.CombinedOutput()
+		assert.NoError(t, err, string(output))
+		cliConfig := fmt.Sprintf("provider_installation {\n  dev_overrides {\n    \"mwudka/ephemerain\" = %q\n  }\n  direct {}\n}\n", pluginDir)
+		assert.NoError(t, ioutil.WriteFile(path.Join(tempDir, "terraformrc"), []byte(cliConfig), 0644))
+
+		workDir := path.Join(tempDir, "config")
+		assert.NoError(t, os.Mkdir(workDir, 0755))
+		assert.NoError(t, ioutil.WriteFile(path.Join(workDir, "ephemerain.tf"), ephemerainTerraformConfig, 0644))
+
+		terraform, err := tfexec.NewTerraform(workDir, terraformExecPath)
+		assert.NoError(t, err)
+		env := map[string]string{}
+		for _, variable := range os.Environ() {
+			if parts := strings.SplitN(variable, "=", 2); len(parts) == 2 {
+				env[parts[0]] = parts[1]
+			}
+		}
+		env["TF_CLI_CONFIG_FILE"] = path.Join(tempDir, "terraformrc")
+		assert.NoError(t, terraform.SetEnv(env))
+
+		// Overridden providers don't need terraform init
+		endpoint := tfexec.Var("endpoint=" + apiClient.Server)
+		assert.NoError(t, terraform.Apply(ctx, endpoint))
+
+		txt, err := resolver.LookupTXT(ctx, "tf.testing.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`hello "world"`}, txt)
+		addrs, err := resolver.LookupHost(ctx, "www.tf.testing.com.")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"192.0.2.1", "192.0.2.2"}, addrs)
+		cname, err := resolver.LookupCNAME(ctx, "api.records.testing.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, "www.tf.testing.com.", cname)
+
+		outputs, err := terraform.Output(ctx)
+		assert.NoError(t, err)
+		var previewDomain, leaseDomain string
+		var wwwValues []string
+		var zoneRecords int
+		assert.NoError(t, json.Unmarshal(outputs["preview_domain"].Value, &previewDomain))
+		assert.NoError(t, json.Unmarshal(outputs["preview_lease_domain"].Value, &leaseDomain))
+		assert.NoError(t, json.Unmarshal(outputs["www_values"].Value, &wwwValues))
+		assert.NoError(t, json.Unmarshal(outputs["zone_records"].Value, &zoneRecords))
+		assert.True(t, strings.HasSuffix(previewDomain, ".eph.example.com."), previewDomain)
+		assert.Equal(t, previewDomain, leaseDomain)
+		assert.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, wwwValues)
+		assert.Equal(t, 3, zoneRecords)
+		addrs, err = resolver.LookupHost(ctx, "www."+previewDomain)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"192.0.2.3"}, addrs)
+
+		// What was applied reads back the same, so there is nothing left to change
+		changed, err := terraform.Plan(ctx, endpoint)
+		assert.NoError(t, err)
+		assert.False(t, changed, "plan after apply should be empty")
+
+		assert.NoError(t, terraform.Destroy(ctx, endpoint))
+		for _, fqdn := range []string{"www.tf.testing.com.", "www." + previewDomain} {
+			_, err = resolver.LookupHost(ctx, fqdn)
+			assert.Error(t, err, "%s should be deleted", fqdn)
+		}
+		response, err := apiClient.ExportZone(ctx, "tf.testing.com.", &ExportZoneParams{})
+		assert.NoError(t, err)
+		var export ZoneExport
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&export))
+		assert.Len(t, export.Records, 2, "only the generated SOA and NS records should be left")
+	})
+}
+
+func TestEphemerainctl(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		tempDir, err := ioutil.TempDir("", "ephemerain-test")
+		defer os.RemoveAll(tempDir)
+		assert.NoError(t, err)
+
+		binary := path.Join(tempDir, "ephemerainctl")
+		build := exec.CommandContext(ctx, "go", "build", "-o", binary, ".")
+		build.Dir = "../ephemerainctl"
+		buildOutput, err := build.CombinedOutput()
+		assert.NoError(t, err, string(buildOutput))
+		ephemerainctl := func(stdin string, args ...string) (string, error) {
+			command := exec.CommandContext(ctx, binary, args...)
+			command.Env = append(os.Environ(), "EPHEMERAIN_ENDPOINT="+apiClient.Server, "EPHEMERAIN_NAMESERVER="+nameserver)
+			command.Stdin = strings.NewReader(stdin)
+			var stdout, stderr strings.Builder
+			command.Stdout, command.Stderr = &stdout, &stderr
+			if err := command.Run(); err != nil {
+				return stdout.String(), fmt.Errorf("%w: %s", err, stderr.String())
+			}
+			return stdout.String(), nil
+		}
+
+		_, err = ephemerainctl("", "record", "set", "-ttl", "300", "www.ctl.testing.com", "A", "192.0.2.1", "192.0.2.2")
+		assert.NoError(t, err)
+		output, err := ephemerainctl("", "-o", "json", "record", "get", "www.ctl.testing.com", "A")
+		assert.NoError(t, err)
+		var record ZoneExportRecord
+		assert.NoError(t, json.Unmarshal([]byte(output), &record))
+		assert.Equal(t, ZoneExportRecord{Domain: "www.ctl.testing.com.", Type: "A", Ttl: 300, Values: []string{"192.0.2.1", "192.0.2.2"}}, record)
+
+		output, err = ephemerainctl("$ORIGIN ctl.testing.com.\n@ 60 IN TXT \"hello world\"\n@ 60 IN MX 10 mail.ctl.testing.com.\n", "zone", "import")
+		assert.NoError(t, err)
+		assert.Contains(t, output, "created")
+		output, err = ephemerainctl("", "-o", "zone", "zone", "export", "ctl.testing.com")
+		assert.NoError(t, err)
+		assert.Contains(t, output, "www.ctl.testing.com.\t300\tIN\tA\t192.0.2.2\n")
+		assert.Contains(t, output, "ctl.testing.com.\t60\tIN\tTXT\t\"hello world\"\n")
+		assert.Contains(t, output, "ctl.testing.com.\t60\tIN\tMX\t10 mail.ctl.testing.com.\n")
+
+		_, err = ephemerainctl("zone ctl.testing.com\nupdate add nsupdate.ctl.testing.com 60 A 192.0.2.9\nsend\n", "update")
+		assert.NoError(t, err)
+		_, err = ephemerainctl("", "wait", "-timeout", "10s", "nsupdate.ctl.testing.com", "A", "192.0.2.9")
+		assert.NoError(t, err)
+		addrs, err := resolver.LookupHost(ctx, "nsupdate.ctl.testing.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"192.0.2.9"}, addrs)
+		_, err = ephemerainctl("", "wait", "-timeout", "1s", "nsupdate.ctl.testing.com", "A", "192.0.2.10")
+		assert.Error(t, err, "waiting for a value that never shows up should time out")
+
+		// Records of any type can be added, and deleted by value, by type or by name
+		_, err = ephemerainctl("update add nsupdate.ctl.testing.com 60 A 192.0.2.10\nupdate add nsupdate.ctl.testing.com 60 AAAA 2001:db8::9\nupdate add nsupdate.ctl.testing.com 60 MX 10 mail.ctl.testing.com.\n", "update")
+		assert.NoError(t, err)
+		addrs, err = resolver.LookupHost(ctx, "nsupdate.ctl.testing.com.")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"192.0.2.9", "192.0.2.10", "2001:db8::9"}, addrs)
+		_, err = ephemerainctl("update delete nsupdate.ctl.testing.com A 192.0.2.9\n", "update")
+		assert.NoError(t, err)
+		addrs, err = resolver.LookupHost(ctx, "nsupdate.ctl.testing.com.")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"192.0.2.10", "2001:db8::9"}, addrs)
+		_, err = ephemerainctl("update delete nsupdate.ctl.testing.com A\n", "update")
+		assert.NoError(t, err)
+		addrs, err = resolver.LookupHost(ctx, "nsupdate.ctl.testing.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2001:db8::9"}, addrs)
+		mx, err := resolver.LookupMX(ctx, "nsupdate.ctl.testing.com.")
+		assert.NoError(t, err)
+		assert.Len(t, mx, 1)
+		_, err = ephemerainctl("update delete nsupdate.ctl.testing.com\n", "update")
+		assert.NoError(t, err)
+		_, err = resolver.LookupHost(ctx, "nsupdate.ctl.testing.com.")
+		assert.Error(t, err)
+		_, err = resolver.LookupMX(ctx, "nsupdate.ctl.testing.com.")
+		assert.Error(t, err)
+
+		_, err = ephemerainctl("", "record", "delete", "www.ctl.testing.com", "A")
+		assert.NoError(t, err)
+		_, err = ephemerainctl("", "record", "get", "www.ctl.testing.com", "A")
+		assert.Error(t, err)
+	})
+}
+
+func withBearerToken(token string) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+func TestDNS_RateLimitedClientsRetryOverTCP(t *testing.T) {
+	config := EphemerainConfig{DNSRateLimit: ResponseRateLimitConfig{ResponsesPerSecond: 1, Window: time.Second, Slip: 1, IPv4PrefixLength: 24, IPv6PrefixLength: 56}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		value := "1.2.3.4"
+		response, err := apiClient.PutDomain(ctx, "tcp.testing.com.", RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+		query := new(dns.Msg)
+		query.SetQuestion("tcp.testing.com.", dns.TypeA)
+		var truncated bool
+		for i := 0; i < 5 && !truncated; i++ {
+			answer, _, err := new(dns.Client).Exchange(query, nameserver)
+			assert.NoError(t, err)
+			truncated = answer.Truncated
+		}
+		assert.True(t, truncated, "rate limited UDP queries should get truncated replies")
+
+		answer, _, err := (&dns.Client{Net: "tcp"}).Exchange(query, nameserver)
+		assert.NoError(t, err)
+		assert.Len(t, answer.Answer, 1, "the truncated query should be answered over TCP")
+	})
+}
+
+func TestMetrics_RequireAdminToken(t *testing.T) {
+	config := EphemerainConfig{AdminToken: "admin"}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		get := func(token string) int {
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(apiClient.Server, "v1/")+"debug/vars", nil)
+			assert.NoError(t, err)
+			if token != "" {
+				request.Header.Set("Authorization", "Bearer "+token)
+			}
+			response, err := http.DefaultClient.Do(request)
+			assert.NoError(t, err)
+			defer response.Body.Close()
+			return response.StatusCode
+		}
+
+		assert.Equal(t, http.StatusForbidden, get(""))
+		assert.Equal(t, http.StatusForbidden, get("tenant"))
+		assert.Equal(t, http.StatusOK, get("admin"))
+	})
+}
+
+func TestMetrics_404IfNoAdminToken(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		response, err := http.Get(strings.TrimSuffix(apiClient.Server, "v1/") + "debug/vars")
+		assert.NoError(t, err)
+		defer response.Body.Close()
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+}
+
+func TestAPI_429_IfRateLimited(t *testing.T) {
+	config := EphemerainConfig{HTTPRateLimit: HTTPRateLimitConfig{RequestsPerIP: 2, Window: time.Minute}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		for i := 0; i < 2; i++ {
+			response, err := apiClient.GetDomain(ctx, "foo.com.", RecordTypeA, &GetDomainParams{})
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusNotFound, response.StatusCode)
+		}
+
+		response, err := apiClient.GetDomain(ctx, "foo.com.", RecordTypeA, &GetDomainParams{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+		retryAfter, err := strconv.Atoi(response.Header.Get("Retry-After"))
+		assert.NoError(t, err)
+		assert.True(t, retryAfter > 0 && retryAfter <= 60, "Retry-After should be within the window, got %d", retryAfter)
+	})
+}
+
+func TestAPI_PutDomain_403_IfQuotaExceeded(t *testing.T) {
+	config := EphemerainConfig{
+		RecordQuotas: RecordQuotaConfig{RecordsPerToken: 2, RecordsPerZone: 3},
+		Subdomains:   SubdomainConfig{Zone: "quota.com.", Lifetime: time.Hour},
+	}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		value := "1.2.3.4"
+		put := func(domain string, token string) int {
+			response, err := apiClient.PutDomain(ctx, Domain(domain), RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value}, withBearerToken(token))
+			assert.NoError(t, err)
+			return response.StatusCode
+		}
+		claim := func() Subdomain {
+			response, err := apiClient.ClaimSubdomain(ctx)
+			assert.NoError(t, err)
+			var subdomain Subdomain
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&subdomain))
+			return subdomain
+		}
+
+		first := claim()
+		assert.Equal(t, http.StatusNoContent, put("a."+first.Domain, first.Token))
+		assert.Equal(t, http.StatusNoContent, put("b."+first.Domain, first.Token))
+		// Updating an existing record doesn't use up any more quota
+		assert.Equal(t, http.StatusNoContent, put("b."+first.Domain, first.Token))
+		assert.Equal(t, http.StatusForbidden, put("c."+first.Domain, first.Token), "token quota should be exceeded")
+
+		second := claim()
+		assert.Equal(t, http.StatusNoContent, put("a."+second.Domain, second.Token))
+		assert.Equal(t, http.StatusForbidden, put("b."+second.Domain, second.Token), "zone quota should be exceeded")
+
+		// Made up tokens don't get a quota of their own, since every request could use a new one
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusNoContent, put(fmt.Sprintf("%d.other-quota.com.", i), fmt.Sprintf("token-%d", i)))
+		}
+		assert.Equal(t, http.StatusForbidden, put("3.other-quota.com.", "token-3"), "zone quota should be exceeded")
+	})
+}
+
+func TestSplitHorizonViews(t *testing.T) {
+	views, err := ParseViewConfig("internal=127.0.0.0/8")
+	assert.NoError(t, err)
+	runIntegrationTestWithConfig(t, EphemerainConfig{Views: views}, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		publicIP, privateIP := "203.0.113.10", "10.0.0.10"
+		internal, defaultView := View("internal"), View(defaultViewName)
+
+		response, err := apiClient.PutDomain(ctx, "app.preview.example.com.", RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &publicIP})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+		response, err = apiClient.PutDomain(ctx, "app.preview.example.com.", RecordTypeA, &PutDomainParams{View: &internal}, PutDomainJSONRequestBody{Value: &privateIP})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+		response, err = apiClient.PutDomain(ctx, "db.preview.example.com.", RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &publicIP})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+		// The test resolver queries from 127.0.0.1, so it sees the internal view
+		host, err := resolver.LookupHost(ctx, "app.preview.example.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{privateIP}, host)
+
+		// Records that only exist in the default view are visible from every view
+		host, err = resolver.LookupHost(ctx, "db.preview.example.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{publicIP}, host)
+
+		getValue := func(params *GetDomainParams) string {
+			response, err := apiClient.GetDomain(ctx, "app.preview.example.com.", RecordTypeA, params)
+			assert.NoError(t, err)
+			defer response.Body.Close()
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			var value RecordValue
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&value))
+			return *value.Value
+		}
+		assert.Equal(t, privateIP, getValue(&GetDomainParams{}))
+		assert.Equal(t, privateIP, getValue(&GetDomainParams{View: &internal}))
+		assert.Equal(t, publicIP, getValue(&GetDomainParams{View: &defaultView}))
+
+		unknown := View("unknown")
+		response, err = apiClient.GetDomain(ctx, "app.preview.example.com.", RecordTypeA, &GetDomainParams{View: &unknown})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+		// Deleting a record in a view never deletes the record in the default view that it falls back to
+		deleteInternal := func() int {
+			response, err := apiClient.DeleteDomain(ctx, "app.preview.example.com.", RecordTypeA, &DeleteDomainParams{View: &internal})
+			assert.NoError(t, err)
+			return response.StatusCode
+		}
+		assert.Equal(t, http.StatusNoContent, deleteInternal())
+		assert.Equal(t, http.StatusNotFound, deleteInternal())
+		assert.Equal(t, publicIP, getValue(&GetDomainParams{View: &internal}))
+	})
+}
+
+func TestWatch(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		prefix := "watched."
+		eventStream, err := apiClient.WatchRecords(ctx, &WatchRecordsParams{Prefix: &prefix})
+		assert.NoError(t, err)
+		defer eventStream.Body.Close()
+		assert.Equal(t, "text/event-stream", eventStream.Header.Get("Content-Type"))
+		events := bufio.NewReader(eventStream.Body)
+		readEvent := func() RecordChange {
+			var change RecordChange
+			for {
+				line, err := events.ReadString('\n')
+				if err != nil {
+					t.Fatalf("Error reading event stream: %v", err)
+				}
+				if strings.HasPrefix(line, "data: ") {
+					assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &change))
+					return change
+				}
+			}
+		}
+
+		webSocketURL := strings.Replace(apiClient.Server, "http://", "ws://", 1) + "watch?prefix=" + prefix
+		webSocket, err := websocket.Dial(webSocketURL, "", apiClient.Server)
+		assert.NoError(t, err)
+		defer webSocket.Close()
+
+		// Both streams start with a resync, which means they're ready
+		assert.Equal(t, RecordChange{Action: RecordChangeResync}, readEvent())
+		var change RecordChange
+		assert.NoError(t, websocket.JSON.Receive(webSocket, &change))
+		assert.Equal(t, RecordChange{Action: RecordChangeResync}, change)
+
+		value := "1.2.3.4"
+		for _, domain := range []Domain{"ignored.testingdomain.com.", "watched.testingdomain.com."} {
+			_, err = apiClient.PutDomain(ctx, domain, RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value})
+			assert.NoError(t, err)
+		}
+
+		expected := RecordChange{Domain: "watched.testingdomain.com.", Type: RecordTypeA, Action: RecordChangeSet, Value: value}
+		assert.Equal(t, expected, readEvent())
+
+		change = RecordChange{}
+		assert.NoError(t, websocket.JSON.Receive(webSocket, &change))
+		assert.Equal(t, expected, change)
+	})
+}
+
+func TestWebhooks(t *testing.T) {
+	config := EphemerainConfig{AdminToken: "admin", Webhooks: WebhookConfig{AllowedNetworks: loopback}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		receiver := newWebhookReceiver(t, "s3cret", 0)
+		defer receiver.Close()
+
+		// The server makes requests to webhooks, so registering them takes a tenant or the admin token
+		registration := CreateWebhookJSONRequestBody{Zone: "webhooks.com", Url: receiver.URL, Secret: "s3cret"}
+		response, err := apiClient.CreateWebhook(ctx, registration)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		response, err = apiClient.CreateWebhook(ctx, registration, withBearerToken("admin"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		var webhook Webhook
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&webhook))
+		assert.Equal(t, "webhooks.com.", webhook.Zone)
+
+		// Changes made through both the API and RFC 2136 updates are delivered
+		value := "1.2.3.4"
+		_, err = apiClient.PutDomain(ctx, "api.webhooks.com.", RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value})
+		assert.NoError(t, err)
+		update := new(dns.Msg)
+		update.SetUpdate("webhooks.com.")
+		update.Insert([]dns.RR{&dns.TXT{Hdr: dns.RR_Header{Name: "update.webhooks.com.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60}, Txt: []string{"hello"}}})
+		_, err = dns.Exchange(update, nameserver)
+		assert.NoError(t, err)
+
+		assert.Eventually(t, func() bool { return len(receiver.received()) == 2 }, 5*time.Second, 10*time.Millisecond)
+		domains := map[string]WebhookEventType{}
+		for _, event := range receiver.received() {
+			domains[event.Domain] = event.Type
+		}
+		assert.Equal(t, map[string]WebhookEventType{
+			"api.webhooks.com.":    WebhookEventTypeCreated,
+			"update.webhooks.com.": WebhookEventTypeCreated,
+		}, domains)
+
+		assert.Eventually(t, func() bool {
+			response, err := apiClient.ListWebhookDeliveries(ctx, WebhookId(webhook.Id), withBearerToken("admin"))
+			if err != nil {
+				return false
+			}
+			defer response.Body.Close()
+			var deliveries []WebhookDelivery
+			return json.NewDecoder(response.Body).Decode(&deliveries) == nil && len(deliveries) == 2
+		}, 5*time.Second, 10*time.Millisecond)
+
+		response, err = apiClient.ListWebhookDeliveries(ctx, WebhookId(webhook.Id))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		response, err = apiClient.DeleteWebhook(ctx, WebhookId(webhook.Id), withBearerToken("admin"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+		response, err = apiClient.ListWebhookDeliveries(ctx, WebhookId(webhook.Id), withBearerToken("admin"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+}
+
+func TestAuditLog(t *testing.T) {
+	config := EphemerainConfig{TSIGSecrets: map[string]string{"audit-key.": "c2VjcmV0"}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		start := time.Now()
+		value := "1.2.3.4"
+		_, err := apiClient.PutDomain(ctx, "api.audit.com.", RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value}, withBearerToken("audit-token"))
+		assert.NoError(t, err)
+
+		_, err = apiClient.PostZoneWithBody(ctx, &PostZoneParams{}, "text/plain", strings.NewReader("upload.audit.com. 60 IN A 5.6.7.8\n"))
+		assert.NoError(t, err)
+
+		update := new(dns.Msg)
+		update.SetUpdate("audit.com.")
+		update.Insert([]dns.RR{&dns.TXT{Hdr: dns.RR_Header{Name: "update.audit.com.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60}, Txt: []string{"signed"}}})
+		update.SetTsig("audit-key.", dns.HmacSHA256, 300, time.Now().Unix())
+		dnsClient := dns.Client{TsigSecret: config.TSIGSecrets}
+		response, _, err := dnsClient.Exchange(update, nameserver)
+		assert.NoError(t, err)
+		assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+
+		// Updates with a bad signature are refused
+		update.SetTsig("audit-key.", dns.HmacSHA256, 300, time.Now().Unix())
+		dnsClient.TsigSecret = map[string]string{"audit-key.": "d3Jvbmc="}
+		response, _, _ = dnsClient.Exchange(update, nameserver)
+		if assert.NotNil(t, response) {
+			assert.Equal(t, dns.RcodeNotAuth, response.Rcode)
+		}
+
+		// A change in another zone, which is filtered out below
+		_, err = apiClient.PutDomain(ctx, "other.zone.com.", RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value})
+		assert.NoError(t, err)
+
+		zone := "audit.com"
+		auditResponse, err := apiClient.GetAudit(ctx, &GetAuditParams{Zone: &zone, Since: &start})
+		assert.NoError(t, err)
+		var entries []AuditEntry
+		assert.NoError(t, json.NewDecoder(auditResponse.Body).Decode(&entries))
+		if assert.Len(t, entries, 3) {
+			assert.Equal(t, "update.audit.com.", entries[0].Domain)
+			assert.Equal(t, "tsig:audit-key.", entries[0].Actor)
+			assert.Equal(t, AuditEntryProtocolRfc2136, entries[0].Protocol)
+			assert.Equal(t, "signed", *entries[0].NewValue)
+
+			assert.Equal(t, "upload.audit.com.", entries[1].Domain)
+			assert.Equal(t, AuditEntryProtocolZoneUpload, entries[1].Protocol)
+			assert.True(t, strings.HasPrefix(entries[1].Actor, "ip:"))
+
+			assert.Equal(t, "api.audit.com.", entries[2].Domain)
+			assert.Equal(t, "token:"+tokenID("audit-token"), entries[2].Actor)
+			assert.Equal(t, AuditEntryProtocolRest, entries[2].Protocol)
+		}
+
+		// Nothing happened before the test started
+		until := start.Add(-time.Second)
+		auditResponse, err = apiClient.GetAudit(ctx, &GetAuditParams{Zone: &zone, Until: &until})
+		assert.NoError(t, err)
+		entries = nil
+		assert.NoError(t, json.NewDecoder(auditResponse.Body).Decode(&entries))
+		assert.Empty(t, entries)
+	})
+}
+
+func TestRecordHistory(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		for _, value := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+			value := value
+			_, err := apiClient.PutDomain(ctx, "www.history.com.", RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value})
+			assert.NoError(t, err)
+		}
+
+		versionsResponse, err := apiClient.ListRecordVersions(ctx, "www.history.com.", RecordTypeA, &ListRecordVersionsParams{})
+		assert.NoError(t, err)
+		var versions []RecordVersion
+		assert.NoError(t, json.NewDecoder(versionsResponse.Body).Decode(&versions))
+		if assert.Len(t, versions, 3) {
+			assert.Equal(t, int64(3), versions[0].Version)
+			assert.Equal(t, "3.3.3.3", versions[0].Value)
+			assert.Equal(t, int64(1), versions[2].Version)
+		}
+
+		restoreResponse, err := apiClient.RestoreRecordVersion(ctx, "www.history.com.", RecordTypeA, 1, &RestoreRecordVersionParams{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, restoreResponse.StatusCode)
+		ips, err := resolver.LookupIP(ctx, "ip4", "www.history.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []net.IP{net.ParseIP("1.1.1.1").To4()}, ips)
+
+		restoreResponse, err = apiClient.RestoreRecordVersion(ctx, "www.history.com.", RecordTypeA, 42, &RestoreRecordVersionParams{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, restoreResponse.StatusCode)
+
+		time.Sleep(10 * time.Millisecond)
+		good := time.Now()
+		time.Sleep(10 * time.Millisecond)
+
+		// A bad upload
+		_, err = apiClient.PostZoneWithBody(ctx, &PostZoneParams{}, "text/plain", strings.NewReader("www.history.com. 60 IN A 6.6.6.6\nnew.history.com. 60 IN A 6.6.6.6\n"))
+		assert.NoError(t, err)
+
+		zoneResponse, err := apiClient.RestoreZone(ctx, "history.com", RestoreZoneJSONRequestBody{Timestamp: good})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, zoneResponse.StatusCode)
+		var result ZoneRestoreResult
+		assert.NoError(t, json.NewDecoder(zoneResponse.Body).Decode(&result))
+		assert.Equal(t, []RecordReference{{Domain: "www.history.com.", Type: RecordTypeA}}, result.Restored)
+		assert.Equal(t, []RecordReference{{Domain: "new.history.com.", Type: RecordTypeA}}, result.Deleted)
+		assert.Empty(t, result.Unrestorable)
+
+		ips, err = resolver.LookupIP(ctx, "ip4", "www.history.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []net.IP{net.ParseIP("1.1.1.1").To4()}, ips)
+		getResponse, err := apiClient.GetDomain(ctx, "new.history.com.", RecordTypeA, &GetDomainParams{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, getResponse.StatusCode)
+	})
+}
+
+func TestEphemeralSubdomains(t *testing.T) {
+	config := EphemerainConfig{Subdomains: SubdomainConfig{Zone: "eph.example.com.", Lifetime: time.Hour}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		response, err := apiClient.ClaimSubdomain(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		var subdomain Subdomain
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&subdomain))
+		assert.True(t, strings.HasSuffix(subdomain.Domain, ".eph.example.com."), subdomain.Domain)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), subdomain.Expires, time.Minute)
+
+		fqdn := Domain("www." + subdomain.Domain)
+		value := "1.2.3.4"
+		response, err = apiClient.PutDomain(ctx, fqdn, RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode, "records in a claimed subdomain need its owner token")
+
+		response, err = apiClient.PutDomain(ctx, fqdn, RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value}, withBearerToken(subdomain.Token))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+		addrs, err := resolver.LookupHost(ctx, string(fqdn))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1.2.3.4"}, addrs)
+
+		response, err = apiClient.PutDomain(ctx, "www.example.com.", RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value}, withBearerToken(subdomain.Token))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode, "owner tokens only work in their own subdomain")
+
+		response, err = apiClient.ClaimSubdomain(ctx)
+		assert.NoError(t, err)
+		var other Subdomain
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&other))
+		assert.NotEqual(t, subdomain.Domain, other.Domain)
+	})
+}
+
+func TestEphemeralSubdomains_404IfDisabled(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		response, err := apiClient.ClaimSubdomain(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+}
+
+func TestLeases(t *testing.T) {
+	config := EphemerainConfig{Subdomains: SubdomainConfig{Zone: "eph.example.com.", Lifetime: time.Second, ReapInterval: 50 * time.Millisecond}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		claim := func() Subdomain {
+			response, err := apiClient.ClaimSubdomain(ctx)
+			assert.NoError(t, err)
+			var subdomain Subdomain
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&subdomain))
+			value := "1.2.3.4"
+			response, err = apiClient.PutDomain(ctx, Domain("www."+subdomain.Domain), RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value}, withBearerToken(subdomain.Token))
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusNoContent, response.StatusCode)
+			return subdomain
+		}
+
+		// Released leases delete their records straight away
+		released := claim()
+		response, err := apiClient.ReleaseLease(ctx, LeaseId(released.LeaseId))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		response, err = apiClient.ReleaseLease(ctx, LeaseId(released.LeaseId), withBearerToken(released.Token))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+		_, err = resolver.LookupHost(ctx, "www."+released.Domain)
+		assert.Error(t, err)
+		response, err = apiClient.ReleaseLease(ctx, LeaseId(released.LeaseId), withBearerToken(released.Token))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+		// Renewed leases last longer than their lifetime
+		renewed := claim()
+		time.Sleep(600 * time.Millisecond)
+		response, err = apiClient.RenewLease(ctx, LeaseId(renewed.LeaseId), withBearerToken(renewed.Token))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var lease Lease
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&lease))
+		assert.True(t, lease.Expires.After(renewed.Expires))
+		response, err = apiClient.GetLease(ctx, LeaseId(renewed.LeaseId))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		response, err = apiClient.GetLease(ctx, LeaseId(renewed.LeaseId), withBearerToken(renewed.Token))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var got Lease
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&got))
+		assert.Equal(t, lease, got)
+		time.Sleep(600 * time.Millisecond)
+		addrs, err := resolver.LookupHost(ctx, "www."+renewed.Domain)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1.2.3.4"}, addrs)
+
+		// Expired leases have their records deleted by the reaper
+		assert.Eventually(t, func() bool {
+			_, err := resolver.LookupHost(ctx, "www."+renewed.Domain)
+			return err != nil
+		}, 3*time.Second, 50*time.Millisecond)
+		response, err = apiClient.RenewLease(ctx, LeaseId(renewed.LeaseId), withBearerToken(renewed.Token))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+}
+
+func TestTenants(t *testing.T) {
+	config := EphemerainConfig{AdminToken: "admin", TSIGSecrets: map[string]string{"acme-key.": "c2VjcmV0", "other-key.": "b3RoZXI="}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, nameserver string) {
+		response, err := apiClient.CreateTenant(ctx, CreateTenantJSONRequestBody{Id: "acme", Zones: []string{"acme.com"}})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+		create := func(tenant NewTenant) CreatedTenant {
+			response, err := apiClient.CreateTenant(ctx, CreateTenantJSONRequestBody(tenant), withBearerToken("admin"))
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusCreated, response.StatusCode)
+			var created CreatedTenant
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&created))
+			return created
+		}
+		acmeKeys := []string{"acme-key"}
+		acme := create(NewTenant{Id: "acme", Zones: []string{"acme.com"}, TsigKeys: &acmeKeys})
+		assert.Equal(t, Tenant{Id: "acme", Zones: []string{"acme.com."}, TsigKeys: []string{"acme-key."}, KeyPrefix: "tenant:acme:"}, acme.Tenant)
+		dev := create(NewTenant{Id: "dev", Zones: []string{"dev.acme.com"}})
+
+		for _, tenant := range []NewTenant{
+			{Id: "acme", Zones: []string{"other.com"}},
+			{Id: "other", Zones: []string{"acme.com"}},
+			{Id: "other", Zones: []string{"other.com"}, TsigKeys: &acmeKeys},
+		} {
+			response, err := apiClient.CreateTenant(ctx, CreateTenantJSONRequestBody(tenant), withBearerToken("admin"))
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusConflict, response.StatusCode)
+		}
+		unknownKeys := []string{"unknown-key"}
+		response, err = apiClient.CreateTenant(ctx, CreateTenantJSONRequestBody{Id: "other", Zones: []string{"other.com"}, TsigKeys: &unknownKeys}, withBearerToken("admin"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+		response, err = apiClient.ListTenants(ctx, withBearerToken("admin"))
+		assert.NoError(t, err)
+		var tenants []Tenant
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&tenants))
+		assert.Equal(t, []Tenant{acme.Tenant, dev.Tenant}, tenants)
+
+		// Tenants can only change records beneath their own zones, even when their zones overlap
+		put := func(fqdn Domain, value string, token string) int {
+			response, err := apiClient.PutDomain(ctx, fqdn, RecordTypeA, &PutDomainParams{}, PutDomainJSONRequestBody{Value: &value}, withBearerToken(token))
+			assert.NoError(t, err)
+			return response.StatusCode
+		}
+		assert.Equal(t, http.StatusNoContent, put("www.acme.com.", "1.1.1.1", acme.Token))
+		assert.Equal(t, http.StatusNoContent, put("www.dev.acme.com.", "2.2.2.2", dev.Token))
+		assert.Equal(t, http.StatusForbidden, put("www.dev.acme.com.", "3.3.3.3", acme.Token))
+		assert.Equal(t, http.StatusForbidden, put("www.acme.com.", "3.3.3.3", dev.Token))
+		assert.Equal(t, http.StatusForbidden, put("www.acme.com.", "3.3.3.3", "some-token"))
+		assert.Equal(t, http.StatusForbidden, put("www.example.com.", "3.3.3.3", acme.Token))
+
+		addrs, err := resolver.LookupHost(ctx, "www.dev.acme.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2.2.2.2"}, addrs)
+
+		// Webhooks and the audit log are kept per tenant as well
+		createWebhook := func(zone string, token string) int {
+			response, err := apiClient.CreateWebhook(ctx, CreateWebhookJSONRequestBody{Zone: zone, Url: "https://hooks.example.com/", Secret: "s3cret"}, withBearerToken(token))
+			assert.NoError(t, err)
+			return response.StatusCode
+		}
+		assert.Equal(t, http.StatusCreated, createWebhook("acme.com", acme.Token))
+		assert.Equal(t, http.StatusForbidden, createWebhook("dev.acme.com", acme.Token))
+		assert.Equal(t, http.StatusForbidden, createWebhook("acme.com", "some-token"))
+		countWebhooks := func(token string) int {
+			response, err := apiClient.ListWebhooks(ctx, &ListWebhooksParams{}, withBearerToken(token))
+			assert.NoError(t, err)
+			var webhooks []Webhook
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&webhooks))
+			return len(webhooks)
+		}
+		assert.Equal(t, 1, countWebhooks(acme.Token))
+		assert.Equal(t, 0, countWebhooks(dev.Token))
+		response, err = apiClient.ListWebhooks(ctx, &ListWebhooksParams{}, withBearerToken("some-token"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		auditDomains := func(token string) []string {
+			response, err := apiClient.GetAudit(ctx, &GetAuditParams{}, withBearerToken(token))
+			assert.NoError(t, err)
+			var entries []AuditEntry
+			assert.NoError(t, json.NewDecoder(response.Body).Decode(&entries))
+			domains := []string{}
+			for _, entry := range entries {
+				domains = append(domains, entry.Domain)
+			}
+			return domains
+		}
+		assert.Equal(t, []string{"www.dev.acme.com."}, auditDomains(dev.Token))
+		assert.Equal(t, []string{}, auditDomains("some-token"))
+		response, err = apiClient.WatchRecords(ctx, &WatchRecordsParams{}, withBearerToken("some-token"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+		// RFC 2136 updates are only accepted from the tenant's TSIG keys
+		updateWith := func(key string) int {
+			update := new(dns.Msg)
+			update.SetUpdate("acme.com.")
+			update.Insert([]dns.RR{&dns.TXT{Hdr: dns.RR_Header{Name: "update.acme.com.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60}, Txt: []string{"signed"}}})
+			update.SetTsig(key, dns.HmacSHA256, 300, time.Now().Unix())
+			dnsClient := dns.Client{TsigSecret: config.TSIGSecrets}
+			response, _, err := dnsClient.Exchange(update, nameserver)
+			assert.NoError(t, err)
+			return response.Rcode
+		}
+		assert.Equal(t, dns.RcodeRefused, updateWith("other-key."))
+
+		// Updates signed with keys that aren't configured can't get around the tenant keys by being anonymous
+		update := new(dns.Msg)
+		update.SetUpdate("example.com.")
+		update.Insert([]dns.RR{&dns.TXT{Hdr: dns.RR_Header{Name: "update.example.com.", Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60}, Txt: []string{"signed"}}})
+		update.SetTsig("unknown-key.", dns.HmacSHA256, 300, time.Now().Unix())
+		dnsClient := dns.Client{TsigSecret: map[string]string{"unknown-key.": "c2VjcmV0"}}
+		updateResponse, _, _ := dnsClient.Exchange(update, nameserver)
+		if assert.NotNil(t, updateResponse) {
+			assert.Equal(t, dns.RcodeNotAuth, updateResponse.Rcode)
+		}
+		assert.Equal(t, dns.RcodeSuccess, updateWith("acme-key."))
+		txts, err := resolver.LookupTXT(ctx, "update.acme.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"signed"}, txts)
+	})
+}
+
+func TestTenants_404IfNoAdminToken(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		response, err := apiClient.ListTenants(ctx, withBearerToken("admin"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+}
+
+func TestACMEDNSCompatibleAPI(t *testing.T) {
+	config := EphemerainConfig{ACMEDNSZone: "auth.example.com.", Zones: ZoneSet{"auth.example.com."}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		base := strings.TrimSuffix(apiClient.Server, "v1/") + "acme-dns"
+
+		response, err := http.Post(base+"/register", "application/json", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		var registration struct {
+			Username   string `json:"username"`
+			Password   string `json:"password"`
+			Fulldomain string `json:"fulldomain"`
+			Subdomain  string `json:"subdomain"`
+		}
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&registration))
+
+		// Wildcard and apex challenges can be solved at the same time
+		apex, wildcard := strings.Repeat("a", 43), strings.Repeat("b", 43)
+		for _, txt := range []string{apex, wildcard} {
+			request, err := http.NewRequest(http.MethodPost, base+"/update", strings.NewReader(`{"subdomain": "`+registration.Subdomain+`", "txt": "`+txt+`"}`))
+			assert.NoError(t, err)
+			request.Header.Set("X-Api-User", registration.Username)
+			request.Header.Set("X-Api-Key", registration.Password)
+			response, err := http.DefaultClient.Do(request)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+		}
+
+		txts, err := resolver.LookupTXT(ctx, registration.Fulldomain)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{apex, wildcard}, txts)
+
+		zone := "auth.example.com"
+		auditResponse, err := apiClient.GetAudit(ctx, &GetAuditParams{Zone: &zone})
+		assert.NoError(t, err)
+		var entries []AuditEntry
+		assert.NoError(t, json.NewDecoder(auditResponse.Body).Decode(&entries))
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, "acme-dns:"+registration.Username, entries[0].Actor)
+			assert.Equal(t, AuditEntryProtocolAcmeDns, entries[0].Protocol)
+		}
+	})
+}
+
+func TestACMEDNSCompatibleAPI_404IfDisabled(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, apiClient *Client, resolver *net.Resolver, _ string) {
+		response, err := http.Post(strings.TrimSuffix(apiClient.Server, "v1/")+"acme-dns/register", "application/json", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"github.com/miekg/dns"
+	"strconv"
+	"strings"
+)
+
+// defaultRecordTTL is the TTL of records that are set without one.
+const defaultRecordTTL uint32 = 60
+
+// maxTXTStringLength is the longest a single character-string of a TXT record can be.
+const maxTXTStringLength = 255
+
+// RecordSet is every value of a record along with its TTL.
+//
+// Registrars store each record as a single string. A record with one value and the default TTL is stored as just that
+// value, the same as before records could have more than one. Otherwise the values are stored one per line, after a
+// "$TTL <seconds>" line if the TTL isn't the default.
+type RecordSet struct {
+	TTL    uint32
+	Values []string
+}
+
+func (s RecordSet) String() string {
+	value := strings.Join(s.Values, "\n")
+	if s.TTL != defaultRecordTTL {
+		value = fmt.Sprintf("$TTL %d\n%s", s.TTL, value)
+	}
+	return value
+}
+
+// parseRecordSet is the inverse of RecordSet.String.
+func parseRecordSet(value string) RecordSet {
+	set := RecordSet{TTL: defaultRecordTTL}
+	lines := strings.Split(value, "\n")
+	if len(lines) > 1 && strings.HasPrefix(lines[0], "$TTL ") {
+		if ttl, err := strconv.ParseUint(strings.TrimPrefix(lines[0], "$TTL "), 10, 32); err == nil {
+			set.TTL = uint32(ttl)
+			lines = lines[1:]
+		}
+	}
+	set.Values = lines
+	return set
+}
+
+// resourceRecords returns the records a DNS answer for the record set contains.
+func (s RecordSet) resourceRecords(name Domain, recordType RecordType) ([]dns.RR, error) {
+	rrs := make([]dns.RR, 0, len(s.Values))
+	for _, value := range s.Values {
+		if recordType == RecordTypeTXT {
+			rrs = append(rrs, &dns.TXT{
+				Hdr: dns.RR_Header{Name: string(name), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: s.TTL},
+				Txt: escapeTXT(splitTXT(value)),
+			})
+			continue
+		}
+
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, s.TTL, recordType, value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", recordType, value, err)
+		}
+		if rr == nil {
+			return nil, fmt.Errorf("empty %s value", recordType)
+		}
+		rrs = append(rrs, rr)
+	}
+	return rrs, nil
+}
+
+// recordValue is the value a record is stored with, which for TXT records is the text rather than its presentation
+// format.
+func recordValue(rr dns.RR) string {
+	if txt, ok := rr.(*dns.TXT); ok {
+		var text strings.Builder
+		for _, str := range txt.Txt {
+			text.WriteString(unescapeTXT(str))
+		}
+		return text.String()
+	}
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+// splitTXT splits text into as many character-strings as a TXT record needs to hold it.
+func splitTXT(text string) []string {
+	var strs []string
+	for len(text) > maxTXTStringLength {
+		strs = append(strs, text[:maxTXTStringLength])
+		text = text[maxTXTStringLength:]
+	}
+	return append(strs, text)
+}
+
+// unescapeTXT returns the text of a character-string of a dns.TXT. The dns package keeps character-strings in their
+// presentation format, whether they were parsed from a zone file or unpacked from a message, so quotes and backslashes
+// are escaped with a backslash and other bytes can be written as \DDD.
+func unescapeTXT(str string) string {
+	if !strings.Contains(str, `\`) {
+		return str
+	}
+	var text strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] != '\\' || i+1 == len(str) {
+			text.WriteByte(str[i])
+			continue
+		}
+		// \DDD is the byte with decimal value DDD, and any other escaped character is itself
+		if i+3 < len(str) && isDigit(str[i+1]) && isDigit(str[i+2]) && isDigit(str[i+3]) {
+			text.WriteByte((str[i+1]-'0')*100 + (str[i+2]-'0')*10 + str[i+3] - '0')
+			i += 3
+			continue
+		}
+		text.WriteByte(str[i+1])
+		i++
+	}
+	return text.String()
+}
+
+// escapeTXT is the inverse of unescapeTXT for every character-string in strs.
+func escapeTXT(strs []string) []string {
+	escaped := make([]string, len(strs))
+	for i, str := range strs {
+		var presentation strings.Builder
+		for _, c := range []byte(str) {
+			switch {
+			case c == '"' || c == '\\':
+				presentation.WriteByte('\\')
+				presentation.WriteByte(c)
+			case c < ' ' || c > '~':
+				fmt.Fprintf(&presentation, "\\%03d", c)
+			default:
+				presentation.WriteByte(c)
+			}
+		}
+		escaped[i] = presentation.String()
+	}
+	return escaped
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// storedRecordTypes are the types of records that can be stored in a registrar.
+var storedRecordTypes = []RecordType{
+	RecordTypeA, RecordTypeAAAA, RecordTypeAFSDB, RecordTypeCAA, RecordTypeCNAME, RecordTypeHINFO, RecordTypeLOC,
+	RecordTypeMX, RecordTypeNAPTR, RecordTypeNS, RecordTypePTR, RecordTypeRP, RecordTypeSRV, RecordTypeTXT,
+}
+
+// isStoredRecordType reports whether records of a type can be stored in a registrar.
+func isStoredRecordType(recordType RecordType) bool {
+	for _, stored := range storedRecordTypes {
+		if recordType == stored {
+			return true
+		}
+	}
+	return false
+}
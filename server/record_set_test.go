@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestRecordSet(t *testing.T) {
+	for _, set := range []RecordSet{
+		{TTL: defaultRecordTTL, Values: []string{"1.1.1.1"}},
+		{TTL: 3600, Values: []string{"1.1.1.1"}},
+		{TTL: defaultRecordTTL, Values: []string{"10 mx1.example.com.", "20 mx2.example.com."}},
+		{TTL: 300, Values: []string{"10 mx1.example.com.", "20 mx2.example.com."}},
+	} {
+		assert.Equal(t, set, parseRecordSet(set.String()))
+	}
+
+	// Records stored before they could have several values are read as they were
+	assert.Equal(t, "1.1.1.1", RecordSet{TTL: defaultRecordTTL, Values: []string{"1.1.1.1"}}.String())
+	assert.Equal(t, RecordSet{TTL: defaultRecordTTL, Values: []string{"$TTL 300"}}, parseRecordSet("$TTL 300"))
+}
+
+func TestRecordSet_ResourceRecords(t *testing.T) {
+	set := RecordSet{TTL: 300, Values: []string{"10 mx1.example.com.", "20 mx2.example.com."}}
+	rrs, err := set.resourceRecords("example.com.", RecordTypeMX)
+	assert.NoError(t, err)
+	if assert.Len(t, rrs, 2) {
+		assert.Equal(t, "example.com.\t300\tIN\tMX\t10 mx1.example.com.", rrs[0].String())
+		assert.Equal(t, "mx2.example.com.", rrs[1].(*dns.MX).Mx)
+	}
+
+	long := strings.Repeat("a", 300)
+	rrs, err = RecordSet{TTL: defaultRecordTTL, Values: []string{long}}.resourceRecords("example.com.", RecordTypeTXT)
+	assert.NoError(t, err)
+	if assert.Len(t, rrs, 1) {
+		assert.Equal(t, []string{long[:255], long[255:]}, rrs[0].(*dns.TXT).Txt)
+		assert.Equal(t, long, recordValue(rrs[0]))
+	}
+
+	_, err = RecordSet{TTL: defaultRecordTTL, Values: []string{"not-an-ip"}}.resourceRecords("example.com.", RecordTypeA)
+	assert.Error(t, err)
+}
+
+func TestRecordSet_TXTEscapes(t *testing.T) {
+	// TXT records are stored as their text, without the escapes of their presentation format
+	parsed, err := dns.NewRR(`example.com. 60 IN TXT "say \"hi\"" "back\\slash\009tab"`)
+	assert.NoError(t, err)
+	text := "say \"hi\"back\\slash\ttab"
+	assert.Equal(t, text, recordValue(parsed))
+
+	rrs, err := RecordSet{TTL: defaultRecordTTL, Values: []string{text}}.resourceRecords("example.com.", RecordTypeTXT)
+	assert.NoError(t, err)
+	if assert.Len(t, rrs, 1) {
+		assert.Equal(t, text, recordValue(rrs[0]))
+		assert.Equal(t, "example.com.\t60\tIN\tTXT\t"+`"say \"hi\"back\\slash\009tab"`, rrs[0].String())
+
+		// The text is the same once the record has been sent in a message
+		m := new(dns.Msg)
+		m.Answer = rrs
+		packed, err := m.Pack()
+		assert.NoError(t, err)
+		assert.NoError(t, m.Unpack(packed))
+		assert.Equal(t, text, recordValue(m.Answer[0]))
+	}
+}
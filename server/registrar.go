@@ -1,11 +1,184 @@
 package main
 
 import (
+	"errors"
 	"golang.org/x/net/context"
+	"strings"
+	"time"
 )
 
+// ErrRecordNotFound is returned by GetRecord when there is no record of the requested type.
+var ErrRecordNotFound = errors.New("record not found")
+
+// ErrQuotaExceeded is returned by ClaimRecordQuota when the scope already owns as many records as it is allowed to.
+var ErrQuotaExceeded = errors.New("record quota exceeded")
+
+// ErrRecordChanged is returned by WriteRecords when a record it was asked to delete doesn't have the expected value.
+var ErrRecordChanged = errors.New("record changed")
+
+// ErrSubdomainTaken is returned by ClaimSubdomain when the subdomain already has a lease.
+var ErrSubdomainTaken = errors.New("subdomain already claimed")
+
+// ErrLeaseNotFound is returned when a subdomain doesn't have a lease, or a lease ID doesn't refer to a lease.
+var ErrLeaseNotFound = errors.New("lease not found")
+
+// ErrLeaseExpired is returned by RenewLease when the lease has already expired.
+var ErrLeaseExpired = errors.New("lease expired")
+
+// ErrTenantConflict is returned by CreateTenant when the tenant's ID, one of its zones or one of its TSIG keys already
+// belongs to another tenant.
+var ErrTenantConflict = errors.New("tenant conflict")
+
+// ErrWebhookNotFound is returned when a webhook ID doesn't refer to a registered webhook.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
 type Registrar interface {
 	SetRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) error
+	// SwapRecord is like SetRecord, but also returns the value the record had before, if it existed.
+	SwapRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) (previous string, existed bool, err error)
 	GetRecord(ctx context.Context, fqdn Domain, recordType RecordType) (string, error)
+	// GetViewRecord is like GetRecord, but only returns the record in the view from ctx, without falling back to the
+	// default view.
+	GetViewRecord(ctx context.Context, fqdn Domain, recordType RecordType) (string, error)
 	DeleteRecord(ctx context.Context, fqdn Domain, recordType RecordType, currentValue string) error
+	// WriteRecords makes every write in the view from ctx at once, so DNS queries see either none or all of them. If
+	// any record being deleted doesn't have the value it is expected to have, nothing is written and
+	// ErrRecordChanged is returned. The results are in the same order as writes.
+	WriteRecords(ctx context.Context, writes []RecordWrite) ([]RecordWriteResult, error)
+	// ListRecords returns every record, in every view, at or beneath zone. Like every other record method, it only
+	// sees the records in the tenant namespace from ctx.
+	ListRecords(ctx context.Context, zone Domain) ([]StoredRecord, error)
+
+	// ConsumeRateLimit counts a request against key in the current fixed window of length window. If more than limit
+	// requests have been counted in the window, it returns how long the caller has to wait until the window resets.
+	ConsumeRateLimit(ctx context.Context, key string, limit int64, window time.Duration) (time.Duration, error)
+	// ClaimRecordQuota records that scope owns the given records in the view from ctx, failing with ErrQuotaExceeded
+	// if scope would then own more than limit records. Either every record is claimed or none are, and claiming a
+	// record that scope already owns always succeeds. Claims are made before their records are written, so a claim
+	// counts for a while even if its record doesn't exist yet.
+	ClaimRecordQuota(ctx context.Context, scope string, records []RecordReference, limit int64) error
+
+	// ClaimSubdomain stores a lease on a subdomain, failing with ErrSubdomainTaken if it already has one, even if it
+	// has expired.
+	ClaimSubdomain(ctx context.Context, lease SubdomainLease) error
+	// GetSubdomainLease returns the lease on a subdomain, or ErrLeaseNotFound.
+	GetSubdomainLease(ctx context.Context, fqdn Domain) (SubdomainLease, error)
+	// GetLease returns the lease with the given ID, or ErrLeaseNotFound.
+	GetLease(ctx context.Context, id string) (SubdomainLease, error)
+	// RenewLease changes when a lease expires, failing with ErrLeaseNotFound if there is no such lease or with
+	// ErrLeaseExpired if it has already expired at now.
+	RenewLease(ctx context.Context, id string, expires time.Time, now time.Time) (SubdomainLease, error)
+	// ListExpiredLeases returns every lease that has expired at now.
+	ListExpiredLeases(ctx context.Context, now time.Time) ([]SubdomainLease, error)
+	// DeleteLease removes a lease so its subdomain can be claimed again. Deleting a lease that doesn't exist succeeds.
+	DeleteLease(ctx context.Context, lease SubdomainLease) error
+
+	// SubscribeRecordChanges notifies about every record that is set or deleted, by this or any other replica, until
+	// ctx is cancelled. A RecordChange with an empty Domain means notifications may have been missed, for example
+	// because the connection to the backend was lost, so anything derived from the records should be refreshed. One is
+	// also sent when a tenant is created, since the names in its zones are read from its namespace from then on.
+	SubscribeRecordChanges(ctx context.Context) <-chan RecordChange
+	// Watch is like SubscribeRecordChanges, but only notifies about records whose name starts with prefix. Like with
+	// SubscribeRecordChanges, a RecordChange with an empty Domain is sent once the watch has started and whenever
+	// notifications may have been missed.
+	Watch(ctx context.Context, prefix string) <-chan RecordChange
+
+	// CreateTenant stores a new tenant, failing with ErrTenantConflict if its ID, any of its zones or any of its TSIG
+	// keys already belong to another tenant.
+	CreateTenant(ctx context.Context, tenant RegisteredTenant) error
+	ListTenants(ctx context.Context) ([]RegisteredTenant, error)
+
+	CreateACMEDNSAccount(ctx context.Context, account ACMEDNSAccount) error
+	// GetACMEDNSAccount returns the account with the given username, or ErrACMEDNSAccountNotFound.
+	GetACMEDNSAccount(ctx context.Context, username string) (ACMEDNSAccount, error)
+
+	CreateWebhook(ctx context.Context, webhook RegisteredWebhook) error
+	ListWebhooks(ctx context.Context) ([]RegisteredWebhook, error)
+	// DeleteWebhook removes a webhook and its delivery log, or returns ErrWebhookNotFound.
+	DeleteWebhook(ctx context.Context, id string) error
+	// LogWebhookDelivery adds to the delivery log of a webhook, which only keeps the most recent deliveries.
+	LogWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error
+	// ListWebhookDeliveries returns the delivery log of a webhook, newest first.
+	ListWebhookDeliveries(ctx context.Context, id string) ([]WebhookDelivery, error)
+
+	// RecordHistory returns the most recent versions of a record in the view from ctx, newest first. Unlike GetRecord,
+	// it doesn't fall back to the default view.
+	RecordHistory(ctx context.Context, fqdn Domain, recordType RecordType) ([]RecordVersion, error)
+	// ListChangedRecords returns every record, in any view, that has been set or deleted since the given time.
+	ListChangedRecords(ctx context.Context, since time.Time) ([]RecordReference, error)
+
+	// AppendAuditEntry adds an entry to the audit log, which is never modified afterwards. The ID of the entry is
+	// assigned by the registrar.
+	AppendAuditEntry(ctx context.Context, entry AuditEntry) error
+	// ListAuditEntries returns the entries matching query, newest first.
+	ListAuditEntries(ctx context.Context, query AuditQuery) ([]AuditEntry, error)
+}
+
+// RecordWrite is a change to a single record made by WriteRecords. Value is the new value of a record that is set, or
+// the current value of a record that is deleted.
+type RecordWrite struct {
+	Domain Domain
+	Type   RecordType
+	Value  string
+	Delete bool
+}
+
+// RecordWriteResult is the value a record had before WriteRecords changed it, if it existed.
+type RecordWriteResult struct {
+	Previous string
+	Existed  bool
+}
+
+// StoredRecord is a record as it is stored by a registrar. View is empty for records in the default view.
+type StoredRecord struct {
+	Domain Domain
+	Type   RecordType
+	View   string
+	Value  string
+}
+
+// maxRecordVersions is how many versions of each record are kept by RecordHistory.
+const maxRecordVersions = 20
+
+// recordHistoryRetention is how long the history of deleted records is kept, and how far back ListChangedRecords
+// can go.
+const recordHistoryRetention = 30 * 24 * time.Hour
+
+// RecordChangeAction is what happened to a record.
+type RecordChangeAction string
+
+const (
+	RecordChangeSet    RecordChangeAction = "set"
+	RecordChangeDelete RecordChangeAction = "delete"
+)
+
+// RecordChange identifies a record that was set or deleted. Value is the new value of a record that was set, or the
+// last value of a record that was deleted. Namespace is the key prefix of the tenant the record belongs to.
+type RecordChange struct {
+	Namespace string             `json:"namespace,omitempty"`
+	Domain    Domain             `json:"domain,omitempty"`
+	Type      RecordType         `json:"type,omitempty"`
+	View      string             `json:"view,omitempty"`
+	Action    RecordChangeAction `json:"action,omitempty"`
+	Value     string             `json:"value,omitempty"`
+}
+
+// filterRecordChanges implements Watch on top of the channel returned by SubscribeRecordChanges.
+func filterRecordChanges(ctx context.Context, changes <-chan RecordChange, prefix string) <-chan RecordChange {
+	prefix = strings.ToLower(prefix)
+	filtered := make(chan RecordChange, cap(changes))
+	go func() {
+		defer close(filtered)
+		for change := range changes {
+			if change.Domain != "" && !strings.HasPrefix(strings.ToLower(string(change.Domain)), prefix) {
+				continue
+			}
+			select {
+			case filtered <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return filtered
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"github.com/hashicorp/go-hclog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachingRegistrar keeps recently read records in memory in front of another Registrar. It caches both records and
+// their absence, and relies on SubscribeRecordChanges to find out when another replica changes a record. Entries also
+// expire after a while, as a safety net in case a change notification is lost.
+type CachingRegistrar struct {
+	Registrar
+
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[recordCacheKey]*list.Element
+	// generation counts invalidations, so that reads that race with an invalidation don't cache what they read
+	generation uint64
+}
+
+type recordCacheKey struct {
+	// namespace is the tenant the record belongs to, since it decides where the record is read from
+	namespace  string
+	fqdn       Domain
+	recordType RecordType
+}
+
+// recordCacheEntry holds every cached view of a single name and type, so that changes to the default view, which all
+// other views fall back to, can invalidate them together.
+type recordCacheEntry struct {
+	key   recordCacheKey
+	views map[string]recordCacheValue
+}
+
+type recordCacheValue struct {
+	value   string
+	found   bool
+	expires time.Time
+}
+
+// NewCachingRegistrar wraps next with a cache of at most size names. The cache stays up to date until ctx is
+// cancelled, after which it should no longer be used.
+func NewCachingRegistrar(ctx context.Context, next Registrar, size int) *CachingRegistrar {
+	c := &CachingRegistrar{
+		Registrar:   next,
+		size:        size,
+		ttl:         time.Minute,
+		negativeTTL: 10 * time.Second,
+		now:         time.Now,
+		lru:         list.New(),
+		entries:     map[recordCacheKey]*list.Element{},
+	}
+
+	changes := next.SubscribeRecordChanges(ctx)
+	go func() {
+		for change := range changes {
+			if change.Domain == "" {
+				hclog.FromContext(ctx).Info("Flushing record cache after (re)subscribing to record changes")
+				c.flush()
+			} else {
+				c.invalidate(change.Namespace, change.Domain, change.Type, change.View)
+			}
+		}
+	}()
+
+	return c
+}
+
+func newRecordCacheKey(namespace string, fqdn Domain, recordType RecordType) recordCacheKey {
+	return recordCacheKey{namespace: namespace, fqdn: Domain(strings.ToLower(string(fqdn))), recordType: recordType}
+}
+
+func (c *CachingRegistrar) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	c.entries = map[recordCacheKey]*list.Element{}
+	c.generation++
+}
+
+func (c *CachingRegistrar) invalidate(namespace string, fqdn Domain, recordType RecordType, view string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	element, ok := c.entries[newRecordCacheKey(namespace, fqdn, recordType)]
+	if !ok {
+		return
+	}
+	if view == "" {
+		c.lru.Remove(element)
+		delete(c.entries, element.Value.(*recordCacheEntry).key)
+	} else {
+		delete(element.Value.(*recordCacheEntry).views, view)
+	}
+}
+
+// lookup returns the cached value, if there is one. Otherwise it returns the current generation, which has to be passed
+// to store.
+func (c *CachingRegistrar) lookup(key recordCacheKey, view string) (recordCacheValue, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return recordCacheValue{}, c.generation, false
+	}
+	cached, ok := element.Value.(*recordCacheEntry).views[view]
+	if !ok || c.now().After(cached.expires) {
+		return recordCacheValue{}, c.generation, false
+	}
+	c.lru.MoveToFront(element)
+	return cached, c.generation, true
+}
+
+// store caches value, unless something was invalidated since generation was returned by lookup.
+func (c *CachingRegistrar) store(generation uint64, key recordCacheKey, view string, value recordCacheValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	element, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(element)
+	} else {
+		element = c.lru.PushFront(&recordCacheEntry{key: key, views: map[string]recordCacheValue{}})
+		c.entries[key] = element
+		for c.lru.Len() > c.size {
+			oldest := c.lru.Back()
+			c.lru.Remove(oldest)
+			delete(c.entries, oldest.Value.(*recordCacheEntry).key)
+		}
+	}
+	element.Value.(*recordCacheEntry).views[view] = value
+}
+
+func (c *CachingRegistrar) GetRecord(ctx context.Context, fqdn Domain, recordType RecordType) (string, error) {
+	key, view := newRecordCacheKey(namespaceFromContext(ctx), fqdn, recordType), viewFromContext(ctx)
+	cached, generation, ok := c.lookup(key, view)
+	if ok {
+		if !cached.found {
+			return "", ErrRecordNotFound
+		}
+		return cached.value, nil
+	}
+
+	value, err := c.Registrar.GetRecord(ctx, fqdn, recordType)
+	switch {
+	case err == nil:
+		c.store(generation, key, view, recordCacheValue{value: value, found: true, expires: c.now().Add(c.ttl)})
+	case errors.Is(err, ErrRecordNotFound):
+		c.store(generation, key, view, recordCacheValue{expires: c.now().Add(c.negativeTTL)})
+	}
+	return value, err
+}
+
+func (c *CachingRegistrar) SetRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) error {
+	err := c.Registrar.SetRecord(ctx, fqdn, recordType, value)
+	c.invalidate(namespaceFromContext(ctx), fqdn, recordType, viewFromContext(ctx))
+	return err
+}
+
+func (c *CachingRegistrar) SwapRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) (string, bool, error) {
+	previous, existed, err := c.Registrar.SwapRecord(ctx, fqdn, recordType, value)
+	c.invalidate(namespaceFromContext(ctx), fqdn, recordType, viewFromContext(ctx))
+	return previous, existed, err
+}
+
+func (c *CachingRegistrar) DeleteRecord(ctx context.Context, fqdn Domain, recordType RecordType, currentValue string) error {
+	err := c.Registrar.DeleteRecord(ctx, fqdn, recordType, currentValue)
+	c.invalidate(namespaceFromContext(ctx), fqdn, recordType, viewFromContext(ctx))
+	return err
+}
+
+func (c *CachingRegistrar) WriteRecords(ctx context.Context, writes []RecordWrite) ([]RecordWriteResult, error) {
+	results, err := c.Registrar.WriteRecords(ctx, writes)
+	for _, write := range writes {
+		c.invalidate(namespaceFromContext(ctx), write.Domain, write.Type, viewFromContext(ctx))
+	}
+	return results, err
+}
+
+// CreateTenant flushes the cache, since names in the tenant's zones are read from its namespace from now on.
+func (c *CachingRegistrar) CreateTenant(ctx context.Context, tenant RegisteredTenant) error {
+	defer c.flush()
+	return c.Registrar.CreateTenant(ctx, tenant)
+}
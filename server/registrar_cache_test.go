@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newSubscribedCachingRegistrar waits for the cache to receive the first notification from the backend, which flushes
+// it, so that the flush doesn't interfere with the test.
+func newSubscribedCachingRegistrar(t *testing.T, ctx context.Context, backend Registrar, size int) *CachingRegistrar {
+	cache := NewCachingRegistrar(ctx, backend, size)
+	assert.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return cache.generation > 0
+	}, time.Second, time.Millisecond)
+	return cache
+}
+
+func TestCachingRegistrar_CachesRecordsAndAbsence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend := newMemoryRegistrar()
+	assert.NoError(t, backend.SetRecord(ctx, "cached.test.", RecordTypeA, "1.2.3.4"))
+	cache := newSubscribedCachingRegistrar(t, ctx, backend, 10)
+
+	for i := 0; i < 3; i++ {
+		value, err := cache.GetRecord(ctx, "Cached.Test.", RecordTypeA)
+		assert.NoError(t, err)
+		assert.Equal(t, "1.2.3.4", value)
+
+		_, err = cache.GetRecord(ctx, "missing.test.", RecordTypeA)
+		assert.ErrorIs(t, err, ErrRecordNotFound)
+	}
+	assert.Equal(t, 2, backend.readCount())
+}
+
+func TestCachingRegistrar_InvalidatesOnChange(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend := newMemoryRegistrar()
+	cache := newSubscribedCachingRegistrar(t, ctx, backend, 10)
+
+	// Writes through the cache are visible immediately
+	_, err := cache.GetRecord(ctx, "changed.test.", RecordTypeTXT)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+	assert.NoError(t, cache.SetRecord(ctx, "changed.test.", RecordTypeTXT, "one"))
+	value, err := cache.GetRecord(ctx, "changed.test.", RecordTypeTXT)
+	assert.NoError(t, err)
+	assert.Equal(t, "one", value)
+
+	// Writes made elsewhere are picked up once the change notification arrives
+	assert.NoError(t, backend.SetRecord(ctx, "changed.test.", RecordTypeTXT, "two"))
+	assert.Eventually(t, func() bool {
+		value, err := cache.GetRecord(ctx, "changed.test.", RecordTypeTXT)
+		return err == nil && value == "two"
+	}, time.Second, time.Millisecond)
+
+	assert.NoError(t, backend.DeleteRecord(ctx, "changed.test.", RecordTypeTXT, "two"))
+	assert.Eventually(t, func() bool {
+		_, err := cache.GetRecord(ctx, "changed.test.", RecordTypeTXT)
+		return err == ErrRecordNotFound
+	}, time.Second, time.Millisecond)
+}
+
+func TestCachingRegistrar_Views(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	internal := withView(ctx, "internal")
+	backend := newMemoryRegistrar()
+	cache := newSubscribedCachingRegistrar(t, ctx, backend, 10)
+
+	// The internal view falls back to the default view, so changing the default view has to invalidate it too
+	assert.NoError(t, backend.SetRecord(ctx, "view.test.", RecordTypeA, "1.1.1.1"))
+	value, err := cache.GetRecord(internal, "view.test.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", value)
+	assert.NoError(t, backend.SetRecord(ctx, "view.test.", RecordTypeA, "2.2.2.2"))
+	assert.Eventually(t, func() bool {
+		value, _ := cache.GetRecord(internal, "view.test.", RecordTypeA)
+		return value == "2.2.2.2"
+	}, time.Second, time.Millisecond)
+
+	// Changing the internal view doesn't evict the default view
+	_, _ = cache.GetRecord(ctx, "view.test.", RecordTypeA)
+	reads := backend.readCount()
+	assert.NoError(t, cache.SetRecord(internal, "view.test.", RecordTypeA, "3.3.3.3"))
+	value, err = cache.GetRecord(ctx, "view.test.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.2.2.2", value)
+	value, err = cache.GetRecord(internal, "view.test.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "3.3.3.3", value)
+	assert.Equal(t, reads+1, backend.readCount())
+}
+
+func TestCachingRegistrar_TenantNamespaces(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend := newMemoryRegistrar()
+	cache := newSubscribedCachingRegistrar(t, ctx, backend, 10)
+	registrar := NewTenantRegistrar(cache, NewTenantDirectory(ctx, cache))
+	acme := withNamespace(ctx, tenantKeyPrefix("acme"))
+
+	assert.NoError(t, backend.SetRecord(ctx, "app.acme.test.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, backend.SetRecord(acme, "app.acme.test.", RecordTypeA, "2.2.2.2"))
+	value, err := registrar.GetRecord(ctx, "app.acme.test.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.1.1.1", value)
+
+	// Once the tenant owns the name, it is read from the tenant's namespace rather than the cache
+	assert.NoError(t, registrar.CreateTenant(ctx, RegisteredTenant{Tenant: Tenant{Id: "acme", Zones: []string{"acme.test."}}}))
+	value, err = registrar.GetRecord(ctx, "app.acme.test.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.2.2.2", value)
+
+	// Changes to the record outside of the namespace don't affect it, but changes to the tenant's record do
+	assert.NoError(t, backend.SetRecord(ctx, "app.acme.test.", RecordTypeA, "3.3.3.3"))
+	assert.NoError(t, backend.SetRecord(acme, "app.acme.test.", RecordTypeA, "4.4.4.4"))
+	assert.Eventually(t, func() bool {
+		value, _ := registrar.GetRecord(ctx, "app.acme.test.", RecordTypeA)
+		return value == "4.4.4.4"
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, backend.SetRecord(ctx, "app.acme.test.", RecordTypeA, "5.5.5.5"))
+	_, err = backend.GetRecord(ctx, "app.acme.test.", RecordTypeA)
+	assert.NoError(t, err)
+	reads := backend.readCount()
+	value, err = registrar.GetRecord(ctx, "app.acme.test.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "4.4.4.4", value)
+	assert.Equal(t, reads, backend.readCount())
+}
+
+func TestCachingRegistrar_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend := newMemoryRegistrar()
+	cache := newSubscribedCachingRegistrar(t, ctx, backend, 2)
+
+	_, _ = cache.GetRecord(ctx, "a.test.", RecordTypeA)
+	_, _ = cache.GetRecord(ctx, "b.test.", RecordTypeA)
+	_, _ = cache.GetRecord(ctx, "a.test.", RecordTypeA)
+	_, _ = cache.GetRecord(ctx, "c.test.", RecordTypeA)
+	assert.Equal(t, 3, backend.readCount())
+
+	// b was the least recently used name, so it's the one that was evicted to make room for c
+	_, _ = cache.GetRecord(ctx, "a.test.", RecordTypeA)
+	_, _ = cache.GetRecord(ctx, "c.test.", RecordTypeA)
+	assert.Equal(t, 3, backend.readCount())
+	_, _ = cache.GetRecord(ctx, "b.test.", RecordTypeA)
+	assert.Equal(t, 4, backend.readCount())
+}
+
+func TestCachingRegistrar_Expiry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	now := time.Now()
+	backend := newMemoryRegistrar()
+	cache := newSubscribedCachingRegistrar(t, ctx, backend, 10)
+	cache.now = func() time.Time { return now }
+
+	assert.NoError(t, backend.SetRecord(ctx, "expiry.test.", RecordTypeA, "1.2.3.4"))
+	_, _ = cache.GetRecord(ctx, "expiry.test.", RecordTypeA)
+	_, _ = cache.GetRecord(ctx, "missing.test.", RecordTypeA)
+
+	// Negative entries expire sooner than positive ones
+	now = now.Add(cache.negativeTTL + time.Second)
+	_, _ = cache.GetRecord(ctx, "expiry.test.", RecordTypeA)
+	_, _ = cache.GetRecord(ctx, "missing.test.", RecordTypeA)
+	assert.Equal(t, 3, backend.readCount())
+
+	now = now.Add(cache.ttl)
+	_, _ = cache.GetRecord(ctx, "expiry.test.", RecordTypeA)
+	assert.Equal(t, 4, backend.readCount())
+}
+
+func TestCachingRegistrar_FlushesAfterMissedChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	backend := newMemoryRegistrar()
+	cache := newSubscribedCachingRegistrar(t, ctx, backend, 10)
+
+	_, _ = cache.GetRecord(ctx, "flush.test.", RecordTypeA)
+	backend.publish(RecordChange{})
+	assert.Eventually(t, func() bool {
+		_, _ = cache.GetRecord(ctx, "flush.test.", RecordTypeA)
+		return backend.readCount() > 1
+	}, time.Second, time.Millisecond)
+}
+
+// discardResponseWriter is a dns.ResponseWriter that throws away responses, so it can be shared between goroutines in
+// benchmarks.
+type discardResponseWriter struct {
+	recordingResponseWriter
+}
+
+func (w *discardResponseWriter) WriteMsg(*dns.Msg) error {
+	return nil
+}
+
+func benchmarkQuery(b *testing.B, registrar Registrar) {
+	ctx := context.Background()
+	names := make([]string, 100)
+	for i := range names {
+		names[i] = fmt.Sprintf("host%d.bench.test.", i)
+		if err := registrar.SetRecord(ctx, Domain(names[i]), RecordTypeA, "10.0.0.1"); err != nil {
+			b.Fatal(err)
+		}
+	}
+	// Logging every query would dominate the results
+	logger := hclog.L()
+	hclog.SetDefault(hclog.NewNullLogger())
+	defer hclog.SetDefault(logger)
+
+	handler := handleIPQuery(registrar, NewSynthesizerRegistry(), nil, nil)
+	w := &discardResponseWriter{recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5353}}}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := new(dns.Msg)
+		for i := 0; pb.Next(); i++ {
+			r.SetQuestion(names[i%len(names)], dns.TypeA)
+			handler(w, r)
+		}
+	})
+}
+
+func BenchmarkQuery(b *testing.B) {
+	backend := newMemoryRegistrar()
+	backend.latency = 200 * time.Microsecond
+	b.Run("uncached", func(b *testing.B) {
+		benchmarkQuery(b, backend)
+	})
+	b.Run("cached", func(b *testing.B) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		benchmarkQuery(b, NewCachingRegistrar(ctx, backend, 1000))
+	})
+}
+
+func BenchmarkQuery_Redis(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := withRedisTestServer(ctx, func(port int) {
+		registrar := NewRedisRegistrar("localhost:" + strconv.Itoa(port))
+		b.Run("uncached", func(b *testing.B) {
+			benchmarkQuery(b, registrar)
+		})
+		b.Run("cached", func(b *testing.B) {
+			benchmarkQuery(b, NewCachingRegistrar(ctx, registrar, 1000))
+		})
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+}
@@ -2,29 +2,152 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/hashicorp/go-hclog"
 	context2 "golang.org/x/net/context"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type RedisRegistrar struct {
 	client *redis.Client
 }
 
-func redisKey(fqdn Domain, recordType RecordType) string {
-	return fmt.Sprintf("%s:%s", strings.ToLower(string(fqdn)), recordType)
+func redisKey(fqdn Domain, recordType RecordType, view string) string {
+	key := fmt.Sprintf("%s:%s", strings.ToLower(string(fqdn)), recordType)
+	if view != "" {
+		key += "@" + view
+	}
+	return key
+}
+
+// recordKey is the key of a record in the tenant namespace from ctx.
+func recordKey(ctx context.Context, fqdn Domain, recordType RecordType, view string) string {
+	return namespaceFromContext(ctx) + redisKey(fqdn, recordType, view)
+}
+
+// parseRedisKey is the inverse of redisKey.
+func parseRedisKey(key string) (RecordReference, bool) {
+	var view string
+	if idx := strings.LastIndex(key, "@"); idx != -1 {
+		key, view = key[:idx], key[idx+1:]
+	}
+	idx := strings.LastIndex(key, ":")
+	if idx == -1 {
+		return RecordReference{}, false
+	}
+	record := RecordReference{Domain: key[:idx], Type: RecordType(key[idx+1:])}
+	if view != "" {
+		record.View = &view
+	}
+	return record, true
+}
+
+// recordChangesChannel is the pub/sub channel that every record change is published to, so that other replicas can
+// find out about them.
+const recordChangesChannel = "ephemerain:record-changes"
+
+func recordChangeMessage(ctx context.Context, fqdn Domain, recordType RecordType, action RecordChangeAction, value string) string {
+	message, _ := json.Marshal(RecordChange{
+		Namespace: namespaceFromContext(ctx),
+		Domain:    Domain(strings.ToLower(string(fqdn))),
+		Type:      recordType,
+		View:      viewFromContext(ctx),
+		Action:    action,
+		Value:     value,
+	})
+	return string(message)
 }
 
 func (r RedisRegistrar) SetRecord(ctx context2.Context, fqdn Domain, recordType RecordType, value string) error {
-	key := redisKey(fqdn, recordType)
-	return r.client.Set(ctx, key, value, 0).Err()
+	_, _, err := r.SwapRecord(ctx, fqdn, recordType, value)
+	return err
+}
+
+// historyKeys are the keys, after the key of the record itself, that the scripts writing records need for keeping
+// track of its history.
+func historyKeys(key string) []string {
+	return []string{key, "history:" + key, "history-version:" + key, recordChangesKey}
+}
+
+// recordChangesKey is a sorted set of every record key, scored by when it was last changed in milliseconds. Keys that
+// haven't changed for recordHistoryRetention are trimmed.
+const recordChangesKey = "history:changes"
+
+// recordVersionLua adds a version to the history of a record, given the keys returned by historyKeys, the encoded
+// version, whether the record existed before the version, the current time in milliseconds, maxRecordVersions and
+// recordHistoryRetention in milliseconds. The history of deleted records expires after recordHistoryRetention unless
+// they are created again.
+const recordVersionLua = `
+local function recordVersion(key, historyKey, versionKey, changesKey, encodedVersion, existed, now, maxVersions, retention)
+  local version = cjson.decode(encodedVersion)
+  version.version = redis.call('INCR', versionKey)
+  version.created = not version.deleted and not existed
+  redis.call('LPUSH', historyKey, cjson.encode(version))
+  redis.call('LTRIM', historyKey, 0, tonumber(maxVersions) - 1)
+  if version.deleted then
+    redis.call('PEXPIRE', historyKey, retention)
+    redis.call('PEXPIRE', versionKey, retention)
+  else
+    redis.call('PERSIST', historyKey)
+    redis.call('PERSIST', versionKey)
+  end
+  redis.call('ZADD', changesKey, now, key)
+  redis.call('ZREMRANGEBYSCORE', changesKey, '-inf', '(' .. (tonumber(now) - tonumber(retention)))
+end
+`
+
+func recordVersionArgs(value string, deleted bool, now time.Time) []interface{} {
+	version, _ := json.Marshal(RecordVersion{Value: value, Deleted: deleted, Timestamp: now.UTC()})
+	return []interface{}{string(version), now.UnixNano() / int64(time.Millisecond), maxRecordVersions, recordHistoryRetention.Milliseconds()}
+}
+
+func (r RedisRegistrar) SwapRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) (string, bool, error) {
+	key := recordKey(ctx, fqdn, recordType, viewFromContext(ctx))
+	args := append([]interface{}{value, recordChangesChannel, recordChangeMessage(ctx, fqdn, recordType, RecordChangeSet, value)}, recordVersionArgs(value, false, time.Now())...)
+	previous, err := swapRecordScript.Run(ctx, r.client, historyKeys(key), args...).Text()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return previous, true, nil
 }
 
 func (r RedisRegistrar) GetRecord(ctx context.Context, fqdn Domain, recordType RecordType) (string, error) {
-	key := redisKey(fqdn, recordType)
-	status := r.client.Get(ctx, key)
-	return status.Val(), status.Err()
+	view := viewFromContext(ctx)
+	if view == "" {
+		value, err := r.client.Get(ctx, recordKey(ctx, fqdn, recordType, "")).Result()
+		if err == redis.Nil {
+			return "", ErrRecordNotFound
+		}
+		return value, err
+	}
+
+	// Records that don't exist in the view fall back to the default view. Both are fetched at once to save a round
+	// trip.
+	values, err := r.client.MGet(ctx, recordKey(ctx, fqdn, recordType, view), recordKey(ctx, fqdn, recordType, "")).Result()
+	if err != nil {
+		return "", err
+	}
+	for _, value := range values {
+		if value, ok := value.(string); ok {
+			return value, nil
+		}
+	}
+	return "", ErrRecordNotFound
+}
+
+func (r RedisRegistrar) GetViewRecord(ctx context.Context, fqdn Domain, recordType RecordType) (string, error) {
+	value, err := r.client.Get(ctx, recordKey(ctx, fqdn, recordType, viewFromContext(ctx))).Result()
+	if err == redis.Nil {
+		return "", ErrRecordNotFound
+	}
+	return value, err
 }
 
 func (r RedisRegistrar) DeleteRecord(ctx context.Context, fqdn Domain, recordType RecordType, currentValue string) error {
@@ -32,19 +155,752 @@ func (r RedisRegistrar) DeleteRecord(ctx context.Context, fqdn Domain, recordTyp
 	// condition, the check + delete happens in a lua script so that redis performs it atomically.
 	// The lua script is sent for each delete rather than being cached because deletes are relatively rare, so the
 	// performance hit is less painful than the complexities around replication with cached scripts.
-	deleteLuaScript := `
+	deleteLuaScript := recordVersionLua + `
 local expectedCurrentValue = ARGV[1]
 local actualCurrentValue = redis.call('GET', KEYS[1])
 if expectedCurrentValue == actualCurrentValue then
   redis.call('DEL', KEYS[1])
+  redis.call('PUBLISH', ARGV[2], ARGV[3])
+  recordVersion(KEYS[1], KEYS[2], KEYS[3], KEYS[4], ARGV[4], true, ARGV[5], ARGV[6], ARGV[7])
   return true
 else
   return error("attempted to delete with wrong current value")
 end
 `
 
-	key := redisKey(fqdn, recordType)
-	return r.client.Eval(ctx, deleteLuaScript, []string{key}, currentValue).Err()
+	key := recordKey(ctx, fqdn, recordType, viewFromContext(ctx))
+	args := append([]interface{}{currentValue, recordChangesChannel, recordChangeMessage(ctx, fqdn, recordType, RecordChangeDelete, currentValue)}, recordVersionArgs(currentValue, true, time.Now())...)
+	return r.client.Eval(ctx, deleteLuaScript, historyKeys(key), args...).Err()
+}
+
+func (r RedisRegistrar) WriteRecords(ctx context.Context, writes []RecordWrite) ([]RecordWriteResult, error) {
+	if len(writes) == 0 {
+		return nil, nil
+	}
+
+	view := viewFromContext(ctx)
+	now := time.Now()
+	keys := []string{recordChangesKey}
+	args := []interface{}{recordChangesChannel, now.UnixNano() / int64(time.Millisecond), maxRecordVersions, recordHistoryRetention.Milliseconds()}
+	for _, write := range writes {
+		key := recordKey(ctx, write.Domain, write.Type, view)
+		action := RecordChangeSet
+		if write.Delete {
+			action = RecordChangeDelete
+		}
+		version, _ := json.Marshal(RecordVersion{Value: write.Value, Deleted: write.Delete, Timestamp: now.UTC()})
+		keys = append(keys, key, "history:"+key, "history-version:"+key)
+		args = append(args, string(action), write.Value, recordChangeMessage(ctx, write.Domain, write.Type, action, write.Value), string(version))
+	}
+
+	previous, err := writeRecordsScript.Run(ctx, r.client, keys, args...).Slice()
+	if err != nil {
+		if strings.HasPrefix(err.Error(), ErrRecordChanged.Error()) {
+			return nil, fmt.Errorf("%w: %s", ErrRecordChanged, err)
+		}
+		return nil, err
+	}
+	results := make([]RecordWriteResult, len(writes))
+	for i, value := range previous {
+		if value, ok := value.(string); ok {
+			results[i] = RecordWriteResult{Previous: value, Existed: true}
+		}
+	}
+	return results, nil
+}
+
+// escapeRedisPattern escapes the characters that are special in the patterns used by SCAN.
+func escapeRedisPattern(s string) string {
+	var escaped strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(c)
+	}
+	return escaped.String()
+}
+
+func (r RedisRegistrar) ListRecords(ctx context.Context, zone Domain) ([]StoredRecord, error) {
+	// Other keys can match the pattern too, like the history of records, so only keys that parse as records of a name
+	// in the zone are kept
+	namespace := namespaceFromContext(ctx)
+	var keys []string
+	var records []StoredRecord
+	iter := r.client.Scan(ctx, 0, escapeRedisPattern(namespace)+"*"+escapeRedisPattern(canonicalName(string(zone)))+":*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if !strings.HasPrefix(iter.Val(), namespace) {
+			continue
+		}
+		record, ok := parseRedisKey(strings.TrimPrefix(iter.Val(), namespace))
+		if !ok || strings.Contains(record.Domain, ":") || !isStoredRecordType(record.Type) || !isSubdomain(Domain(record.Domain), zone) {
+			continue
+		}
+		stored := StoredRecord{Domain: Domain(record.Domain), Type: record.Type}
+		if record.View != nil {
+			stored.View = *record.View
+		}
+		keys = append(keys, iter.Val())
+		records = append(records, stored)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return records, nil
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	// Records deleted since they were scanned are left out
+	existing := records[:0]
+	for i, value := range values {
+		if value, ok := value.(string); ok {
+			records[i].Value = value
+			existing = append(existing, records[i])
+		}
+	}
+	return existing, nil
+}
+
+func (r RedisRegistrar) RecordHistory(ctx context.Context, fqdn Domain, recordType RecordType) ([]RecordVersion, error) {
+	encoded, err := r.client.LRange(ctx, "history:"+recordKey(ctx, fqdn, recordType, viewFromContext(ctx)), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]RecordVersion, 0, len(encoded))
+	for _, value := range encoded {
+		var version RecordVersion
+		if err := json.Unmarshal([]byte(value), &version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func (r RedisRegistrar) ListChangedRecords(ctx context.Context, since time.Time) ([]RecordReference, error) {
+	keys, err := r.client.ZRangeByScore(ctx, recordChangesKey, &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixNano()/int64(time.Millisecond), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	namespace := namespaceFromContext(ctx)
+	records := make([]RecordReference, 0, len(keys))
+	for _, key := range keys {
+		if !strings.HasPrefix(key, namespace) {
+			continue
+		}
+		if record, ok := parseRedisKey(strings.TrimPrefix(key, namespace)); ok && !strings.Contains(record.Domain, ":") {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// Setting records, rate limiting and quota checks happen on every API request, so unlike the delete script these scripts are cached by
+// redis. redis.Script falls back to sending the full script if the server doesn't have it cached.
+var rateLimitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+if count > tonumber(ARGV[1]) then
+  return redis.call('PTTL', KEYS[1])
+end
+return 0
+`)
+
+var swapRecordScript = redis.NewScript(recordVersionLua + `
+local previous = redis.call('GET', KEYS[1])
+redis.call('SET', KEYS[1], ARGV[1])
+redis.call('PUBLISH', ARGV[2], ARGV[3])
+recordVersion(KEYS[1], KEYS[2], KEYS[3], KEYS[4], ARGV[4], previous, ARGV[5], ARGV[6], ARGV[7])
+return previous
+`)
+
+// writeRecordsScript is passed recordChangesKey followed by the record, history and version keys of every write as
+// KEYS, and the pub/sub channel, the current time in milliseconds, maxRecordVersions and recordHistoryRetention in
+// milliseconds followed by the action, value, change message and version of every write as ARGV. Deletes are checked before anything is written, so that either
+// every write is made or none are.
+var writeRecordsScript = redis.NewScript(recordVersionLua + `
+local writes = (#KEYS - 1) / 3
+for i = 0, writes - 1 do
+  if ARGV[5 + 4 * i] == 'delete' and redis.call('GET', KEYS[2 + 3 * i]) ~= ARGV[6 + 4 * i] then
+    return redis.error_reply('record changed: ' .. KEYS[2 + 3 * i])
+  end
+end
+local previous = {}
+for i = 0, writes - 1 do
+  local key = KEYS[2 + 3 * i]
+  previous[i + 1] = redis.call('GET', key)
+  if ARGV[5 + 4 * i] == 'delete' then
+    redis.call('DEL', key)
+  else
+    redis.call('SET', key, ARGV[6 + 4 * i])
+  end
+  redis.call('PUBLISH', ARGV[1], ARGV[7 + 4 * i])
+  recordVersion(key, KEYS[3 + 3 * i], KEYS[4 + 3 * i], KEYS[1], ARGV[8 + 4 * i], previous[i + 1], ARGV[2], ARGV[3], ARGV[4])
+end
+return previous
+`)
+
+// quotaClaimGracePeriod is how long a record quota claim counts even though its record doesn't exist. Records are
+// written after they are claimed, so a shorter grace period would let claims be pruned before their writes.
+const quotaClaimGracePeriod = time.Minute
+
+// claimQuotaScript is passed the quota key as KEYS, and the limit, the current time in milliseconds and the keys of the
+// records being claimed as ARGV. The quota key is a sorted set of the keys of claimed records, scored by when they were
+// last claimed.
+var claimQuotaScript = redis.NewScript(`
+local quotaKey = KEYS[1]
+local limit = tonumber(ARGV[1])
+local unclaimed = 0
+for i = 3, #ARGV do
+  if not redis.call('ZSCORE', quotaKey, ARGV[i]) then
+    unclaimed = unclaimed + 1
+  end
+end
+if unclaimed > 0 and redis.call('ZCARD', quotaKey) + unclaimed > limit then
+  return 0
+end
+for i = 3, #ARGV do
+  redis.call('ZADD', quotaKey, ARGV[2], ARGV[i])
+end
+return 1
+`)
+
+// pruneQuotaScript is passed the quota key as KEYS, and a time in milliseconds followed by the claims to remove as
+// ARGV. Claims made again since that time are kept.
+var pruneQuotaScript = redis.NewScript(`
+local pruned = 0
+for i = 2, #ARGV do
+  local claimed = redis.call('ZSCORE', KEYS[1], ARGV[i])
+  if claimed and tonumber(claimed) <= tonumber(ARGV[1]) then
+    pruned = pruned + redis.call('ZREM', KEYS[1], ARGV[i])
+  end
+end
+return pruned
+`)
+
+func (r RedisRegistrar) ConsumeRateLimit(ctx context.Context, key string, limit int64, window time.Duration) (time.Duration, error) {
+	retryAfterMillis, err := rateLimitScript.Run(ctx, r.client, []string{"ratelimit:" + key}, limit, window.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(retryAfterMillis) * time.Millisecond, nil
+}
+
+func (r RedisRegistrar) ClaimRecordQuota(ctx context.Context, scope string, records []RecordReference, limit int64) error {
+	quotaKey := "quota-claims:" + scope
+	args := []interface{}{limit, nil}
+	claiming := map[string]bool{}
+	for _, record := range records {
+		key := recordKey(ctx, Domain(record.Domain), record.Type, viewFromContext(ctx))
+		if !claiming[key] {
+			claiming[key] = true
+			args = append(args, key)
+		}
+	}
+	if len(claiming) == 0 {
+		return nil
+	}
+
+	claim := func() (bool, error) {
+		args[1] = unixMillis(time.Now())
+		claimed, err := claimQuotaScript.Run(ctx, r.client, []string{quotaKey}, args...).Int64()
+		return claimed == 1, err
+	}
+	claimed, err := claim()
+	if err == nil && !claimed {
+		// Claims are only pruned lazily, once the quota is reached. That way deleting a record (which can happen
+		// through RFC 2136 without any notion of who owns it) doesn't need to know about quotas.
+		var pruned int64
+		if pruned, err = r.pruneRecordQuota(ctx, quotaKey); err == nil && pruned > 0 {
+			claimed, err = claim()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// pruneRecordQuota removes the claims in quotaKey on records that don't exist, other than claims made within
+// quotaClaimGracePeriod, whose records may not have been written yet. The records are checked outside of a script,
+// since Redis Cluster requires scripts to be passed every key they use.
+func (r RedisRegistrar) pruneRecordQuota(ctx context.Context, quotaKey string) (int64, error) {
+	cutoff := strconv.FormatInt(unixMillis(time.Now().Add(-quotaClaimGracePeriod)), 10)
+	claims, err := r.client.ZRangeByScore(ctx, quotaKey, &redis.ZRangeBy{Min: "-inf", Max: cutoff}).Result()
+	if err != nil || len(claims) == 0 {
+		return 0, err
+	}
+	pipeline := r.client.Pipeline()
+	exists := make([]*redis.IntCmd, len(claims))
+	for i, claim := range claims {
+		exists[i] = pipeline.Exists(ctx, claim)
+	}
+	if _, err := pipeline.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	args := []interface{}{cutoff}
+	for i, claim := range claims {
+		if exists[i].Val() == 0 {
+			args = append(args, claim)
+		}
+	}
+	if len(args) == 1 {
+		return 0, nil
+	}
+	return pruneQuotaScript.Run(ctx, r.client, []string{quotaKey}, args...).Int64()
+}
+
+// subdomainLeasesKey is a hash of the encoded lease on each claimed subdomain. Leases are kept in the namespace of the
+// tenant that owns their subdomain.
+func subdomainLeasesKey(ctx context.Context) string {
+	return namespaceFromContext(ctx) + "subdomain-leases"
+}
+
+// subdomainLeaseExpiriesKey is a sorted set of every claimed subdomain, scored by when its lease expires in
+// milliseconds.
+func subdomainLeaseExpiriesKey(ctx context.Context) string {
+	return namespaceFromContext(ctx) + "subdomain-lease-expiries"
+}
+
+// subdomainLeaseIDsKey is a hash of the subdomain each lease ID is for.
+func subdomainLeaseIDsKey(ctx context.Context) string {
+	return namespaceFromContext(ctx) + "subdomain-lease-ids"
+}
+
+var claimSubdomainScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+  return 0
+end
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+redis.call('HSET', KEYS[3], ARGV[4], ARGV[1])
+return 1
+`)
+
+var renewLeaseScript = redis.NewScript(`
+local expires = redis.call('ZSCORE', KEYS[2], ARGV[1])
+if not expires then
+  return 0
+end
+if tonumber(expires) <= tonumber(ARGV[4]) then
+  return -1
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`)
+
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func (r RedisRegistrar) ClaimSubdomain(ctx context.Context, lease SubdomainLease) error {
+	encoded, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+	domain := canonicalName(string(lease.Domain))
+	claimed, err := claimSubdomainScript.Run(ctx, r.client, []string{subdomainLeasesKey(ctx), subdomainLeaseExpiriesKey(ctx), subdomainLeaseIDsKey(ctx)}, domain, encoded, unixMillis(lease.Expires), lease.Id).Int64()
+	if err != nil {
+		return err
+	}
+	if claimed == 0 {
+		return ErrSubdomainTaken
+	}
+	return nil
+}
+
+func (r RedisRegistrar) GetSubdomainLease(ctx context.Context, fqdn Domain) (SubdomainLease, error) {
+	var lease SubdomainLease
+	encoded, err := r.client.HGet(ctx, subdomainLeasesKey(ctx), canonicalName(string(fqdn))).Result()
+	if err == redis.Nil {
+		return lease, ErrLeaseNotFound
+	}
+	if err != nil {
+		return lease, err
+	}
+	err = json.Unmarshal([]byte(encoded), &lease)
+	return lease, err
+}
+
+func (r RedisRegistrar) GetLease(ctx context.Context, id string) (SubdomainLease, error) {
+	domain, err := r.client.HGet(ctx, subdomainLeaseIDsKey(ctx), id).Result()
+	if err == redis.Nil {
+		return SubdomainLease{}, ErrLeaseNotFound
+	}
+	if err != nil {
+		return SubdomainLease{}, err
+	}
+	return r.GetSubdomainLease(ctx, Domain(domain))
+}
+
+func (r RedisRegistrar) RenewLease(ctx context.Context, id string, expires time.Time, now time.Time) (SubdomainLease, error) {
+	lease, err := r.GetLease(ctx, id)
+	if err != nil {
+		return lease, err
+	}
+	lease.Expires = expires
+	encoded, err := json.Marshal(lease)
+	if err != nil {
+		return lease, err
+	}
+	// The expiry is checked again by the script, since the lease may have expired or been deleted since it was read
+	renewed, err := renewLeaseScript.Run(ctx, r.client, []string{subdomainLeasesKey(ctx), subdomainLeaseExpiriesKey(ctx)}, canonicalName(string(lease.Domain)), encoded, unixMillis(expires), unixMillis(now)).Int64()
+	if err != nil {
+		return lease, err
+	}
+	switch renewed {
+	case 0:
+		return lease, ErrLeaseNotFound
+	case -1:
+		return lease, ErrLeaseExpired
+	}
+	return lease, nil
+}
+
+func (r RedisRegistrar) ListExpiredLeases(ctx context.Context, now time.Time) ([]SubdomainLease, error) {
+	domains, err := r.client.ZRangeByScore(ctx, subdomainLeaseExpiriesKey(ctx), &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(unixMillis(now), 10)}).Result()
+	if err != nil || len(domains) == 0 {
+		return nil, err
+	}
+	encoded, err := r.client.HMGet(ctx, subdomainLeasesKey(ctx), domains...).Result()
+	if err != nil {
+		return nil, err
+	}
+	var leases []SubdomainLease
+	for _, value := range encoded {
+		// Leases deleted since the expiries were read are skipped
+		value, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var lease SubdomainLease
+		if err := json.Unmarshal([]byte(value), &lease); err != nil {
+			return nil, err
+		}
+		leases = append(leases, lease)
+	}
+	return leases, nil
+}
+
+func (r RedisRegistrar) DeleteLease(ctx context.Context, lease SubdomainLease) error {
+	domain := canonicalName(string(lease.Domain))
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, subdomainLeasesKey(ctx), domain)
+		pipe.ZRem(ctx, subdomainLeaseExpiriesKey(ctx), domain)
+		pipe.HDel(ctx, subdomainLeaseIDsKey(ctx), lease.Id)
+		return nil
+	})
+	return err
+}
+
+func (r RedisRegistrar) SubscribeRecordChanges(ctx context.Context) <-chan RecordChange {
+	changes := make(chan RecordChange, 100)
+	pubsub := r.client.Subscribe(ctx, recordChangesChannel)
+
+	go func() {
+		defer close(changes)
+		defer pubsub.Close()
+		logger := hclog.FromContext(ctx)
+
+		// Subscription messages are delivered every time go-redis (re)subscribes, which is when messages may have been
+		// missed
+		messages := pubsub.ChannelWithSubscriptions(ctx, 100)
+		for {
+			var change RecordChange
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				if message, isMessage := message.(*redis.Message); isMessage {
+					if err := json.Unmarshal([]byte(message.Payload), &change); err != nil {
+						logger.Warn("Ignoring malformed record change", "payload", message.Payload, "error", err)
+						continue
+					}
+				}
+			}
+
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes
+}
+
+func (r RedisRegistrar) Watch(ctx context.Context, prefix string) <-chan RecordChange {
+	return filterRecordChanges(ctx, r.SubscribeRecordChanges(ctx), prefix)
+}
+
+const (
+	// tenantsKey is a hash of each encoded tenant by ID.
+	tenantsKey = "tenants"
+	// tenantZonesKey is a hash of the ID of the tenant each zone belongs to.
+	tenantZonesKey = "tenant-zones"
+	// tenantTSIGKeysKey is a hash of the ID of the tenant each TSIG key belongs to.
+	tenantTSIGKeysKey = "tenant-tsig-keys"
+)
+
+// createTenantScript is passed the tenant ID, the encoded tenant, the number of zones and the record changes channel,
+// followed by the zones and then the TSIG keys, as ARGV. Everything is checked before anything is written, so that two
+// tenants created at once can't end up with the same zone. An empty record change is published once the tenant is
+// stored, so that every replica reads the tenants again and flushes what it has cached for the tenant's zones.
+var createTenantScript = redis.NewScript(`
+local zones = tonumber(ARGV[3])
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 1 then
+  return redis.error_reply('tenant conflict: tenant ' .. ARGV[1] .. ' already exists')
+end
+for i = 5, #ARGV do
+  local hash = KEYS[2]
+  if i > 4 + zones then
+    hash = KEYS[3]
+  end
+  local owner = redis.call('HGET', hash, ARGV[i])
+  if owner then
+    return redis.error_reply('tenant conflict: ' .. ARGV[i] .. ' already belongs to tenant ' .. owner)
+  end
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+for i = 5, #ARGV do
+  if i > 4 + zones then
+    redis.call('HSET', KEYS[3], ARGV[i], ARGV[1])
+  else
+    redis.call('HSET', KEYS[2], ARGV[i], ARGV[1])
+  end
+end
+redis.call('PUBLISH', ARGV[4], '{}')
+return 1
+`)
+
+func (r RedisRegistrar) CreateTenant(ctx context.Context, tenant RegisteredTenant) error {
+	encoded, err := json.Marshal(tenant)
+	if err != nil {
+		return err
+	}
+	args := []interface{}{tenant.Id, encoded, len(tenant.Zones), recordChangesChannel}
+	for _, zone := range tenant.Zones {
+		args = append(args, canonicalName(zone))
+	}
+	for _, key := range tenant.TsigKeys {
+		args = append(args, strings.ToLower(key))
+	}
+	err = createTenantScript.Run(ctx, r.client, []string{tenantsKey, tenantZonesKey, tenantTSIGKeysKey}, args...).Err()
+	if err != nil && strings.HasPrefix(err.Error(), ErrTenantConflict.Error()) {
+		return fmt.Errorf("%w: %s", ErrTenantConflict, strings.TrimPrefix(err.Error(), ErrTenantConflict.Error()+": "))
+	}
+	return err
+}
+
+func (r RedisRegistrar) ListTenants(ctx context.Context) ([]RegisteredTenant, error) {
+	encoded, err := r.client.HGetAll(ctx, tenantsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	tenants := make([]RegisteredTenant, 0, len(encoded))
+	for _, value := range encoded {
+		var tenant RegisteredTenant
+		if err := json.Unmarshal([]byte(value), &tenant); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+// acmeDNSAccountsKey is a hash of each encoded acme-dns account by username, kept in the namespace of the tenant that
+// owns the account's subdomain.
+func acmeDNSAccountsKey(ctx context.Context) string {
+	return namespaceFromContext(ctx) + "acme-dns-accounts"
+}
+
+func (r RedisRegistrar) CreateACMEDNSAccount(ctx context.Context, account ACMEDNSAccount) error {
+	encoded, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, acmeDNSAccountsKey(ctx), account.Username, encoded).Err()
+}
+
+func (r RedisRegistrar) GetACMEDNSAccount(ctx context.Context, username string) (ACMEDNSAccount, error) {
+	var account ACMEDNSAccount
+	encoded, err := r.client.HGet(ctx, acmeDNSAccountsKey(ctx), username).Result()
+	if err == redis.Nil {
+		return account, ErrACMEDNSAccountNotFound
+	}
+	if err != nil {
+		return account, err
+	}
+	err = json.Unmarshal([]byte(encoded), &account)
+	return account, err
+}
+
+// Webhooks and their delivery logs are kept in the namespace of the tenant that registered them.
+func webhooksKey(ctx context.Context) string {
+	return namespaceFromContext(ctx) + "webhooks"
+}
+
+// maxWebhookDeliveries is how many deliveries are kept in the log of each webhook.
+const maxWebhookDeliveries = 100
+
+func webhookDeliveriesKey(ctx context.Context, id string) string {
+	return namespaceFromContext(ctx) + "webhook-deliveries:" + id
+}
+
+func (r RedisRegistrar) CreateWebhook(ctx context.Context, webhook RegisteredWebhook) error {
+	encoded, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, webhooksKey(ctx), webhook.Id, encoded).Err()
+}
+
+func (r RedisRegistrar) ListWebhooks(ctx context.Context) ([]RegisteredWebhook, error) {
+	encoded, err := r.client.HGetAll(ctx, webhooksKey(ctx)).Result()
+	if err != nil {
+		return nil, err
+	}
+	webhooks := make([]RegisteredWebhook, 0, len(encoded))
+	for _, value := range encoded {
+		var webhook RegisteredWebhook
+		if err := json.Unmarshal([]byte(value), &webhook); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+func (r RedisRegistrar) DeleteWebhook(ctx context.Context, id string) error {
+	var deleted *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.HDel(ctx, webhooksKey(ctx), id)
+		pipe.Del(ctx, webhookDeliveriesKey(ctx, id))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if deleted.Val() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r RedisRegistrar) LogWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	encoded, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, webhookDeliveriesKey(ctx, delivery.WebhookId), encoded)
+		pipe.LTrim(ctx, webhookDeliveriesKey(ctx, delivery.WebhookId), 0, maxWebhookDeliveries-1)
+		return nil
+	})
+	return err
+}
+
+func (r RedisRegistrar) ListWebhookDeliveries(ctx context.Context, id string) ([]WebhookDelivery, error) {
+	var exists *redis.BoolCmd
+	var encoded *redis.StringSliceCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		exists = pipe.HExists(ctx, webhooksKey(ctx), id)
+		encoded = pipe.LRange(ctx, webhookDeliveriesKey(ctx, id), 0, -1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !exists.Val() {
+		return nil, ErrWebhookNotFound
+	}
+	deliveries := make([]WebhookDelivery, 0, len(encoded.Val()))
+	for _, value := range encoded.Val() {
+		var delivery WebhookDelivery
+		if err := json.Unmarshal([]byte(value), &delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// The audit log is a stream of every entry, plus a stream per zone so that filtering by zone doesn't have to scan
+// everything. Entries have the same ID in both streams. Each tenant has its own audit log in its namespace.
+func auditKey(ctx context.Context) string {
+	return namespaceFromContext(ctx) + "audit"
+}
+
+func auditZoneKey(ctx context.Context, zone Domain) string {
+	return namespaceFromContext(ctx) + "audit:zone:" + canonicalName(string(zone))
+}
+
+var appendAuditEntryScript = redis.NewScript(`
+local id = redis.call('XADD', KEYS[1], '*', 'entry', ARGV[1])
+redis.call('XADD', KEYS[2], id, 'entry', ARGV[1])
+return id
+`)
+
+func (r RedisRegistrar) AppendAuditEntry(ctx context.Context, entry AuditEntry) error {
+	encoded, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return appendAuditEntryScript.Run(ctx, r.client, []string{auditKey(ctx), auditZoneKey(ctx, Domain(entry.Zone))}, encoded).Err()
+}
+
+func (r RedisRegistrar) ListAuditEntries(ctx context.Context, query AuditQuery) ([]AuditEntry, error) {
+	key := auditKey(ctx)
+	if query.Zone != "" {
+		key = auditZoneKey(ctx, query.Zone)
+	}
+	// Stream IDs start with the time the entry was added in milliseconds, so time ranges map directly onto ID ranges
+	start, end := "-", "+"
+	if !query.Since.IsZero() {
+		start = strconv.FormatInt(query.Since.UnixNano()/int64(time.Millisecond), 10)
+	}
+	if !query.Until.IsZero() {
+		end = strconv.FormatInt(query.Until.UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	var messages []redis.XMessage
+	var err error
+	if query.Limit > 0 {
+		messages, err = r.client.XRevRangeN(ctx, key, end, start, int64(query.Limit)).Result()
+	} else {
+		messages, err = r.client.XRevRange(ctx, key, end, start).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]AuditEntry, 0, len(messages))
+	for _, message := range messages {
+		var entry AuditEntry
+		encoded, _ := message.Values["entry"].(string)
+		if err := json.Unmarshal([]byte(encoded), &entry); err != nil {
+			return nil, err
+		}
+		entry.Id = message.ID
+		entries = append(entries, entry)
+	}
+	return entries, nil
 }
 
 func NewRedisRegistrar(redisAddress string) Registrar {
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func TestDelete(t *testing.T) {
@@ -41,3 +43,256 @@ func TestDelete(t *testing.T) {
 
 	assert.NoError(t, err)
 }
+
+func TestClaimRecordQuota(t *testing.T) {
+	ctx := context.Background()
+	err := withRedisTestServer(ctx, func(port int) {
+		registrar := NewRedisRegistrar("localhost:" + strconv.Itoa(port))
+		records := func(fqdns ...string) []RecordReference {
+			var references []RecordReference
+			for _, fqdn := range fqdns {
+				references = append(references, RecordReference{Domain: fqdn, Type: RecordTypeA})
+			}
+			return references
+		}
+
+		// Claims count before their records are written, so neither a batch nor another request gets past the limit
+		assert.NoError(t, registrar.ClaimRecordQuota(ctx, "test", records("a.quota.", "b.quota."), 2))
+		assert.ErrorIs(t, registrar.ClaimRecordQuota(ctx, "test", records("c.quota."), 2), ErrQuotaExceeded)
+		assert.ErrorIs(t, registrar.ClaimRecordQuota(ctx, "other", records("a.quota.", "b.quota.", "c.quota."), 2), ErrQuotaExceeded)
+		assert.NoError(t, registrar.ClaimRecordQuota(ctx, "other", records("a.quota.", "b.quota.", "a.quota."), 2))
+		for _, fqdn := range []Domain{"a.quota.", "b.quota."} {
+			assert.NoError(t, registrar.SetRecord(ctx, fqdn, RecordTypeA, "1.2.3.4"))
+		}
+
+		// Already claimed records can always be claimed again, but new ones are over the limit
+		assert.NoError(t, registrar.ClaimRecordQuota(ctx, "test", records("a.quota."), 2))
+		assert.ErrorIs(t, registrar.ClaimRecordQuota(ctx, "test", records("b.quota.", "c.quota."), 2), ErrQuotaExceeded)
+
+		// Deleting a record frees up its quota, but only once its claim is older than the grace period
+		assert.NoError(t, registrar.DeleteRecord(ctx, "a.quota.", RecordTypeA, "1.2.3.4"))
+		assert.ErrorIs(t, registrar.ClaimRecordQuota(ctx, "test", records("c.quota."), 2), ErrQuotaExceeded)
+		claimed := float64(unixMillis(time.Now().Add(-2 * quotaClaimGracePeriod)))
+		for _, fqdn := range []Domain{"a.quota.", "b.quota."} {
+			claim := &redis.Z{Score: claimed, Member: recordKey(ctx, fqdn, RecordTypeA, "")}
+			assert.NoError(t, registrar.(RedisRegistrar).client.ZAdd(ctx, "quota-claims:test", claim).Err())
+		}
+		assert.NoError(t, registrar.ClaimRecordQuota(ctx, "test", records("c.quota."), 2))
+		assert.ErrorIs(t, registrar.ClaimRecordQuota(ctx, "test", records("d.quota."), 2), ErrQuotaExceeded)
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestWriteRecords(t *testing.T) {
+	ctx := context.Background()
+	err := withRedisTestServer(ctx, func(port int) {
+		registrar := NewRedisRegistrar("localhost:" + strconv.Itoa(port))
+		assert.NoError(t, registrar.SetRecord(ctx, "old.batch.com.", RecordTypeA, "1.1.1.1"))
+		assert.NoError(t, registrar.SetRecord(ctx, "kept.batch.com.", RecordTypeA, "2.2.2.2"))
+		assert.NoError(t, registrar.SetRecord(withView(ctx, "internal"), "kept.batch.com.", RecordTypeA, "10.2.2.2"))
+		assert.NoError(t, registrar.SetRecord(ctx, "other.com.", RecordTypeA, "3.3.3.3"))
+		value, err := registrar.GetViewRecord(withView(ctx, "internal"), "kept.batch.com.", RecordTypeA)
+		assert.NoError(t, err)
+		assert.Equal(t, "10.2.2.2", value)
+		_, err = registrar.GetViewRecord(withView(ctx, "internal"), "old.batch.com.", RecordTypeA)
+		assert.ErrorIs(t, err, ErrRecordNotFound, "records aren't read from the default view")
+
+		records, err := registrar.ListRecords(ctx, "batch.com.")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []StoredRecord{
+			{Domain: "old.batch.com.", Type: RecordTypeA, Value: "1.1.1.1"},
+			{Domain: "kept.batch.com.", Type: RecordTypeA, Value: "2.2.2.2"},
+			{Domain: "kept.batch.com.", Type: RecordTypeA, View: "internal", Value: "10.2.2.2"},
+		}, records)
+
+		// Nothing is written if a record being deleted has changed
+		_, err = registrar.WriteRecords(ctx, []RecordWrite{
+			{Domain: "new.batch.com.", Type: RecordTypeA, Value: "4.4.4.4"},
+			{Domain: "old.batch.com.", Type: RecordTypeA, Value: "9.9.9.9", Delete: true},
+		})
+		assert.ErrorIs(t, err, ErrRecordChanged)
+		_, err = registrar.GetRecord(ctx, "new.batch.com.", RecordTypeA)
+		assert.ErrorIs(t, err, ErrRecordNotFound)
+
+		results, err := registrar.WriteRecords(ctx, []RecordWrite{
+			{Domain: "new.batch.com.", Type: RecordTypeA, Value: "4.4.4.4"},
+			{Domain: "kept.batch.com.", Type: RecordTypeA, Value: "5.5.5.5"},
+			{Domain: "old.batch.com.", Type: RecordTypeA, Value: "1.1.1.1", Delete: true},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []RecordWriteResult{{}, {Previous: "2.2.2.2", Existed: true}, {Previous: "1.1.1.1", Existed: true}}, results)
+
+		records, err = registrar.ListRecords(ctx, "batch.com.")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []StoredRecord{
+			{Domain: "new.batch.com.", Type: RecordTypeA, Value: "4.4.4.4"},
+			{Domain: "kept.batch.com.", Type: RecordTypeA, Value: "5.5.5.5"},
+			{Domain: "kept.batch.com.", Type: RecordTypeA, View: "internal", Value: "10.2.2.2"},
+		}, records)
+
+		versions, err := registrar.RecordHistory(ctx, "old.batch.com.", RecordTypeA)
+		assert.NoError(t, err)
+		if assert.Len(t, versions, 2) {
+			assert.True(t, versions[0].Deleted)
+			assert.Equal(t, int64(2), versions[0].Version)
+			assert.True(t, versions[1].Created)
+		}
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestRecordHistory_ExpiresOnceDeleted(t *testing.T) {
+	ctx := context.Background()
+	err := withRedisTestServer(ctx, func(port int) {
+		registrar := NewRedisRegistrar("localhost:" + strconv.Itoa(port))
+		client := registrar.(RedisRegistrar).client
+		key := recordKey(ctx, "www.history.com.", RecordTypeA, "")
+		ttls := func() []time.Duration {
+			var ttls []time.Duration
+			for _, key := range []string{"history:" + key, "history-version:" + key} {
+				ttl, err := client.PTTL(ctx, key).Result()
+				assert.NoError(t, err)
+				ttls = append(ttls, ttl)
+			}
+			return ttls
+		}
+
+		// Changes that are older than the history that is kept are trimmed
+		old := &redis.Z{Score: float64(unixMillis(time.Now().Add(-2 * recordHistoryRetention))), Member: "old"}
+		assert.NoError(t, client.ZAdd(ctx, recordChangesKey, old).Err())
+
+		assert.NoError(t, registrar.SetRecord(ctx, "www.history.com.", RecordTypeA, "1.1.1.1"))
+		assert.Equal(t, []time.Duration{-1, -1}, ttls())
+		assert.ErrorIs(t, client.ZScore(ctx, recordChangesKey, "old").Err(), redis.Nil)
+
+		// The history of deleted records expires, unless they are created again
+		assert.NoError(t, registrar.DeleteRecord(ctx, "www.history.com.", RecordTypeA, "1.1.1.1"))
+		for _, ttl := range ttls() {
+			assert.InDelta(t, recordHistoryRetention, ttl, float64(time.Minute))
+		}
+		assert.NoError(t, registrar.SetRecord(ctx, "www.history.com.", RecordTypeA, "2.2.2.2"))
+		assert.Equal(t, []time.Duration{-1, -1}, ttls())
+
+		versions, err := registrar.RecordHistory(ctx, "www.history.com.", RecordTypeA)
+		assert.NoError(t, err)
+		var created []bool
+		for _, version := range versions {
+			created = append(created, version.Created)
+		}
+		assert.Equal(t, []bool{true, false, true}, created)
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestSubdomainLeases(t *testing.T) {
+	ctx := context.Background()
+	err := withRedisTestServer(ctx, func(port int) {
+		registrar := NewRedisRegistrar("localhost:" + strconv.Itoa(port))
+		_, err := registrar.GetSubdomainLease(ctx, "abc.eph.example.com.")
+		assert.ErrorIs(t, err, ErrLeaseNotFound)
+
+		now := time.Now()
+		lease := SubdomainLease{Id: "lease", Domain: "abc.eph.example.com.", TokenHash: secretTokenHash("eph_token"), Expires: now.Add(time.Hour).UTC()}
+		assert.NoError(t, registrar.ClaimSubdomain(ctx, lease))
+		assert.ErrorIs(t, registrar.ClaimSubdomain(ctx, SubdomainLease{Domain: "ABC.eph.example.com.", Expires: lease.Expires}), ErrSubdomainTaken)
+
+		stored, err := registrar.GetSubdomainLease(ctx, "abc.eph.example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, lease.TokenHash, stored.TokenHash)
+		assert.True(t, lease.Expires.Equal(stored.Expires))
+
+		byID, err := registrar.GetLease(ctx, "lease")
+		assert.NoError(t, err)
+		assert.Equal(t, stored, byID)
+
+		renewed, err := registrar.RenewLease(ctx, "lease", now.Add(2*time.Hour), now)
+		assert.NoError(t, err)
+		assert.True(t, now.Add(2*time.Hour).Equal(renewed.Expires))
+		expired, err := registrar.ListExpiredLeases(ctx, now.Add(90*time.Minute))
+		assert.NoError(t, err)
+		assert.Empty(t, expired)
+		expired, err = registrar.ListExpiredLeases(ctx, now.Add(3*time.Hour))
+		assert.NoError(t, err)
+		if assert.Len(t, expired, 1) {
+			assert.Equal(t, "lease", expired[0].Id)
+		}
+		_, err = registrar.RenewLease(ctx, "lease", now.Add(4*time.Hour), now.Add(3*time.Hour))
+		assert.ErrorIs(t, err, ErrLeaseExpired)
+
+		assert.NoError(t, registrar.DeleteLease(ctx, renewed))
+		_, err = registrar.GetLease(ctx, "lease")
+		assert.ErrorIs(t, err, ErrLeaseNotFound)
+		assert.NoError(t, registrar.ClaimSubdomain(ctx, lease), "the subdomain can be claimed again")
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestTenantNamespaces(t *testing.T) {
+	ctx := context.Background()
+	err := withRedisTestServer(ctx, func(port int) {
+		registrar := NewRedisRegistrar("localhost:" + strconv.Itoa(port))
+		subscribeCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		changes := registrar.SubscribeRecordChanges(subscribeCtx)
+		assert.Equal(t, RecordChange{}, <-changes)
+		tenant := RegisteredTenant{Tenant: Tenant{Id: "acme", Zones: []string{"acme.com."}, TsigKeys: []string{"acme-key."}, KeyPrefix: tenantKeyPrefix("acme")}}
+		assert.NoError(t, registrar.CreateTenant(ctx, tenant))
+		assert.Equal(t, RecordChange{}, <-changes, "other replicas are told to read the tenants again")
+		for _, conflicting := range []Tenant{
+			{Id: "acme", Zones: []string{"other.com."}},
+			{Id: "other", Zones: []string{"acme.com."}},
+			{Id: "other", Zones: []string{"other.com."}, TsigKeys: []string{"acme-key."}},
+		} {
+			assert.ErrorIs(t, registrar.CreateTenant(ctx, RegisteredTenant{Tenant: conflicting}), ErrTenantConflict)
+		}
+		tenants, err := registrar.ListTenants(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []RegisteredTenant{tenant}, tenants)
+
+		// The same name can be stored in several namespaces without them seeing each other's records
+		start := time.Now()
+		namespaced := withNamespace(ctx, tenantKeyPrefix("acme"))
+		assert.NoError(t, registrar.SetRecord(namespaced, "www.acme.com.", RecordTypeA, "1.1.1.1"))
+		assert.NoError(t, registrar.SetRecord(ctx, "www.acme.com.", RecordTypeA, "2.2.2.2"))
+		value, err := registrar.GetRecord(namespaced, "www.acme.com.", RecordTypeA)
+		assert.NoError(t, err)
+		assert.Equal(t, "1.1.1.1", value)
+
+		records, err := registrar.ListRecords(namespaced, "acme.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []StoredRecord{{Domain: "www.acme.com.", Type: RecordTypeA, Value: "1.1.1.1"}}, records)
+		records, err = registrar.ListRecords(ctx, "acme.com.")
+		assert.NoError(t, err)
+		assert.Equal(t, []StoredRecord{{Domain: "www.acme.com.", Type: RecordTypeA, Value: "2.2.2.2"}}, records)
+
+		changed, err := registrar.ListChangedRecords(namespaced, start)
+		assert.NoError(t, err)
+		assert.Equal(t, []RecordReference{{Domain: "www.acme.com.", Type: RecordTypeA}}, changed)
+		changed, err = registrar.ListChangedRecords(ctx, start)
+		assert.NoError(t, err)
+		assert.Equal(t, []RecordReference{{Domain: "www.acme.com.", Type: RecordTypeA}}, changed)
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestACMEDNSAccounts(t *testing.T) {
+	ctx := context.Background()
+	err := withRedisTestServer(ctx, func(port int) {
+		registrar := NewRedisRegistrar("localhost:" + strconv.Itoa(port))
+		_, err := registrar.GetACMEDNSAccount(ctx, "user")
+		assert.ErrorIs(t, err, ErrACMEDNSAccountNotFound)
+
+		account := ACMEDNSAccount{Username: "user", PasswordHash: secretTokenHash("password"), Subdomain: "sub", AllowFrom: []string{"192.0.2.0/24"}}
+		assert.NoError(t, registrar.CreateACMEDNSAccount(ctx, account))
+		stored, err := registrar.GetACMEDNSAccount(ctx, "user")
+		assert.NoError(t, err)
+		assert.Equal(t, account, stored)
+	})
+
+	assert.NoError(t, err)
+}
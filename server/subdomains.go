@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/teris-io/shortid"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrSubdomainForbidden is returned when a record in a claimed subdomain is changed without the subdomain's owner
+// token, or when an owner token is used to change a record outside of its subdomain.
+var ErrSubdomainForbidden = errors.New("not allowed to change records in this subdomain")
+
+// SubdomainConfig configures the ephemeral subdomains that can be claimed through the API.
+type SubdomainConfig struct {
+	// Zone is the zone subdomains are claimed beneath. Claiming subdomains is disabled if it is empty.
+	Zone Domain
+	// Lifetime is how long a claimed subdomain lasts, and how much longer it lasts each time its lease is renewed.
+	Lifetime time.Duration
+	// ReapInterval is how often the records of expired leases are deleted. It defaults to a minute.
+	ReapInterval time.Duration
+}
+
+// SubdomainLease records who a claimed subdomain belongs to. Only a hash of the owner token is kept.
+type SubdomainLease struct {
+	Id        string    `json:"id"`
+	Domain    Domain    `json:"domain"`
+	TokenHash string    `json:"tokenHash"`
+	Expires   time.Time `json:"expires"`
+}
+
+// subdomainTokenPrefix starts every owner token, so they can be told apart from other API tokens.
+const subdomainTokenPrefix = "eph_"
+
+// newSecretToken generates an API token that is only ever returned once, such as an owner token.
+func newSecretToken(prefix string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(raw), nil
+}
+
+// secretTokenHash is how a token from newSecretToken is stored. Unlike tokenID, it is the whole hash, since it's used
+// to check the token rather than just to identify it.
+func secretTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSubdomainLabel returns a random label that is valid in a hostname. shortid uses both cases of letters along with
+// '-' and '_', so the label is lower cased and '_' is replaced.
+func newSubdomainLabel() (string, error) {
+	for {
+		id, err := shortid.Generate()
+		if err != nil {
+			return "", err
+		}
+		label := strings.Trim(strings.ReplaceAll(strings.ToLower(id), "_", "-"), "-")
+		if label != "" {
+			return label, nil
+		}
+	}
+}
+
+// leasedSubdomain returns the subdomain of zone that a lease on fqdn would be for, which is the name one label beneath
+// zone. It returns false for the zone itself and for names outside of it.
+func leasedSubdomain(fqdn, zone Domain) (Domain, bool) {
+	name, parent := canonicalName(string(fqdn)), canonicalName(string(zone))
+	if name == parent || !isSubdomain(Domain(name), Domain(parent)) {
+		return "", false
+	}
+	labels := dnsLabels(strings.TrimSuffix(name, parent))
+	return Domain(labels[len(labels)-1] + "." + parent), true
+}
+
+type apiTokenContextKey struct{}
+
+// apiTokenMiddleware makes the bearer token of API requests available to SubdomainRegistrar.
+func apiTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiTokenContextKey{}, apiToken(r))))
+	})
+}
+
+func apiTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(apiTokenContextKey{}).(string)
+	return token
+}
+
+// SubdomainRegistrar only lets records in claimed subdomains be changed with the owner token of the subdomain, and
+// only lets owner tokens change records in their own subdomain. It wraps the registrar used by both the HTTP API and
+// the DNS UPDATE handler. DNS updates don't have a token, so they can't change records in claimed subdomains.
+type SubdomainRegistrar struct {
+	Registrar
+
+	zone Domain
+	now  func() time.Time
+}
+
+func NewSubdomainRegistrar(next Registrar, zone Domain) *SubdomainRegistrar {
+	return &SubdomainRegistrar{Registrar: next, zone: zone, now: time.Now}
+}
+
+// authorize checks whether the token in ctx can change records at fqdn. Once a lease has expired its records can only
+// be deleted, so that nothing can be added while they are being cleaned up.
+func (s *SubdomainRegistrar) authorize(ctx context.Context, fqdn Domain, deleting bool) error {
+	token := apiTokenFromContext(ctx)
+	subdomain, ok := leasedSubdomain(fqdn, s.zone)
+	if !ok {
+		if strings.HasPrefix(token, subdomainTokenPrefix) {
+			return ErrSubdomainForbidden
+		}
+		return nil
+	}
+
+	lease, err := s.Registrar.GetSubdomainLease(ctx, subdomain)
+	if errors.Is(err, ErrLeaseNotFound) {
+		if strings.HasPrefix(token, subdomainTokenPrefix) {
+			return ErrSubdomainForbidden
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if lease.TokenHash != secretTokenHash(token) || (!deleting && !s.now().Before(lease.Expires)) {
+		return ErrSubdomainForbidden
+	}
+	return nil
+}
+
+func (s *SubdomainRegistrar) SetRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) error {
+	_, _, err := s.SwapRecord(ctx, fqdn, recordType, value)
+	return err
+}
+
+func (s *SubdomainRegistrar) SwapRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) (string, bool, error) {
+	if err := s.authorize(ctx, fqdn, false); err != nil {
+		return "", false, err
+	}
+	return s.Registrar.SwapRecord(ctx, fqdn, recordType, value)
+}
+
+func (s *SubdomainRegistrar) DeleteRecord(ctx context.Context, fqdn Domain, recordType RecordType, currentValue string) error {
+	if err := s.authorize(ctx, fqdn, true); err != nil {
+		return err
+	}
+	return s.Registrar.DeleteRecord(ctx, fqdn, recordType, currentValue)
+}
+
+func (s *SubdomainRegistrar) WriteRecords(ctx context.Context, writes []RecordWrite) ([]RecordWriteResult, error) {
+	type check struct {
+		fqdn     Domain
+		deleting bool
+	}
+	authorized := map[check]bool{}
+	for _, write := range writes {
+		c := check{fqdn: Domain(canonicalName(string(write.Domain))), deleting: write.Delete}
+		if authorized[c] {
+			continue
+		}
+		if err := s.authorize(ctx, c.fqdn, c.deleting); err != nil {
+			return nil, err
+		}
+		authorized[c] = true
+	}
+	return s.Registrar.WriteRecords(ctx, writes)
+}
+
+// maxSubdomainClaimAttempts is how many random labels are tried before giving up on claiming a subdomain.
+const maxSubdomainClaimAttempts = 10
+
+// errNoUnusedSubdomain is returned by claimSubdomain when every label it tried was already in use.
+var errNoUnusedSubdomain = errors.New("couldn't find an unused subdomain")
+
+// claimSubdomain claims a random subdomain of zone that doesn't have a lease or any records, returning the lease and
+// its owner token.
+func claimSubdomain(ctx context.Context, registrar Registrar, zone Domain, expires time.Time) (SubdomainLease, string, error) {
+	token, err := newSecretToken(subdomainTokenPrefix)
+	if err != nil {
+		return SubdomainLease{}, "", err
+	}
+	id, err := shortid.Generate()
+	if err != nil {
+		return SubdomainLease{}, "", err
+	}
+	for i := 0; i < maxSubdomainClaimAttempts; i++ {
+		label, err := newSubdomainLabel()
+		if err != nil {
+			return SubdomainLease{}, "", err
+		}
+		lease := SubdomainLease{Id: id, Domain: Domain(label + "." + canonicalName(string(zone))), TokenHash: secretTokenHash(token), Expires: expires}
+
+		// Records can be created without a lease, so a label nobody has claimed may still be in use
+		records, err := registrar.ListRecords(ctx, lease.Domain)
+		if err != nil {
+			return SubdomainLease{}, "", err
+		}
+		if len(records) > 0 {
+			continue
+		}
+
+		err = registrar.ClaimSubdomain(ctx, lease)
+		if errors.Is(err, ErrSubdomainTaken) {
+			continue
+		}
+		if err != nil {
+			return SubdomainLease{}, "", err
+		}
+		return lease, token, nil
+	}
+	return SubdomainLease{}, "", errNoUnusedSubdomain
+}
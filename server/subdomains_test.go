@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestLeasedSubdomain(t *testing.T) {
+	for fqdn, expected := range map[Domain]Domain{
+		"abc.eph.example.com.":      "abc.eph.example.com.",
+		"www.ABC.eph.example.com":   "abc.eph.example.com.",
+		"a.b.abc.eph.example.com.":  "abc.eph.example.com.",
+		"eph.example.com.":          "",
+		"abc.other.example.com.":    "",
+		"abc.eph.example.com.evil.": "",
+	} {
+		subdomain, ok := leasedSubdomain(fqdn, "eph.example.com.")
+		assert.Equal(t, expected != "", ok, "%s", fqdn)
+		assert.Equal(t, expected, subdomain, "%s", fqdn)
+	}
+}
+
+func TestNewSubdomainLabel(t *testing.T) {
+	hostnameLabel := regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	for i := 0; i < 100; i++ {
+		label, err := newSubdomainLabel()
+		assert.NoError(t, err)
+		assert.Regexp(t, hostnameLabel, label)
+	}
+}
+
+func TestClaimSubdomain(t *testing.T) {
+	ctx := context.Background()
+	registrar := newMemoryRegistrar()
+	expires := time.Now().Add(time.Hour)
+
+	lease, token, err := claimSubdomain(ctx, registrar, "eph.example.com.", expires)
+	assert.NoError(t, err)
+	assert.True(t, isSubdomain(lease.Domain, "eph.example.com."))
+	assert.Equal(t, secretTokenHash(token), lease.TokenHash)
+	assert.Equal(t, expires, lease.Expires)
+
+	stored, err := registrar.GetSubdomainLease(ctx, lease.Domain)
+	assert.NoError(t, err)
+	assert.Equal(t, lease, stored)
+	assert.ErrorIs(t, registrar.ClaimSubdomain(ctx, lease), ErrSubdomainTaken)
+
+	other, otherToken, err := claimSubdomain(ctx, registrar, "eph.example.com.", expires)
+	assert.NoError(t, err)
+	assert.NotEqual(t, lease.Domain, other.Domain)
+	assert.NotEqual(t, token, otherToken)
+}
+
+func TestSubdomainRegistrar(t *testing.T) {
+	backend := newMemoryRegistrar()
+	registrar := NewSubdomainRegistrar(backend, "eph.example.com.")
+	now := time.Now()
+	registrar.now = func() time.Time { return now }
+
+	owner := "eph_owner"
+	assert.NoError(t, backend.ClaimSubdomain(context.Background(), SubdomainLease{Domain: "abc.eph.example.com.", TokenHash: secretTokenHash(owner), Expires: now.Add(time.Hour)}))
+	withToken := func(token string) context.Context {
+		return context.WithValue(context.Background(), apiTokenContextKey{}, token)
+	}
+
+	// Only the owner token can change records in a claimed subdomain
+	assert.NoError(t, registrar.SetRecord(withToken(owner), "www.abc.eph.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.ErrorIs(t, registrar.SetRecord(withToken(""), "www.abc.eph.example.com.", RecordTypeA, "2.2.2.2"), ErrSubdomainForbidden)
+	assert.ErrorIs(t, registrar.SetRecord(withToken("someone-else"), "abc.eph.example.com.", RecordTypeA, "2.2.2.2"), ErrSubdomainForbidden)
+	assert.ErrorIs(t, registrar.DeleteRecord(withToken(""), "www.abc.eph.example.com.", RecordTypeA, "1.1.1.1"), ErrSubdomainForbidden)
+	_, err := registrar.WriteRecords(withToken(""), []RecordWrite{
+		{Domain: "unclaimed.eph.example.com.", Type: RecordTypeA, Value: "3.3.3.3"},
+		{Domain: "www.abc.eph.example.com.", Type: RecordTypeA, Value: "1.1.1.1", Delete: true},
+	})
+	assert.ErrorIs(t, err, ErrSubdomainForbidden)
+	_, err = backend.GetRecord(context.Background(), "unclaimed.eph.example.com.", RecordTypeA)
+	assert.ErrorIs(t, err, ErrRecordNotFound, "nothing is written if any write isn't allowed")
+
+	// Owner tokens only work in their own subdomain
+	assert.ErrorIs(t, registrar.SetRecord(withToken(owner), "unclaimed.eph.example.com.", RecordTypeA, "3.3.3.3"), ErrSubdomainForbidden)
+	assert.ErrorIs(t, registrar.SetRecord(withToken(owner), "www.example.com.", RecordTypeA, "3.3.3.3"), ErrSubdomainForbidden)
+	assert.NoError(t, registrar.SetRecord(withToken(""), "unclaimed.eph.example.com.", RecordTypeA, "3.3.3.3"))
+	assert.NoError(t, registrar.SetRecord(withToken("someone-else"), "www.example.com.", RecordTypeA, "3.3.3.3"))
+
+	// Records of expired subdomains can only be deleted, and only by the owner
+	now = now.Add(2 * time.Hour)
+	assert.ErrorIs(t, registrar.SetRecord(withToken(owner), "new.abc.eph.example.com.", RecordTypeA, "1.1.1.1"), ErrSubdomainForbidden)
+	assert.ErrorIs(t, registrar.DeleteRecord(withToken(""), "www.abc.eph.example.com.", RecordTypeA, "1.1.1.1"), ErrSubdomainForbidden)
+	assert.NoError(t, registrar.DeleteRecord(withToken(owner), "www.abc.eph.example.com.", RecordTypeA, "1.1.1.1"))
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/miekg/dns"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SynthesisRequest is a query that a Synthesizer may be able to answer without consulting the registrar.
+type SynthesisRequest struct {
+	// Zone is the zone the queried name is in
+	Zone  Domain
+	Name  Domain
+	Qtype uint16
+	// Query is the full query message, for synthesizers that need to look at things like EDNS options
+	Query      *dns.Msg
+	RemoteAddr net.Addr
+	// Transport is the network the query arrived over, either "udp" or "tcp"
+	Transport string
+}
+
+// Synthesizer generates answers for names on the fly instead of looking them up in the registrar.
+type Synthesizer interface {
+	// Synthesize returns the answer records for the request. If handled is false the synthesizer doesn't own the
+	// name, and the next synthesizer (or the registrar) is consulted. If handled is true but there are no answers,
+	// records of the queried type stored in the registrar are answered instead, and if there aren't any the response
+	// is NODATA.
+	Synthesize(ctx context.Context, request SynthesisRequest) (answers []dns.RR, handled bool)
+}
+
+// SynthesizerFunc adapts an ordinary function to a Synthesizer.
+type SynthesizerFunc func(ctx context.Context, request SynthesisRequest) ([]dns.RR, bool)
+
+func (f SynthesizerFunc) Synthesize(ctx context.Context, request SynthesisRequest) ([]dns.RR, bool) {
+	return f(ctx, request)
+}
+
+// synthesizerFactories are the built in synthesizers, keyed by the name used to configure them.
+var synthesizerFactories = map[string]func() Synthesizer{
+	"ip":     func() Synthesizer { return SynthesizerFunc(synthesizeIPEcho) },
+	"whoami": func() Synthesizer { return SynthesizerFunc(synthesizeWhoami) },
+	"random": func() Synthesizer { return SynthesizerFunc(synthesizeRandomIP) },
+	"time":   func() Synthesizer { return SynthesizerFunc(synthesizeTime) },
+}
+
+// defaultSynthesizerZone is the key used in SynthesizerConfig for zones that don't have their own configuration.
+const defaultSynthesizerZone = Domain("*")
+
+// SynthesizerConfig maps zones to the names of the synthesizers enabled for them.
+type SynthesizerConfig map[Domain][]string
+
+// ParseSynthesizerConfig parses the SYNTHESIZERS environment variable, which is a semicolon separated list of
+// zone=synthesizer,synthesizer entries. The zone * configures zones that don't have their own entry.
+func ParseSynthesizerConfig(raw string) (SynthesizerConfig, error) {
+	config := SynthesizerConfig{}
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("synthesizer entry %q should be of the form zone=synthesizer,synthesizer", entry)
+		}
+
+		zone := defaultSynthesizerZone
+		if strings.TrimSpace(parts[0]) != string(defaultSynthesizerZone) {
+			zone = Domain(canonicalName(strings.TrimSpace(parts[0])))
+		}
+		config[zone] = []string{}
+		for _, name := range strings.Split(parts[1], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				config[zone] = append(config[zone], name)
+			}
+		}
+	}
+	return config, nil
+}
+
+// SynthesizerRegistry keeps track of which synthesizers are enabled in which zone.
+type SynthesizerRegistry struct {
+	zones map[Domain][]Synthesizer
+	// zoneSet is the configured zones along with the zones synthesizers were registered for
+	zoneSet  ZoneSet
+	defaults []Synthesizer
+}
+
+// NewSynthesizerRegistry returns a registry where every zone uses the IP echo and whoami synthesizers.
+func NewSynthesizerRegistry() *SynthesizerRegistry {
+	return &SynthesizerRegistry{
+		zones:    map[Domain][]Synthesizer{},
+		defaults: []Synthesizer{synthesizerFactories["ip"](), synthesizerFactories["whoami"]()},
+	}
+}
+
+// NewSynthesizerRegistryFromConfig builds a registry from the built in synthesizers named in config. Names in zones
+// without their own configuration use the default synthesizers relative to the zone they are in.
+func NewSynthesizerRegistryFromConfig(config SynthesizerConfig, zones ZoneSet) (*SynthesizerRegistry, error) {
+	registry := NewSynthesizerRegistry()
+	registry.zoneSet = append(registry.zoneSet, zones...)
+	for zone, names := range config {
+		var synthesizers []Synthesizer
+		for _, name := range names {
+			factory, ok := synthesizerFactories[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown synthesizer %q for zone %s", name, zone)
+			}
+			synthesizers = append(synthesizers, factory())
+		}
+
+		if zone == defaultSynthesizerZone {
+			registry.defaults = synthesizers
+		} else {
+			registry.Register(zone, synthesizers...)
+		}
+	}
+	return registry, nil
+}
+
+// Register replaces the synthesizers used for zone. They are consulted in the order given.
+func (s *SynthesizerRegistry) Register(zone Domain, synthesizers ...Synthesizer) {
+	zone = Domain(canonicalName(string(zone)))
+	if _, exists := s.zones[zone]; !exists {
+		s.zoneSet = append(s.zoneSet, zone)
+	}
+	s.zones[zone] = synthesizers
+}
+
+// Lookup returns the zone a name belongs to, along with the synthesizers enabled for it. Names that aren't in a
+// registered zone use the default synthesizers.
+func (s *SynthesizerRegistry) Lookup(name Domain) (Domain, []Synthesizer) {
+	zone := s.zoneSet.ZoneOf(name)
+	if synthesizers, ok := s.zones[zone]; ok {
+		return zone, synthesizers
+	}
+	return zone, s.defaults
+}
+
+// Synthesize asks each synthesizer enabled for the request's zone to answer it, and returns the answers of the first
+// one that handles it.
+func (s *SynthesizerRegistry) Synthesize(ctx context.Context, request SynthesisRequest) (Domain, []dns.RR, bool) {
+	zone, synthesizers := s.Lookup(request.Name)
+	request.Zone = zone
+	for _, synthesizer := range synthesizers {
+		if answers, handled := synthesizer.Synthesize(ctx, request); handled {
+			return zone, answers, true
+		}
+	}
+	return zone, nil, false
+}
+
+// addressRecord returns an A or AAAA record for ip, depending on its family, or nil if it is not of the family that
+// was asked for.
+func addressRecord(name Domain, qtype uint16, ttl uint32, ip net.IP) dns.RR {
+	if ipv4 := ip.To4(); ipv4 != nil && qtype == dns.TypeA {
+		return &dns.A{
+			Hdr: dns.RR_Header{Name: string(name), Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+			A:   ipv4,
+		}
+	} else if ipv4 == nil && qtype == dns.TypeAAAA {
+		return &dns.AAAA{
+			Hdr:  dns.RR_Header{Name: string(name), Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: ttl},
+			AAAA: ip,
+		}
+	}
+	return nil
+}
+
+func addressAnswers(name Domain, qtype uint16, ttl uint32, ip net.IP) []dns.RR {
+	if rr := addressRecord(name, qtype, ttl, ip); rr != nil {
+		return []dns.RR{rr}
+	}
+	return nil
+}
+
+// synthesizeIPEcho answers nip.io/sslip.io style names like 10.20.30.40.ip.example.com with the IP address they
+// contain.
+func synthesizeIPEcho(_ context.Context, request SynthesisRequest) ([]dns.RR, bool) {
+	ip, isMagicIP := parseMagicIP(request.Name, request.Zone)
+	if !isMagicIP {
+		return nil, false
+	}
+	return addressAnswers(request.Name, request.Qtype, 60, ip), true
+}
+
+func remoteIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+// synthesizeWhoami answers whoami.<zone> with the address the query came from. TXT queries get a set of key=value
+// records describing how the query reached the server, which helps with debugging split DNS setups.
+func synthesizeWhoami(_ context.Context, request SynthesisRequest) ([]dns.RR, bool) {
+	if canonicalName(string(request.Name)) != "whoami."+string(request.Zone) {
+		return nil, false
+	}
+	if request.Qtype != dns.TypeTXT {
+		return addressAnswers(request.Name, request.Qtype, 0, remoteIP(request.RemoteAddr)), true
+	}
+
+	var answers []dns.RR
+	for _, diagnostic := range resolverDiagnostics(request) {
+		answers = append(answers, &dns.TXT{
+			Hdr: dns.RR_Header{Name: string(request.Name), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 0},
+			Txt: []string{diagnostic},
+		})
+	}
+	return answers, true
+}
+
+func resolverDiagnostics(request SynthesisRequest) []string {
+	diagnostics := []string{
+		"source=" + request.RemoteAddr.String(),
+		"transport=" + request.Transport,
+		fmt.Sprintf("request-id=%d", request.Query.Id),
+	}
+
+	bufferSize, clientSubnet := "none", "none"
+	if opt := request.Query.IsEdns0(); opt != nil {
+		bufferSize = strconv.Itoa(int(opt.UDPSize()))
+		for _, option := range opt.Option {
+			if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+				clientSubnet = fmt.Sprintf("%s/%d", subnet.Address, subnet.SourceNetmask)
+			}
+		}
+	}
+	diagnostics = append(diagnostics, "edns-buffer-size="+bufferSize, "edns-client-subnet="+clientSubnet)
+
+	return diagnostics
+}
+
+// synthesizeRandomIP answers random.<zone>, and any name beneath it, with a different private address every time,
+// which is handy for load tests that need to defeat caching.
+func synthesizeRandomIP(_ context.Context, request SynthesisRequest) ([]dns.RR, bool) {
+	if !isSubdomain(request.Name, Domain("random."+string(request.Zone))) {
+		return nil, false
+	}
+
+	var ip net.IP
+	if request.Qtype == dns.TypeAAAA {
+		// fd00::/8 unique local addresses
+		ip = make(net.IP, net.IPv6len)
+		rand.Read(ip)
+		ip[0] = 0xfd
+	} else {
+		// 10.0.0.0/8 private addresses
+		ip = net.IPv4(10, byte(rand.Intn(256)), byte(rand.Intn(256)), byte(rand.Intn(256)))
+	}
+	return addressAnswers(request.Name, request.Qtype, 0, ip), true
+}
+
+// synthesizeTime answers TXT queries for time.<zone> with the current time.
+func synthesizeTime(_ context.Context, request SynthesisRequest) ([]dns.RR, bool) {
+	if canonicalName(string(request.Name)) != "time."+string(request.Zone) {
+		return nil, false
+	}
+	if request.Qtype != dns.TypeTXT {
+		return nil, true
+	}
+	return []dns.RR{&dns.TXT{
+		Hdr: dns.RR_Header{Name: string(request.Name), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 0},
+		Txt: []string{time.Now().UTC().Format(time.RFC3339Nano)},
+	}}, true
+}
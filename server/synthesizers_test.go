@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func synthesizerTestQuery(t *testing.T, registry *SynthesizerRegistry, name string, qtype uint16, remoteAddr net.Addr) *dns.Msg {
+	w := &recordingResponseWriter{remoteAddr: remoteAddr}
+	r := new(dns.Msg)
+	r.SetQuestion(name, qtype)
+	handleIPQuery(newMemoryRegistrar(), registry, nil, nil)(w, r)
+	assert.Len(t, w.messages, 1)
+	return w.messages[0]
+}
+
+func TestParseSynthesizerConfig(t *testing.T) {
+	config, err := ParseSynthesizerConfig("Example.com=ip,whoami; *=ip ;load.test.=random,time")
+	assert.NoError(t, err)
+	assert.Equal(t, SynthesizerConfig{
+		"example.com.": {"ip", "whoami"},
+		"*":            {"ip"},
+		"load.test.":   {"random", "time"},
+	}, config)
+
+	_, err = ParseSynthesizerConfig("example.com")
+	assert.Error(t, err)
+
+	_, err = NewSynthesizerRegistryFromConfig(SynthesizerConfig{"example.com.": {"nonexistent"}}, nil)
+	assert.Error(t, err)
+}
+
+func TestSynthesizerRegistry_PerZone(t *testing.T) {
+	registry, err := NewSynthesizerRegistryFromConfig(SynthesizerConfig{
+		"example.com.":     {"whoami"},
+		"sub.example.com.": {"ip", "time"},
+	}, nil)
+	assert.NoError(t, err)
+	client := &net.UDPAddr{IP: net.ParseIP("192.0.2.7"), Port: 5353}
+
+	response := synthesizerTestQuery(t, registry, "whoami.example.com.", dns.TypeA, client)
+	assert.Equal(t, "192.0.2.7", response.Answer[0].(*dns.A).A.String())
+
+	response = synthesizerTestQuery(t, registry, "whoami.example.com.", dns.TypeAAAA, client)
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.Empty(t, response.Answer)
+	assert.Equal(t, "example.com.", response.Ns[0].Header().Name)
+
+	response = synthesizerTestQuery(t, registry, "10.1.2.3.ip.sub.example.com.", dns.TypeA, client)
+	assert.Equal(t, "10.1.2.3", response.Answer[0].(*dns.A).A.String())
+
+	response = synthesizerTestQuery(t, registry, "time.sub.example.com.", dns.TypeTXT, client)
+	synthesizedTime, err := time.Parse(time.RFC3339Nano, response.Answer[0].(*dns.TXT).Txt[0])
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), synthesizedTime, time.Minute)
+
+	// Unconfigured zones fall back to the IP echo synthesizer
+	response = synthesizerTestQuery(t, registry, "10.1.2.3.ip.other.org.", dns.TypeA, client)
+	assert.Equal(t, "10.1.2.3", response.Answer[0].(*dns.A).A.String())
+}
+
+func TestSynthesizerRegistry_ConfiguredZones(t *testing.T) {
+	registry, err := NewSynthesizerRegistryFromConfig(SynthesizerConfig{}, ZoneSet{"dev.example.co.uk."})
+	assert.NoError(t, err)
+	client := &net.UDPAddr{IP: net.ParseIP("192.0.2.7"), Port: 5353}
+
+	// The last two labels would make the zone co.uk.
+	response := synthesizerTestQuery(t, registry, "10.1.2.3.ip.dev.example.co.uk.", dns.TypeA, client)
+	assert.Equal(t, "10.1.2.3", response.Answer[0].(*dns.A).A.String())
+	response = synthesizerTestQuery(t, registry, "whoami.dev.example.co.uk.", dns.TypeA, client)
+	assert.Equal(t, "192.0.2.7", response.Answer[0].(*dns.A).A.String())
+	response = synthesizerTestQuery(t, registry, "10.1.2.3.ip.dev.example.co.uk.", dns.TypeAAAA, client)
+	assert.Empty(t, response.Answer)
+	assert.Equal(t, "dev.example.co.uk.", response.Ns[0].Header().Name)
+}
+
+func TestSynthesizerRegistry_StoredRecordsBeneathSynthesizedNames(t *testing.T) {
+	registrar := newMemoryRegistrar()
+	ctx := context.Background()
+	assert.NoError(t, registrar.SetRecord(ctx, "_acme-challenge.10-1-2-3.ip.example.com.", RecordTypeTXT, "token"))
+	assert.NoError(t, registrar.SetRecord(ctx, "10-1-2-3.ip.example.com.", RecordTypeA, "192.0.2.1"))
+	handler := handleIPQuery(registrar, NewSynthesizerRegistry(), nil, nil)
+	query := func(name string, qtype uint16) *dns.Msg {
+		w := &recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP("192.0.2.7"), Port: 5353}}
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+		handler(w, r)
+		assert.Len(t, w.messages, 1)
+		return w.messages[0]
+	}
+
+	response := query("_acme-challenge.10-1-2-3.ip.example.com.", dns.TypeTXT)
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.Equal(t, []string{"token"}, response.Answer[0].(*dns.TXT).Txt)
+
+	// Synthesized answers still take precedence over stored ones
+	response = query("10-1-2-3.ip.example.com.", dns.TypeA)
+	assert.Equal(t, "10.1.2.3", response.Answer[0].(*dns.A).A.String())
+
+	response = query("_acme-challenge.10-1-2-3.ip.example.com.", dns.TypeMX)
+	assert.Equal(t, dns.RcodeSuccess, response.Rcode)
+	assert.Empty(t, response.Answer)
+	assert.Len(t, response.Ns, 1)
+}
+
+func TestSynthesizeRandomIP(t *testing.T) {
+	registry := NewSynthesizerRegistry()
+	registry.Register("load.test.", SynthesizerFunc(synthesizeRandomIP))
+	client := &net.UDPAddr{IP: net.ParseIP("192.0.2.7"), Port: 5353}
+
+	response := synthesizerTestQuery(t, registry, "a.random.load.test.", dns.TypeA, client)
+	ip := response.Answer[0].(*dns.A).A
+	assert.True(t, ip.IsPrivate(), "%s should be a private address", ip)
+
+	response = synthesizerTestQuery(t, registry, "random.load.test.", dns.TypeAAAA, client)
+	ip = response.Answer[0].(*dns.AAAA).AAAA
+	assert.True(t, ip.IsPrivate(), "%s should be a unique local address", ip)
+}
+
+func TestSynthesizerRegistry_CustomSynthesizer(t *testing.T) {
+	registry := NewSynthesizerRegistry()
+	registry.Register("custom.test.", SynthesizerFunc(func(ctx context.Context, request SynthesisRequest) ([]dns.RR, bool) {
+		if request.Qtype != dns.TypeTXT {
+			return nil, false
+		}
+		return []dns.RR{&dns.TXT{
+			Hdr: dns.RR_Header{Name: string(request.Name), Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+			Txt: []string{string(request.Zone)},
+		}}, true
+	}))
+	client := &net.UDPAddr{IP: net.ParseIP("192.0.2.7"), Port: 5353}
+
+	response := synthesizerTestQuery(t, registry, "anything.custom.test.", dns.TypeTXT, client)
+	assert.Equal(t, []string{"custom.test."}, response.Answer[0].(*dns.TXT).Txt)
+}
+
+func TestSynthesizeWhoami_TXTDiagnostics(t *testing.T) {
+	registry := NewSynthesizerRegistry()
+	w := &recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP("192.0.2.7"), Port: 5353}}
+	r := new(dns.Msg)
+	r.SetQuestion("whoami.example.com.", dns.TypeTXT)
+	r.SetEdns0(1232, false)
+	opt := r.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        1,
+		SourceNetmask: 24,
+		Address:       net.ParseIP("198.51.100.0").To4(),
+	})
+
+	handleIPQuery(newMemoryRegistrar(), registry, nil, nil)(w, r)
+
+	var txt []string
+	for _, rr := range w.messages[0].Answer {
+		txt = append(txt, rr.(*dns.TXT).Txt...)
+	}
+	assert.Equal(t, []string{
+		"source=192.0.2.7:5353",
+		"transport=udp",
+		"request-id=" + strconv.Itoa(int(r.Id)),
+		"edns-buffer-size=1232",
+		"edns-client-subnet=198.51.100.0/24",
+	}, txt)
+}
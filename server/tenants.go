@@ -0,0 +1,552 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTenantForbidden is returned when a record is changed by a tenant other than the one that owns it.
+var ErrTenantForbidden = errors.New("record belongs to another tenant")
+
+// RegisteredTenant is a tenant along with the hashes of its API tokens, which are never returned by the API.
+type RegisteredTenant struct {
+	Tenant
+	TokenHashes []string `json:"tokenHashes"`
+}
+
+// tenantIDPattern matches valid tenant IDs, which end up in backend keys.
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// tenantKeyPrefix is the prefix of the backend keys of a tenant's records.
+func tenantKeyPrefix(id string) string {
+	return "tenant:" + id + ":"
+}
+
+// tenantTokenPrefix starts every tenant API token, so they can be told apart from other API tokens.
+const tenantTokenPrefix = "ten_"
+
+func (t RegisteredTenant) recordQuotas() RecordQuotaConfig {
+	var quotas RecordQuotaConfig
+	if t.Quotas.RecordsPerToken != nil {
+		quotas.RecordsPerToken = *t.Quotas.RecordsPerToken
+	}
+	if t.Quotas.RecordsPerZone != nil {
+		quotas.RecordsPerZone = *t.Quotas.RecordsPerZone
+	}
+	return quotas
+}
+
+// zoneOf returns the longest of the tenant's zones that contains fqdn.
+func (t RegisteredTenant) zoneOf(fqdn Domain) Domain {
+	var longest Domain
+	for _, zone := range t.Zones {
+		if isSubdomain(fqdn, Domain(zone)) && len(zone) > len(longest) {
+			longest = Domain(zone)
+		}
+	}
+	return longest
+}
+
+type namespaceContextKey struct{}
+
+// withNamespace makes registrars read and write records under a key prefix, which is empty for records that don't
+// belong to a tenant.
+func withNamespace(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, namespaceContextKey{}, prefix)
+}
+
+func namespaceFromContext(ctx context.Context) string {
+	prefix, _ := ctx.Value(namespaceContextKey{}).(string)
+	return prefix
+}
+
+type tsigKeyContextKey struct{}
+
+// withTSIGKey records the verified TSIG key an RFC 2136 update was signed with, or an empty string if it wasn't signed
+// with a configured key.
+func withTSIGKey(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, tsigKeyContextKey{}, strings.ToLower(name))
+}
+
+// TenantDirectory keeps the tenants in memory so that every record lookup doesn't have to read them from the
+// registrar. Tenants created by other replicas are picked up as soon as the registrar says so, or within ttl in case
+// that notification is lost.
+type TenantDirectory struct {
+	registrar Registrar
+	ttl       time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	tenants []RegisteredTenant
+	loaded  time.Time
+}
+
+// NewTenantDirectory reads the tenants from registrar, and reads them again whenever registrar notifies that record
+// changes may have been missed, which it also does when a tenant is created, until ctx is cancelled.
+func NewTenantDirectory(ctx context.Context, registrar Registrar) *TenantDirectory {
+	d := &TenantDirectory{registrar: registrar, ttl: 10 * time.Second, now: time.Now}
+
+	changes := registrar.SubscribeRecordChanges(ctx)
+	go func() {
+		for change := range changes {
+			if change.Domain == "" {
+				d.invalidate()
+			}
+		}
+	}()
+
+	return d
+}
+
+func (d *TenantDirectory) list(ctx context.Context) ([]RegisteredTenant, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.tenants == nil || d.now().Sub(d.loaded) >= d.ttl {
+		tenants, err := d.registrar.ListTenants(ctx)
+		if err != nil {
+			return nil, err
+		}
+		d.tenants = append([]RegisteredTenant{}, tenants...)
+		d.loaded = d.now()
+	}
+	return d.tenants, nil
+}
+
+// invalidate makes the next lookup read the tenants from the registrar again.
+func (d *TenantDirectory) invalidate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tenants = nil
+}
+
+// owner returns the tenant with the longest zone containing fqdn, or nil if no tenant's zones contain it.
+func (d *TenantDirectory) owner(ctx context.Context, fqdn Domain) (*RegisteredTenant, error) {
+	tenants, err := d.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var owner *RegisteredTenant
+	var longest Domain
+	for i, tenant := range tenants {
+		if zone := tenant.zoneOf(fqdn); len(zone) > len(longest) {
+			owner, longest = &tenants[i], zone
+		}
+	}
+	return owner, nil
+}
+
+// forToken returns the tenant an API token belongs to, or nil if it isn't a tenant token.
+func (d *TenantDirectory) forToken(ctx context.Context, token string) (*RegisteredTenant, error) {
+	if !strings.HasPrefix(token, tenantTokenPrefix) {
+		return nil, nil
+	}
+	tenants, err := d.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hash := secretTokenHash(token)
+	for i, tenant := range tenants {
+		if containsString(tenant.TokenHashes, hash) {
+			return &tenants[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// forTSIGKey returns the tenant a TSIG key belongs to, or nil if it doesn't belong to one.
+func (d *TenantDirectory) forTSIGKey(ctx context.Context, name string) (*RegisteredTenant, error) {
+	tenants, err := d.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, tenant := range tenants {
+		for _, key := range tenant.TsigKeys {
+			if strings.EqualFold(key, name) {
+				return &tenants[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// requester returns the tenant that the API token or TSIG key in ctx belongs to. The second return value is false if
+// ctx isn't from an API request or an RFC 2136 update, such as when expired leases are cleaned up, in which case
+// nothing needs to be checked.
+func (d *TenantDirectory) requester(ctx context.Context) (*RegisteredTenant, bool, error) {
+	if token, ok := ctx.Value(apiTokenContextKey{}).(string); ok {
+		tenant, err := d.forToken(ctx, token)
+		return tenant, true, err
+	}
+	if key, ok := ctx.Value(tsigKeyContextKey{}).(string); ok {
+		if key == "" {
+			return nil, true, nil
+		}
+		tenant, err := d.forTSIGKey(ctx, key)
+		return tenant, true, err
+	}
+	return nil, false, nil
+}
+
+// TenantRegistrar keeps the records of each tenant in its own namespace. Each record is read from and written to the
+// namespace of the tenant that owns its name, and changes made with an API token or TSIG key are only allowed if they
+// belong to that tenant. Records that no tenant owns can only be changed by requests that don't belong to a tenant.
+// Webhooks and the audit log are kept per tenant too, so it has to wrap the registrars that deliver and log changes, as
+// are subdomain leases and acme-dns accounts, with the tenant that owns their name.
+type TenantRegistrar struct {
+	Registrar
+
+	tenants *TenantDirectory
+}
+
+func NewTenantRegistrar(next Registrar, tenants *TenantDirectory) *TenantRegistrar {
+	return &TenantRegistrar{Registrar: next, tenants: tenants}
+}
+
+func namespaceOf(tenant *RegisteredTenant) string {
+	if tenant == nil {
+		return ""
+	}
+	return tenantKeyPrefix(tenant.Id)
+}
+
+// route returns ctx set up to read the records of fqdn.
+func (t *TenantRegistrar) route(ctx context.Context, fqdn Domain) (context.Context, error) {
+	owner, err := t.tenants.owner(ctx, fqdn)
+	if err != nil {
+		return ctx, err
+	}
+	return withNamespace(ctx, namespaceOf(owner)), nil
+}
+
+// authorize returns ctx set up to change the records of fqdn, if the requester in ctx is allowed to.
+func (t *TenantRegistrar) authorize(ctx context.Context, fqdn Domain) (context.Context, error) {
+	owner, err := t.tenants.owner(ctx, fqdn)
+	if err != nil {
+		return ctx, err
+	}
+	requester, checked, err := t.tenants.requester(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	if checked && namespaceOf(requester) != namespaceOf(owner) {
+		return ctx, ErrTenantForbidden
+	}
+	return withNamespace(ctx, namespaceOf(owner)), nil
+}
+
+func (t *TenantRegistrar) SetRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) error {
+	_, _, err := t.SwapRecord(ctx, fqdn, recordType, value)
+	return err
+}
+
+func (t *TenantRegistrar) SwapRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) (string, bool, error) {
+	ctx, err := t.authorize(ctx, fqdn)
+	if err != nil {
+		return "", false, err
+	}
+	return t.Registrar.SwapRecord(ctx, fqdn, recordType, value)
+}
+
+func (t *TenantRegistrar) DeleteRecord(ctx context.Context, fqdn Domain, recordType RecordType, currentValue string) error {
+	ctx, err := t.authorize(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+	return t.Registrar.DeleteRecord(ctx, fqdn, recordType, currentValue)
+}
+
+// WriteRecords only allows writes that are all to records of the same tenant, since a single write can only be made
+// to a single namespace.
+func (t *TenantRegistrar) WriteRecords(ctx context.Context, writes []RecordWrite) ([]RecordWriteResult, error) {
+	if len(writes) == 0 {
+		return t.Registrar.WriteRecords(ctx, writes)
+	}
+	writeCtx, err := t.authorize(ctx, writes[0].Domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, write := range writes[1:] {
+		c, err := t.authorize(ctx, write.Domain)
+		if err != nil {
+			return nil, err
+		}
+		if namespaceFromContext(c) != namespaceFromContext(writeCtx) {
+			return nil, ErrTenantForbidden
+		}
+	}
+	return t.Registrar.WriteRecords(writeCtx, writes)
+}
+
+func (t *TenantRegistrar) GetRecord(ctx context.Context, fqdn Domain, recordType RecordType) (string, error) {
+	ctx, err := t.route(ctx, fqdn)
+	if err != nil {
+		return "", err
+	}
+	return t.Registrar.GetRecord(ctx, fqdn, recordType)
+}
+
+func (t *TenantRegistrar) GetViewRecord(ctx context.Context, fqdn Domain, recordType RecordType) (string, error) {
+	ctx, err := t.route(ctx, fqdn)
+	if err != nil {
+		return "", err
+	}
+	return t.Registrar.GetViewRecord(ctx, fqdn, recordType)
+}
+
+// ListRecords returns the records beneath zone in the namespace of the tenant that owns zone. Records beneath zone
+// that belong to a tenant with a zone beneath it aren't included.
+func (t *TenantRegistrar) ListRecords(ctx context.Context, zone Domain) ([]StoredRecord, error) {
+	ctx, err := t.route(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	return t.Registrar.ListRecords(ctx, zone)
+}
+
+func (t *TenantRegistrar) RecordHistory(ctx context.Context, fqdn Domain, recordType RecordType) ([]RecordVersion, error) {
+	ctx, err := t.route(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	return t.Registrar.RecordHistory(ctx, fqdn, recordType)
+}
+
+// requesterNamespace returns ctx set up to read what belongs to the requester in ctx, rather than to a name.
+func (t *TenantRegistrar) requesterNamespace(ctx context.Context) (context.Context, error) {
+	requester, _, err := t.tenants.requester(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	return withNamespace(ctx, namespaceOf(requester)), nil
+}
+
+// ListChangedRecords returns the changed records in the namespace of the requester in ctx.
+func (t *TenantRegistrar) ListChangedRecords(ctx context.Context, since time.Time) ([]RecordReference, error) {
+	ctx, err := t.requesterNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.Registrar.ListChangedRecords(ctx, since)
+}
+
+// Watch only notifies about changes to records in the namespace of the requester in ctx. Once there are tenants,
+// requesters that don't belong to one can't watch anything, and the returned channel is closed straight away.
+func (t *TenantRegistrar) Watch(ctx context.Context, prefix string) <-chan RecordChange {
+	filtered := make(chan RecordChange)
+	requester, checked, err := t.tenants.requester(ctx)
+	if err == nil && checked && requester == nil {
+		var tenants []RegisteredTenant
+		if tenants, err = t.tenants.list(ctx); err == nil && len(tenants) > 0 {
+			err = ErrTenantForbidden
+		}
+	}
+	if err != nil {
+		close(filtered)
+		return filtered
+	}
+
+	changes := t.Registrar.Watch(ctx, prefix)
+	namespace := namespaceOf(requester)
+	go func() {
+		defer close(filtered)
+		for change := range changes {
+			if change.Domain != "" && change.Namespace != namespace {
+				continue
+			}
+			select {
+			case filtered <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return filtered
+}
+
+// CreateWebhook registers the webhook in the namespace of the tenant that owns its zone, if the requester in ctx is
+// allowed to change the zone's records.
+func (t *TenantRegistrar) CreateWebhook(ctx context.Context, webhook RegisteredWebhook) error {
+	ctx, err := t.authorize(ctx, Domain(webhook.Zone))
+	if err != nil {
+		return err
+	}
+	return t.Registrar.CreateWebhook(ctx, webhook)
+}
+
+// ListWebhooks returns the webhooks in the namespace of the requester in ctx.
+func (t *TenantRegistrar) ListWebhooks(ctx context.Context) ([]RegisteredWebhook, error) {
+	ctx, err := t.requesterNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.Registrar.ListWebhooks(ctx)
+}
+
+// DeleteWebhook only finds webhooks in the namespace of the requester in ctx.
+func (t *TenantRegistrar) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, err := t.requesterNamespace(ctx)
+	if err != nil {
+		return err
+	}
+	return t.Registrar.DeleteWebhook(ctx, id)
+}
+
+func (t *TenantRegistrar) ListWebhookDeliveries(ctx context.Context, id string) ([]WebhookDelivery, error) {
+	ctx, err := t.requesterNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.Registrar.ListWebhookDeliveries(ctx, id)
+}
+
+// ListAuditEntries returns the audit log of the requester in ctx. Changes are logged in the namespace of the tenant
+// that owns the record, by the registrars this one wraps.
+func (t *TenantRegistrar) ListAuditEntries(ctx context.Context, query AuditQuery) ([]AuditEntry, error) {
+	ctx, err := t.requesterNamespace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.Registrar.ListAuditEntries(ctx, query)
+}
+
+// ClaimRecordQuota claims records in the namespace of the tenant that owns them, so every record has to belong to the
+// same tenant.
+func (t *TenantRegistrar) ClaimRecordQuota(ctx context.Context, scope string, records []RecordReference, limit int64) error {
+	if len(records) == 0 {
+		return t.Registrar.ClaimRecordQuota(ctx, scope, records, limit)
+	}
+	claimCtx, err := t.route(ctx, Domain(records[0].Domain))
+	if err != nil {
+		return err
+	}
+	for _, record := range records[1:] {
+		c, err := t.route(ctx, Domain(record.Domain))
+		if err != nil {
+			return err
+		}
+		if namespaceFromContext(c) != namespaceFromContext(claimCtx) {
+			return ErrTenantForbidden
+		}
+	}
+	return t.Registrar.ClaimRecordQuota(claimCtx, scope, records, limit)
+}
+
+// namespaces returns ctx set up for the default namespace, followed by ctx set up for the namespace of each tenant.
+func (t *TenantRegistrar) namespaces(ctx context.Context) ([]context.Context, error) {
+	tenants, err := t.tenants.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := []context.Context{withNamespace(ctx, "")}
+	for i := range tenants {
+		namespaces = append(namespaces, withNamespace(ctx, namespaceOf(&tenants[i])))
+	}
+	return namespaces, nil
+}
+
+// ClaimSubdomain keeps the lease in the namespace of the tenant that owns the subdomain.
+func (t *TenantRegistrar) ClaimSubdomain(ctx context.Context, lease SubdomainLease) error {
+	ctx, err := t.route(ctx, lease.Domain)
+	if err != nil {
+		return err
+	}
+	return t.Registrar.ClaimSubdomain(ctx, lease)
+}
+
+func (t *TenantRegistrar) GetSubdomainLease(ctx context.Context, fqdn Domain) (SubdomainLease, error) {
+	ctx, err := t.route(ctx, fqdn)
+	if err != nil {
+		return SubdomainLease{}, err
+	}
+	return t.Registrar.GetSubdomainLease(ctx, fqdn)
+}
+
+// GetLease looks for the lease in every namespace, since its ID doesn't say which tenant owns its subdomain.
+func (t *TenantRegistrar) GetLease(ctx context.Context, id string) (SubdomainLease, error) {
+	namespaces, err := t.namespaces(ctx)
+	if err != nil {
+		return SubdomainLease{}, err
+	}
+	for _, ctx := range namespaces {
+		if lease, err := t.Registrar.GetLease(ctx, id); !errors.Is(err, ErrLeaseNotFound) {
+			return lease, err
+		}
+	}
+	return SubdomainLease{}, ErrLeaseNotFound
+}
+
+func (t *TenantRegistrar) RenewLease(ctx context.Context, id string, expires time.Time, now time.Time) (SubdomainLease, error) {
+	namespaces, err := t.namespaces(ctx)
+	if err != nil {
+		return SubdomainLease{}, err
+	}
+	for _, ctx := range namespaces {
+		if lease, err := t.Registrar.RenewLease(ctx, id, expires, now); !errors.Is(err, ErrLeaseNotFound) {
+			return lease, err
+		}
+	}
+	return SubdomainLease{}, ErrLeaseNotFound
+}
+
+// ListExpiredLeases returns the expired leases of every namespace.
+func (t *TenantRegistrar) ListExpiredLeases(ctx context.Context, now time.Time) ([]SubdomainLease, error) {
+	namespaces, err := t.namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var leases []SubdomainLease
+	for _, ctx := range namespaces {
+		expired, err := t.Registrar.ListExpiredLeases(ctx, now)
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, expired...)
+	}
+	return leases, nil
+}
+
+func (t *TenantRegistrar) DeleteLease(ctx context.Context, lease SubdomainLease) error {
+	ctx, err := t.route(ctx, lease.Domain)
+	if err != nil {
+		return err
+	}
+	return t.Registrar.DeleteLease(ctx, lease)
+}
+
+// CreateACMEDNSAccount keeps the account in the namespace of the tenant that owns its domain.
+func (t *TenantRegistrar) CreateACMEDNSAccount(ctx context.Context, account ACMEDNSAccount) error {
+	ctx, err := t.route(ctx, account.Domain)
+	if err != nil {
+		return err
+	}
+	return t.Registrar.CreateACMEDNSAccount(ctx, account)
+}
+
+// GetACMEDNSAccount looks for the account in every namespace, since its username doesn't say which tenant owns it.
+func (t *TenantRegistrar) GetACMEDNSAccount(ctx context.Context, username string) (ACMEDNSAccount, error) {
+	namespaces, err := t.namespaces(ctx)
+	if err != nil {
+		return ACMEDNSAccount{}, err
+	}
+	for _, ctx := range namespaces {
+		if account, err := t.Registrar.GetACMEDNSAccount(ctx, username); !errors.Is(err, ErrACMEDNSAccountNotFound) {
+			return account, err
+		}
+	}
+	return ACMEDNSAccount{}, ErrACMEDNSAccountNotFound
+}
+
+// CreateTenant also makes the new tenant take effect straight away on this replica.
+func (t *TenantRegistrar) CreateTenant(ctx context.Context, tenant RegisteredTenant) error {
+	defer t.tenants.invalidate()
+	return t.Registrar.CreateTenant(ctx, tenant)
+}
+
+// isForbidden reports whether err means the requester isn't allowed to change a record.
+func isForbidden(err error) bool {
+	return errors.Is(err, ErrSubdomainForbidden) || errors.Is(err, ErrTenantForbidden)
+}
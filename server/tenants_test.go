@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestTenants(t *testing.T) (*memoryRegistrar, *TenantRegistrar) {
+	backend := newMemoryRegistrar()
+	assert.NoError(t, backend.CreateTenant(context.Background(), RegisteredTenant{
+		Tenant:      Tenant{Id: "acme", Zones: []string{"acme.example.com."}, TsigKeys: []string{"acme."}, KeyPrefix: tenantKeyPrefix("acme")},
+		TokenHashes: []string{secretTokenHash("ten_acme")},
+	}))
+	assert.NoError(t, backend.CreateTenant(context.Background(), RegisteredTenant{
+		Tenant:      Tenant{Id: "dev", Zones: []string{"dev.acme.example.com."}, TsigKeys: []string{}, KeyPrefix: tenantKeyPrefix("dev")},
+		TokenHashes: []string{secretTokenHash("ten_dev")},
+	}))
+	return backend, NewTenantRegistrar(backend, NewTenantDirectory(context.Background(), backend))
+}
+
+func TestTenantRegistrar(t *testing.T) {
+	backend, registrar := newTestTenants(t)
+	withToken := func(token string) context.Context {
+		return context.WithValue(context.Background(), apiTokenContextKey{}, token)
+	}
+
+	// Names belong to the tenant with the longest zone containing them
+	assert.NoError(t, registrar.SetRecord(withToken("ten_acme"), "www.acme.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, registrar.SetRecord(withToken("ten_dev"), "www.dev.acme.example.com.", RecordTypeA, "2.2.2.2"))
+	assert.ErrorIs(t, registrar.SetRecord(withToken("ten_acme"), "www.dev.acme.example.com.", RecordTypeA, "3.3.3.3"), ErrTenantForbidden)
+	assert.ErrorIs(t, registrar.SetRecord(withToken("ten_dev"), "www.acme.example.com.", RecordTypeA, "3.3.3.3"), ErrTenantForbidden)
+	assert.ErrorIs(t, registrar.DeleteRecord(withToken("ten_dev"), "www.acme.example.com.", RecordTypeA, "1.1.1.1"), ErrTenantForbidden)
+
+	// Tenant tokens can't change names outside their zones, and other tokens can't change names in them
+	assert.ErrorIs(t, registrar.SetRecord(withToken("ten_acme"), "www.example.com.", RecordTypeA, "3.3.3.3"), ErrTenantForbidden)
+	assert.ErrorIs(t, registrar.SetRecord(withToken(""), "www.acme.example.com.", RecordTypeA, "3.3.3.3"), ErrTenantForbidden)
+	assert.ErrorIs(t, registrar.SetRecord(withToken("ten_unknown"), "www.acme.example.com.", RecordTypeA, "3.3.3.3"), ErrTenantForbidden)
+	assert.NoError(t, registrar.SetRecord(withToken(""), "www.example.com.", RecordTypeA, "4.4.4.4"))
+
+	// RFC 2136 updates are checked against the TSIG key they were signed with
+	assert.NoError(t, registrar.SetRecord(withTSIGKey(context.Background(), "ACME."), "mail.acme.example.com.", RecordTypeA, "5.5.5.5"))
+	assert.ErrorIs(t, registrar.SetRecord(withTSIGKey(context.Background(), ""), "mail.acme.example.com.", RecordTypeA, "5.5.5.5"), ErrTenantForbidden)
+	assert.ErrorIs(t, registrar.SetRecord(withTSIGKey(context.Background(), "acme."), "www.example.com.", RecordTypeA, "5.5.5.5"), ErrTenantForbidden)
+
+	// A single write can't span tenants
+	_, err := registrar.WriteRecords(withToken("ten_acme"), []RecordWrite{
+		{Domain: "a.acme.example.com.", Type: RecordTypeA, Value: "6.6.6.6"},
+		{Domain: "a.dev.acme.example.com.", Type: RecordTypeA, Value: "6.6.6.6"},
+	})
+	assert.ErrorIs(t, err, ErrTenantForbidden)
+
+	// Each tenant's records are stored under its own key prefix, and are read from it
+	assert.Equal(t, "1.1.1.1", backend.records["tenant:acme:www.acme.example.com.:A"])
+	assert.Equal(t, "2.2.2.2", backend.records["tenant:dev:www.dev.acme.example.com.:A"])
+	assert.Equal(t, "4.4.4.4", backend.records["www.example.com.:A"])
+	value, err := registrar.GetRecord(context.Background(), "www.dev.acme.example.com.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "2.2.2.2", value)
+
+	// Listing a zone only returns the records of the tenant that owns it
+	records, err := registrar.ListRecords(context.Background(), "acme.example.com.")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []StoredRecord{
+		{Domain: "www.acme.example.com.", Type: RecordTypeA, Value: "1.1.1.1"},
+		{Domain: "mail.acme.example.com.", Type: RecordTypeA, Value: "5.5.5.5"},
+	}, records)
+	records, err = registrar.ListRecords(context.Background(), "example.com.")
+	assert.NoError(t, err)
+	assert.Equal(t, []StoredRecord{{Domain: "www.example.com.", Type: RecordTypeA, Value: "4.4.4.4"}}, records)
+
+	// Changes made without credentials, like cleaning up expired leases, aren't checked
+	assert.NoError(t, registrar.DeleteRecord(context.Background(), "www.dev.acme.example.com.", RecordTypeA, "2.2.2.2"))
+}
+
+func TestTenantRegistrar_Watch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, registrar := newTestTenants(t)
+	withToken := func(token string) context.Context {
+		return context.WithValue(ctx, apiTokenContextKey{}, token)
+	}
+
+	// Requests that don't belong to a tenant can't watch anything once there are tenants
+	_, ok := <-registrar.Watch(withToken("some-token"), "")
+	assert.False(t, ok)
+
+	acme, dev := registrar.Watch(withToken("ten_acme"), ""), registrar.Watch(withToken("ten_dev"), "")
+	assert.Equal(t, RecordChange{}, <-acme)
+	assert.Equal(t, RecordChange{}, <-dev)
+
+	// Each tenant only hears about changes to its own records, even when its zone is beneath another tenant's
+	assert.NoError(t, registrar.SetRecord(withToken("ten_dev"), "www.dev.acme.example.com.", RecordTypeA, "2.2.2.2"))
+	assert.NoError(t, registrar.SetRecord(withToken("ten_acme"), "www.acme.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.Equal(t, Domain("www.acme.example.com."), (<-acme).Domain)
+	assert.Equal(t, Domain("www.dev.acme.example.com."), (<-dev).Domain)
+	select {
+	case change := <-acme:
+		t.Errorf("Unexpected change %v", change)
+	case change := <-dev:
+		t.Errorf("Unexpected change %v", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestTenantRegistrar_RefusesUpdatesWithForbiddenRecords(t *testing.T) {
+	backend, registrar := newTestTenants(t)
+	handler := handleIPQuery(registrar, NewSynthesizerRegistry(), nil, nil)
+
+	// Unsigned updates can't change a tenant's records, and updates are atomic so the changes before it aren't made
+	// either
+	update := new(dns.Msg)
+	update.SetUpdate("example.com.")
+	allowed, _ := dns.NewRR("www.example.com. 60 IN A 2.2.2.2")
+	forbidden, _ := dns.NewRR("www.acme.example.com. 60 IN A 1.1.1.1")
+	update.Insert([]dns.RR{allowed, forbidden})
+	w := &recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP("192.0.2.7"), Port: 5353}}
+	handler(w, update)
+
+	assert.Len(t, w.messages, 1)
+	assert.Equal(t, dns.RcodeRefused, w.messages[0].Rcode)
+	assert.Empty(t, backend.records)
+}
+
+func TestTenantRegistrar_WebhooksAndAuditLog(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	receiver := newWebhookReceiver(t, "s3cret", 0)
+	defer receiver.Close()
+	backend, _ := newTestTenants(t)
+	var registrar Registrar = NewAuditingRegistrar(backend, nil)
+	registrar = NewWebhookRegistrar(ctx, registrar, nil, WebhookConfig{AllowedNetworks: loopback})
+	registrar = NewTenantRegistrar(registrar, NewTenantDirectory(context.Background(), backend))
+	withToken := func(token string) context.Context {
+		return withAuditActor(context.WithValue(ctx, apiTokenContextKey{}, token), "token:"+token, AuditEntryProtocolRest)
+	}
+	webhook := func(id string, zone string) RegisteredWebhook {
+		return RegisteredWebhook{Webhook: Webhook{Id: id, Zone: zone, Url: receiver.URL}, Secret: "s3cret"}
+	}
+
+	// Webhooks can only be registered for zones the requester could change records in
+	assert.NoError(t, registrar.CreateWebhook(withToken("ten_acme"), webhook("acme-hook", "acme.example.com.")))
+	assert.NoError(t, registrar.CreateWebhook(withToken(""), webhook("hook", "example.com.")))
+	assert.ErrorIs(t, registrar.CreateWebhook(withToken("ten_acme"), webhook("other", "example.com.")), ErrTenantForbidden)
+	assert.ErrorIs(t, registrar.CreateWebhook(withToken(""), webhook("other", "acme.example.com.")), ErrTenantForbidden)
+
+	// Each tenant only sees and deletes its own webhooks
+	listIDs := func(ctx context.Context) []string {
+		webhooks, err := registrar.ListWebhooks(ctx)
+		assert.NoError(t, err)
+		var ids []string
+		for _, webhook := range webhooks {
+			ids = append(ids, webhook.Id)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{"acme-hook"}, listIDs(withToken("ten_acme")))
+	assert.Equal(t, []string{"hook"}, listIDs(withToken("")))
+	assert.Empty(t, listIDs(withToken("ten_dev")))
+	assert.ErrorIs(t, registrar.DeleteWebhook(withToken(""), "acme-hook"), ErrWebhookNotFound)
+	_, err := registrar.ListWebhookDeliveries(withToken("ten_dev"), "acme-hook")
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+
+	// Changes to a tenant's records are delivered to its webhooks, and only appear in its audit log
+	assert.NoError(t, registrar.SetRecord(withToken("ten_acme"), "www.acme.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.Eventually(t, func() bool {
+		deliveries, err := registrar.ListWebhookDeliveries(withToken("ten_acme"), "acme-hook")
+		return err == nil && len(deliveries) == 1
+	}, time.Second, time.Millisecond)
+	assert.Len(t, receiver.received(), 1)
+	entries, err := registrar.ListAuditEntries(withToken("ten_acme"), AuditQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "www.acme.example.com.", entries[0].Domain)
+	entries, err = registrar.ListAuditEntries(withToken(""), AuditQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	entries, err = registrar.ListAuditEntries(withToken("ten_dev"), AuditQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	assert.NoError(t, registrar.DeleteWebhook(withToken("ten_acme"), "acme-hook"))
+	assert.Empty(t, listIDs(withToken("ten_acme")))
+}
+
+func TestTenantRegistrar_LeasesAndACMEDNSAccounts(t *testing.T) {
+	ctx := context.Background()
+	backend, registrar := newTestTenants(t)
+	now := time.Now()
+
+	// Leases and accounts are kept with the tenant that owns their name, and can still be found by ID or username
+	assert.NoError(t, registrar.ClaimSubdomain(ctx, SubdomainLease{Id: "acme", Domain: "abc.acme.example.com.", Expires: now.Add(-time.Minute)}))
+	assert.NoError(t, registrar.ClaimSubdomain(ctx, SubdomainLease{Id: "global", Domain: "abc.example.com.", Expires: now.Add(-time.Minute)}))
+	assert.Contains(t, backend.leases["tenant:acme:"], Domain("abc.acme.example.com."))
+	assert.Contains(t, backend.leases[""], Domain("abc.example.com."))
+	lease, err := registrar.GetLease(ctx, "acme")
+	assert.NoError(t, err)
+	assert.Equal(t, Domain("abc.acme.example.com."), lease.Domain)
+	_, err = registrar.GetSubdomainLease(ctx, "abc.acme.example.com.")
+	assert.NoError(t, err)
+	_, err = registrar.RenewLease(ctx, "acme", now.Add(time.Hour), now.Add(-2*time.Minute))
+	assert.NoError(t, err)
+	_, err = registrar.RenewLease(ctx, "missing", now.Add(time.Hour), now)
+	assert.ErrorIs(t, err, ErrLeaseNotFound)
+
+	expired, err := registrar.ListExpiredLeases(ctx, now)
+	assert.NoError(t, err)
+	if assert.Len(t, expired, 1) {
+		assert.Equal(t, "global", expired[0].Id)
+	}
+	expired, err = registrar.ListExpiredLeases(ctx, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, expired, 2)
+	assert.NoError(t, registrar.DeleteLease(ctx, lease))
+	assert.Empty(t, backend.leases["tenant:acme:"])
+
+	assert.NoError(t, registrar.CreateACMEDNSAccount(ctx, ACMEDNSAccount{Username: "user", Domain: "uuid.dev.acme.example.com."}))
+	assert.Contains(t, backend.acmeDNS["tenant:dev:"], "user")
+	account, err := registrar.GetACMEDNSAccount(ctx, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, Domain("uuid.dev.acme.example.com."), account.Domain)
+	_, err = registrar.GetACMEDNSAccount(ctx, "missing")
+	assert.ErrorIs(t, err, ErrACMEDNSAccountNotFound)
+}
+
+func TestTenantRegistrar_CreateTenant(t *testing.T) {
+	_, registrar := newTestTenants(t)
+	ctx := context.Background()
+
+	// Tenants take effect as soon as they are created
+	assert.NoError(t, registrar.SetRecord(ctx, "www.new.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, registrar.CreateTenant(ctx, RegisteredTenant{
+		Tenant:      Tenant{Id: "new", Zones: []string{"new.example.com."}, KeyPrefix: tenantKeyPrefix("new")},
+		TokenHashes: []string{secretTokenHash("ten_new")},
+	}))
+	_, err := registrar.GetRecord(ctx, "www.new.example.com.", RecordTypeA)
+	assert.ErrorIs(t, err, ErrRecordNotFound, "records from before the tenant existed aren't in its namespace")
+
+	for _, tenant := range []Tenant{
+		{Id: "acme", Zones: []string{"other.example.com."}},
+		{Id: "other", Zones: []string{"ACME.example.com"}},
+		{Id: "other", Zones: []string{"other.example.com."}, TsigKeys: []string{"acme."}},
+	} {
+		assert.ErrorIs(t, registrar.CreateTenant(ctx, RegisteredTenant{Tenant: tenant}), ErrTenantConflict, "%v", tenant)
+	}
+}
+
+// notifyingRegistrar sends the record changes the test gives it, rather than any from the backend.
+type notifyingRegistrar struct {
+	*memoryRegistrar
+	changes chan RecordChange
+}
+
+func (n notifyingRegistrar) SubscribeRecordChanges(context.Context) <-chan RecordChange {
+	return n.changes
+}
+
+func TestTenantDirectory_ReloadsWhenNotified(t *testing.T) {
+	ctx := context.Background()
+	backend := notifyingRegistrar{memoryRegistrar: newMemoryRegistrar(), changes: make(chan RecordChange)}
+	defer close(backend.changes)
+	directory := NewTenantDirectory(ctx, backend)
+	directory.ttl = time.Hour
+	tenants, err := directory.list(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, tenants)
+
+	// A tenant created by another replica is picked up once the backend says so, without waiting for the ttl
+	assert.NoError(t, backend.CreateTenant(ctx, RegisteredTenant{Tenant: Tenant{Id: "acme", Zones: []string{"acme.example.com."}}}))
+	tenants, err = directory.list(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, tenants)
+	backend.changes <- RecordChange{Domain: "www.acme.example.com.", Type: RecordTypeA}
+	backend.changes <- RecordChange{}
+	assert.Eventually(t, func() bool {
+		tenants, err := directory.list(ctx)
+		return err == nil && len(tenants) == 1
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,82 @@
+terraform {
+  required_providers {
+    ephemerain = {
+      source = "mwudka/ephemerain"
+    }
+  }
+}
+
+variable "endpoint" {}
+
+provider "ephemerain" {
+  endpoint = var.endpoint
+}
+
+resource "ephemerain_zone" "zone" {
+  name = "tf.testing.com."
+  records = [
+    {
+      name   = "@"
+      type   = "TXT"
+      values = ["hello \"world\""]
+    },
+    {
+      name   = "www"
+      type   = "A"
+      ttl    = 300
+      values = ["192.0.2.1", "192.0.2.2"]
+    },
+    {
+      name   = "@"
+      type   = "MX"
+      values = ["10 mail.tf.testing.com."]
+    },
+  ]
+}
+
+resource "ephemerain_record" "api" {
+  name   = "api.records.testing.com."
+  type   = "CNAME"
+  values = ["www.tf.testing.com."]
+}
+
+resource "ephemerain_subdomain" "preview" {}
+
+resource "ephemerain_record" "preview" {
+  name   = "www.${ephemerain_subdomain.preview.domain}"
+  type   = "A"
+  values = ["192.0.2.3"]
+  token  = ephemerain_subdomain.preview.token
+}
+
+data "ephemerain_record" "www" {
+  name       = "www.tf.testing.com."
+  type       = "A"
+  depends_on = [ephemerain_zone.zone]
+}
+
+data "ephemerain_zone" "zone" {
+  name       = ephemerain_zone.zone.name
+  depends_on = [ephemerain_zone.zone]
+}
+
+data "ephemerain_subdomain" "preview" {
+  id    = ephemerain_subdomain.preview.id
+  token = ephemerain_subdomain.preview.token
+}
+
+output "preview_domain" {
+  value = ephemerain_subdomain.preview.domain
+}
+
+output "www_values" {
+  value = data.ephemerain_record.www.values
+}
+
+output "zone_records" {
+  value = length(data.ephemerain_zone.zone.records)
+}
+
+output "preview_lease_domain" {
+  value = data.ephemerain_subdomain.preview.domain
+}
@@ -12,9 +12,11 @@ import (
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/go-connections/nat"
 	"github.com/hashicorp/go-hclog"
+	"github.com/miekg/dns"
 	"github.com/teris-io/shortid"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -152,6 +154,11 @@ func withServer(ctx context.Context, config EphemerainConfig, callback func(clie
 	}
 	dnsServerPort := dnsListener.LocalAddr().(*net.UDPAddr).Port
 	config.DNSListener = dnsListener
+	dnsTCPListener, err := net.Listen("tcp", fmt.Sprintf(":%d", dnsServerPort))
+	if err != nil {
+		return err
+	}
+	config.DNSTCPListener = dnsTCPListener
 
 	httpListener, err := net.Listen("tcp", ":0")
 	if err != nil {
@@ -176,12 +183,14 @@ func withServer(ctx context.Context, config EphemerainConfig, callback func(clie
 }
 
 func runIntegrationTest(t *testing.T, callback func(context.Context, *Client, *net.Resolver, string)) {
+	runIntegrationTestWithConfig(t, EphemerainConfig{}, callback)
+}
+
+func runIntegrationTestWithConfig(t *testing.T, config EphemerainConfig, callback func(context.Context, *Client, *net.Resolver, string)) {
 	ctx := context.Background()
 	err := withRedisTestServer(ctx, func(redisPort int) {
-		config := EphemerainConfig{
-			JSONLogs:     false,
-			RedisAddress: fmt.Sprintf("localhost:%d", redisPort),
-		}
+		config.JSONLogs = false
+		config.RedisAddress = fmt.Sprintf("localhost:%d", redisPort)
 		err := withServer(ctx, config, func(apiClient *Client, resolver *net.Resolver, nameserver string) {
 			callback(ctx, apiClient, resolver, nameserver)
 		})
@@ -193,3 +202,472 @@ func runIntegrationTest(t *testing.T, callback func(context.Context, *Client, *n
 		t.Fatalf("Error running redis test server: %v", err)
 	}
 }
+
+// recordingResponseWriter is a dns.ResponseWriter that keeps the messages written to it, so DNS handlers can be tested
+// without a network listener.
+type recordingResponseWriter struct {
+	remoteAddr net.Addr
+	messages   []*dns.Msg
+}
+
+func (w *recordingResponseWriter) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53}
+}
+
+func (w *recordingResponseWriter) RemoteAddr() net.Addr {
+	return w.remoteAddr
+}
+
+func (w *recordingResponseWriter) WriteMsg(m *dns.Msg) error {
+	w.messages = append(w.messages, m)
+	return nil
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	m := new(dns.Msg)
+	if err := m.Unpack(b); err != nil {
+		return 0, err
+	}
+	return len(b), w.WriteMsg(m)
+}
+
+func (w *recordingResponseWriter) Close() error {
+	return nil
+}
+
+func (w *recordingResponseWriter) TsigStatus() error {
+	return nil
+}
+
+func (w *recordingResponseWriter) TsigTimersOnly(bool) {
+}
+
+func (w *recordingResponseWriter) Hijack() {
+}
+
+// memoryRegistrar is a Registrar that keeps records in memory, for testing code built on top of a Registrar without
+// needing Redis. Every GetRecord call is counted, and takes at least latency to simulate a round trip to the backend.
+type memoryRegistrar struct {
+	latency time.Duration
+
+	mu          sync.Mutex
+	records     map[string]string
+	reads       int
+	subscribers []chan RecordChange
+	webhooks    map[string]map[string]RegisteredWebhook
+	deliveries  map[string][]WebhookDelivery
+	audit       map[string][]AuditEntry
+	history     map[string][]RecordVersion
+	changed     map[string]time.Time
+	leases      map[string]map[Domain]SubdomainLease
+	tenants     map[string]RegisteredTenant
+	acmeDNS     map[string]map[string]ACMEDNSAccount
+	now         func() time.Time
+}
+
+func newMemoryRegistrar() *memoryRegistrar {
+	return &memoryRegistrar{
+		records:    map[string]string{},
+		webhooks:   map[string]map[string]RegisteredWebhook{},
+		deliveries: map[string][]WebhookDelivery{},
+		audit:      map[string][]AuditEntry{},
+		history:    map[string][]RecordVersion{},
+		changed:    map[string]time.Time{},
+		leases:     map[string]map[Domain]SubdomainLease{},
+		tenants:    map[string]RegisteredTenant{},
+		acmeDNS:    map[string]map[string]ACMEDNSAccount{},
+		now:        time.Now,
+	}
+}
+
+func (m *memoryRegistrar) readCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reads
+}
+
+// publish sends change to every subscriber, as if it had been made by another replica.
+func (m *memoryRegistrar) publish(change RecordChange) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, subscriber := range m.subscribers {
+		subscriber <- change
+	}
+}
+
+func (m *memoryRegistrar) SetRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) error {
+	_, _, err := m.SwapRecord(ctx, fqdn, recordType, value)
+	return err
+}
+
+func (m *memoryRegistrar) SwapRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) (string, bool, error) {
+	m.mu.Lock()
+	key := recordKey(ctx, fqdn, recordType, viewFromContext(ctx))
+	previous, existed := m.records[key]
+	m.records[key] = value
+	m.recordVersion(key, value, false, existed)
+	m.mu.Unlock()
+	m.publish(RecordChange{Namespace: namespaceFromContext(ctx), Domain: fqdn, Type: recordType, View: viewFromContext(ctx), Action: RecordChangeSet, Value: value})
+	return previous, existed, nil
+}
+
+func (m *memoryRegistrar) GetRecord(ctx context.Context, fqdn Domain, recordType RecordType) (string, error) {
+	time.Sleep(m.latency)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	if value, ok := m.records[recordKey(ctx, fqdn, recordType, viewFromContext(ctx))]; ok {
+		return value, nil
+	}
+	if value, ok := m.records[recordKey(ctx, fqdn, recordType, "")]; ok {
+		return value, nil
+	}
+	return "", ErrRecordNotFound
+}
+
+func (m *memoryRegistrar) GetViewRecord(ctx context.Context, fqdn Domain, recordType RecordType) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	if value, ok := m.records[recordKey(ctx, fqdn, recordType, viewFromContext(ctx))]; ok {
+		return value, nil
+	}
+	return "", ErrRecordNotFound
+}
+
+func (m *memoryRegistrar) DeleteRecord(ctx context.Context, fqdn Domain, recordType RecordType, currentValue string) error {
+	m.mu.Lock()
+	key := recordKey(ctx, fqdn, recordType, viewFromContext(ctx))
+	if m.records[key] != currentValue {
+		m.mu.Unlock()
+		return fmt.Errorf("current value of %s is not %s", key, currentValue)
+	}
+	delete(m.records, key)
+	m.recordVersion(key, currentValue, true, true)
+	m.mu.Unlock()
+	m.publish(RecordChange{Namespace: namespaceFromContext(ctx), Domain: fqdn, Type: recordType, View: viewFromContext(ctx), Action: RecordChangeDelete, Value: currentValue})
+	return nil
+}
+
+func (m *memoryRegistrar) WriteRecords(ctx context.Context, writes []RecordWrite) ([]RecordWriteResult, error) {
+	view := viewFromContext(ctx)
+	m.mu.Lock()
+	for _, write := range writes {
+		if key := recordKey(ctx, write.Domain, write.Type, view); write.Delete && m.records[key] != write.Value {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("%w: current value of %s is not %s", ErrRecordChanged, key, write.Value)
+		}
+	}
+	results := make([]RecordWriteResult, len(writes))
+	changes := make([]RecordChange, len(writes))
+	for i, write := range writes {
+		key := recordKey(ctx, write.Domain, write.Type, view)
+		results[i].Previous, results[i].Existed = m.records[key]
+		changes[i] = RecordChange{Namespace: namespaceFromContext(ctx), Domain: write.Domain, Type: write.Type, View: view, Action: RecordChangeSet, Value: write.Value}
+		if write.Delete {
+			delete(m.records, key)
+			changes[i].Action = RecordChangeDelete
+		} else {
+			m.records[key] = write.Value
+		}
+		m.recordVersion(key, write.Value, write.Delete, results[i].Existed)
+	}
+	m.mu.Unlock()
+	for _, change := range changes {
+		m.publish(change)
+	}
+	return results, nil
+}
+
+// namespacedRecord parses key if it is the key of a record in namespace.
+func namespacedRecord(key string, namespace string) (RecordReference, bool) {
+	if !strings.HasPrefix(key, namespace) {
+		return RecordReference{}, false
+	}
+	record, ok := parseRedisKey(strings.TrimPrefix(key, namespace))
+	return record, ok && !strings.Contains(record.Domain, ":")
+}
+
+func (m *memoryRegistrar) ListRecords(ctx context.Context, zone Domain) ([]StoredRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var records []StoredRecord
+	for key, value := range m.records {
+		if record, ok := namespacedRecord(key, namespaceFromContext(ctx)); ok && isSubdomain(Domain(record.Domain), zone) {
+			stored := StoredRecord{Domain: Domain(record.Domain), Type: record.Type, Value: value}
+			if record.View != nil {
+				stored.View = *record.View
+			}
+			records = append(records, stored)
+		}
+	}
+	return records, nil
+}
+
+func (m *memoryRegistrar) ConsumeRateLimit(context.Context, string, int64, time.Duration) (time.Duration, error) {
+	return 0, nil
+}
+
+func (m *memoryRegistrar) ClaimRecordQuota(context.Context, string, []RecordReference, int64) error {
+	return nil
+}
+
+func (m *memoryRegistrar) ClaimSubdomain(ctx context.Context, lease SubdomainLease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	namespace, domain := namespaceFromContext(ctx), Domain(canonicalName(string(lease.Domain)))
+	if _, ok := m.leases[namespace][domain]; ok {
+		return ErrSubdomainTaken
+	}
+	if m.leases[namespace] == nil {
+		m.leases[namespace] = map[Domain]SubdomainLease{}
+	}
+	m.leases[namespace][domain] = lease
+	return nil
+}
+
+func (m *memoryRegistrar) GetSubdomainLease(ctx context.Context, fqdn Domain) (SubdomainLease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lease, ok := m.leases[namespaceFromContext(ctx)][Domain(canonicalName(string(fqdn)))]; ok {
+		return lease, nil
+	}
+	return SubdomainLease{}, ErrLeaseNotFound
+}
+
+func (m *memoryRegistrar) GetLease(ctx context.Context, id string) (SubdomainLease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, lease := range m.leases[namespaceFromContext(ctx)] {
+		if lease.Id == id {
+			return lease, nil
+		}
+	}
+	return SubdomainLease{}, ErrLeaseNotFound
+}
+
+func (m *memoryRegistrar) RenewLease(ctx context.Context, id string, expires time.Time, now time.Time) (SubdomainLease, error) {
+	lease, err := m.GetLease(ctx, id)
+	if err != nil {
+		return lease, err
+	}
+	if !lease.Expires.After(now) {
+		return lease, ErrLeaseExpired
+	}
+	lease.Expires = expires
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.leases[namespaceFromContext(ctx)][Domain(canonicalName(string(lease.Domain)))] = lease
+	return lease, nil
+}
+
+func (m *memoryRegistrar) ListExpiredLeases(ctx context.Context, now time.Time) ([]SubdomainLease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var leases []SubdomainLease
+	for _, lease := range m.leases[namespaceFromContext(ctx)] {
+		if !lease.Expires.After(now) {
+			leases = append(leases, lease)
+		}
+	}
+	return leases, nil
+}
+
+func (m *memoryRegistrar) DeleteLease(ctx context.Context, lease SubdomainLease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.leases[namespaceFromContext(ctx)], Domain(canonicalName(string(lease.Domain))))
+	return nil
+}
+
+func (m *memoryRegistrar) SubscribeRecordChanges(ctx context.Context) <-chan RecordChange {
+	// Like with redis, subscribers are told that they may have missed changes as soon as they're subscribed
+	changes := make(chan RecordChange, 100)
+	changes <- RecordChange{}
+	m.mu.Lock()
+	m.subscribers = append(m.subscribers, changes)
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, subscriber := range m.subscribers {
+			if subscriber == changes {
+				m.subscribers = append(m.subscribers[:i], m.subscribers[i+1:]...)
+				break
+			}
+		}
+		close(changes)
+	}()
+	return changes
+}
+
+func (m *memoryRegistrar) Watch(ctx context.Context, prefix string) <-chan RecordChange {
+	return filterRecordChanges(ctx, m.SubscribeRecordChanges(ctx), prefix)
+}
+
+func (m *memoryRegistrar) CreateTenant(_ context.Context, tenant RegisteredTenant) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tenants[tenant.Id]; ok {
+		return fmt.Errorf("%w: tenant %s already exists", ErrTenantConflict, tenant.Id)
+	}
+	for _, existing := range m.tenants {
+		for _, zone := range tenant.Zones {
+			for _, existingZone := range existing.Zones {
+				if canonicalName(zone) == canonicalName(existingZone) {
+					return fmt.Errorf("%w: %s already belongs to tenant %s", ErrTenantConflict, zone, existing.Id)
+				}
+			}
+		}
+		for _, key := range tenant.TsigKeys {
+			for _, existingKey := range existing.TsigKeys {
+				if strings.EqualFold(key, existingKey) {
+					return fmt.Errorf("%w: %s already belongs to tenant %s", ErrTenantConflict, key, existing.Id)
+				}
+			}
+		}
+	}
+	m.tenants[tenant.Id] = tenant
+	return nil
+}
+
+func (m *memoryRegistrar) ListTenants(context.Context) ([]RegisteredTenant, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenants := []RegisteredTenant{}
+	for _, tenant := range m.tenants {
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+func (m *memoryRegistrar) CreateACMEDNSAccount(ctx context.Context, account ACMEDNSAccount) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	namespace := namespaceFromContext(ctx)
+	if m.acmeDNS[namespace] == nil {
+		m.acmeDNS[namespace] = map[string]ACMEDNSAccount{}
+	}
+	m.acmeDNS[namespace][account.Username] = account
+	return nil
+}
+
+func (m *memoryRegistrar) GetACMEDNSAccount(ctx context.Context, username string) (ACMEDNSAccount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if account, ok := m.acmeDNS[namespaceFromContext(ctx)][username]; ok {
+		return account, nil
+	}
+	return ACMEDNSAccount{}, ErrACMEDNSAccountNotFound
+}
+
+func (m *memoryRegistrar) CreateWebhook(ctx context.Context, webhook RegisteredWebhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	namespace := namespaceFromContext(ctx)
+	if m.webhooks[namespace] == nil {
+		m.webhooks[namespace] = map[string]RegisteredWebhook{}
+	}
+	m.webhooks[namespace][webhook.Id] = webhook
+	return nil
+}
+
+func (m *memoryRegistrar) ListWebhooks(ctx context.Context) ([]RegisteredWebhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var webhooks []RegisteredWebhook
+	for _, webhook := range m.webhooks[namespaceFromContext(ctx)] {
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+func (m *memoryRegistrar) DeleteWebhook(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	namespace := namespaceFromContext(ctx)
+	if _, ok := m.webhooks[namespace][id]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(m.webhooks[namespace], id)
+	delete(m.deliveries, namespace+id)
+	return nil
+}
+
+func (m *memoryRegistrar) LogWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := namespaceFromContext(ctx) + delivery.WebhookId
+	m.deliveries[key] = append([]WebhookDelivery{delivery}, m.deliveries[key]...)
+	return nil
+}
+
+func (m *memoryRegistrar) ListWebhookDeliveries(ctx context.Context, id string) ([]WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	namespace := namespaceFromContext(ctx)
+	if _, ok := m.webhooks[namespace][id]; !ok {
+		return nil, ErrWebhookNotFound
+	}
+	return append([]WebhookDelivery{}, m.deliveries[namespace+id]...), nil
+}
+
+func (m *memoryRegistrar) AppendAuditEntry(ctx context.Context, entry AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	namespace := namespaceFromContext(ctx)
+	entry.Id = strconv.Itoa(len(m.audit[namespace]) + 1)
+	m.audit[namespace] = append(m.audit[namespace], entry)
+	return nil
+}
+
+func (m *memoryRegistrar) ListAuditEntries(ctx context.Context, query AuditQuery) ([]AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	audit := m.audit[namespaceFromContext(ctx)]
+	entries := []AuditEntry{}
+	for i := len(audit) - 1; i >= 0 && (query.Limit <= 0 || len(entries) < query.Limit); i-- {
+		entry := audit[i]
+		if (query.Zone == "" || Domain(entry.Zone) == query.Zone) &&
+			(query.Since.IsZero() || !entry.Timestamp.Before(query.Since)) &&
+			(query.Until.IsZero() || !entry.Timestamp.After(query.Until)) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (m *memoryRegistrar) recordVersion(key string, value string, deleted bool, existed bool) {
+	now := m.now()
+	var number int64 = 1
+	if versions := m.history[key]; len(versions) > 0 {
+		number = versions[0].Version + 1
+	}
+	version := RecordVersion{Version: number, Value: value, Deleted: deleted, Created: !deleted && !existed, Timestamp: now}
+	versions := append([]RecordVersion{version}, m.history[key]...)
+	if len(versions) > maxRecordVersions {
+		versions = versions[:maxRecordVersions]
+	}
+	m.history[key] = versions
+	m.changed[key] = now
+}
+
+func (m *memoryRegistrar) RecordHistory(ctx context.Context, fqdn Domain, recordType RecordType) ([]RecordVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]RecordVersion{}, m.history[recordKey(ctx, fqdn, recordType, viewFromContext(ctx))]...), nil
+}
+
+func (m *memoryRegistrar) ListChangedRecords(ctx context.Context, since time.Time) ([]RecordReference, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	records := []RecordReference{}
+	for key, changed := range m.changed {
+		if record, ok := namespacedRecord(key, namespaceFromContext(ctx)); ok && !changed.Before(since) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/miekg/dns"
+	"strings"
+	"time"
+)
+
+// ParseTSIGSecrets parses the TSIG_SECRETS environment variable, which is a semicolon separated list of
+// name=base64-secret entries, for example certbot=c2VjcmV0;lego=b3RoZXI=.
+func ParseTSIGSecrets(raw string) (map[string]string, error) {
+	secrets := map[string]string{}
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("TSIG secret entry %q should be of the form name=secret", entry)
+		}
+		name, secret := dns.Fqdn(strings.ToLower(strings.TrimSpace(parts[0]))), strings.TrimSpace(parts[1])
+		if _, err := base64.StdEncoding.DecodeString(secret); err != nil {
+			return nil, fmt.Errorf("TSIG secret for %s isn't valid base64: %w", name, err)
+		}
+		secrets[name] = secret
+	}
+	return secrets, nil
+}
+
+// updateAuditActor identifies who sent an RFC 2136 update: the TSIG key it was signed with, or the address it came
+// from if it wasn't signed with a configured key. It fails if the update was signed with a configured key but the
+// signature didn't verify, or if it was signed with a key that isn't configured once there are tenants.
+func updateAuditActor(ctx context.Context, registrar Registrar, w dns.ResponseWriter, r *dns.Msg) (string, error) {
+	if tsig := r.IsTsig(); tsig != nil {
+		switch err := w.TsigStatus(); {
+		case err == nil:
+			return "tsig:" + strings.ToLower(tsig.Hdr.Name), nil
+		case !errors.Is(err, dns.ErrSecret):
+			return "", fmt.Errorf("TSIG verification failed for key %s: %w", tsig.Hdr.Name, err)
+		}
+		// Updates signed with unknown keys have always been accepted, so keep accepting them without trusting the key
+		// name, unless tenants are configured, since tenants are told apart by their keys
+		tenants, err := registrar.ListTenants(ctx)
+		if err != nil {
+			return "", err
+		}
+		if len(tenants) > 0 {
+			return "", fmt.Errorf("TSIG key %s isn't configured", tsig.Hdr.Name)
+		}
+	}
+	return "ip:" + remoteIP(w.RemoteAddr()).String(), nil
+}
+
+// verifiedTSIGKey returns the name of the configured key an update was signed with, or an empty string if it wasn't
+// signed with a configured key.
+func verifiedTSIGKey(w dns.ResponseWriter, r *dns.Msg) string {
+	if tsig := r.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		return strings.ToLower(tsig.Hdr.Name)
+	}
+	return ""
+}
+
+// signUpdateResponse signs the response to an update that was signed with a configured key, as RFC 2845 requires.
+func signUpdateResponse(w dns.ResponseWriter, r *dns.Msg, m *dns.Msg) {
+	if tsig := r.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseTSIGSecrets(t *testing.T) {
+	secrets, err := ParseTSIGSecrets("Certbot=c2VjcmV0; lego.=b3RoZXI=")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"certbot.": "c2VjcmV0", "lego.": "b3RoZXI="}, secrets)
+
+	_, err = ParseTSIGSecrets("certbot")
+	assert.Error(t, err)
+	_, err = ParseTSIGSecrets("certbot=not base64")
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/miekg/dns"
+	"net"
+	"strings"
+)
+
+// ViewDefinition is a named group of client networks that see their own version of records. Records that don't exist
+// in a view fall back to the default view, which is what clients outside of every view see.
+type ViewDefinition struct {
+	Name     string
+	Networks []*net.IPNet
+}
+
+// defaultViewName can be used in the API to explicitly refer to the default view.
+const defaultViewName = "default"
+
+// ViewConfig is the list of configured views. The first view with a network containing the client wins.
+type ViewConfig []ViewDefinition
+
+// ParseViewConfig parses the VIEWS environment variable, which is a semicolon separated list of
+// name=cidr,cidr entries, for example internal=10.0.0.0/8,192.168.0.0/16;vpn=100.64.0.0/10.
+func ParseViewConfig(raw string) (ViewConfig, error) {
+	var views ViewConfig
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("view entry %q should be of the form name=cidr,cidr", entry)
+		}
+
+		view := ViewDefinition{Name: strings.TrimSpace(parts[0])}
+		if view.Name == defaultViewName {
+			return nil, fmt.Errorf("the %s view can't be configured", defaultViewName)
+		}
+		for _, cidr := range strings.Split(parts[1], ",") {
+			_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+			if err != nil {
+				return nil, fmt.Errorf("view %s: %w", view.Name, err)
+			}
+			view.Networks = append(view.Networks, network)
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// Match returns the name of the view ip belongs to, or an empty string for the default view.
+func (v ViewConfig) Match(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	for _, view := range v {
+		for _, network := range view.Networks {
+			if network.Contains(ip) {
+				return view.Name
+			}
+		}
+	}
+	return ""
+}
+
+// Exists reports whether name is a configured view. The default view always exists.
+func (v ViewConfig) Exists(name string) bool {
+	if name == "" || name == defaultViewName {
+		return true
+	}
+	for _, view := range v {
+		if view.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// clientSubnet returns the EDNS Client Subnet option of a query, or nil if it doesn't have one.
+func clientSubnet(r *dns.Msg) *dns.EDNS0_SUBNET {
+	if opt := r.IsEdns0(); opt != nil {
+		for _, option := range opt.Option {
+			if subnet, ok := option.(*dns.EDNS0_SUBNET); ok && subnet.Address != nil {
+				return subnet
+			}
+		}
+	}
+	return nil
+}
+
+// queryClientIP returns the address that views are matched against for a DNS query. If the query was forwarded by one
+// of the trusted recursive resolvers and it sent an EDNS Client Subnet option, that is used in place of the resolver's
+// own address. Anyone else could pick their view by sending the option themselves, so it is ignored for them.
+func queryClientIP(w dns.ResponseWriter, r *dns.Msg, trustedResolvers []*net.IPNet) (net.IP, *dns.EDNS0_SUBNET) {
+	resolver := remoteIP(w.RemoteAddr())
+	if subnet := clientSubnet(r); subnet != nil && networksContain(trustedResolvers, resolver) {
+		return subnet.Address, subnet
+	}
+	return resolver, nil
+}
+
+// setClientSubnetScope echoes the EDNS Client Subnet option of a query in its response, as RFC 7871 requires. The
+// scope tells the resolver how much of the client address the answer depends on, which is all of the address it sent
+// when the answer came from a view it picked, and none of it otherwise.
+func setClientSubnetScope(m *dns.Msg, r *dns.Msg, used *dns.EDNS0_SUBNET, views ViewConfig) {
+	subnet := clientSubnet(r)
+	if subnet == nil {
+		return
+	}
+	echo := *subnet
+	echo.SourceScope = 0
+	if used != nil && len(views) > 0 {
+		echo.SourceScope = subnet.SourceNetmask
+	}
+	m.SetEdns0(dns.DefaultMsgSize, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &echo)
+}
+
+type viewContextKey struct{}
+
+// withView returns a context that makes the registrar read and write records in the named view.
+func withView(ctx context.Context, view string) context.Context {
+	if view == defaultViewName {
+		view = ""
+	}
+	return context.WithValue(ctx, viewContextKey{}, view)
+}
+
+// viewFromContext returns the view set by withView, or an empty string for the default view.
+func viewFromContext(ctx context.Context) string {
+	view, _ := ctx.Value(viewContextKey{}).(string)
+	return view
+}
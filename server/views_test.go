@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestParseViewConfig(t *testing.T) {
+	views, err := ParseViewConfig("internal=10.0.0.0/8, 192.168.0.0/16; vpn=100.64.0.0/10;everything=0.0.0.0/0")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "internal", views.Match(net.ParseIP("192.168.4.5")))
+	assert.Equal(t, "vpn", views.Match(net.ParseIP("100.64.0.1")))
+	assert.Equal(t, "everything", views.Match(net.ParseIP("203.0.113.1")))
+	assert.Equal(t, "", views.Match(net.ParseIP("2001:db8::1")))
+	assert.True(t, views.Exists("vpn"))
+	assert.True(t, views.Exists(defaultViewName))
+	assert.False(t, views.Exists("nonexistent"))
+
+	_, err = ParseViewConfig("internal=10.0.0.0")
+	assert.Error(t, err)
+	_, err = ParseViewConfig("default=10.0.0.0/8")
+	assert.Error(t, err)
+}
+
+func TestQueryClientIP_PrefersClientSubnetFromTrustedResolvers(t *testing.T) {
+	trustedResolvers, err := ParseNetworks("8.8.8.0/24")
+	assert.NoError(t, err)
+	query := func(resolver string, r *dns.Msg) string {
+		w := &recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP(resolver), Port: 5353}}
+		ip, _ := queryClientIP(w, r, trustedResolvers)
+		return ip.String()
+	}
+
+	r := new(dns.Msg)
+	r.SetQuestion("app.preview.example.com.", dns.TypeA)
+	assert.Equal(t, "8.8.8.8", query("8.8.8.8", r))
+
+	r.SetEdns0(1232, false)
+	opt := r.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        1,
+		SourceNetmask: 24,
+		Address:       net.ParseIP("10.1.2.0").To4(),
+	})
+	assert.Equal(t, "10.1.2.0", query("8.8.8.8", r))
+	assert.Equal(t, "203.0.113.5", query("203.0.113.5", r))
+}
+
+func TestClientSubnetScope(t *testing.T) {
+	views, err := ParseViewConfig("internal=10.0.0.0/8")
+	assert.NoError(t, err)
+	trustedResolvers, err := ParseNetworks("8.8.8.0/24")
+	assert.NoError(t, err)
+	query := func(handler func(dns.ResponseWriter, *dns.Msg), resolver string, withSubnet bool) *dns.Msg {
+		w := &recordingResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP(resolver), Port: 5353}}
+		r := new(dns.Msg)
+		r.SetQuestion("app.preview.example.com.", dns.TypeA)
+		if withSubnet {
+			r.SetEdns0(1232, false)
+			opt := r.IsEdns0()
+			opt.Option = append(opt.Option, &dns.EDNS0_SUBNET{
+				Code:          dns.EDNS0SUBNET,
+				Family:        1,
+				SourceNetmask: 24,
+				Address:       net.ParseIP("10.1.2.0").To4(),
+			})
+		}
+		handler(w, r)
+		assert.Len(t, w.messages, 1)
+		return w.messages[0]
+	}
+	scope := func(m *dns.Msg) uint8 {
+		subnet := clientSubnet(m)
+		if !assert.NotNil(t, subnet) {
+			return 0
+		}
+		assert.Equal(t, "10.1.2.0", subnet.Address.String())
+		assert.Equal(t, uint8(24), subnet.SourceNetmask)
+		return subnet.SourceScope
+	}
+	registrar := newMemoryRegistrar()
+	assert.NoError(t, registrar.SetRecord(withView(context.Background(), "internal"), "app.preview.example.com.", RecordTypeA, "10.0.0.1"))
+	assert.NoError(t, registrar.SetRecord(context.Background(), "app.preview.example.com.", RecordTypeA, "203.0.113.1"))
+	handler := handleIPQuery(registrar, NewSynthesizerRegistry(), views, trustedResolvers)
+
+	response := query(handler, "8.8.8.8", true)
+	assert.Equal(t, "10.0.0.1", response.Answer[0].(*dns.A).A.String())
+	assert.Equal(t, uint8(24), scope(response))
+
+	// The answer didn't depend on the subnet of an untrusted resolver
+	response = query(handler, "203.0.113.5", true)
+	assert.Equal(t, "203.0.113.1", response.Answer[0].(*dns.A).A.String())
+	assert.Equal(t, uint8(0), scope(response))
+
+	response = query(handler, "8.8.8.8", false)
+	assert.Nil(t, response.IsEdns0())
+
+	// Without views, every client gets the same answer
+	response = query(handleIPQuery(registrar, NewSynthesizerRegistry(), nil, trustedResolvers), "8.8.8.8", true)
+	assert.Equal(t, "203.0.113.1", response.Answer[0].(*dns.A).A.String())
+	assert.Equal(t, uint8(0), scope(response))
+}
+
+func TestWithView_DefaultViewName(t *testing.T) {
+	assert.Equal(t, "", viewFromContext(context.Background()))
+	assert.Equal(t, "", viewFromContext(withView(context.Background(), defaultViewName)))
+	assert.Equal(t, "internal", viewFromContext(withView(context.Background(), "internal")))
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/teris-io/shortid"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RegisteredWebhook is a webhook along with the secret its payloads are signed with, which is never returned by the
+// API.
+type RegisteredWebhook struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookConfig configures delivery of webhooks. The zero value makes a single attempt at each delivery.
+type WebhookConfig struct {
+	// MaxAttempts is how many times delivering an event is attempted before giving up.
+	MaxAttempts int
+	// InitialBackoff is how long to wait before the first retry. Every retry after that waits twice as long as the
+	// last one.
+	InitialBackoff time.Duration
+	Timeout        time.Duration
+	// AllowedNetworks are internal networks that webhooks may be delivered to. Deliveries to loopback, private and
+	// link-local addresses outside of them are refused, so webhooks can't be used to reach internal services.
+	AllowedNetworks []*net.IPNet
+}
+
+// webhookSignature is the value of the X-Ephemerain-Signature header for a payload.
+func webhookSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookRegistrar sends events to the registered webhooks whenever a record is written through it. Wrapping the
+// registrar used by both the HTTP API and the DNS UPDATE handler means each change is delivered exactly once, by the
+// replica that made it.
+type WebhookRegistrar struct {
+	Registrar
+
+	ctx    context.Context
+	zones  ZoneSet
+	config WebhookConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhookRegistrar wraps next so writes are sent to webhooks. Deliveries that are still being retried are abandoned
+// when ctx is cancelled.
+func NewWebhookRegistrar(ctx context.Context, next Registrar, zones ZoneSet, config WebhookConfig) *WebhookRegistrar {
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	// Addresses are checked as they are connected to, after the webhook's host has been resolved, so neither a host that
+	// resolves to an internal address nor a redirect to one gets around the check
+	dialer := &net.Dialer{Timeout: timeout, Control: func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); isInternalAddress(ip) && !networksContain(config.AllowedNetworks, ip) {
+			return fmt.Errorf("refusing to deliver webhook to internal address %s", ip)
+		}
+		return nil
+	}}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &WebhookRegistrar{
+		Registrar: next,
+		ctx:       ctx,
+		zones:     zones,
+		config:    config,
+		client:    &http.Client{Timeout: timeout, Transport: transport},
+		now:       time.Now,
+	}
+}
+
+// isInternalAddress reports whether ip is a loopback, private, link-local or otherwise non-public address.
+func isInternalAddress(ip net.IP) bool {
+	return ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+func (w *WebhookRegistrar) SetRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) error {
+	_, _, err := w.SwapRecord(ctx, fqdn, recordType, value)
+	return err
+}
+
+func (w *WebhookRegistrar) SwapRecord(ctx context.Context, fqdn Domain, recordType RecordType, value string) (string, bool, error) {
+	previous, existed, err := w.Registrar.SwapRecord(ctx, fqdn, recordType, value)
+	if err != nil {
+		return previous, existed, err
+	}
+
+	event := w.newEvent(ctx, fqdn, recordType, WebhookEventTypeCreated, value)
+	if existed {
+		event.Type = WebhookEventTypeUpdated
+		event.PreviousValue = &previous
+	}
+	w.dispatch(ctx, event)
+	return previous, existed, nil
+}
+
+func (w *WebhookRegistrar) DeleteRecord(ctx context.Context, fqdn Domain, recordType RecordType, currentValue string) error {
+	if err := w.Registrar.DeleteRecord(ctx, fqdn, recordType, currentValue); err != nil {
+		return err
+	}
+	w.dispatch(ctx, w.newEvent(ctx, fqdn, recordType, WebhookEventTypeDeleted, currentValue))
+	return nil
+}
+
+func (w *WebhookRegistrar) WriteRecords(ctx context.Context, writes []RecordWrite) ([]RecordWriteResult, error) {
+	results, err := w.Registrar.WriteRecords(ctx, writes)
+	if err != nil {
+		return results, err
+	}
+
+	events := make([]WebhookEvent, len(writes))
+	for i, write := range writes {
+		events[i] = w.newEvent(ctx, write.Domain, write.Type, WebhookEventTypeDeleted, write.Value)
+		if !write.Delete {
+			events[i].Type = WebhookEventTypeCreated
+			if results[i].Existed {
+				events[i].Type = WebhookEventTypeUpdated
+				events[i].PreviousValue = &results[i].Previous
+			}
+		}
+	}
+	w.dispatch(ctx, events...)
+	return results, nil
+}
+
+func (w *WebhookRegistrar) newEvent(ctx context.Context, fqdn Domain, recordType RecordType, eventType WebhookEventType, value string) WebhookEvent {
+	id, _ := shortid.Generate()
+	event := WebhookEvent{
+		Id:         id,
+		Type:       eventType,
+		Zone:       string(w.zones.ZoneOf(fqdn)),
+		Domain:     canonicalName(string(fqdn)),
+		RecordType: recordType,
+		Value:      &value,
+		Timestamp:  w.now().UTC(),
+	}
+	if view := viewFromContext(ctx); view != "" {
+		event.View = &view
+	}
+	if eventType == WebhookEventTypeDeleted && leaseExpiryFromContext(ctx) {
+		event.Type = WebhookEventTypeExpired
+	}
+	return event
+}
+
+// dispatch starts delivering events to every webhook registered for a zone containing their records. The webhooks are
+// only listed once, so writing many records at a time doesn't read them for every record. It doesn't wait for the
+// deliveries to finish.
+func (w *WebhookRegistrar) dispatch(ctx context.Context, events ...WebhookEvent) {
+	logger := hclog.FromContext(ctx)
+	webhooks, err := w.Registrar.ListWebhooks(ctx)
+	if err != nil {
+		logger.Error("Error listing webhooks; not delivering events", "events", len(events), "error", err)
+		return
+	}
+
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			logger.Error("Error encoding webhook event", "error", err)
+			continue
+		}
+		for _, webhook := range webhooks {
+			if isSubdomain(Domain(event.Domain), Domain(webhook.Zone)) {
+				logger.Info("Delivering webhook event", "webhook", webhook.Id, "event", event.Id, "type", event.Type)
+				deliveryCtx := withNamespace(w.ctx, namespaceFromContext(ctx))
+				go w.deliver(hclog.WithContext(deliveryCtx, logger.With("webhook", webhook.Id, "event", event.Id)), webhook, event, payload)
+			}
+		}
+	}
+}
+
+func (w *WebhookRegistrar) deliver(ctx context.Context, webhook RegisteredWebhook, event WebhookEvent, payload []byte) {
+	logger := hclog.FromContext(ctx)
+	backoff := w.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		delivery := WebhookDelivery{
+			WebhookId: webhook.Id,
+			EventId:   event.Id,
+			EventType: string(event.Type),
+			Attempt:   attempt,
+			Timestamp: w.now().UTC(),
+		}
+		statusCode, err := w.post(ctx, webhook, event, payload)
+		if statusCode != 0 {
+			delivery.StatusCode = &statusCode
+		}
+		if err != nil {
+			message := err.Error()
+			delivery.Error = &message
+		} else {
+			delivery.Success = true
+		}
+		if err := w.Registrar.LogWebhookDelivery(ctx, delivery); err != nil {
+			logger.Warn("Error logging webhook delivery", "error", err)
+		}
+
+		if delivery.Success {
+			return
+		}
+		if attempt >= w.config.MaxAttempts {
+			logger.Warn("Giving up on delivering webhook event", "attempts", attempt, "error", *delivery.Error)
+			return
+		}
+		logger.Info("Webhook delivery failed; retrying", "attempt", attempt, "backoff", backoff, "error", *delivery.Error)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one delivery attempt, returning the status code if the webhook responded.
+func (w *WebhookRegistrar) post(ctx context.Context, webhook RegisteredWebhook, event WebhookEvent, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Ephemerain-Event", string(event.Type))
+	req.Header.Set("X-Ephemerain-Delivery", event.Id)
+	req.Header.Set("X-Ephemerain-Signature", webhookSignature(webhook.Secret, payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver is a webhook endpoint that records the events it receives, failing the first failures requests.
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	failures int
+	events   []WebhookEvent
+}
+
+func newWebhookReceiver(t *testing.T, secret string, failures int) *webhookReceiver {
+	receiver := &webhookReceiver{failures: failures}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, webhookSignature(secret, payload), r.Header.Get("X-Ephemerain-Signature"))
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		if receiver.failures > 0 {
+			receiver.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event WebhookEvent
+		assert.NoError(t, json.Unmarshal(payload, &event))
+		receiver.events = append(receiver.events, event)
+	}))
+	return receiver
+}
+
+// loopback lets webhooks be delivered to receivers, which listen on the loopback interface.
+var loopback = []*net.IPNet{{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)}}
+
+func (r *webhookReceiver) received() []WebhookEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]WebhookEvent(nil), r.events...)
+}
+
+func TestWebhookRegistrar_DeliversChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	receiver := newWebhookReceiver(t, "s3cret", 0)
+	defer receiver.Close()
+
+	backend := newMemoryRegistrar()
+	assert.NoError(t, backend.CreateWebhook(ctx, RegisteredWebhook{
+		Webhook: Webhook{Id: "hook", Zone: "example.com.", Url: receiver.URL},
+		Secret:  "s3cret",
+	}))
+	registrar := NewWebhookRegistrar(ctx, backend, ZoneSet{"example.com.", "example.net."}, WebhookConfig{AllowedNetworks: loopback})
+
+	assert.NoError(t, registrar.SetRecord(ctx, "www.Example.com.", RecordTypeA, "1.1.1.1"))
+	assert.Eventually(t, func() bool { return len(receiver.received()) == 1 }, time.Second, time.Millisecond)
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.com.", RecordTypeA, "2.2.2.2"))
+	assert.Eventually(t, func() bool { return len(receiver.received()) == 2 }, time.Second, time.Millisecond)
+	assert.NoError(t, registrar.DeleteRecord(ctx, "www.example.com.", RecordTypeA, "2.2.2.2"))
+	assert.Eventually(t, func() bool { return len(receiver.received()) == 3 }, time.Second, time.Millisecond)
+
+	// Changes outside of the webhook's zone aren't delivered
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.net.", RecordTypeA, "3.3.3.3"))
+
+	events := receiver.received()
+	assert.Len(t, events, 3)
+	for _, event := range events {
+		assert.Equal(t, "www.example.com.", event.Domain)
+		assert.Equal(t, "example.com.", event.Zone)
+		assert.Equal(t, RecordTypeA, event.RecordType)
+	}
+	assert.Equal(t, WebhookEventTypeCreated, events[0].Type)
+	assert.Equal(t, "1.1.1.1", *events[0].Value)
+	assert.Nil(t, events[0].PreviousValue)
+	assert.Equal(t, WebhookEventTypeUpdated, events[1].Type)
+	assert.Equal(t, "2.2.2.2", *events[1].Value)
+	assert.Equal(t, "1.1.1.1", *events[1].PreviousValue)
+	assert.Equal(t, WebhookEventTypeDeleted, events[2].Type)
+	assert.Equal(t, "2.2.2.2", *events[2].Value)
+}
+
+// webhookListCounter counts how often the webhooks are listed.
+type webhookListCounter struct {
+	Registrar
+
+	mu    sync.Mutex
+	lists int
+}
+
+func (c *webhookListCounter) ListWebhooks(ctx context.Context) ([]RegisteredWebhook, error) {
+	c.mu.Lock()
+	c.lists++
+	c.mu.Unlock()
+	return c.Registrar.ListWebhooks(ctx)
+}
+
+func TestWebhookRegistrar_ListsWebhooksOncePerWrite(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	receiver := newWebhookReceiver(t, "s3cret", 0)
+	defer receiver.Close()
+
+	backend := &webhookListCounter{Registrar: newMemoryRegistrar()}
+	assert.NoError(t, backend.CreateWebhook(ctx, RegisteredWebhook{
+		Webhook: Webhook{Id: "hook", Zone: "example.com.", Url: receiver.URL},
+		Secret:  "s3cret",
+	}))
+	registrar := NewWebhookRegistrar(ctx, backend, ZoneSet{"example.com."}, WebhookConfig{AllowedNetworks: loopback})
+
+	_, err := registrar.WriteRecords(ctx, []RecordWrite{
+		{Domain: "a.example.com.", Type: RecordTypeA, Value: "1.1.1.1"},
+		{Domain: "b.example.com.", Type: RecordTypeA, Value: "2.2.2.2"},
+		{Domain: "c.example.com.", Type: RecordTypeTXT, Value: "three"},
+	})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(receiver.received()) == 3 }, time.Second, time.Millisecond)
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	assert.Equal(t, 1, backend.lists)
+}
+
+func TestWebhookRegistrar_RetriesWithBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	receiver := newWebhookReceiver(t, "s3cret", 2)
+	defer receiver.Close()
+
+	backend := newMemoryRegistrar()
+	assert.NoError(t, backend.CreateWebhook(ctx, RegisteredWebhook{
+		Webhook: Webhook{Id: "hook", Zone: "example.com.", Url: receiver.URL},
+		Secret:  "s3cret",
+	}))
+	registrar := NewWebhookRegistrar(ctx, backend, nil, WebhookConfig{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond, AllowedNetworks: loopback})
+
+	assert.NoError(t, registrar.SetRecord(ctx, "retry.example.com.", RecordTypeTXT, "hello"))
+	var deliveries []WebhookDelivery
+	assert.Eventually(t, func() bool {
+		deliveries, _ = backend.ListWebhookDeliveries(ctx, "hook")
+		return len(deliveries) == 3 && deliveries[0].Success
+	}, time.Second, time.Millisecond)
+	assert.Len(t, receiver.received(), 1)
+
+	if assert.Len(t, deliveries, 3) {
+		// The log is newest first
+		assert.Equal(t, 3, deliveries[0].Attempt)
+		assert.True(t, deliveries[0].Success)
+		for _, delivery := range deliveries[1:] {
+			assert.False(t, delivery.Success)
+			assert.Equal(t, http.StatusServiceUnavailable, *delivery.StatusCode)
+			assert.NotNil(t, delivery.Error)
+		}
+		// The second retry waits twice as long as the first
+		assert.GreaterOrEqual(t, deliveries[0].Timestamp.Sub(deliveries[1].Timestamp), 20*time.Millisecond)
+		assert.GreaterOrEqual(t, deliveries[1].Timestamp.Sub(deliveries[2].Timestamp), 10*time.Millisecond)
+	}
+}
+
+func TestWebhookRegistrar_RefusesInternalAddresses(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	receiver := newWebhookReceiver(t, "s3cret", 0)
+	defer receiver.Close()
+	_, port, err := net.SplitHostPort(receiver.Listener.Addr().String())
+	assert.NoError(t, err)
+
+	backend := newMemoryRegistrar()
+	urls := map[string]string{
+		"loopback": receiver.URL,
+		// Hosts are checked once they have been resolved
+		"localhost": "http://localhost:" + port,
+		"metadata":  "http://169.254.169.254/latest/meta-data/",
+		"private":   "http://10.0.0.1/",
+	}
+	for id, url := range urls {
+		assert.NoError(t, backend.CreateWebhook(ctx, RegisteredWebhook{Webhook: Webhook{Id: id, Zone: "example.com.", Url: url}, Secret: "s3cret"}))
+	}
+	registrar := NewWebhookRegistrar(ctx, backend, nil, WebhookConfig{MaxAttempts: 1})
+
+	assert.NoError(t, registrar.SetRecord(ctx, "internal.example.com.", RecordTypeTXT, "hello"))
+	for id := range urls {
+		var deliveries []WebhookDelivery
+		assert.Eventually(t, func() bool {
+			deliveries, _ = backend.ListWebhookDeliveries(ctx, id)
+			return len(deliveries) == 1
+		}, time.Second, time.Millisecond, id)
+		if assert.Len(t, deliveries, 1, id) {
+			assert.False(t, deliveries[0].Success, id)
+			assert.Nil(t, deliveries[0].StatusCode, id)
+			assert.Contains(t, *deliveries[0].Error, "internal address", id)
+		}
+	}
+	assert.Empty(t, receiver.received())
+
+	assert.True(t, isInternalAddress(net.ParseIP("172.16.0.1")))
+	assert.True(t, isInternalAddress(net.ParseIP("fd00::1")))
+	assert.True(t, isInternalAddress(net.ParseIP("fe80::1")))
+	assert.True(t, isInternalAddress(net.ParseIP("::1")))
+	assert.True(t, isInternalAddress(net.ParseIP("0.0.0.0")))
+	assert.False(t, isInternalAddress(net.ParseIP("93.184.216.34")))
+	assert.False(t, isInternalAddress(net.ParseIP("2606:2800:220:1::1")))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// zoneFileContentType is the media type zone files are exported as.
+const zoneFileContentType = "text/dns"
+
+// exportZone returns every record at or beneath zone in the view in ctx, along with the SOA and NS records generated for
+// the zone apex. Records in the default view are included unless the view has its own record with the same name and
+// type, the same as for DNS queries.
+func exportZone(ctx context.Context, registrar Registrar, zone Domain) ([]ZoneExportRecord, error) {
+	stored, err := registrar.ListRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	view := viewFromContext(ctx)
+	byKey := map[string]StoredRecord{}
+	for _, record := range stored {
+		// NS records at the apex are generated, so any stored ones aren't what DNS queries see
+		if record.Domain == zone && record.Type == RecordTypeNS {
+			continue
+		}
+		key := string(record.Domain) + " " + string(record.Type)
+		if _, ok := byKey[key]; record.View == view || (record.View == "" && !ok) {
+			byKey[key] = record
+		}
+	}
+	records := make([]StoredRecord, 0, len(byKey))
+	for _, record := range byKey {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if (records[i].Domain == zone) != (records[j].Domain == zone) {
+			return records[i].Domain == zone
+		}
+		if records[i].Domain != records[j].Domain {
+			return records[i].Domain < records[j].Domain
+		}
+		return records[i].Type < records[j].Type
+	})
+
+	soa, ns := soaRecord(zone), nsRecord(zone)
+	exported := []ZoneExportRecord{
+		{Domain: string(zone), Type: "SOA", Ttl: soa.Hdr.Ttl, Values: []string{recordValue(soa)}},
+		{Domain: string(zone), Type: "NS", Ttl: ns.Hdr.Ttl, Values: []string{recordValue(ns)}},
+	}
+	for _, record := range records {
+		set := parseRecordSet(record.Value)
+		exported = append(exported, ZoneExportRecord{Domain: string(record.Domain), Type: string(record.Type), Ttl: set.TTL, Values: set.Values})
+	}
+	return exported, nil
+}
+
+// writeZoneFile writes records as an RFC 1035 zone file. Every name is fully qualified, so the file means the same thing
+// wherever it's imported. Records whose stored value isn't valid for their type are written as comments, since they
+// can't be served either.
+func writeZoneFile(w io.Writer, zone Domain, records []ZoneExportRecord) error {
+	if _, err := fmt.Fprintf(w, "$ORIGIN %s\n", zone); err != nil {
+		return err
+	}
+	for _, record := range records {
+		set := RecordSet{TTL: record.Ttl, Values: record.Values}
+		rrs, err := set.resourceRecords(Domain(record.Domain), RecordType(record.Type))
+		if err != nil {
+			if _, err := fmt.Fprintf(w, "; %s %s: %s\n", record.Domain, record.Type, err); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, rr := range rrs {
+			if _, err := fmt.Fprintln(w, rr.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
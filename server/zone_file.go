@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/miekg/dns"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// zoneFileEntry is a record or directive from a zone file, with any lines continued by parentheses joined together and
+// comments removed.
+type zoneFileEntry struct {
+	line int
+	text string
+}
+
+// splitZoneFile splits a zone file into entries, keeping track of the line each one starts on.
+func splitZoneFile(src io.Reader) ([]zoneFileEntry, error) {
+	raw, err := ioutil.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []zoneFileEntry
+	var text strings.Builder
+	line, start := 1, 1
+	quoted, escaped, comment, parens := false, false, false, 0
+	for _, c := range string(raw) {
+		switch {
+		case c == '\n':
+			comment = false
+			escaped = false
+			line++
+			if parens > 0 {
+				text.WriteRune(' ')
+				continue
+			}
+			if strings.TrimSpace(text.String()) != "" {
+				entries = append(entries, zoneFileEntry{line: start, text: text.String()})
+			}
+			text.Reset()
+			quoted = false
+			start = line
+			continue
+		case comment:
+			continue
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == ';':
+			comment = true
+			continue
+		case c == '(':
+			parens++
+		case c == ')':
+			parens--
+		}
+		if text.Len() == 0 && strings.TrimSpace(string(c)) != "" {
+			start = line
+		}
+		text.WriteRune(c)
+	}
+	// An unterminated entry at the end of the file is kept so that parsing it reports the problem
+	if strings.TrimSpace(text.String()) != "" {
+		entries = append(entries, zoneFileEntry{line: start, text: text.String()})
+	}
+	return entries, nil
+}
+
+// zoneFileRecord is a record parsed from a zone file, or the reason a line of the file couldn't be parsed.
+type zoneFileRecord struct {
+	line int
+	rr   dns.RR
+	err  error
+}
+
+// parseZoneFile parses every record in a zone file. $ORIGIN and $TTL directives apply to the records after them, and
+// records without a TTL or a $TTL directive before them get the TTL of the record before them, like RFC 1035 says.
+// $INCLUDE directives aren't allowed, since the files they refer to would be read from the server.
+//
+// Each entry is parsed on its own so that every record can be attributed to the line it came from. Entries that can't
+// be parsed are returned with an error rather than ending parsing, so that every problem in a file can be reported at
+// once.
+func parseZoneFile(src io.Reader) ([]zoneFileRecord, error) {
+	entries, err := splitZoneFile(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []zoneFileRecord
+	var origin, ttlDirective, owner string
+	ttl := defaultRecordTTL
+	for _, entry := range entries {
+		fields := strings.Fields(entry.text)
+		switch strings.ToUpper(fields[0]) {
+		case "$ORIGIN":
+			if len(fields) != 2 || !dns.IsFqdn(fields[1]) {
+				records = append(records, zoneFileRecord{line: entry.line, err: fmt.Errorf("$ORIGIN must be a fully qualified domain name")})
+				continue
+			}
+			origin = fields[1]
+			continue
+		case "$TTL":
+			if _, err := parseZoneEntry(entry.text, origin, ttl); err != nil {
+				records = append(records, zoneFileRecord{line: entry.line, err: err})
+				continue
+			}
+			ttlDirective = entry.text
+			continue
+		case "$INCLUDE":
+			records = append(records, zoneFileRecord{line: entry.line, err: fmt.Errorf("$INCLUDE isn't supported")})
+			continue
+		}
+
+		// Records that start with a space belong to the same name as the record before them
+		text := entry.text
+		if text[0] == ' ' || text[0] == '\t' {
+			if owner == "" {
+				records = append(records, zoneFileRecord{line: entry.line, err: fmt.Errorf("no previous owner name")})
+				continue
+			}
+			text = owner + text
+		}
+		if ttlDirective != "" {
+			text = ttlDirective + "\n" + text
+		}
+
+		rr, err := parseZoneEntry(text, origin, ttl)
+		if err == nil && rr == nil {
+			err = fmt.Errorf("no record found")
+		}
+		if err != nil {
+			records = append(records, zoneFileRecord{line: entry.line, err: err})
+			continue
+		}
+		owner = rr.Header().Name
+		ttl = rr.Header().Ttl
+		records = append(records, zoneFileRecord{line: entry.line, rr: rr})
+	}
+	return records, nil
+}
+
+// parseZoneEntry parses text as a zone file containing at most one record.
+func parseZoneEntry(text, origin string, defaultTTL uint32) (dns.RR, error) {
+	parser := dns.NewZoneParser(strings.NewReader(text+"\n"), origin, "")
+	parser.SetDefaultTTL(defaultTTL)
+	parser.SetIncludeAllowed(false)
+	rr, _ := parser.Next()
+	if err := parser.Err(); err != nil {
+		// The position in the error is relative to text rather than the zone file, so it's left out
+		message := strings.TrimPrefix(err.Error(), "dns: ")
+		if i := strings.LastIndex(message, " at line: "); i >= 0 {
+			message = message[:i]
+		}
+		return nil, errors.New(message)
+	}
+	return rr, nil
+}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
@@ -69,9 +74,13 @@ func TestMatchesBind(t *testing.T) {
 		zoneFile, err := os.Open("test_data/zonefile")
 		assert.NoError(t, err)
 		defer zoneFile.Close()
-		response, err := apiClient.PostZoneWithBody(ctx, "text/plain", zoneFile)
+		response, err := apiClient.PostZoneWithBody(ctx, &PostZoneParams{}, "text/plain", zoneFile)
 		assert.NoError(t, err)
-		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		var report ZoneImportReport
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&report))
+		assert.NotEmpty(t, report.Created)
+		assert.Empty(t, report.Failed)
 
 		err = withBind9TestServer(ctx, func(bindResolver *net.Resolver) {
 			// TODO: Once the dns query handler implements RFC1034, this test should systematically compare
@@ -90,8 +99,375 @@ func TestMatchesBind(t *testing.T) {
 
 func TestInvalidZoneFile_400s(t *testing.T) {
 	runIntegrationTest(t, func(ctx context.Context, c *Client, resolver *net.Resolver, nameserver string) {
-		body, err := c.PostZoneWithBody(ctx, "text/plain", strings.NewReader("not\nso\nvalid"))
+		body, err := c.PostZoneWithBody(ctx, &PostZoneParams{}, "text/plain", strings.NewReader("not\nso\nvalid"))
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusBadRequest, body.StatusCode)
+		var report ZoneImportReport
+		assert.NoError(t, json.NewDecoder(body.Body).Decode(&report))
+		if assert.Len(t, report.Failed, 3) {
+			assert.Equal(t, 2, report.Failed[1].Line)
+		}
+	})
+}
+
+func TestZoneImport_DryRun(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, c *Client, resolver *net.Resolver, nameserver string) {
+		dryRun := true
+		zone := "$ORIGIN dryrun.com.\n$TTL 300\nwww A 1.2.3.4\n@ MX 10 mail\n"
+		response, err := c.PostZoneWithBody(ctx, &PostZoneParams{DryRun: &dryRun}, "text/plain", strings.NewReader(zone))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var report ZoneImportReport
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&report))
+		assert.True(t, report.DryRun)
+		assert.Len(t, report.Created, 2)
+
+		_, err = resolver.LookupHost(ctx, "www.dryrun.com")
+		assert.Error(t, err)
+
+		response, err = c.PostZoneWithBody(ctx, &PostZoneParams{}, "text/plain", strings.NewReader(zone))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+
+		mxs, err := resolver.LookupMX(ctx, "dryrun.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []*net.MX{{Host: "mail.dryrun.com.", Pref: 10}}, mxs)
+	})
+}
+
+func TestParseZoneFile(t *testing.T) {
+	zone := `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1 hostmaster (
+		2021110101 ; serial
+		7200 3600 1209600 300 )
+www		A	1.1.1.1 ; comment
+	300	TXT	"semi;colon" "two \"quoted\""
+mail.example.com. 120 IN MX 10 mx
+$INCLUDE other.zone
+bad		A	not-an-ip
+`
+	records, err := parseZoneFile(strings.NewReader(zone))
+	assert.NoError(t, err)
+	if !assert.Len(t, records, 6) {
+		return
+	}
+
+	assert.Equal(t, 3, records[0].line)
+	assert.Equal(t, "example.com.", records[0].rr.Header().Name)
+	assert.Equal(t, uint32(300), records[0].rr.(*dns.SOA).Minttl)
+
+	assert.Equal(t, 6, records[1].line)
+	assert.Equal(t, "www.example.com.", records[1].rr.Header().Name)
+	assert.Equal(t, uint32(3600), records[1].rr.Header().Ttl)
+
+	// A record without an owner name belongs to the name before it
+	assert.Equal(t, 7, records[2].line)
+	assert.Equal(t, "www.example.com.", records[2].rr.Header().Name)
+	assert.Equal(t, uint32(300), records[2].rr.Header().Ttl)
+	// TXT values are their text, without the escapes they have in the zone file
+	assert.Equal(t, `semi;colontwo "quoted"`, recordValue(records[2].rr))
+
+	assert.Equal(t, 8, records[3].line)
+	assert.Equal(t, "10 mx.example.com.", recordValue(records[3].rr))
+
+	assert.Equal(t, 9, records[4].line)
+	assert.EqualError(t, records[4].err, "$INCLUDE isn't supported")
+
+	assert.Equal(t, 10, records[5].line)
+	assert.Error(t, records[5].err)
+}
+
+func TestParseZoneFile_InheritsPreviousTTL(t *testing.T) {
+	records, err := parseZoneFile(strings.NewReader("a.example.com. A 1.1.1.1\nb.example.com. 300 A 2.2.2.2\nc.example.com. A 3.3.3.3\n"))
+	assert.NoError(t, err)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, defaultRecordTTL, records[0].rr.Header().Ttl)
+		assert.Equal(t, uint32(300), records[1].rr.Header().Ttl)
+		assert.Equal(t, uint32(300), records[2].rr.Header().Ttl)
+	}
+}
+
+func TestImportZone(t *testing.T) {
+	ctx := context.Background()
+	registrar := newMemoryRegistrar()
+	assert.NoError(t, registrar.SetRecord(ctx, "www.zonetransfer.me.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, registrar.SetRecord(ctx, "home.zonetransfer.me.", RecordTypeA, RecordSet{TTL: 7200, Values: []string{"127.0.0.1"}}.String()))
+
+	var claims [][]RecordReference
+	importTestZone := func(dryRun bool) ZoneImportReport {
+		zoneFile, err := os.Open("test_data/zonefile")
+		assert.NoError(t, err)
+		defer zoneFile.Close()
+		records, err := parseZoneFile(zoneFile)
+		assert.NoError(t, err)
+		assert.Empty(t, zoneFileErrors(records))
+
+		report := newZoneImportReport(dryRun)
+		sets := planZoneImport(records, nil, &report)
+		assert.NoError(t, importZone(ctx, registrar, sets, "", &report, func(records []RecordReference) error {
+			claims = append(claims, records)
+			return nil
+		}))
+		return report
+	}
+
+	report := importTestZone(true)
+	assert.Len(t, report.Created, 44)
+	if assert.Len(t, report.Updated, 1) {
+		assert.Equal(t, "www.zonetransfer.me.", *report.Updated[0].Domain)
+		assert.Equal(t, 51, report.Updated[0].Line)
+	}
+	// The SOA records, the NS records at the apex and the record that is already up to date
+	assert.Len(t, report.Skipped, 5)
+	assert.Empty(t, report.Failed)
+	_, err := registrar.GetRecord(ctx, "zonetransfer.me.", RecordTypeMX)
+	assert.ErrorIs(t, err, ErrRecordNotFound, "dry runs don't change anything")
+	assert.Empty(t, claims)
+
+	report = importTestZone(false)
+	assert.Len(t, report.Created, 44)
+	assert.Len(t, report.Updated, 1)
+	// Every record that is written is claimed at once
+	if assert.Len(t, claims, 1) {
+		assert.Contains(t, claims[0], RecordReference{Domain: "zonetransfer.me.", Type: RecordTypeMX})
+		assert.Contains(t, claims[0], RecordReference{Domain: "www.zonetransfer.me.", Type: RecordTypeA})
+	}
+
+	mx, err := registrar.GetRecord(ctx, "zonetransfer.me.", RecordTypeMX)
+	assert.NoError(t, err)
+	set := parseRecordSet(mx)
+	assert.Equal(t, uint32(7200), set.TTL)
+	assert.Len(t, set.Values, 7)
+	assert.Equal(t, "0 ASPMX.L.GOOGLE.COM.", set.Values[0])
+
+	// Importing the same zone again doesn't change anything
+	report = importTestZone(false)
+	assert.Empty(t, report.Created)
+	assert.Empty(t, report.Updated)
+	assert.Len(t, report.Skipped, 50)
+	assert.Len(t, claims, 1)
+}
+
+func TestImportZone_Replace(t *testing.T) {
+	ctx := context.Background()
+	registrar := newMemoryRegistrar()
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, registrar.SetRecord(ctx, "old.example.com.", RecordTypeA, "2.2.2.2"))
+	assert.NoError(t, registrar.SetRecord(withView(ctx, "internal"), "old.example.com.", RecordTypeA, "10.0.0.1"))
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.org.", RecordTypeA, "3.3.3.3"))
+
+	replaceZone := func(zone string, dryRun bool) ZoneImportReport {
+		records, err := parseZoneFile(strings.NewReader(zone))
+		assert.NoError(t, err)
+		assert.Empty(t, zoneFileErrors(records))
+		assert.Empty(t, zoneScopeErrors(records, "example.com."))
+
+		report := newZoneImportReport(dryRun)
+		sets := planZoneImport(records, ZoneSet{"example.com."}, &report)
+		assert.NoError(t, importZone(ctx, registrar, sets, "example.com.", &report, func([]RecordReference) error { return nil }))
+		return report
+	}
+
+	zone := "$ORIGIN example.com.\nwww A 4.4.4.4\nmail A 5.5.5.5\n"
+	report := replaceZone(zone, true)
+	assert.Len(t, report.Created, 1)
+	assert.Len(t, report.Updated, 1)
+	if assert.NotNil(t, report.Deleted) {
+		assert.Equal(t, []RecordReference{{Domain: "old.example.com.", Type: RecordTypeA}}, *report.Deleted)
+	}
+	value, err := registrar.GetRecord(ctx, "old.example.com.", RecordTypeA)
+	assert.NoError(t, err, "dry runs don't change anything")
+	assert.Equal(t, "2.2.2.2", value)
+
+	replaceZone(zone, false)
+	_, err = registrar.GetRecord(ctx, "old.example.com.", RecordTypeA)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+	value, err = registrar.GetRecord(ctx, "www.example.com.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "4.4.4.4", value)
+
+	// Records in other views and other zones are left alone
+	value, err = registrar.GetRecord(withView(ctx, "internal"), "old.example.com.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", value)
+	value, err = registrar.GetRecord(ctx, "www.example.org.", RecordTypeA)
+	assert.NoError(t, err)
+	assert.Equal(t, "3.3.3.3", value)
+
+	records, err := parseZoneFile(strings.NewReader("www.example.com. A 1.1.1.1\nwww.example.org. A 2.2.2.2\n"))
+	assert.NoError(t, err)
+	if failed := zoneScopeErrors(records, "example.com."); assert.Len(t, failed, 1) {
+		assert.Equal(t, 2, failed[0].Line)
+		assert.Equal(t, "outside of zone example.com.", *failed[0].Reason)
+	}
+}
+
+func TestReplaceZone(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, c *Client, resolver *net.Resolver, nameserver string) {
+		response, err := c.PostZoneWithBody(ctx, &PostZoneParams{}, "text/plain", strings.NewReader("$ORIGIN replace.com.\nwww A 1.1.1.1\nold A 2.2.2.2\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+
+		response, err = c.ImportZoneRecordsWithBody(ctx, "replace.com", &ImportZoneRecordsParams{}, "text/plain", strings.NewReader("$ORIGIN replace.com.\nmail A 3.3.3.3\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		hosts, err := resolver.LookupHost(ctx, "old.replace.com")
+		assert.NoError(t, err, "importing records merges them into the zone")
+		assert.Equal(t, []string{"2.2.2.2"}, hosts)
+
+		response, err = c.ReplaceZoneWithBody(ctx, "replace.com", &ReplaceZoneParams{}, "text/plain", strings.NewReader("$ORIGIN replace.com.\nwww A 4.4.4.4\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var report ZoneImportReport
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&report))
+		assert.Len(t, report.Updated, 1)
+		if assert.NotNil(t, report.Deleted) {
+			assert.Len(t, *report.Deleted, 2)
+		}
+
+		hosts, err = resolver.LookupHost(ctx, "www.replace.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"4.4.4.4"}, hosts)
+		for _, domain := range []string{"old.replace.com", "mail.replace.com"} {
+			_, err = resolver.LookupHost(ctx, domain)
+			assert.Error(t, err, "%s should have been deleted", domain)
+		}
+
+		response, err = c.ReplaceZoneWithBody(ctx, "replace.com", &ReplaceZoneParams{}, "text/plain", strings.NewReader("www.other.com. A 5.5.5.5\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+}
+
+func TestZoneImport_403_IfQuotaExceeded(t *testing.T) {
+	config := EphemerainConfig{RecordQuotas: RecordQuotaConfig{RecordsPerZone: 3}}
+	runIntegrationTestWithConfig(t, config, func(ctx context.Context, c *Client, resolver *net.Resolver, nameserver string) {
+		// Every record of a zone file is claimed at once, so a file with more records than the quota imports nothing
+		response, err := c.PostZoneWithBody(ctx, &PostZoneParams{}, "text/plain", strings.NewReader("$ORIGIN quota.com.\na A 1.1.1.1\nb A 1.1.1.1\nc A 1.1.1.1\nd A 1.1.1.1\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		_, err = resolver.LookupHost(ctx, "a.quota.com")
+		assert.Error(t, err)
+
+		response, err = c.PostZoneWithBody(ctx, &PostZoneParams{}, "text/plain", strings.NewReader("$ORIGIN quota.com.\na A 1.1.1.1\nb A 1.1.1.1\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		response, err = c.ImportZoneRecordsWithBody(ctx, "quota.com", &ImportZoneRecordsParams{}, "text/plain", strings.NewReader("$ORIGIN quota.com.\nb A 2.2.2.2\nc A 1.1.1.1\nd A 1.1.1.1\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		hosts, err := resolver.LookupHost(ctx, "b.quota.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1.1.1.1"}, hosts)
+
+		// Records that are already claimed don't count again
+		response, err = c.ImportZoneRecordsWithBody(ctx, "quota.com", &ImportZoneRecordsParams{}, "text/plain", strings.NewReader("$ORIGIN quota.com.\nb A 2.2.2.2\nc A 1.1.1.1\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+	})
+}
+
+func TestExportZone_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	importZoneFile := func(registrar Registrar, src io.Reader) ZoneImportReport {
+		records, err := parseZoneFile(src)
+		assert.NoError(t, err)
+		assert.Empty(t, zoneFileErrors(records))
+
+		report := newZoneImportReport(false)
+		sets := planZoneImport(records, ZoneSet{"zonetransfer.me."}, &report)
+		assert.NoError(t, importZone(ctx, registrar, sets, "", &report, func([]RecordReference) error { return nil }))
+		assert.Empty(t, report.Failed)
+		return report
+	}
+	exportZoneFile := func(registrar Registrar) string {
+		records, err := exportZone(ctx, registrar, "zonetransfer.me.")
+		assert.NoError(t, err)
+		var zoneFile strings.Builder
+		assert.NoError(t, writeZoneFile(&zoneFile, "zonetransfer.me.", records))
+		return zoneFile.String()
+	}
+
+	original := newMemoryRegistrar()
+	zoneFile, err := os.Open("test_data/zonefile")
+	assert.NoError(t, err)
+	defer zoneFile.Close()
+	importZoneFile(original, zoneFile)
+	exported := exportZoneFile(original)
+	assert.True(t, strings.HasPrefix(exported, "$ORIGIN zonetransfer.me.\nzonetransfer.me.\t60\tIN\tSOA\t"), exported)
+
+	// Importing the export changes nothing, and the only records it skips are the generated ones
+	report := importZoneFile(original, strings.NewReader(exported))
+	assert.Empty(t, report.Created)
+	assert.Empty(t, report.Updated)
+	for _, skipped := range report.Skipped {
+		if *skipped.Type != "SOA" && *skipped.Type != "NS" {
+			assert.Equal(t, "unchanged", *skipped.Reason)
+		}
+	}
+
+	copied := newMemoryRegistrar()
+	importZoneFile(copied, strings.NewReader(exported))
+	originalRecords, err := original.ListRecords(ctx, "zonetransfer.me.")
+	assert.NoError(t, err)
+	copiedRecords, err := copied.ListRecords(ctx, "zonetransfer.me.")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, originalRecords, copiedRecords)
+	assert.Equal(t, exported, exportZoneFile(copied))
+}
+
+func TestExportZone_View(t *testing.T) {
+	ctx := context.Background()
+	registrar := newMemoryRegistrar()
+	assert.NoError(t, registrar.SetRecord(ctx, "www.example.com.", RecordTypeA, "1.1.1.1"))
+	assert.NoError(t, registrar.SetRecord(ctx, "mail.example.com.", RecordTypeA, "2.2.2.2"))
+	assert.NoError(t, registrar.SetRecord(withView(ctx, "internal"), "www.example.com.", RecordTypeA, "10.0.0.1"))
+	assert.NoError(t, registrar.SetRecord(ctx, "example.com.", RecordTypeNS, "ns.elsewhere.com."))
+
+	records, err := exportZone(withView(ctx, "internal"), registrar, "example.com.")
+	assert.NoError(t, err)
+	assert.Equal(t, []ZoneExportRecord{
+		{Domain: "example.com.", Type: "SOA", Ttl: 60, Values: []string{recordValue(soaRecord("example.com."))}},
+		{Domain: "example.com.", Type: "NS", Ttl: 60, Values: []string{"ns1.bam0.com."}},
+		{Domain: "mail.example.com.", Type: "A", Ttl: 60, Values: []string{"2.2.2.2"}},
+		{Domain: "www.example.com.", Type: "A", Ttl: 60, Values: []string{"10.0.0.1"}},
+	}, records)
+}
+
+func TestExportZone(t *testing.T) {
+	runIntegrationTest(t, func(ctx context.Context, c *Client, resolver *net.Resolver, nameserver string) {
+		zoneFile, err := os.Open("test_data/zonefile")
+		assert.NoError(t, err)
+		defer zoneFile.Close()
+		response, err := c.PostZoneWithBody(ctx, &PostZoneParams{}, "text/plain", zoneFile)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+
+		response, err = c.ExportZone(ctx, "zonetransfer.me", &ExportZoneParams{}, func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("Accept", "text/dns")
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "text/dns", response.Header.Get("Content-Type"))
+		exported, err := ioutil.ReadAll(response.Body)
+		assert.NoError(t, err)
+
+		response, err = c.PostZoneWithBody(ctx, &PostZoneParams{}, "text/plain", bytes.NewReader(exported))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.StatusCode)
+		var report ZoneImportReport
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&report))
+		assert.Empty(t, report.Created)
+		assert.Empty(t, report.Updated)
+		assert.Empty(t, report.Failed)
+
+		response, err = c.ExportZone(ctx, "zonetransfer.me", &ExportZoneParams{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		var export ZoneExport
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&export))
+		assert.Equal(t, "zonetransfer.me.", export.Zone)
+		assert.Equal(t, "SOA", export.Records[0].Type)
 	})
 }
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/miekg/dns"
+	"sort"
+)
+
+// zoneImportSet is a record to import from a zone file, along with the records in the file it's made up of.
+type zoneImportSet struct {
+	domain     Domain
+	recordType RecordType
+	set        RecordSet
+	records    []ZoneImportRecord
+}
+
+func newZoneImportReport(dryRun bool) ZoneImportReport {
+	return ZoneImportReport{
+		DryRun:  dryRun,
+		Created: []ZoneImportRecord{},
+		Updated: []ZoneImportRecord{},
+		Skipped: []ZoneImportRecord{},
+		Failed:  []ZoneImportRecord{},
+	}
+}
+
+func newZoneImportRecord(record zoneFileRecord) ZoneImportRecord {
+	entry := ZoneImportRecord{Line: record.line}
+	if record.rr != nil {
+		header := record.rr.Header()
+		domain := canonicalName(header.Name)
+		recordType := dns.TypeToString[header.Rrtype]
+		value := recordValue(record.rr)
+		entry.Domain = &domain
+		entry.Type = &recordType
+		entry.Ttl = &header.Ttl
+		entry.Value = &value
+	}
+	if record.err != nil {
+		reason := record.err.Error()
+		entry.Reason = &reason
+	}
+	return entry
+}
+
+func withReason(records []ZoneImportRecord, reason string) []ZoneImportRecord {
+	withReason := make([]ZoneImportRecord, len(records))
+	for i, record := range records {
+		record.Reason = &reason
+		withReason[i] = record
+	}
+	return withReason
+}
+
+// zoneFileErrors returns the entries of a zone file that couldn't be parsed.
+func zoneFileErrors(records []zoneFileRecord) []ZoneImportRecord {
+	var failed []ZoneImportRecord
+	for _, record := range records {
+		if record.err != nil {
+			failed = append(failed, newZoneImportRecord(record))
+		}
+	}
+	return failed
+}
+
+// planZoneImport groups the records parsed from a zone file into the records to import, which have every value with
+// the same name and type. Records that can't be imported are added to report as skipped or failed instead.
+func planZoneImport(records []zoneFileRecord, zones ZoneSet, report *ZoneImportReport) []zoneImportSet {
+	var sets []zoneImportSet
+	index := map[string]int{}
+	for _, record := range records {
+		entry := newZoneImportRecord(record)
+		if record.err != nil {
+			report.Failed = append(report.Failed, entry)
+			continue
+		}
+
+		header := record.rr.Header()
+		domain := Domain(canonicalName(header.Name))
+		recordType := RecordType(dns.TypeToString[header.Rrtype])
+		switch {
+		case header.Class != dns.ClassINET:
+			report.Failed = append(report.Failed, withReason([]ZoneImportRecord{entry}, "only IN class records are supported")...)
+			continue
+		case header.Rrtype == dns.TypeSOA:
+			report.Skipped = append(report.Skipped, withReason([]ZoneImportRecord{entry}, "SOA records are generated by the server")...)
+			continue
+		case header.Rrtype == dns.TypeNS && domain == zones.ZoneOf(domain):
+			report.Skipped = append(report.Skipped, withReason([]ZoneImportRecord{entry}, "NS records at the zone apex are generated by the server")...)
+			continue
+		case !isStoredRecordType(recordType):
+			report.Failed = append(report.Failed, withReason([]ZoneImportRecord{entry}, fmt.Sprintf("%s records aren't supported", recordType))...)
+			continue
+		}
+
+		key := string(domain) + " " + string(recordType)
+		i, ok := index[key]
+		if !ok {
+			i = len(sets)
+			index[key] = i
+			sets = append(sets, zoneImportSet{domain: domain, recordType: recordType, set: RecordSet{TTL: header.Ttl}})
+		}
+		set := &sets[i]
+		value := recordValue(record.rr)
+		if containsString(set.set.Values, value) {
+			report.Skipped = append(report.Skipped, withReason([]ZoneImportRecord{entry}, "duplicate record")...)
+			continue
+		}
+		// Records with the same name and type are supposed to have the same TTL. RFC 2181 says to use the lowest one
+		// when they don't.
+		if header.Ttl < set.set.TTL {
+			set.set.TTL = header.Ttl
+		}
+		set.set.Values = append(set.set.Values, value)
+		set.records = append(set.records, entry)
+	}
+	return sets
+}
+
+// importZone imports records into the registrar with a single WriteRecords call, adding each of the zone file records
+// they're made of to report. If replace isn't empty, every other record in the default view at or beneath it is deleted
+// by the same call. Nothing is changed if the report is for a dry run. claim is called once with every record that is
+// created or updated before anything is written, and nothing is imported if it returns an error.
+func importZone(ctx context.Context, registrar Registrar, sets []zoneImportSet, replace Domain, report *ZoneImportReport, claim func([]RecordReference) error) error {
+	var writes []RecordWrite
+	var claimed []RecordReference
+	importing := map[string]bool{}
+	for _, set := range sets {
+		importing[string(set.domain)+" "+string(set.recordType)] = true
+
+		var outcome *[]ZoneImportRecord
+		existing, err := registrar.GetRecord(ctx, set.domain, set.recordType)
+		switch {
+		case errors.Is(err, ErrRecordNotFound):
+			outcome = &report.Created
+		case err != nil:
+			report.Failed = append(report.Failed, withReason(set.records, err.Error())...)
+			continue
+		case existing == set.set.String():
+			report.Skipped = append(report.Skipped, withReason(set.records, "unchanged")...)
+			continue
+		default:
+			outcome = &report.Updated
+		}
+
+		writes = append(writes, RecordWrite{Domain: set.domain, Type: set.recordType, Value: set.set.String()})
+		claimed = append(claimed, RecordReference{Domain: string(set.domain), Type: set.recordType})
+		*outcome = append(*outcome, set.records...)
+	}
+
+	if replace != "" {
+		existing, err := registrar.ListRecords(ctx, replace)
+		if err != nil {
+			return err
+		}
+		sort.Slice(existing, func(i, j int) bool {
+			if existing[i].Domain != existing[j].Domain {
+				return existing[i].Domain < existing[j].Domain
+			}
+			return existing[i].Type < existing[j].Type
+		})
+		deleted := []RecordReference{}
+		for _, record := range existing {
+			if record.View != "" || importing[string(record.Domain)+" "+string(record.Type)] {
+				continue
+			}
+			writes = append(writes, RecordWrite{Domain: record.Domain, Type: record.Type, Value: record.Value, Delete: true})
+			deleted = append(deleted, RecordReference{Domain: string(record.Domain), Type: record.Type})
+		}
+		report.Deleted = &deleted
+	}
+
+	if report.DryRun || len(writes) == 0 {
+		return nil
+	}
+	if len(claimed) > 0 {
+		if err := claim(claimed); err != nil {
+			return err
+		}
+	}
+	_, err := registrar.WriteRecords(ctx, writes)
+	return err
+}
+
+// zoneScopeErrors returns the records of a zone file that aren't at or beneath zone.
+func zoneScopeErrors(records []zoneFileRecord, zone Domain) []ZoneImportRecord {
+	var failed []ZoneImportRecord
+	for _, record := range records {
+		if record.rr != nil && !isSubdomain(Domain(record.rr.Header().Name), zone) {
+			failed = append(failed, withReason([]ZoneImportRecord{newZoneImportRecord(record)}, fmt.Sprintf("outside of zone %s", zone))...)
+		}
+	}
+	return failed
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
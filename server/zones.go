@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+)
+
+// ZoneSet is the list of zones the server is authoritative for.
+type ZoneSet []Domain
+
+// ParseZoneSet parses a comma separated list of zone names, such as the ZONES environment variable.
+func ParseZoneSet(raw string) ZoneSet {
+	var zones ZoneSet
+	for _, zone := range strings.Split(raw, ",") {
+		zone = strings.TrimSpace(zone)
+		if zone == "" {
+			continue
+		}
+		zones = append(zones, Domain(canonicalName(zone)))
+	}
+	return zones
+}
+
+// canonicalName lower cases a name and makes sure it is fully qualified, so names can be compared with each other.
+func canonicalName(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// isSubdomain reports whether name is equal to or beneath parent.
+func isSubdomain(name, parent Domain) bool {
+	n, p := canonicalName(string(name)), canonicalName(string(parent))
+	return n == p || p == "." || strings.HasSuffix(n, "."+p)
+}
+
+// Find returns the longest configured zone containing fqdn.
+func (z ZoneSet) Find(fqdn Domain) (Domain, bool) {
+	var best Domain
+	for _, zone := range z {
+		if isSubdomain(fqdn, zone) && len(zone) > len(best) {
+			best = zone
+		}
+	}
+	return best, best != ""
+}
+
+// ZoneOf returns the zone fqdn belongs to. Names outside of the configured zones are treated as belonging to their
+// last two labels, which matches how names are handled when no zones are configured at all.
+func (z ZoneSet) ZoneOf(fqdn Domain) Domain {
+	if zone, ok := z.Find(fqdn); ok {
+		return zone
+	}
+	labels := dnsLabels(canonicalName(string(fqdn)))
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return Domain(canonicalName(strings.Join(labels, ".")))
+}
+
+func dnsLabels(name string) []string {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return nil
+	}
+	return strings.Split(name, ".")
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestZoneSet_ZoneOf(t *testing.T) {
+	zones := ParseZoneSet("example.com, preview.example.com.,Other.Org")
+
+	assert.Equal(t, ZoneSet{"example.com.", "preview.example.com.", "other.org."}, zones)
+	assert.Equal(t, Domain("preview.example.com."), zones.ZoneOf("App.Preview.Example.com."))
+	assert.Equal(t, Domain("example.com."), zones.ZoneOf("app.example.com"))
+	assert.Equal(t, Domain("other.org."), zones.ZoneOf("other.org."))
+	assert.Equal(t, Domain("unconfigured.net."), zones.ZoneOf("a.b.unconfigured.net."))
+
+	_, found := zones.Find("notexample.com.")
+	assert.False(t, found)
+}
@@ -0,0 +1,128 @@
+// Package provider implements the Ephemerain Terraform provider with the Plugin Framework. Every resource and data
+// source uses the generated client for the HTTP API.
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"os"
+	"server/client"
+)
+
+// Address is the registry address the provider is published at.
+const Address = "registry.terraform.io/mwudka/ephemerain"
+
+// Environment variables the provider configuration falls back to.
+const (
+	envEndpoint = "EPHEMERAIN_ENDPOINT"
+	envToken    = "EPHEMERAIN_TOKEN"
+)
+
+var _ tfsdk.Provider = (*ephemerainProvider)(nil)
+
+type ephemerainProvider struct {
+	version string
+
+	// client is set once the provider is configured
+	client *client.ClientWithResponses
+}
+
+func New(version string) func() tfsdk.Provider {
+	return func() tfsdk.Provider {
+		return &ephemerainProvider{version: version}
+	}
+}
+
+type providerModel struct {
+	Endpoint types.String `tfsdk:"endpoint"`
+	Token    types.String `tfsdk:"token"`
+}
+
+func (p *ephemerainProvider) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Description: "Manages records, ephemeral subdomains and zones of an Ephemerain server through its HTTP API.",
+		Attributes: map[string]tfsdk.Attribute{
+			"endpoint": {
+				Description: "Base URL of the API, including the /v1 prefix, e.g. http://ephemerain/v1. Defaults to the " + envEndpoint + " environment variable.",
+				Type:        types.StringType,
+				Optional:    true,
+			},
+			"token": {
+				Description: "API token sent as a bearer token with every request. Defaults to the " + envToken + " environment variable.",
+				Type:        types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+			},
+		},
+	}, nil
+}
+
+func (p *ephemerainProvider) Configure(ctx context.Context, req tfsdk.ConfigureProviderRequest, resp *tfsdk.ConfigureProviderResponse) {
+	var config providerModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	endpoint, token := os.Getenv(envEndpoint), os.Getenv(envToken)
+	if !config.Endpoint.Null {
+		endpoint = config.Endpoint.Value
+	}
+	if !config.Token.Null {
+		token = config.Token.Value
+	}
+	if endpoint == "" {
+		resp.Diagnostics.AddAttributeError(path.Root("endpoint"), "Missing endpoint", "The endpoint has to be configured, either in the provider block or with the "+envEndpoint+" environment variable.")
+		return
+	}
+
+	var options []client.ClientOption
+	if token != "" {
+		options = append(options, client.WithBearerToken(token))
+	}
+	apiClient, err := client.NewClientWithResponses(endpoint, options...)
+	if err != nil {
+		resp.Diagnostics.AddError("Error creating API client", err.Error())
+		return
+	}
+	p.client = apiClient
+}
+
+func (p *ephemerainProvider) GetResources(ctx context.Context) (map[string]tfsdk.ResourceType, diag.Diagnostics) {
+	return map[string]tfsdk.ResourceType{
+		"ephemerain_record":    recordResourceType{},
+		"ephemerain_subdomain": subdomainResourceType{},
+		"ephemerain_zone":      zoneResourceType{},
+	}, nil
+}
+
+func (p *ephemerainProvider) GetDataSources(ctx context.Context) (map[string]tfsdk.DataSourceType, diag.Diagnostics) {
+	return map[string]tfsdk.DataSourceType{
+		"ephemerain_record":    recordDataSourceType{},
+		"ephemerain_subdomain": subdomainDataSourceType{},
+		"ephemerain_zone":      zoneDataSourceType{},
+	}, nil
+}
+
+// apiClientOf returns the API client the provider was configured with, which is nil when resources and data sources
+// are used before the provider has been configured, such as when validating configuration.
+func apiClientOf(p tfsdk.Provider) (*client.ClientWithResponses, diag.Diagnostics) {
+	var diagnostics diag.Diagnostics
+	provider, ok := p.(*ephemerainProvider)
+	if !ok {
+		diagnostics.AddError("Unexpected provider", "The resource wasn't created by the Ephemerain provider. This is a bug in the provider.")
+		return nil, diagnostics
+	}
+	return provider.client, diagnostics
+}
+
+// requestToken returns the request editors that send token instead of the provider's token, if it is set.
+func requestToken(token types.String) []client.RequestEditorFn {
+	if token.Null || token.Unknown || token.Value == "" {
+		return nil
+	}
+	return []client.RequestEditorFn{client.BearerToken(token.Value)}
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"server/client"
+)
+
+var _ tfsdk.DataSourceType = recordDataSourceType{}
+
+type recordDataSourceType struct{}
+
+// recordDataSource reads every value of a single record.
+type recordDataSource struct {
+	client *client.ClientWithResponses
+}
+
+func (t recordDataSourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Description: "A record with every one of its values.",
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				Type:     types.StringType,
+				Computed: true,
+			},
+			"name": {
+				Description: "Fully qualified name of the record.",
+				Type:        types.StringType,
+				Required:    true,
+			},
+			"type": {
+				Description: "Type of the record, such as A or TXT.",
+				Type:        types.StringType,
+				Required:    true,
+			},
+			"values": {
+				Description: "Every value of the record.",
+				Type:        types.ListType{ElemType: types.StringType},
+				Computed:    true,
+			},
+			"ttl": {
+				Description: "TTL of the record in seconds.",
+				Type:        types.Int64Type,
+				Computed:    true,
+			},
+			"view": {
+				Description: "Split-horizon view to read the record from. The record is read from the default view if it isn't set.",
+				Type:        types.StringType,
+				Optional:    true,
+			},
+			"token": {
+				Description: "API token to read the record with instead of the provider's.",
+				Type:        types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+			},
+		},
+	}, nil
+}
+
+func (t recordDataSourceType) NewDataSource(ctx context.Context, p tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	apiClient, diagnostics := apiClientOf(p)
+	return &recordDataSource{client: apiClient}, diagnostics
+}
+
+func (d *recordDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	var config recordModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	values, ttl, found, err := getRecord(ctx, d.client, config.Name.Value, config.Type.Value, config.View, config.Token)
+	if err != nil {
+		resp.Diagnostics.AddError("Error getting record", err.Error())
+		return
+	}
+	if !found {
+		resp.Diagnostics.AddError("Record not found", fmt.Sprintf("There is no %s record for %s.", config.Type.Value, config.Name.Value))
+		return
+	}
+	config.ID = types.String{Value: recordID(config.Name.Value, config.Type.Value, config.View.Value)}
+	config.Values, config.TTL = values, types.Int64{Value: ttl}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
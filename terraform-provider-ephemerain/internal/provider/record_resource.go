@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"net/http"
+	"server/client"
+	"strings"
+)
+
+// defaultTTL is the TTL the server gives records that are set without one.
+const defaultTTL = 60
+
+var (
+	_ tfsdk.ResourceType            = recordResourceType{}
+	_ tfsdk.ResourceWithImportState = (*recordResource)(nil)
+)
+
+type recordResourceType struct{}
+
+// recordResource manages every value of a single record.
+type recordResource struct {
+	client *client.ClientWithResponses
+}
+
+type recordModel struct {
+	ID     types.String `tfsdk:"id"`
+	Name   types.String `tfsdk:"name"`
+	Type   types.String `tfsdk:"type"`
+	Values []string     `tfsdk:"values"`
+	TTL    types.Int64  `tfsdk:"ttl"`
+	View   types.String `tfsdk:"view"`
+	Token  types.String `tfsdk:"token"`
+}
+
+// recordID identifies a record by its name, type and, if it isn't in the default view, its view.
+func recordID(name, recordType, view string) string {
+	id := name + "/" + recordType
+	if view != "" {
+		id += "/" + view
+	}
+	return id
+}
+
+func viewParam(view types.String) *client.View {
+	if view.Null || view.Value == "" {
+		return nil
+	}
+	v := client.View(view.Value)
+	return &v
+}
+
+// defaultTTLModifier plans the default TTL for a TTL that isn't configured, so that records read back the same as
+// they are planned.
+type defaultTTLModifier struct{}
+
+func (m defaultTTLModifier) Description(ctx context.Context) string {
+	return fmt.Sprintf("Defaults to %d.", defaultTTL)
+}
+
+func (m defaultTTLModifier) MarkdownDescription(ctx context.Context) string {
+	return m.Description(ctx)
+}
+
+func (m defaultTTLModifier) Modify(ctx context.Context, req tfsdk.ModifyAttributePlanRequest, resp *tfsdk.ModifyAttributePlanResponse) {
+	if req.AttributeConfig == nil || !req.AttributeConfig.IsNull() {
+		return
+	}
+	resp.AttributePlan = types.Int64{Value: defaultTTL}
+}
+
+func (t recordResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Description: "A record with every one of its values. Records can be imported with an ID of the form name/type or name/type/view.",
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				Type:          types.StringType,
+				Computed:      true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{tfsdk.UseStateForUnknown()},
+			},
+			"name": {
+				Description:   "Fully qualified name of the record.",
+				Type:          types.StringType,
+				Required:      true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{tfsdk.RequiresReplace()},
+			},
+			"type": {
+				Description:   "Type of the record, such as A or TXT.",
+				Type:          types.StringType,
+				Required:      true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{tfsdk.RequiresReplace()},
+			},
+			"values": {
+				Description: "Every value of the record. TXT values are the text of each record, without quotes.",
+				Type:        types.ListType{ElemType: types.StringType},
+				Required:    true,
+			},
+			"ttl": {
+				Description:   "TTL of the record in seconds.",
+				Type:          types.Int64Type,
+				Optional:      true,
+				Computed:      true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{defaultTTLModifier{}},
+			},
+			"view": {
+				Description:   "Split-horizon view the record belongs to. The record is in the default view if it isn't set.",
+				Type:          types.StringType,
+				Optional:      true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{tfsdk.RequiresReplace()},
+			},
+			"token": {
+				Description: "API token to change the record with instead of the provider's, such as the token of an ephemerain_subdomain the record is in.",
+				Type:        types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+			},
+		},
+	}, nil
+}
+
+func (t recordResourceType) NewResource(ctx context.Context, p tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	apiClient, diagnostics := apiClientOf(p)
+	return &recordResource{client: apiClient}, diagnostics
+}
+
+func (r *recordResource) put(ctx context.Context, model *recordModel) error {
+	ttl := uint32(model.TTL.Value)
+	values := model.Values
+	response, err := r.client.PutDomainWithResponse(ctx, client.Domain(model.Name.Value), client.RecordType(model.Type.Value), &client.PutDomainParams{View: viewParam(model.View)}, client.PutDomainJSONRequestBody{Values: &values, Ttl: &ttl}, requestToken(model.Token)...)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode() != http.StatusNoContent {
+		return fmt.Errorf("unexpected status %s", response.Status())
+	}
+	model.ID = types.String{Value: recordID(model.Name.Value, model.Type.Value, model.View.Value)}
+	return nil
+}
+
+func (r *recordResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	var plan recordModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := r.put(ctx, &plan); err != nil {
+		resp.Diagnostics.AddError("Error setting record", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// getRecord returns the values and TTL of a record, or false if it doesn't exist.
+func getRecord(ctx context.Context, apiClient *client.ClientWithResponses, name, recordType string, view types.String, token types.String) ([]string, int64, bool, error) {
+	response, err := apiClient.GetDomainWithResponse(ctx, client.Domain(name), client.RecordType(recordType), &client.GetDomainParams{View: viewParam(view)}, requestToken(token)...)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if response.StatusCode() == http.StatusNotFound {
+		return nil, 0, false, nil
+	}
+	if response.JSON200 == nil {
+		return nil, 0, false, fmt.Errorf("unexpected status %s", response.Status())
+	}
+	var values []string
+	if response.JSON200.Values != nil {
+		values = *response.JSON200.Values
+	} else if response.JSON200.Value != nil {
+		values = []string{*response.JSON200.Value}
+	}
+	ttl := int64(defaultTTL)
+	if response.JSON200.Ttl != nil {
+		ttl = int64(*response.JSON200.Ttl)
+	}
+	return values, ttl, true, nil
+}
+
+func (r *recordResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var state recordModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	values, ttl, found, err := getRecord(ctx, r.client, state.Name.Value, state.Type.Value, state.View, state.Token)
+	if err != nil {
+		resp.Diagnostics.AddError("Error getting record", err.Error())
+		return
+	}
+	// Records can disappear without Terraform deleting them, such as when the lease of their subdomain expires
+	if !found {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	state.Values, state.TTL = values, types.Int64{Value: ttl}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *recordResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	var plan recordModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := r.put(ctx, &plan); err != nil {
+		resp.Diagnostics.AddError("Error setting record", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *recordResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	var state recordModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	response, err := r.client.DeleteDomainWithResponse(ctx, client.Domain(state.Name.Value), client.RecordType(state.Type.Value), &client.DeleteDomainParams{View: viewParam(state.View)}, requestToken(state.Token)...)
+	if err != nil {
+		resp.Diagnostics.AddError("Error deleting record", err.Error())
+		return
+	}
+	// The record having already been deleted is as good as deleting it
+	if response.StatusCode() != http.StatusNoContent && response.StatusCode() != http.StatusNotFound {
+		resp.Diagnostics.AddError("Error deleting record", fmt.Sprintf("unexpected status %s", response.Status()))
+	}
+}
+
+func (r *recordResource) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
+	parts := strings.Split(req.ID, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		resp.Diagnostics.AddError("Invalid import ID", "Records are imported with an ID of the form name/type or name/type/view.")
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), parts[0])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("type"), parts[1])...)
+	if len(parts) == 3 {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("view"), parts[2])...)
+	}
+}
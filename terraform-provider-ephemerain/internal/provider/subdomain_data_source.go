@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"server/client"
+	"time"
+)
+
+var _ tfsdk.DataSourceType = subdomainDataSourceType{}
+
+type subdomainDataSourceType struct{}
+
+// subdomainDataSource reads the lease on a subdomain claimed outside of Terraform.
+type subdomainDataSource struct {
+	client *client.ClientWithResponses
+}
+
+func (t subdomainDataSourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Description: "An ephemeral subdomain that has already been claimed.",
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				Description: "ID of the lease on the subdomain.",
+				Type:        types.StringType,
+				Required:    true,
+			},
+			"token": {
+				Description: "Owner token of the subdomain, which is needed to read its lease.",
+				Type:        types.StringType,
+				Required:    true,
+				Sensitive:   true,
+			},
+			"domain": {
+				Description: "Fully qualified name of the subdomain.",
+				Type:        types.StringType,
+				Computed:    true,
+			},
+			"expires": {
+				Description: "When the lease on the subdomain expires, in RFC 3339 format.",
+				Type:        types.StringType,
+				Computed:    true,
+			},
+		},
+	}, nil
+}
+
+func (t subdomainDataSourceType) NewDataSource(ctx context.Context, p tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	apiClient, diagnostics := apiClientOf(p)
+	return &subdomainDataSource{client: apiClient}, diagnostics
+}
+
+func (d *subdomainDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	var config subdomainModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	lease, found, err := getLease(ctx, d.client, config.ID.Value, config.Token)
+	if err != nil {
+		resp.Diagnostics.AddError("Error getting lease", err.Error())
+		return
+	}
+	if !found {
+		resp.Diagnostics.AddError("Subdomain not found", "The lease "+config.ID.Value+" doesn't exist or has expired.")
+		return
+	}
+	config.Domain = types.String{Value: lease.Domain}
+	config.Expires = types.String{Value: lease.Expires.UTC().Format(time.RFC3339)}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"net/http"
+	"server/client"
+	"time"
+)
+
+var _ tfsdk.ResourceType = subdomainResourceType{}
+
+type subdomainResourceType struct{}
+
+// subdomainResource claims an ephemeral subdomain, releasing it when it is destroyed. A subdomain whose lease has
+// expired is claimed again, which gives it a different name.
+type subdomainResource struct {
+	client *client.ClientWithResponses
+}
+
+type subdomainModel struct {
+	ID      types.String `tfsdk:"id"`
+	Domain  types.String `tfsdk:"domain"`
+	Token   types.String `tfsdk:"token"`
+	Expires types.String `tfsdk:"expires"`
+}
+
+func (t subdomainResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	unchanged := tfsdk.AttributePlanModifiers{tfsdk.UseStateForUnknown()}
+	return tfsdk.Schema{
+		Description: "An ephemeral subdomain, claimed from the server's subdomain zone when it is created and released when it is destroyed.",
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				Description:   "ID of the lease on the subdomain.",
+				Type:          types.StringType,
+				Computed:      true,
+				PlanModifiers: unchanged,
+			},
+			"domain": {
+				Description:   "Fully qualified name of the subdomain.",
+				Type:          types.StringType,
+				Computed:      true,
+				PlanModifiers: unchanged,
+			},
+			"token": {
+				Description:   "Owner token of the subdomain, which is the only token that can change records at or beneath it.",
+				Type:          types.StringType,
+				Computed:      true,
+				Sensitive:     true,
+				PlanModifiers: unchanged,
+			},
+			"expires": {
+				Description:   "When the lease on the subdomain expires, in RFC 3339 format.",
+				Type:          types.StringType,
+				Computed:      true,
+				PlanModifiers: unchanged,
+			},
+		},
+	}, nil
+}
+
+func (t subdomainResourceType) NewResource(ctx context.Context, p tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	apiClient, diagnostics := apiClientOf(p)
+	return &subdomainResource{client: apiClient}, diagnostics
+}
+
+func (r *subdomainResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	response, err := r.client.ClaimSubdomainWithResponse(ctx)
+	if err != nil {
+		resp.Diagnostics.AddError("Error claiming subdomain", err.Error())
+		return
+	}
+	if response.JSON201 == nil {
+		resp.Diagnostics.AddError("Error claiming subdomain", fmt.Sprintf("unexpected status %s", response.Status()))
+		return
+	}
+	subdomain := response.JSON201
+	resp.Diagnostics.Append(resp.State.Set(ctx, &subdomainModel{
+		ID:      types.String{Value: subdomain.LeaseId},
+		Domain:  types.String{Value: subdomain.Domain},
+		Token:   types.String{Value: subdomain.Token},
+		Expires: types.String{Value: subdomain.Expires.UTC().Format(time.RFC3339)},
+	})...)
+}
+
+// getLease returns the lease with the given ID, or false if it doesn't exist or has expired.
+func getLease(ctx context.Context, apiClient *client.ClientWithResponses, id string, token types.String) (*client.Lease, bool, error) {
+	response, err := apiClient.GetLeaseWithResponse(ctx, client.LeaseId(id), requestToken(token)...)
+	if err != nil {
+		return nil, false, err
+	}
+	if response.StatusCode() == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if response.JSON200 == nil {
+		return nil, false, fmt.Errorf("unexpected status %s", response.Status())
+	}
+	// Expired leases are only deleted once the server gets around to releasing them
+	if !response.JSON200.Expires.After(time.Now()) {
+		return nil, false, nil
+	}
+	return response.JSON200, true, nil
+}
+
+func (r *subdomainResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var state subdomainModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	lease, found, err := getLease(ctx, r.client, state.ID.Value, state.Token)
+	if err != nil {
+		resp.Diagnostics.AddError("Error getting lease", err.Error())
+		return
+	}
+	if !found {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	state.Domain = types.String{Value: lease.Domain}
+	state.Expires = types.String{Value: lease.Expires.UTC().Format(time.RFC3339)}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// Update never changes anything, since every attribute of a subdomain is computed.
+func (r *subdomainResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	var plan subdomainModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *subdomainResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	var state subdomainModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	response, err := r.client.ReleaseLeaseWithResponse(ctx, client.LeaseId(state.ID.Value), requestToken(state.Token)...)
+	if err != nil {
+		resp.Diagnostics.AddError("Error releasing subdomain", err.Error())
+		return
+	}
+	// The lease having already expired and been released is as good as releasing it
+	if response.StatusCode() != http.StatusNoContent && response.StatusCode() != http.StatusNotFound {
+		resp.Diagnostics.AddError("Error releasing subdomain", fmt.Sprintf("unexpected status %s", response.Status()))
+	}
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"context"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"server/client"
+)
+
+var _ tfsdk.DataSourceType = zoneDataSourceType{}
+
+type zoneDataSourceType struct{}
+
+// zoneDataSource reads every record in the default view of a zone.
+type zoneDataSource struct {
+	client *client.ClientWithResponses
+}
+
+func (t zoneDataSourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Description: "Every record in the default view of a zone, except for the SOA and NS records the server generates at the zone apex.",
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				Type:     types.StringType,
+				Computed: true,
+			},
+			"name": {
+				Description: "Fully qualified name of the zone.",
+				Type:        types.StringType,
+				Required:    true,
+			},
+			"records": {
+				Description: "Records in the zone.",
+				Computed:    true,
+				Attributes: tfsdk.SetNestedAttributes(map[string]tfsdk.Attribute{
+					"name": {
+						Description: "Name of the record relative to the zone, or @ for the zone apex.",
+						Type:        types.StringType,
+						Computed:    true,
+					},
+					"type": {
+						Description: "Type of the record.",
+						Type:        types.StringType,
+						Computed:    true,
+					},
+					"ttl": {
+						Description: "TTL of the record in seconds.",
+						Type:        types.Int64Type,
+						Computed:    true,
+					},
+					"values": {
+						Description: "Every value of the record.",
+						Type:        types.ListType{ElemType: types.StringType},
+						Computed:    true,
+					},
+				}),
+			},
+		},
+	}, nil
+}
+
+func (t zoneDataSourceType) NewDataSource(ctx context.Context, p tfsdk.Provider) (tfsdk.DataSource, diag.Diagnostics) {
+	apiClient, diagnostics := apiClientOf(p)
+	return &zoneDataSource{client: apiClient}, diagnostics
+}
+
+func (d *zoneDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceRequest, resp *tfsdk.ReadDataSourceResponse) {
+	var config zoneModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	zone := canonicalZone(config.Name.Value)
+	records, err := exportZone(ctx, d.client, zone)
+	if err != nil {
+		resp.Diagnostics.AddError("Error exporting zone", err.Error())
+		return
+	}
+	config.ID = types.String{Value: zone}
+	config.Records = records
+	resp.Diagnostics.Append(resp.State.Set(ctx, &config)...)
+}
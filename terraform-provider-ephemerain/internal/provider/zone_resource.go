@@ -0,0 +1,269 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"server/client"
+	"strings"
+)
+
+// maxTXTStringLength is the length of the longest character-string a TXT record can hold.
+const maxTXTStringLength = 255
+
+var (
+	_ tfsdk.ResourceType            = zoneResourceType{}
+	_ tfsdk.ResourceWithModifyPlan  = (*zoneResource)(nil)
+	_ tfsdk.ResourceWithImportState = (*zoneResource)(nil)
+)
+
+type zoneResourceType struct{}
+
+// zoneResource manages every record in the default view of a zone at once, the same as importing a zone file does.
+// Records in the zone that aren't in the configuration are deleted.
+type zoneResource struct {
+	client *client.ClientWithResponses
+}
+
+type zoneModel struct {
+	ID      types.String      `tfsdk:"id"`
+	Name    types.String      `tfsdk:"name"`
+	Records []zoneRecordModel `tfsdk:"records"`
+}
+
+type zoneRecordModel struct {
+	Name   string      `tfsdk:"name"`
+	Type   string      `tfsdk:"type"`
+	TTL    types.Int64 `tfsdk:"ttl"`
+	Values []string    `tfsdk:"values"`
+}
+
+func (t zoneResourceType) GetSchema(ctx context.Context) (tfsdk.Schema, diag.Diagnostics) {
+	return tfsdk.Schema{
+		Description: "Every record in the default view of a zone. Records that aren't configured are deleted, except for the SOA and NS records the server generates at the zone apex. Zones can be imported by name.",
+		Attributes: map[string]tfsdk.Attribute{
+			"id": {
+				Type:          types.StringType,
+				Computed:      true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{tfsdk.UseStateForUnknown()},
+			},
+			"name": {
+				Description:   "Fully qualified name of the zone, which has to be served by the server.",
+				Type:          types.StringType,
+				Required:      true,
+				PlanModifiers: tfsdk.AttributePlanModifiers{tfsdk.RequiresReplace()},
+			},
+			"records": {
+				Description: "Records in the zone.",
+				Required:    true,
+				Attributes: tfsdk.SetNestedAttributes(map[string]tfsdk.Attribute{
+					"name": {
+						Description: "Name of the record relative to the zone, or @ for the zone apex.",
+						Type:        types.StringType,
+						Required:    true,
+					},
+					"type": {
+						Description: "Type of the record, such as A or TXT.",
+						Type:        types.StringType,
+						Required:    true,
+					},
+					"ttl": {
+						Description: "TTL of the record in seconds.",
+						Type:        types.Int64Type,
+						Optional:    true,
+						Computed:    true,
+					},
+					"values": {
+						Description: "Every value of the record, in zone file presentation format with fully qualified names. TXT values are the text of each record, without quotes.",
+						Type:        types.ListType{ElemType: types.StringType},
+						Required:    true,
+					},
+				}),
+			},
+		},
+	}, nil
+}
+
+func (t zoneResourceType) NewResource(ctx context.Context, p tfsdk.Provider) (tfsdk.Resource, diag.Diagnostics) {
+	apiClient, diagnostics := apiClientOf(p)
+	return &zoneResource{client: apiClient}, diagnostics
+}
+
+// ModifyPlan plans the default TTL for records whose TTL isn't configured. Attribute plan modifiers can't do this,
+// since changing an attribute of a set element changes the element.
+func (r *zoneResource) ModifyPlan(ctx context.Context, req tfsdk.ModifyResourcePlanRequest, resp *tfsdk.ModifyResourcePlanResponse) {
+	// The plan is null when the zone is being destroyed
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var records []zoneRecordModel
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("records"), &records)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	changed := false
+	for i := range records {
+		if records[i].TTL.Null || records[i].TTL.Unknown {
+			records[i].TTL = types.Int64{Value: defaultTTL}
+			changed = true
+		}
+	}
+	if changed {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("records"), records)...)
+	}
+}
+
+// canonicalZone returns zone with a trailing dot.
+func canonicalZone(zone string) string {
+	if !strings.HasSuffix(zone, ".") {
+		return zone + "."
+	}
+	return zone
+}
+
+// zoneFile writes records as an RFC 1035 zone file for zone.
+func zoneFile(zone string, records []zoneRecordModel) string {
+	var file strings.Builder
+	fmt.Fprintf(&file, "$ORIGIN %s\n", zone)
+	for _, record := range records {
+		owner := zone
+		if record.Name != "@" && record.Name != "" {
+			owner = record.Name + "." + zone
+		}
+		for _, value := range record.Values {
+			if record.Type == "TXT" {
+				value = quoteTXT(value)
+			}
+			fmt.Fprintf(&file, "%s %d IN %s %s\n", owner, record.TTL.Value, record.Type, value)
+		}
+	}
+	return file.String()
+}
+
+// quoteTXT returns text as the quoted character-strings of a TXT record in a zone file, split into as many as are
+// needed to hold it.
+func quoteTXT(text string) string {
+	var strs []string
+	for {
+		chunk := text
+		if len(chunk) > maxTXTStringLength {
+			chunk = text[:maxTXTStringLength]
+		}
+		text = text[len(chunk):]
+		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(chunk)
+		strs = append(strs, `"`+escaped+`"`)
+		if text == "" {
+			return strings.Join(strs, " ")
+		}
+	}
+}
+
+// replaceZone replaces every record in the default view of zone with records.
+func replaceZone(ctx context.Context, apiClient *client.ClientWithResponses, zone string, records []zoneRecordModel) error {
+	response, err := apiClient.ReplaceZoneWithBodyWithResponse(ctx, zone, &client.ReplaceZoneParams{}, "text/plain", strings.NewReader(zoneFile(zone, records)))
+	if err != nil {
+		return err
+	}
+	if response.JSON400 != nil && len(response.JSON400.Failed) > 0 {
+		var reasons []string
+		for _, failed := range response.JSON400.Failed {
+			reason := fmt.Sprintf("line %d", failed.Line)
+			if failed.Reason != nil {
+				reason += ": " + *failed.Reason
+			}
+			reasons = append(reasons, reason)
+		}
+		return fmt.Errorf("invalid records: %s", strings.Join(reasons, "; "))
+	}
+	if response.JSON200 == nil {
+		return fmt.Errorf("unexpected status %s", response.Status())
+	}
+	return nil
+}
+
+// exportZone returns the records in the default view of zone, leaving out the SOA and NS records the server generates
+// at its apex.
+func exportZone(ctx context.Context, apiClient *client.ClientWithResponses, zone string) ([]zoneRecordModel, error) {
+	response, err := apiClient.ExportZoneWithResponse(ctx, zone, &client.ExportZoneParams{})
+	if err != nil {
+		return nil, err
+	}
+	if response.JSON200 == nil {
+		return nil, fmt.Errorf("unexpected status %s", response.Status())
+	}
+	records := []zoneRecordModel{}
+	for _, record := range response.JSON200.Records {
+		if record.Domain == zone && (record.Type == "SOA" || record.Type == "NS") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimSuffix(record.Domain, zone), ".")
+		if name == "" {
+			name = "@"
+		}
+		records = append(records, zoneRecordModel{Name: name, Type: record.Type, TTL: types.Int64{Value: int64(record.Ttl)}, Values: record.Values})
+	}
+	return records, nil
+}
+
+func (r *zoneResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	var plan zoneModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := replaceZone(ctx, r.client, canonicalZone(plan.Name.Value), plan.Records); err != nil {
+		resp.Diagnostics.AddError("Error replacing zone", err.Error())
+		return
+	}
+	plan.ID = types.String{Value: canonicalZone(plan.Name.Value)}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *zoneResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	var state zoneModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	records, err := exportZone(ctx, r.client, canonicalZone(state.Name.Value))
+	if err != nil {
+		resp.Diagnostics.AddError("Error exporting zone", err.Error())
+		return
+	}
+	state.Records = records
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *zoneResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	var plan zoneModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := replaceZone(ctx, r.client, canonicalZone(plan.Name.Value), plan.Records); err != nil {
+		resp.Diagnostics.AddError("Error replacing zone", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *zoneResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	var state zoneModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// Replacing the zone with an empty one deletes every record in it
+	if err := replaceZone(ctx, r.client, canonicalZone(state.Name.Value), nil); err != nil {
+		resp.Diagnostics.AddError("Error deleting zone records", err.Error())
+	}
+}
+
+func (r *zoneResource) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), canonicalZone(req.ID))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), req.ID)...)
+}
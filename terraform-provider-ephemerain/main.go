@@ -0,0 +1,28 @@
+// Command terraform-provider-ephemerain is a Terraform provider that manages records, ephemeral subdomains and zones
+// through the Ephemerain HTTP API.
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"log"
+	"terraform-provider-ephemerain/internal/provider"
+)
+
+// version is set when releases are built.
+var version = "dev"
+
+func main() {
+	var debug bool
+	flag.BoolVar(&debug, "debug", false, "run the provider with support for debuggers like delve")
+	flag.Parse()
+
+	err := providerserver.Serve(context.Background(), provider.New(version), providerserver.ServeOpts{
+		Address: provider.Address,
+		Debug:   debug,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -14,7 +14,7 @@ resource "google_compute_firewall" "production" {
 
   allow {
     protocol = "tcp"
-    ports    = ["80", "443"]
+    ports    = ["53", "80", "443"]
   }
 
   allow {